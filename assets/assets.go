@@ -0,0 +1,9 @@
+// Package assets содержит ресурсы, встроенные в бинарный файл
+package assets
+
+import "embed"
+
+// Fonts - шрифты DejaVu Sans для PDF отчетов (fonts/DejaVuSans.ttf, fonts/DejaVuSans-Bold.ttf)
+//
+//go:embed fonts/*.ttf
+var Fonts embed.FS
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"farmix-cli/internal/bitrix"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// requiredConfigKeys must be present for Bitrix24 commands to work
+var requiredConfigKeys = []string{"bitrix_webhook_url", "catalog_id"}
+
+// configTemplate is the commented config written by "config init"
+const configTemplate = `# Конфигурация farmix-cli
+
+# URL входящего вебхука Bitrix24 (Разработчикам → Другое → Входящий вебхук)
+# Права вебхука: crm, catalog
+# Можно не хранить в файле: переменная окружения FARMIX_BITRIX_WEBHOOK_URL имеет приоритет
+# (так же FARMIX_CATALOG_ID и FARMIX_STORE_ID)
+bitrix_webhook_url: "https://your-domain.bitrix24.ru/rest/1/your-webhook-code/"
+
+# Настройки HTTP клиента Bitrix24 для всех команд (по умолчанию: таймаут 30 с, без повторов,
+# без ограничения частоты запросов)
+# bitrix:
+#   timeout_seconds: 30
+#   max_retries: 3     # повторы при HTTP 429, QUERY_LIMIT_EXCEEDED и ошибках соединения (прочие сетевые ошибки и 5xx - только при чтении), паузы 1, 2, 4... с
+#   rate_limit: 2      # не больше N запросов в секунду
+
+# ID каталога товаров (Магазин → Каталог товаров, IBLOCK_ID в URL)
+catalog_id: "23"
+
+# ID склада по умолчанию для команды crm-add-store (список: farmix-cli crm-list-stores)
+store_id: "1"
+
+# Код пользовательского поля документа прихода со ссылкой на сделку (crm-add-store)
+# Без него документ не связывается со сделкой и повторный приход не проверяется
+# store_document_deal_field: "UF_CAT_STORE_DOCUMENT_S_XXXXX"
+
+# Коды кастомных полей сделок для команды crm-report
+# (CRM → Настройки → Поля → Сделки)
+report_custom_fields:
+  machine_cost: "UF_CRM_XXXXX"       # Рассчетная стоимость м/ч
+  human_cost: "UF_CRM_XXXXX"         # Рассчетная стоимость ч/ч
+  material_cost: "UF_CRM_XXXXX"      # Рассчетная стоимость материала
+  total_cost: "UF_CRM_XXXXX"         # Итоговая стоимость изготовления
+  payment_received: "UF_CRM_XXXXX"   # Оплата получена
+
+# Коды кастомных полей сделок для шапки отчетов команды order
+# order_custom_fields:
+#   project: "UF_CRM_XXXXX"          # Проект
+
+# Статусы сделок, которые исключаются из отчета crm-report (финальные)
+report_excluded_statuses: ["WON", "LOST"]
+# Если ID финальных стадий в воронках разные, укажите их по ID воронки:
+# report_excluded_statuses:
+#   default: ["WON", "LOSE"]
+#   "5": ["C5:WON", "C5:LOSE"]
+
+# Максимальная ширина текстовых колонок таблицы crm-report (по умолчанию 40, -1 - без ограничения)
+# report_max_text_width: 40
+
+# Курсы валют для общего итога crm-report --convert-to (курс каждой валюты в одной базовой валюте)
+# exchange_rates:
+#   RUB: 1
+#   EUR: 100
+
+# Принтеры для материалов: колонка "Принтер" сменного задания order
+# (материалы без назначения печатаются на любом принтере)
+# printer_assignments:
+#   "Bambu ASA-GF": "X1 Carbon"
+
+# Учитывать непечатаемые объекты (вспомогательная геометрия) в list, order, pdf и quote
+# (по умолчанию пропускаются, флаг --include-non-printable переопределяет)
+# include_non_printable: false
+
+# Команда превращения STEP в STL для команды volume
+# ({input} и {output} заменяются путями файлов, без них пути добавляются в конец;
+# путь с пробелами берется в кавычки: '"/Applications/Free CAD.app/Contents/MacOS/FreeCADCmd" step2stl.py')
+# step_tessellator: "freecadcmd step2stl.py {input} {output}"
+`
+
+var configForce bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Работа с конфигурацией ~/.farmix-cli",
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Создать шаблон конфигурации ~/.farmix-cli",
+	Long: `Создать шаблон конфигурации ~/.farmix-cli с комментариями.
+
+Существующая конфигурация не перезаписывается, используйте --force, чтобы заменить ее шаблоном.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		path, err := configFilePath()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: не удалось определить домашний каталог: %v\n", err)
+			os.Exit(1)
+		}
+		if err := writeConfigTemplate(path, configForce); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Создан шаблон конфигурации: %s\n", path)
+		fmt.Println("Заполните bitrix_webhook_url и catalog_id, затем проверьте: farmix-cli config validate")
+	},
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Проверить конфигурацию ~/.farmix-cli",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if _, err := os.Stat(viper.ConfigFileUsed()); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: файл конфигурации %s не найден, создайте его командой farmix-cli config init\n", configDisplayPath)
+			os.Exit(1)
+		}
+
+		problems := validateConfig(viper.GetViper())
+		if len(problems) > 0 {
+			fmt.Fprintf(os.Stderr, "Конфигурация %s содержит ошибки:\n", viper.ConfigFileUsed())
+			for _, problem := range problems {
+				fmt.Fprintf(os.Stderr, "  - %s\n", problem)
+			}
+			os.Exit(1)
+		}
+		fmt.Printf("Конфигурация %s в порядке ✓\n", viper.ConfigFileUsed())
+	},
+}
+
+// writeConfigTemplate writes configTemplate to path, an existing file is kept unless force is set
+func writeConfigTemplate(path string, force bool) error {
+	if !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("файл конфигурации уже существует: %s (используйте --force для перезаписи)", path)
+		}
+	}
+
+	if err := os.WriteFile(path, []byte(configTemplate), 0600); err != nil {
+		return fmt.Errorf("не удалось записать файл конфигурации: %v", err)
+	}
+	return nil
+}
+
+// validateConfig checks that required keys are set, the webhook URL is well-formed
+// and the bitrix client settings are valid numbers
+func validateConfig(v *viper.Viper) []string {
+	var problems []string
+
+	for _, key := range requiredConfigKeys {
+		if strings.TrimSpace(v.GetString(key)) == "" {
+			problems = append(problems, fmt.Sprintf("не задан обязательный ключ %s", key))
+		}
+	}
+
+	if webhookURL := v.GetString("bitrix_webhook_url"); webhookURL != "" {
+		if err := bitrix.ValidateWebhookURL(webhookURL); err != nil {
+			problems = append(problems, fmt.Sprintf("bitrix_webhook_url: %v", err))
+		}
+	}
+
+	if _, err := bitrixClientOptions(v); err != nil {
+		problems = append(problems, err.Error())
+	}
+
+	return problems
+}
+
+func init() {
+	configInitCmd.Flags().BoolVar(&configForce, "force", false, "Перезаписать существующий файл конфигурации")
+
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configValidateCmd)
+	rootCmd.AddCommand(configCmd)
+}
\ No newline at end of file
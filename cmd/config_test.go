@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+// newConfigFromYAML returns a viper instance loaded from YAML content
+func newConfigFromYAML(t *testing.T, content string) *viper.Viper {
+	t.Helper()
+	v := viper.New()
+	v.SetConfigType("yaml")
+	if err := v.ReadConfig(strings.NewReader(content)); err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	return v
+}
+
+func TestWriteConfigTemplate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), configFileName)
+
+	if err := writeConfigTemplate(path, false); err != nil {
+		t.Fatalf("writeConfigTemplate() error = %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read template: %v", err)
+	}
+	v := newConfigFromYAML(t, string(content))
+	for _, key := range []string{"bitrix_webhook_url", "catalog_id", "store_id", "report_custom_fields.machine_cost", "report_excluded_statuses"} {
+		if !v.IsSet(key) {
+			t.Errorf("template does not set %s", key)
+		}
+	}
+	if problems := validateConfig(v); len(problems) != 0 {
+		t.Errorf("template should pass validation, got %v", problems)
+	}
+
+	if err := writeConfigTemplate(path, false); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("expected refusal to overwrite existing config, got %v", err)
+	}
+	if err := writeConfigTemplate(path, true); err != nil {
+		t.Errorf("writeConfigTemplate() with force error = %v", err)
+	}
+}
+
+func TestValidateConfig(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		wantProblems []string
+	}{
+		{
+			name:    "valid config",
+			content: "bitrix_webhook_url: \"https://farmix.bitrix24.ru/rest/10/abc123/\"\ncatalog_id: \"23\"\n",
+		},
+		{
+			name:         "missing catalog_id",
+			content:      "bitrix_webhook_url: \"https://farmix.bitrix24.ru/rest/10/abc123/\"\n",
+			wantProblems: []string{"catalog_id"},
+		},
+		{
+			name:         "missing all required keys",
+			content:      "store_id: \"1\"\n",
+			wantProblems: []string{"bitrix_webhook_url", "catalog_id"},
+		},
+		{
+			name:         "malformed webhook URL",
+			content:      "bitrix_webhook_url: \"farmix.bitrix24.ru/rest/10/abc123\"\ncatalog_id: \"23\"\n",
+			wantProblems: []string{"scheme must be http or https"},
+		},
+		{
+			name:         "webhook URL without rest path",
+			content:      "bitrix_webhook_url: \"https://farmix.bitrix24.ru/\"\ncatalog_id: \"23\"\n",
+			wantProblems: []string{"/rest/"},
+		},
+		{
+			name:         "invalid bitrix client settings",
+			content:      "bitrix_webhook_url: \"https://farmix.bitrix24.ru/rest/10/abc123/\"\ncatalog_id: \"23\"\nbitrix:\n  max_retries: -1\n",
+			wantProblems: []string{"bitrix.max_retries"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems := validateConfig(newConfigFromYAML(t, tt.content))
+			if len(problems) != len(tt.wantProblems) {
+				t.Fatalf("validateConfig() = %v, want %d problems", problems, len(tt.wantProblems))
+			}
+			for i, want := range tt.wantProblems {
+				if !strings.Contains(problems[i], want) {
+					t.Errorf("problem %d = %q, want it to mention %q", i, problems[i], want)
+				}
+			}
+		})
+	}
+}
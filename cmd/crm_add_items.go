@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -16,12 +21,25 @@ import (
 
 
 var (
-	dealID      string
-	projectName string
-	stlDir      string
-	dryRun      bool
+	dealID          string
+	projectName     string
+	stlDir          string
+	dryRun          bool
+	mirrorDirs      bool
+	migrate         bool
+	extensions      []string
+	concurrency     int
+	dedupByHash     bool
+	dryRunOutput    string
+	roundQuantities string
 )
 
+// supported3DExtensions lists file extensions that can become catalog products
+var supported3DExtensions = []string{".stl", ".step", ".3mf", ".obj"}
+
+// default3DExtensions are scanned when --extensions is not specified
+var default3DExtensions = []string{".stl", ".step"}
+
 var crmAddItemsCmd = &cobra.Command{
 	Use:   "crm-add-items",
 	Short: "Add 3D model files (STL/STEP) as products to Bitrix24 deal",
@@ -37,7 +55,32 @@ This command will:
 
 Product names will have "Изделие " prefix and include directory structure.
 
-Use --dry-run flag to preview what would be created without making changes.`,
+Use --extensions to scan other formats (supported: stl, step, 3mf, obj;
+default: stl, step).
+
+Use --migrate to move a legacy customer folder found in the catalog root
+into the "Компании" folder.
+
+Use --mirror-dirs to create catalog subfolders mirroring the directory
+structure under the project folder instead of encoding directories into
+product names.
+
+Use --dedup-by-hash to merge byte-identical files saved under different
+names (left.stl, left_copy.stl) into one product with the summed quantity.
+
+Products are created by --concurrency parallel requests (default 4). A failed
+product doesn't stop the others; all failures are reported at the end and the
+products created meanwhile are reused on the next run.
+
+Product quantities must be whole numbers: Bitrix24 rejects fractional
+quantities for count-tracked products. By default a non-integer quantity is an
+error listing the offending products (checked before anything is created);
+--round-quantities ceil rounds them up, round rounds to the nearest integer.
+
+Use --dry-run flag to preview what would be created without making changes.
+Add --dry-run-output <file> to also save the plan as JSON: the companies,
+customer and project folders (existing/new) and every product with its
+action (create/skip), name, quantity and directory.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runCRMAddItems(); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -60,21 +103,46 @@ func runCRMAddItems() error {
 		return fmt.Errorf("3D files directory cannot be empty")
 	}
 
+	fileExtensions, err := parseExtensions(extensions)
+	if err != nil {
+		return err
+	}
+
+	if concurrency < 1 {
+		return fmt.Errorf("concurrency must be at least 1, got %d", concurrency)
+	}
+
+	if dryRunOutput != "" && !dryRun {
+		return fmt.Errorf("--dry-run-output requires --dry-run")
+	}
+
+	rounding, err := bitrix.ParseQuantityRounding(roundQuantities)
+	if err != nil {
+		return err
+	}
+
 	// Check if 3D files directory exists
 	if _, err := os.Stat(stlDir); os.IsNotExist(err) {
 		return fmt.Errorf("3D files directory does not exist: %s", stlDir)
 	}
 
-	// Get webhook URL from config
-	webhookURL := viper.GetString("bitrix_webhook_url")
-	if webhookURL == "" {
-		return fmt.Errorf("bitrix_webhook_url not configured. Please set it in ~/.farmix-cli config")
+	// Create Bitrix24 client (no requests yet, a missing or malformed webhook URL is reported here);
+	// the dry-run plan is collected only when it is saved
+	clientOptions := []bitrix.ClientOption{bitrix.WithConcurrency(concurrency)}
+	var plan *bitrix.DryRunPlan
+	if dryRunOutput != "" {
+		plan = &bitrix.DryRunPlan{DealID: dealID}
+		clientOptions = append(clientOptions, bitrix.WithDryRunPlan(plan))
+	}
+	client, err := newBitrixClient(clientOptions...)
+	if err != nil {
+		return err
 	}
 
 	// Get catalog ID from config
 	catalogID := viper.GetString("catalog_id")
 	if catalogID == "" {
-		return fmt.Errorf("catalog_id not configured. Please set it in ~/.farmix-cli config")
+		return errNotConfigured("catalog_id")
 	}
 
 	if dryRun {
@@ -83,8 +151,45 @@ func runCRMAddItems() error {
 		fmt.Printf("Processing deal %s with project '%s'...\n", dealID, projectName)
 	}
 
-	// Create Bitrix24 client
-	client := bitrix.NewClient(webhookURL)
+	// Find 3D files
+	extensionsLabel := strings.ToUpper(strings.ReplaceAll(strings.Join(fileExtensions, "/"), ".", ""))
+	fmt.Printf("Scanning for 3D files (%s) in %s...\n", extensionsLabel, stlDir)
+	files3D, err := find3DFiles(stlDir, fileExtensions)
+	if err != nil {
+		return fmt.Errorf("failed to find 3D files: %v", err)
+	}
+
+	if len(files3D) == 0 {
+		return fmt.Errorf("no 3D files (%s) found in directory: %s", extensionsLabel, stlDir)
+	}
+
+	// Identical meshes saved under different names become one product
+	if dedupByHash {
+		var merges []fileMerge
+		files3D, merges, err = dedupFilesByHash(stlDir, files3D)
+		if err != nil {
+			return fmt.Errorf("failed to compare 3D files: %v", err)
+		}
+		for _, merge := range merges {
+			fmt.Printf("Merged identical files into %s (quantity: %.0f): %s\n", merge.Kept, merge.Quantity, strings.Join(merge.Duplicates, ", "))
+		}
+	}
+
+	// Sort files alphabetically by their final product names (including directory prefixes)
+	sort.Slice(files3D, func(i, j int) bool {
+		cleanI, quantityI := files3D[i].ParseName()
+		cleanJ, quantityJ := files3D[j].ParseName()
+		nameI := bitrix.FormatProductNameWithDir(cleanI, files3D[i].DirPath, quantityI)
+		nameJ := bitrix.FormatProductNameWithDir(cleanJ, files3D[j].DirPath, quantityJ)
+		return strings.ToLower(nameI) < strings.ToLower(nameJ)
+	})
+
+	fmt.Printf("Found %d 3D files\n", len(files3D))
+
+	// Fractional quantities are rejected before any catalog changes (sections included)
+	if _, err := bitrix.CreateDealProductRowsWithRounding(fileProductInfos(files3D), rounding); err != nil {
+		return fmt.Errorf("%v (use --round-quantities ceil or round)", err)
+	}
 
 	// Get deal information
 	fmt.Println("Getting deal information...")
@@ -107,7 +212,7 @@ func runCRMAddItems() error {
 	} else {
 		fmt.Printf("Ensuring companies folder and customer '%s' exist...\n", customerName)
 	}
-	customerSectionID, err := client.EnsureCustomerSection(customerName, catalogID, dryRun)
+	customerSectionID, err := client.EnsureCustomerSection(customerName, catalogID, dryRun, migrate)
 	if err != nil {
 		return fmt.Errorf("failed to ensure customer section: %v", err)
 	}
@@ -123,35 +228,24 @@ func runCRMAddItems() error {
 		return fmt.Errorf("failed to ensure project section: %v", err)
 	}
 
-	// Find 3D files
-	fmt.Printf("Scanning for 3D files (STL/STEP) in %s...\n", stlDir)
-	files3D, err := find3DFiles(stlDir)
-	if err != nil {
-		return fmt.Errorf("failed to find 3D files: %v", err)
-	}
-
-	if len(files3D) == 0 {
-		return fmt.Errorf("no 3D files (STL/STEP) found in directory: %s", stlDir)
-	}
-
-	// Sort files alphabetically by their final product names (including directory prefixes)
-	sort.Slice(files3D, func(i, j int) bool {
-		cleanI, quantityI := bitrix.ParseFileName(files3D[i].FileName)
-		cleanJ, quantityJ := bitrix.ParseFileName(files3D[j].FileName)
-		nameI := bitrix.FormatProductNameWithDir(cleanI, files3D[i].DirPath, quantityI)
-		nameJ := bitrix.FormatProductNameWithDir(cleanJ, files3D[j].DirPath, quantityJ)
-		return strings.ToLower(nameI) < strings.ToLower(nameJ)
-	})
-
-	fmt.Printf("Found %d 3D files\n", len(files3D))
-
 	// Create products for 3D files
 	if dryRun {
 		fmt.Printf("[DRY RUN] Analyzing products that would be created...\n")
 	} else {
 		fmt.Println("Creating products in catalog...")
 	}
-	products, err := client.CreateProductsFrom3DFiles(files3D, projectSectionID, catalogID, dryRun)
+	// Dry run keeps per-product preview lines, real runs show a compact progress counter
+	var progress bitrix.ProgressFunc
+	if !dryRun {
+		progress = newProgressPrinter(os.Stdout, "Creating products", isTerminal(os.Stdout))
+	}
+
+	var products []bitrix.ProductInfo
+	if mirrorDirs {
+		products, err = client.CreateProductsInDirSections(files3D, projectSectionID, catalogID, dryRun, progress)
+	} else {
+		products, err = client.CreateProductsFrom3DFiles(files3D, projectSectionID, catalogID, dryRun, progress)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create products: %v", err)
 	}
@@ -168,7 +262,10 @@ func runCRMAddItems() error {
 	} else {
 		fmt.Println("Adding products to deal...")
 	}
-	productRows := bitrix.CreateDealProductRows(products)
+	productRows, err := bitrix.CreateDealProductRowsWithRounding(products, rounding)
+	if err != nil {
+		return fmt.Errorf("%v (use --round-quantities ceil or round)", err)
+	}
 	err = client.AddProductRowsToDeal(dealID, productRows, dryRun)
 	if err != nil {
 		return fmt.Errorf("failed to add products to deal: %v", err)
@@ -185,21 +282,106 @@ func runCRMAddItems() error {
 		fmt.Println("Products created:")
 	}
 	for i, fileInfo := range files3D {
-		cleanName, quantity := bitrix.ParseFileName(fileInfo.FileName)
+		cleanName, quantity := fileInfo.ParseName()
+		rowQuantity := productRows[i].Quantity
 		productName := bitrix.FormatProductNameWithDir(cleanName, fileInfo.DirPath, quantity)
+		if mirrorDirs && fileInfo.DirPath != "" {
+			productName = filepath.ToSlash(fileInfo.DirPath) + "/" + bitrix.FormatProductName(cleanName, quantity)
+		}
 		if dryRun {
-			fmt.Printf("  - %s (ID: %s, Quantity: %.0f)\n", productName, products[i].ID, quantity)
+			fmt.Printf("  - %s (ID: %s, Quantity: %.0f)\n", productName, products[i].ID, rowQuantity)
 		} else {
-			fmt.Printf("  - %s (ID: %s, Quantity: %.0f)\n", productName, products[i].ID, quantity)
+			fmt.Printf("  - %s (ID: %s, Quantity: %.0f) %s\n", productName, products[i].ID, rowQuantity, client.GetProductURL(catalogID, products[i].ID))
+		}
+	}
+
+	// Placeholder IDs of a dry run have no catalog pages
+	if !dryRun {
+		fmt.Printf("Project folder: %s\n", client.GetSectionURL(catalogID, projectSectionID))
+	}
+
+	if plan != nil {
+		if err := writeDryRunPlan(dryRunOutput, plan); err != nil {
+			return err
 		}
+		fmt.Printf("[DRY RUN] Plan saved to %s\n", dryRunOutput)
 	}
 
 	return nil
 }
 
-// find3DFiles finds all 3D model files (.stl and .step) in the specified directory
+// fileProductInfos describes the products the files would become (no IDs yet) for quantity validation
+func fileProductInfos(files []bitrix.FileInfo) []bitrix.ProductInfo {
+	infos := make([]bitrix.ProductInfo, len(files))
+	for i, fileInfo := range files {
+		cleanName, quantity := fileInfo.ParseName()
+		infos[i] = bitrix.ProductInfo{
+			Name:     bitrix.FormatProductNameWithDir(cleanName, fileInfo.DirPath, quantity),
+			Quantity: quantity,
+		}
+	}
+	return infos
+}
+
+// writeDryRunPlan saves the collected dry-run plan as indented JSON
+func writeDryRunPlan(path string, plan *bitrix.DryRunPlan) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create dry-run output file: %v", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(plan); err != nil {
+		return fmt.Errorf("failed to write dry-run plan: %v", err)
+	}
+	return nil
+}
+
+// parseExtensions normalizes --extensions values ("STL", ".step") and validates them against supported3DExtensions
+func parseExtensions(values []string) ([]string, error) {
+	if len(values) == 0 {
+		return default3DExtensions, nil
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, value := range values {
+		ext := strings.ToLower(strings.TrimSpace(value))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+
+		supported := false
+		for _, allowed := range supported3DExtensions {
+			if ext == allowed {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return nil, fmt.Errorf("unsupported extension %q (supported: %s)", value, strings.Join(supported3DExtensions, ", "))
+		}
+
+		if !seen[ext] {
+			seen[ext] = true
+			result = append(result, ext)
+		}
+	}
+
+	if len(result) == 0 {
+		return default3DExtensions, nil
+	}
+	return result, nil
+}
+
+// find3DFiles finds all 3D model files with the given extensions (e.g. ".stl") in the specified directory
 // Returns files with directory information in the order they are discovered
-func find3DFiles(dir string) ([]bitrix.FileInfo, error) {
+func find3DFiles(dir string, extensions []string) ([]bitrix.FileInfo, error) {
 	var files3D []bitrix.FileInfo
 
 	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
@@ -211,8 +393,7 @@ func find3DFiles(dir string) ([]bitrix.FileInfo, error) {
 			return nil
 		}
 
-		lowerName := strings.ToLower(d.Name())
-		if strings.HasSuffix(lowerName, ".stl") || strings.HasSuffix(lowerName, ".step") {
+		if hasExtension(d.Name(), extensions) {
 			// Calculate relative directory path from base directory
 			relDir, err := filepath.Rel(dir, filepath.Dir(path))
 			if err != nil {
@@ -224,9 +405,10 @@ func find3DFiles(dir string) ([]bitrix.FileInfo, error) {
 				relDir = ""
 			}
 
+			// Always use "/" so product names and catalog sections don't depend on the OS
 			files3D = append(files3D, bitrix.FileInfo{
 				FileName: d.Name(),
-				DirPath:  relDir,
+				DirPath:  filepath.ToSlash(relDir),
 			})
 		}
 
@@ -240,11 +422,93 @@ func find3DFiles(dir string) ([]bitrix.FileInfo, error) {
 	return files3D, nil
 }
 
+// fileMerge describes identical files merged into one product by --dedup-by-hash
+type fileMerge struct {
+	Kept       string   // relative path of the file the product is created from
+	Duplicates []string // relative paths of the files merged into it
+	Quantity   float64  // summed quantity of all merged files
+}
+
+// dedupFilesByHash merges files with identical content (SHA-256) into the first of them.
+// The kept file gets the summed quantity of the group, the order of the remaining files is preserved
+func dedupFilesByHash(dir string, files []bitrix.FileInfo) ([]bitrix.FileInfo, []fileMerge, error) {
+	var result []bitrix.FileInfo
+	var merges []*fileMerge
+	keptByHash := make(map[string]int)       // hash -> index in result
+	mergeByIndex := make(map[int]*fileMerge) // index in result -> merge
+
+	for _, fileInfo := range files {
+		hash, err := hashFile(filepath.Join(dir, filepath.FromSlash(fileInfo.DirPath), fileInfo.FileName))
+		if err != nil {
+			return nil, nil, err
+		}
+
+		_, quantity := fileInfo.ParseName()
+		index, seen := keptByHash[hash]
+		if !seen {
+			keptByHash[hash] = len(result)
+			result = append(result, fileInfo)
+			continue
+		}
+
+		kept := &result[index]
+		merge, merged := mergeByIndex[index]
+		if !merged {
+			_, keptQuantity := kept.ParseName()
+			merge = &fileMerge{Kept: path.Join(kept.DirPath, kept.FileName), Quantity: keptQuantity}
+			mergeByIndex[index] = merge
+			merges = append(merges, merge)
+		}
+		merge.Duplicates = append(merge.Duplicates, path.Join(fileInfo.DirPath, fileInfo.FileName))
+		merge.Quantity += quantity
+		kept.Quantity = merge.Quantity
+	}
+
+	report := make([]fileMerge, len(merges))
+	for i, merge := range merges {
+		report[i] = *merge
+	}
+	return result, report, nil
+}
+
+// hashFile returns the hex SHA-256 of the file content
+func hashFile(filePath string) (string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", filePath, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hasExtension reports whether fileName ends with one of extensions (case-insensitive)
+func hasExtension(fileName string, extensions []string) bool {
+	lowerName := strings.ToLower(fileName)
+	for _, ext := range extensions {
+		if strings.HasSuffix(lowerName, ext) {
+			return true
+		}
+	}
+	return false
+}
+
 func init() {
 	crmAddItemsCmd.Flags().StringVar(&dealID, "deal-id", "", "Bitrix24 deal ID (required)")
 	crmAddItemsCmd.Flags().StringVar(&projectName, "project-name", "", "Project name for folder creation (required)")
 	crmAddItemsCmd.Flags().StringVar(&stlDir, "stl-dir", "", "Directory containing 3D model files (STL/STEP) (required)")
 	crmAddItemsCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview what would be created without making changes")
+	crmAddItemsCmd.Flags().StringVar(&dryRunOutput, "dry-run-output", "", "Save the dry-run plan as JSON to this file (requires --dry-run)")
+	crmAddItemsCmd.Flags().StringSliceVar(&extensions, "extensions", []string{"stl", "step"}, "3D file extensions to scan (supported: stl, step, 3mf, obj)")
+	crmAddItemsCmd.Flags().BoolVar(&migrate, "migrate", false, "Move a customer folder found in the catalog root into the companies folder")
+	crmAddItemsCmd.Flags().BoolVar(&mirrorDirs, "mirror-dirs", false, "Mirror the directory structure as catalog subfolders under the project folder")
+	crmAddItemsCmd.Flags().IntVar(&concurrency, "concurrency", 4, "Number of products created in parallel")
+	crmAddItemsCmd.Flags().StringVar(&roundQuantities, "round-quantities", "error", "Handling of non-integer product quantities: error, ceil or round")
+	crmAddItemsCmd.Flags().BoolVar(&dedupByHash, "dedup-by-hash", false, "Merge files with identical content into one product with the summed quantity")
 
 	crmAddItemsCmd.MarkFlagRequired("deal-id")
 	crmAddItemsCmd.MarkFlagRequired("project-name")
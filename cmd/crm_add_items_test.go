@@ -13,6 +13,7 @@ func TestFind3DFiles(t *testing.T) {
 	tests := []struct {
 		name        string
 		setupFiles  []string
+		extensions  []string // nil means default3DExtensions
 		expectedFiles []bitrix.FileInfo
 		expectError bool
 	}{
@@ -109,6 +110,22 @@ func TestFind3DFiles(t *testing.T) {
 			expectedFiles: []bitrix.FileInfo{},
 			expectError: false,
 		},
+		{
+			name: "mixed directory with requested 3MF and OBJ only",
+			setupFiles: []string{
+				"part1.stl",
+				"gear.step",
+				"assembly.3MF",
+				"sub/model.obj",
+				"readme.txt",
+			},
+			extensions: []string{".3mf", ".obj"},
+			expectedFiles: []bitrix.FileInfo{
+				{FileName: "assembly.3MF", DirPath: ""},
+				{FileName: "model.obj", DirPath: "sub"},
+			},
+			expectError: false,
+		},
 		{
 			name: "handles files with similar extensions and quantity prefixes",
 			setupFiles: []string{
@@ -158,7 +175,11 @@ func TestFind3DFiles(t *testing.T) {
 			}
 
 			// Test the function
-			result, err := find3DFiles(tempDir)
+			fileExtensions := tt.extensions
+			if fileExtensions == nil {
+				fileExtensions = default3DExtensions
+			}
+			result, err := find3DFiles(tempDir, fileExtensions)
 
 			// Check error expectation
 			if tt.expectError && err == nil {
@@ -207,7 +228,7 @@ func TestFind3DFiles(t *testing.T) {
 func TestFind3DFilesNonExistentDirectory(t *testing.T) {
 	// Test with non-existent directory
 	nonExistentDir := "/tmp/non_existent_directory_12345"
-	_, err := find3DFiles(nonExistentDir)
+	_, err := find3DFiles(nonExistentDir, default3DExtensions)
 	
 	if err == nil {
 		t.Error("Expected error for non-existent directory, but got none")
@@ -232,7 +253,7 @@ func TestFind3DFilesPermissionDenied(t *testing.T) {
 	// The function should handle permission errors gracefully
 	// Since filepath.WalkDir handles permission errors by calling the WalkDirFunc with the error,
 	// and our implementation returns that error, we expect an error here
-	_, err = find3DFiles(tempDir)
+	_, err = find3DFiles(tempDir, default3DExtensions)
 	
 	// On some systems, permission errors might be handled differently
 	// The important thing is that the function doesn't panic
@@ -241,3 +262,96 @@ func TestFind3DFilesPermissionDenied(t *testing.T) {
 	}
 }
 
+
+
+func TestParseExtensions(t *testing.T) {
+	tests := []struct {
+		name        string
+		values      []string
+		expected    []string
+		expectError bool
+	}{
+		{
+			name:     "empty uses defaults",
+			values:   nil,
+			expected: []string{".stl", ".step"},
+		},
+		{
+			name:     "normalizes case and dots",
+			values:   []string{"STL", ".3mf", " obj "},
+			expected: []string{".stl", ".3mf", ".obj"},
+		},
+		{
+			name:     "removes duplicates",
+			values:   []string{"stl", ".STL", "step"},
+			expected: []string{".stl", ".step"},
+		},
+		{
+			name:        "rejects unsupported extension",
+			values:      []string{"stl", "dwg"},
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseExtensions(tt.values)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error for %v, got %v", tt.values, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseExtensions(%v) = %v, want %v", tt.values, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestDedupFilesByHash(t *testing.T) {
+	dir := t.TempDir()
+	mesh := []byte("solid left\nendsolid left\n")
+	for name, content := range map[string][]byte{
+		"left.stl":                             mesh,
+		filepath.Join("copies", "2x_left.stl"): mesh,
+		"right.stl":                            []byte("solid right\nendsolid right\n"),
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := find3DFiles(dir, default3DExtensions)
+	if err != nil {
+		t.Fatalf("find3DFiles() error = %v", err)
+	}
+
+	result, merges, err := dedupFilesByHash(dir, files)
+	if err != nil {
+		t.Fatalf("dedupFilesByHash() error = %v", err)
+	}
+
+	expected := []bitrix.FileInfo{
+		{FileName: "2x_left.stl", DirPath: "copies", Quantity: 3},
+		{FileName: "right.stl"},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("files = %+v, want %+v", result, expected)
+	}
+	if _, quantity := result[0].ParseName(); quantity != 3 {
+		t.Errorf("merged quantity = %v, want 3", quantity)
+	}
+
+	expectedMerges := []fileMerge{{Kept: "copies/2x_left.stl", Duplicates: []string{"left.stl"}, Quantity: 3}}
+	if !reflect.DeepEqual(merges, expectedMerges) {
+		t.Errorf("merges = %+v, want %+v", merges, expectedMerges)
+	}
+}
@@ -1,10 +1,13 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 
 	"farmix-cli/internal/bitrix"
+	"farmix-cli/internal/formatter"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,8 +18,15 @@ var (
 	addStoreStoreID string
 	addStoreDryRun  bool
 	addStoreCurrency string
+	addStoreStrict   bool
+	addStoreForce    bool
+	addStoreResume   string
+	addStoreNoVerify bool
 )
 
+// defaultStoreCurrency is used when neither --currency nor the deal sets a currency
+const defaultStoreCurrency = "RUB"
+
 var crmAddStoreCmd = &cobra.Command{
 	Use:   "crm-add-store",
 	Short: "Создание документа прихода на склад из товаров сделки Bitrix24",
@@ -33,25 +43,54 @@ var crmAddStoreCmd = &cobra.Command{
 Документ будет использовать ID товаров для точности и останется в статусе черновика.
 Для обновления складских остатков документ нужно провести вручную в Bitrix24.
 
+Валюта документа берется из флага --currency, если он указан явно,
+иначе из сделки (CURRENCY_ID), а при ее отсутствии используется RUB.
+
+Каждый добавленный товар проверяется по ID созданного элемента документа.
+Если добавлено меньше товаров, чем в сделке, команда завершается ошибкой;
+с флагом --strict=false выводится только предупреждение.
+
+Документ связывается со сделкой через пользовательское поле документа, код
+которого задается ключом store_document_deal_field в ~/.farmix-cli
+(например, UF_CAT_STORE_DOCUMENT_S_1758649547). Без этого ключа связь не
+устанавливается и проверка повторного прихода не выполняется.
+
+Перед созданием проверяются документы прихода, уже связанные со сделкой.
+Если есть проведенный документ, команда откажется создавать новый, чтобы
+не оприходовать товары дважды; используйте --force, чтобы создать его все равно.
+
+Если предыдущий запуск прервался (товары добавлены не все или документ не
+проведен), используйте --resume <ID документа>: новый документ не создается,
+в указанный непроведенный документ добавляются только недостающие товары сделки
+(сверка по ID товара с catalog.document.element.list), после чего документ проводится.
+После проведения количества товаров в документе сверяются с количествами в
+сделке; при расхождении выводится таблица сверки и команда завершается ошибкой
+(--no-verify отключает проверку).
+
 Используйте флаг --dry-run для предварительного просмотра без внесения изменений.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := runCRMAddStore(); err != nil {
+		if err := runCRMAddStore(cmd.Flags().Changed("currency")); err != nil {
 			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
 			os.Exit(1)
 		}
 	},
 }
 
-func runCRMAddStore() error {
+func runCRMAddStore(currencyChanged bool) error {
 	// Validate parameters
 	if err := bitrix.ValidateDealID(addStoreDealID); err != nil {
 		return fmt.Errorf("неверный ID сделки: %v", err)
 	}
+	if addStoreResume != "" {
+		if id, err := strconv.Atoi(addStoreResume); err != nil || id <= 0 {
+			return fmt.Errorf("неверный ID документа для --resume: %s", addStoreResume)
+		}
+	}
 
-	// Get webhook URL from config
-	webhookURL := viper.GetString("bitrix_webhook_url")
-	if webhookURL == "" {
-		return fmt.Errorf("bitrix_webhook_url не настроен. Пожалуйста, установите его в конфигурации ~/.farmix-cli")
+	// Create Bitrix24 client (no requests yet, a missing or malformed webhook URL is reported here)
+	client, err := newBitrixClient()
+	if err != nil {
+		return err
 	}
 
 	// Use store_id from config if not specified via flag
@@ -68,14 +107,11 @@ func runCRMAddStore() error {
 		fmt.Printf("Обработка сделки %s для создания документа прихода...\n", addStoreDealID)
 	}
 
-	// Create Bitrix24 client
-	client := bitrix.NewClient(webhookURL)
-
 	// Check if warehouse management is enabled
 	fmt.Println("Проверка статуса складского учета...")
 	enabled, err := client.CheckStoreDocumentMode()
 	if err != nil {
-		return fmt.Errorf("не удалось проверить статус складского учета: %v", err)
+		return storeAccessError("не удалось проверить статус складского учета", err)
 	}
 	if !enabled {
 		return fmt.Errorf("складской учет не включен в Bitrix24")
@@ -86,7 +122,7 @@ func runCRMAddStore() error {
 	fmt.Println("Тестирование доступа к API складов...")
 	stores, listErr := client.ListStores()
 	if listErr != nil {
-		return fmt.Errorf("нет доступа к API складов: %v\n\nПроверьте права доступа:\n1. Войдите в Bitrix24 → Разработчикам → Другое → Входящий вебхук\n2. Найдите ваш вебхук и нажмите \"Изменить\"\n3. Убедитесь, что включены права доступа:\n   - catalog (Торговый каталог)\n   - crm (CRM)\n4. Сохраните изменения и попробуйте снова\n\nТакже проверьте:\n- Складской учет активирован в Bitrix24 (Настройки → Настройки модулей → Торговый каталог)\n- Созданы склады в разделе \"Магазин\" → \"Склады\"", listErr)
+		return storeAccessError("не удалось получить список складов", listErr)
 	}
 
 	if len(stores) == 0 {
@@ -136,6 +172,29 @@ func runCRMAddStore() error {
 	}
 	fmt.Printf("Сделка: %s\n", deal.Title)
 
+	if addStoreResume != "" {
+		return resumeStoreDocument(client, addStoreResume, addStoreDealID, addStoreStoreID, addStoreDryRun, !addStoreNoVerify)
+	}
+
+	// Refuse to double-count inventory if the deal already has a confirmed receipt
+	// Documents are linked to deals by a portal-specific custom field
+	dealField := viper.GetString("store_document_deal_field")
+	if dealField == "" {
+		fmt.Println("Внимание: store_document_deal_field не настроен, документ не будет связан со сделкой и проверка повторного прихода пропущена")
+	} else {
+		fmt.Println("Проверка существующих документов прихода по сделке...")
+		existingDocuments, err := client.ListStoreDocumentsForDeal(addStoreDealID, dealField)
+		if err != nil {
+			return fmt.Errorf("не удалось получить документы прихода по сделке: %v", err)
+		}
+		if err := checkExistingStoreDocuments(existingDocuments, addStoreForce); err != nil {
+			return err
+		}
+	}
+
+	currency, currencySource := selectStoreCurrency(addStoreCurrency, currencyChanged, deal.CurrencyID)
+	fmt.Printf("Валюта документа: %s (%s)\n", currency, currencySource)
+
 	// Get products from deal
 	fmt.Println("Получение товаров из сделки...")
 	products, err := client.GetExistingProductRows(addStoreDealID)
@@ -153,7 +212,7 @@ func runCRMAddStore() error {
 		fmt.Printf("[ТЕСТОВЫЙ РЕЖИМ] Товары, которые будут добавлены на склад:\n")
 		for _, product := range products {
 			fmt.Printf("  - ID товара: %s, Количество: %.2f, Цена: %.2f %s\n",
-				product.ProductID.String(), product.Quantity, product.Price, addStoreCurrency)
+				product.ProductID.String(), product.Quantity, product.Price, currency)
 		}
 		fmt.Printf("[ТЕСТОВЫЙ РЕЖИМ] Будет создан документ прихода с %d товарами\n", len(products))
 		fmt.Printf("[ТЕСТОВЫЙ РЕЖИМ] Товары будут добавлены на склад: %s (ID: %d)\n", store.Title, store.ID)
@@ -162,7 +221,7 @@ func runCRMAddStore() error {
 
 	// Create warehouse receipt document
 	fmt.Println("Создание документа прихода...")
-	documentID, err := client.CreateStoreDocument(deal, addStoreCurrency, fmt.Sprintf("Приход товаров по сделке %s", addStoreDealID))
+	documentID, err := client.CreateStoreDocument(deal, currency, fmt.Sprintf("Приход товаров по сделке %s", addStoreDealID), dealField)
 	if err != nil {
 		return fmt.Errorf("не удалось создать документ прихода: %v", err)
 	}
@@ -171,11 +230,15 @@ func runCRMAddStore() error {
 	// Add products to document
 	fmt.Println("Добавление товаров в документ...")
 	fmt.Printf("Добавляем товары в документ ID: %s на склад ID: %s\n", documentID, addStoreStoreID)
-	err = client.AddElementsToStoreDocument(documentID, products, addStoreStoreID)
+	added, err := client.AddElementsToStoreDocument(documentID, products, addStoreStoreID, addStoreStrict)
 	if err != nil {
-		return fmt.Errorf("не удалось добавить товары в документ: %v", err)
+		return fmt.Errorf("не удалось добавить товары в документ %s (документ не проводите): %v", documentID, err)
+	}
+	if added != len(products) {
+		fmt.Printf("Внимание: добавлено %d из %d товаров, документ неполный\n", added, len(products))
+	} else {
+		fmt.Printf("Добавлено %d товаров в документ\n", added)
 	}
-	fmt.Printf("Добавлено %d товаров в документ\n", len(products))
 
 	fmt.Printf("Успешно создан документ прихода %s (черновик)\n", documentID)
 	fmt.Printf("Товары добавлены в документ (ID склада: %s):\n", addStoreStoreID)
@@ -187,11 +250,147 @@ func runCRMAddStore() error {
 	return nil
 }
 
+// storeAccessError describes a failed warehouse API call; when Bitrix24 denies access
+// the message explains which webhook permissions are needed
+func storeAccessError(action string, err error) error {
+	if !errors.Is(err, bitrix.ErrAccessDenied) {
+		return fmt.Errorf("%s: %v", action, err)
+	}
+	return fmt.Errorf("нет доступа к API складов: %v\n\nПроверьте права доступа:\n1. Войдите в Bitrix24 → Разработчикам → Другое → Входящий вебхук\n2. Найдите ваш вебхук и нажмите \"Изменить\"\n3. Убедитесь, что включены права доступа:\n   - catalog (Торговый каталог)\n   - crm (CRM)\n4. Сохраните изменения и попробуйте снова\n\nТакже проверьте:\n- Складской учет активирован в Bitrix24 (Настройки → Настройки модулей → Торговый каталог)\n- Созданы склады в разделе \"Магазин\" → \"Склады\"", err)
+}
+
+// resumeStoreDocument completes an unconfirmed receipt document left by an interrupted run:
+// it adds only the deal products that have no element in the document yet and confirms it.
+// Missing products are added in strict mode, so an incomplete document is never confirmed
+func resumeStoreDocument(client *bitrix.Client, documentID, dealID, storeID string, dryRun, verify bool) error {
+	fmt.Printf("Продолжение документа прихода %s...\n", documentID)
+	document, err := client.GetStoreDocument(documentID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить документ прихода %s: %v", documentID, err)
+	}
+	if document.IsConfirmed() {
+		return fmt.Errorf("документ %s уже проведен", documentID)
+	}
+	if document.DocType != "" && document.DocType != "S" {
+		return fmt.Errorf("документ %s не является документом прихода (тип %s)", documentID, document.DocType)
+	}
+
+	products, err := client.GetExistingProductRows(dealID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить товары из сделки: %v", err)
+	}
+	if len(products) == 0 {
+		return fmt.Errorf("в сделке %s не найдено товаров", dealID)
+	}
+
+	elements, err := client.ListStoreDocumentElements(documentID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить товары документа %s: %v", documentID, err)
+	}
+	missing := bitrix.MissingStoreDocumentProducts(products, elements)
+	fmt.Printf("В документе %d товаров, недостает %d из %d товаров сделки\n", len(elements), len(missing), len(products))
+
+	if dryRun {
+		for _, product := range missing {
+			fmt.Printf("[ТЕСТОВЫЙ РЕЖИМ] Будет добавлен товар ID %s, Количество: %.2f\n", product.ProductID.String(), product.Quantity)
+		}
+		fmt.Printf("[ТЕСТОВЫЙ РЕЖИМ] Документ %s будет проведен\n", documentID)
+		return nil
+	}
+
+	if len(missing) > 0 {
+		added, err := client.AddElementsToStoreDocument(documentID, missing, storeID, true)
+		if err != nil {
+			return fmt.Errorf("не удалось добавить товары в документ %s (документ не проведен): %v", documentID, err)
+		}
+		fmt.Printf("Добавлено %d товаров в документ\n", added)
+	}
+
+	fmt.Println("Проведение документа...")
+	if err := client.ConfirmStoreDocument(documentID); err != nil {
+		return fmt.Errorf("не удалось провести документ %s: %v", documentID, err)
+	}
+	fmt.Printf("Документ прихода %s проведен\n", documentID)
+
+	if !verify {
+		return nil
+	}
+	return verifyStoreDocument(client, documentID, products)
+}
+
+// verifyStoreDocument re-reads the confirmed document and compares product amounts with the deal,
+// printing the reconciliation table; any difference (partial add, rounding) is an error
+func verifyStoreDocument(client *bitrix.Client, documentID string, products []bitrix.DealProductRow) error {
+	fmt.Println("Сверка документа с товарами сделки...")
+	elements, err := client.ListStoreDocumentElements(documentID)
+	if err != nil {
+		return fmt.Errorf("не удалось получить товары документа %s для сверки: %v", documentID, err)
+	}
+
+	rows := bitrix.ReconcileStoreDocument(products, elements)
+	if err := formatter.FormatStoreReconciliationAsTable(rows, os.Stdout); err != nil {
+		return err
+	}
+
+	mismatched := 0
+	for _, row := range rows {
+		if !row.Matches() {
+			mismatched++
+		}
+	}
+	if mismatched > 0 {
+		return fmt.Errorf("количества в документе %s не совпадают со сделкой по %d товарам", documentID, mismatched)
+	}
+	fmt.Println("Количества в документе совпадают со сделкой ✓")
+	return nil
+}
+
+// checkExistingStoreDocuments prints receipt documents already linked to the deal and
+// returns an error if one of them is confirmed, unless force is set
+func checkExistingStoreDocuments(documents []bitrix.StoreDocument, force bool) error {
+	var confirmed *bitrix.StoreDocument
+	for i, document := range documents {
+		status := "черновик"
+		if document.IsConfirmed() {
+			status = "проведен"
+			if confirmed == nil {
+				confirmed = &documents[i]
+			}
+		}
+		fmt.Printf("Найден документ прихода по сделке: ID %s (%s)\n", document.ID.String(), status)
+	}
+
+	if confirmed == nil {
+		return nil
+	}
+	if force {
+		fmt.Printf("Внимание: документ %s уже проведен, создаем новый документ (--force)\n", confirmed.ID.String())
+		return nil
+	}
+	return fmt.Errorf("по сделке уже есть проведенный документ прихода ID %s; используйте --force, чтобы создать еще один", confirmed.ID.String())
+}
+
+// selectStoreCurrency picks the store document currency and describes its source:
+// an explicit --currency wins, otherwise the deal currency, otherwise RUB
+func selectStoreCurrency(flagValue string, flagChanged bool, dealCurrency string) (string, string) {
+	if flagChanged && flagValue != "" {
+		return flagValue, "из флага --currency"
+	}
+	if dealCurrency != "" {
+		return dealCurrency, "из сделки"
+	}
+	return defaultStoreCurrency, "по умолчанию"
+}
+
 func init() {
 	crmAddStoreCmd.Flags().StringVar(&addStoreDealID, "deal-id", "", "ID сделки Bitrix24 (обязательно)")
 	crmAddStoreCmd.Flags().StringVar(&addStoreStoreID, "store-id", "1", "ID склада (по умолчанию: 1, или из конфигурации ~/.farmix-cli)")
-	crmAddStoreCmd.Flags().StringVar(&addStoreCurrency, "currency", "RUB", "Валюта для документа (по умолчанию: RUB)")
+	crmAddStoreCmd.Flags().StringVar(&addStoreCurrency, "currency", defaultStoreCurrency, "Валюта для документа (по умолчанию: валюта сделки или RUB)")
 	crmAddStoreCmd.Flags().BoolVar(&addStoreDryRun, "dry-run", false, "Предварительный просмотр без внесения изменений")
+	crmAddStoreCmd.Flags().BoolVar(&addStoreForce, "force", false, "Создать документ, даже если по сделке уже есть проведенный документ прихода")
+	crmAddStoreCmd.Flags().StringVar(&addStoreResume, "resume", "", "ID непроведенного документа прихода: добавить недостающие товары и провести его")
+	crmAddStoreCmd.Flags().BoolVar(&addStoreNoVerify, "no-verify", false, "Не сверять количества проведенного документа (--resume) с товарами сделки")
+	crmAddStoreCmd.Flags().BoolVar(&addStoreStrict, "strict", true, "Ошибка, если в документ добавлены не все товары (false - только предупреждение)")
 
 	crmAddStoreCmd.MarkFlagRequired("deal-id")
 
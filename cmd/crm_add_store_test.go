@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"farmix-cli/internal/bitrix"
@@ -35,7 +39,7 @@ func TestValidateAddStoreParameters(t *testing.T) {
 		{
 			name:    "negative deal ID",
 			dealID:  "-123",
-			wantErr: false, // strconv.Atoi accepts negative numbers
+			wantErr: true,
 		},
 	}
 
@@ -110,4 +114,158 @@ func TestStoreIDConfigLogic(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+func TestSelectStoreCurrency(t *testing.T) {
+	tests := []struct {
+		name         string
+		flagValue    string
+		flagChanged  bool
+		dealCurrency string
+		expected     string
+	}{
+		{"explicit flag wins over deal currency", "USD", true, "EUR", "USD"},
+		{"explicit default value still wins", "RUB", true, "EUR", "RUB"},
+		{"flag not specified, deal currency used", "RUB", false, "EUR", "EUR"},
+		{"flag not specified, deal without currency", "RUB", false, "", "RUB"},
+		{"explicit empty flag falls back to deal", "", true, "EUR", "EUR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			currency, source := selectStoreCurrency(tt.flagValue, tt.flagChanged, tt.dealCurrency)
+			if currency != tt.expected {
+				t.Errorf("selectStoreCurrency(%q, %v, %q) = %q, want %q", tt.flagValue, tt.flagChanged, tt.dealCurrency, currency, tt.expected)
+			}
+			if source == "" {
+				t.Errorf("selectStoreCurrency(%q, %v, %q) returned empty source", tt.flagValue, tt.flagChanged, tt.dealCurrency)
+			}
+		})
+	}
+}
+
+func TestCheckExistingStoreDocuments(t *testing.T) {
+	draft := bitrix.StoreDocument{ID: "18", Status: "N"}
+	confirmed := bitrix.StoreDocument{ID: "17", Status: "Y"}
+
+	tests := []struct {
+		name      string
+		documents []bitrix.StoreDocument
+		force     bool
+		wantErr   bool
+	}{
+		{"no documents", nil, false, false},
+		{"only drafts", []bitrix.StoreDocument{draft}, false, false},
+		{"confirmed document refuses", []bitrix.StoreDocument{draft, confirmed}, false, true},
+		{"confirmed document with force", []bitrix.StoreDocument{confirmed}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkExistingStoreDocuments(tt.documents, tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkExistingStoreDocuments() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "ID 17") {
+				t.Errorf("error %q should mention the existing document ID", err)
+			}
+		})
+	}
+}
+
+// newResumeTestServer serves a receipt document 7 that already has existingAmount of product 10,
+// while the deal has 1 x 10, 2 x 11 and 3 x 12; added product IDs and confirmation are recorded
+// and added elements are returned by catalog.document.element.list with their amounts
+func newResumeTestServer(t *testing.T, status, existingAmount string, added *[]string, confirmed *bool) *httptest.Server {
+	t.Helper()
+
+	elements := []string{`{"id":501,"docId":7,"elementId":10,"amount":` + existingAmount + `}`}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/catalog.document.list"):
+			w.Write([]byte(`{"result":{"documents":[{"id":7,"docType":"S","status":"` + status + `"}]}}`))
+		case strings.HasSuffix(r.URL.Path, "/crm.deal.productrows.get"):
+			w.Write([]byte(`{"result":[{"PRODUCT_ID":"10","QUANTITY":1},{"PRODUCT_ID":"11","QUANTITY":2},{"PRODUCT_ID":"12","QUANTITY":3}]}`))
+		case strings.HasSuffix(r.URL.Path, "/catalog.document.element.list"):
+			w.Write([]byte(`{"result":{"documentElements":[` + strings.Join(elements, ",") + `]}}`))
+		case strings.HasSuffix(r.URL.Path, "/catalog.document.element.add"):
+			r.ParseForm()
+			*added = append(*added, r.Form.Get("fields[elementId]"))
+			elements = append(elements, `{"id":600,"docId":7,"elementId":`+r.Form.Get("fields[elementId]")+`,"amount":`+r.Form.Get("fields[amount]")+`}`)
+			w.Write([]byte(`{"result":{"documentElement":{"id":600}}}`))
+		case strings.HasSuffix(r.URL.Path, "/catalog.document.confirm"):
+			*confirmed = true
+			w.Write([]byte(`{"result":true}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResumeStoreDocumentAddsOnlyMissing(t *testing.T) {
+	var added []string
+	var confirmed bool
+	server := newResumeTestServer(t, "N", "1", &added, &confirmed)
+
+	if err := resumeStoreDocument(bitrix.NewClient(server.URL), "7", "123", "1", false, true); err != nil {
+		t.Fatalf("resumeStoreDocument() error = %v", err)
+	}
+	if strings.Join(added, ",") != "11,12" {
+		t.Errorf("added products = %v, want [11 12]", added)
+	}
+	if !confirmed {
+		t.Error("expected the document to be confirmed")
+	}
+}
+
+func TestResumeStoreDocumentRefusesConfirmed(t *testing.T) {
+	var added []string
+	var confirmed bool
+	server := newResumeTestServer(t, "Y", "1", &added, &confirmed)
+
+	if err := resumeStoreDocument(bitrix.NewClient(server.URL), "7", "123", "1", false, true); err == nil {
+		t.Fatal("expected error for an already confirmed document")
+	}
+	if len(added) != 0 || confirmed {
+		t.Errorf("confirmed document must not be changed: added %v, confirmed %v", added, confirmed)
+	}
+}
+
+func TestStoreAccessError(t *testing.T) {
+	denied := fmt.Errorf("failed to list stores: %w", &bitrix.APIError{Code: "ACCESS_DENIED", Description: "Access denied"})
+	if err := storeAccessError("не удалось получить список складов", denied); !strings.Contains(err.Error(), "Проверьте права доступа") {
+		t.Errorf("access denied error %q should explain webhook permissions", err)
+	}
+
+	other := fmt.Errorf("failed to list stores: %w", &bitrix.TransportError{Err: fmt.Errorf("connection reset")})
+	err := storeAccessError("не удалось получить список складов", other)
+	if strings.Contains(err.Error(), "Проверьте права доступа") || !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("transport error %q should not get permission guidance", err)
+	}
+}
+
+func TestResumeStoreDocumentReconciliationMismatch(t *testing.T) {
+	var added []string
+	var confirmed bool
+	// The interrupted run added product 10 with a rounded amount
+	server := newResumeTestServer(t, "N", "0.99", &added, &confirmed)
+	client := bitrix.NewClient(server.URL)
+
+	err := resumeStoreDocument(client, "7", "123", "1", false, true)
+	if err == nil || !strings.Contains(err.Error(), "по 1 товарам") {
+		t.Fatalf("resumeStoreDocument() error = %v, want reconciliation mismatch for 1 product", err)
+	}
+	if !confirmed {
+		t.Error("reconciliation runs after the document is confirmed")
+	}
+
+	added, confirmed = nil, false
+	server = newResumeTestServer(t, "N", "0.99", &added, &confirmed)
+	if err := resumeStoreDocument(bitrix.NewClient(server.URL), "7", "123", "1", false, false); err != nil {
+		t.Errorf("resumeStoreDocument() with --no-verify error = %v", err)
+	}
+}
@@ -1,18 +1,21 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
 	"farmix-cli/internal/bitrix"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var (
 	clearDealID string
 	clearDryRun bool
+	clearYes    bool
 )
 
 var crmClearDealItemsCmd = &cobra.Command{
@@ -23,27 +26,29 @@ var crmClearDealItemsCmd = &cobra.Command{
 This command will:
 1. Get deal information from Bitrix24
 2. Show all existing products/services in the deal
-3. Remove all products/services from the deal
+3. Ask for confirmation (type the deal ID or "y")
+4. Remove all products/services from the deal
 
-Use --dry-run flag to preview what products would be cleared without making changes.`,
+Use --dry-run flag to preview what products would be cleared without making changes.
+Use --yes flag to skip the confirmation prompt in scripts.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		if err := runCRMClearDealItems(); err != nil {
+		if err := runCRMClearDealItems(cmd.InOrStdin()); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 	},
 }
 
-func runCRMClearDealItems() error {
+func runCRMClearDealItems(in io.Reader) error {
 	// Validate parameters
 	if err := bitrix.ValidateDealID(clearDealID); err != nil {
 		return fmt.Errorf("invalid deal ID: %v", err)
 	}
 
-	// Get webhook URL from config
-	webhookURL := viper.GetString("bitrix_webhook_url")
-	if webhookURL == "" {
-		return fmt.Errorf("bitrix_webhook_url not configured. Please set it in ~/.farmix-cli config")
+	// Create Bitrix24 client (no requests yet, a missing or malformed webhook URL is reported here)
+	client, err := newBitrixClient()
+	if err != nil {
+		return err
 	}
 
 	if clearDryRun {
@@ -52,11 +57,27 @@ func runCRMClearDealItems() error {
 		fmt.Printf("Processing deal %s...\n", clearDealID)
 	}
 
-	// Create Bitrix24 client
-	client := bitrix.NewClient(webhookURL)
+	// Ask for confirmation before the irreversible clear
+	if !clearDryRun && !clearYes {
+		products, err := client.GetExistingProductRows(clearDealID)
+		if err != nil {
+			return fmt.Errorf("failed to get existing products: %v", err)
+		}
+
+		if len(products) > 0 {
+			confirmed, err := confirmClearDealItems(in, clearDealID, products)
+			if err != nil {
+				return fmt.Errorf("failed to read confirmation: %v", err)
+			}
+			if !confirmed {
+				fmt.Printf("Aborted, deal %s was not changed\n", clearDealID)
+				return nil
+			}
+		}
+	}
 
 	// Clear deal product rows
-	err := client.ClearDealProductRows(clearDealID, clearDryRun)
+	err = client.ClearDealProductRows(clearDealID, clearDryRun)
 	if err != nil {
 		return fmt.Errorf("failed to clear deal items: %v", err)
 	}
@@ -70,9 +91,30 @@ func runCRMClearDealItems() error {
 	return nil
 }
 
+// confirmClearDealItems lists the products about to be removed and asks the operator
+// to type the deal ID or "y" to proceed
+func confirmClearDealItems(in io.Reader, dealID string, products []bitrix.DealProductRow) (bool, error) {
+	fmt.Printf("The following %d products will be removed from deal %s:\n", len(products), dealID)
+	for i, product := range products {
+		fmt.Printf("  %d. Product ID: %s (Quantity: %.0f, Price: %.2f)\n",
+			i+1, product.ProductID.String(), product.Quantity, product.Price)
+	}
+	fmt.Printf("Type the deal ID (%s) or \"y\" to confirm: ", dealID)
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	fmt.Println()
+
+	answer = strings.TrimSpace(answer)
+	return answer == dealID || strings.EqualFold(answer, "y"), nil
+}
+
 func init() {
 	crmClearDealItemsCmd.Flags().StringVar(&clearDealID, "deal-id", "", "Bitrix24 deal ID (required)")
 	crmClearDealItemsCmd.Flags().BoolVar(&clearDryRun, "dry-run", false, "Preview what would be cleared without making changes")
+	crmClearDealItemsCmd.Flags().BoolVarP(&clearYes, "yes", "y", false, "Skip the confirmation prompt")
 
 	crmClearDealItemsCmd.MarkFlagRequired("deal-id")
 
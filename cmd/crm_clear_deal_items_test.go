@@ -1,8 +1,13 @@
 package cmd
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"farmix-cli/internal/bitrix"
+
+	"github.com/spf13/viper"
 )
 
 func TestCrmClearDealItemsValidation(t *testing.T) {
@@ -35,9 +40,10 @@ func TestCrmClearDealItemsValidation(t *testing.T) {
 			errorMsg:    "deal ID must be a number",
 		},
 		{
-			name:        "negative deal ID should be valid (strconv.Atoi accepts it)",
+			name:        "negative deal ID should fail",
 			dealID:      "-123",
-			expectError: false,
+			expectError: true,
+			errorMsg:    "deal ID cannot be negative",
 		},
 		{
 			name:        "decimal deal ID should fail",
@@ -93,6 +99,88 @@ func TestCrmClearDealItemsCommandStructure(t *testing.T) {
 	if dryRunFlag == nil {
 		t.Error("Expected 'dry-run' flag to exist")
 	}
+
+	yesFlag := crmClearDealItemsCmd.Flags().Lookup("yes")
+	if yesFlag == nil {
+		t.Error("Expected 'yes' flag to exist")
+	}
+}
+
+func TestRunCRMClearDealItemsConfirmation(t *testing.T) {
+	tests := []struct {
+		name          string
+		input         string
+		yes           bool
+		expectCleared bool
+	}{
+		{
+			name:          "declined with n",
+			input:         "n\n",
+			expectCleared: false,
+		},
+		{
+			name:          "empty answer declines",
+			input:         "",
+			expectCleared: false,
+		},
+		{
+			name:          "wrong deal ID declines",
+			input:         "124\n",
+			expectCleared: false,
+		},
+		{
+			name:          "matching deal ID proceeds",
+			input:         "123\n",
+			expectCleared: true,
+		},
+		{
+			name:          "y proceeds",
+			input:         "Y\n",
+			expectCleared: true,
+		},
+		{
+			name:          "--yes skips prompt",
+			input:         "",
+			yes:           true,
+			expectCleared: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearCalls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/crm.deal.productrows.get"):
+					w.Write([]byte(`{"result":[{"PRODUCT_ID":"5","QUANTITY":2,"PRICE":100}]}`))
+				case strings.HasSuffix(r.URL.Path, "/crm.deal.productrows.set"):
+					clearCalls++
+					w.Write([]byte(`{"result":true}`))
+				default:
+					t.Errorf("unexpected request: %s", r.URL.Path)
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			viper.Set("bitrix_webhook_url", server.URL+"/rest/1/token/")
+			defer viper.Set("bitrix_webhook_url", "")
+
+			clearDealID, clearDryRun, clearYes = "123", false, tt.yes
+			defer func() {
+				clearDealID, clearDryRun, clearYes = "", false, false
+			}()
+
+			if err := runCRMClearDealItems(strings.NewReader(tt.input)); err != nil {
+				t.Fatalf("runCRMClearDealItems() error = %v", err)
+			}
+
+			if cleared := clearCalls > 0; cleared != tt.expectCleared {
+				t.Errorf("clear called = %v, want %v", cleared, tt.expectCleared)
+			}
+		})
+	}
 }
 
 // Helper function to check if string contains substring
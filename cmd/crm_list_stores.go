@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"farmix-cli/internal/bitrix"
+	"farmix-cli/internal/formatter"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	listStoresFormat string
+)
+
+var crmListStoresCmd = &cobra.Command{
+	Use:   "crm-list-stores",
+	Short: "Вывести список складов Bitrix24",
+	Long: `Вывести список складов Bitrix24 с ID, названием, статусом, адресом и кодом.
+
+Неактивные склады отмечены статусом НЕАКТИВЕН. ID нужного склада можно указать
+в флаге --store-id команды crm-add-store или в ключе store_id конфигурации ~/.farmix-cli.
+
+Форматы вывода: text (таблица, по умолчанию), csv, json.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runCRMListStores(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func runCRMListStores(out io.Writer) error {
+	format := strings.ToLower(listStoresFormat)
+	if format != "text" && format != "csv" && format != "json" {
+		return fmt.Errorf("неподдерживаемый формат вывода: %s (поддерживаются: text, csv, json)", listStoresFormat)
+	}
+
+	// Create Bitrix24 client (no requests yet, a missing or malformed webhook URL is reported here)
+	client, err := newBitrixClient()
+	if err != nil {
+		return err
+	}
+
+	stores, err := client.ListStores()
+	if err != nil {
+		return fmt.Errorf("не удалось получить список складов: %v", err)
+	}
+
+	return formatStores(stores, format, out)
+}
+
+// formatStores writes stores in the given output format
+func formatStores(stores []bitrix.Store, format string, out io.Writer) error {
+	switch format {
+	case "csv":
+		return formatter.FormatStoresAsCSV(stores, out)
+	case "json":
+		return formatter.FormatStoresAsJSON(stores, out)
+	default:
+		return formatter.FormatStoresAsTable(stores, out)
+	}
+}
+
+func init() {
+	crmListStoresCmd.Flags().StringVarP(&listStoresFormat, "format", "f", "text", "Формат вывода (text, csv, json)")
+
+	rootCmd.AddCommand(crmListStoresCmd)
+}
\ No newline at end of file
@@ -2,11 +2,16 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"farmix-cli/internal/bitrix"
 	"farmix-cli/internal/formatter"
+	"farmix-cli/internal/parser"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -15,8 +20,20 @@ import (
 var (
 	reportFormat    string
 	reportCategoryID string
+	reportDateFrom   string
+	reportDateTo     string
+	report3MFDir     string
+	reportASCII      bool
+	reportMaxWidth   int
+	reportConvertTo  string
+	reportLimit      int
+	reportSortBy     string
+	reportSortDesc   bool
 )
 
+// reportDateFlagLayout is the date format accepted by --from and --to flags
+const reportDateFlagLayout = "2006-01-02"
+
 var crmReportCmd = &cobra.Command{
 	Use:   "crm-report",
 	Short: "Вывести отчет по активным сделкам Bitrix24",
@@ -24,8 +41,11 @@ var crmReportCmd = &cobra.Command{
 
 Команда выполнит следующие действия:
 1. Получит список сделок из Bitrix24
-2. Отфильтрует сделки, исключив финальные статусы (по умолчанию WON и LOST)
-3. Отсортирует сделки по ID (возрастание)
+2. Отфильтрует сделки, исключив финальные статусы (по умолчанию WON и LOST,
+   можно задать отдельно для каждой воронки)
+3. Отсортирует сделки по ID (возрастание) или по полю --sort-by (id, date,
+   total_cost, opportunity; --desc - по убыванию, сделки без значения - в конце)
+   и оставит первые --limit сделок
 4. Выведет таблицу с полями:
    - ID сделки
    - Название сделки
@@ -36,6 +56,22 @@ var crmReportCmd = &cobra.Command{
    - Итоговая стоимость изготовления (кастомное поле)
    - Итоговая цена (стоимость сделки)
    - Оплата получена (кастомное поле)
+5. Выведет итоги по выведенным сделкам: сумма сделок (OPPORTUNITY), оплачено (payment_received) и
+   к оплате - их разницу (отсутствующие значения считаются нулем), отдельно для
+   каждой валюты сделок (CURRENCY_ID)
+
+С флагом --convert-to добавляется общий итог в указанной валюте по статическим
+курсам из ~/.farmix-cli (курс каждой валюты в одной базовой валюте):
+
+exchange_rates:
+  RUB: 1
+  EUR: 100
+  USD: 90
+
+Сделки без валюты (CURRENCY_ID) при этом считаются в базовой валюте портала.
+
+Форматы вывода: text (таблица, по умолчанию), csv (итоги - последними строками),
+json (объект с массивом deals и итогами totals).
 
 Для работы команды необходимо настроить коды кастомных полей в ~/.farmix-cli:
 
@@ -46,7 +82,33 @@ report_custom_fields:
   total_cost: "UF_CRM_XXXXX"
   payment_received: "UF_CRM_XXXXX"
 
-Коды полей можно найти в Bitrix24: CRM -> Настройки -> Поля -> Сделки`,
+Коды полей можно найти в Bitrix24: CRM -> Настройки -> Поля -> Сделки
+
+Исключаемые статусы задаются ключом report_excluded_statuses: списком для всех
+воронок или, если ID стадий в воронках разные, по ID воронки ("default" -
+для остальных воронок):
+
+report_excluded_statuses:
+  default: ["WON", "LOSE"]
+  "5": ["C5:WON", "C5:LOSE"]
+  "7": ["C7:WON", "C7:LOSE"]
+
+Для выборки сделок за период используйте флаги --from и --to (формат ГГГГ-ММ-ДД,
+обе границы включительно), например: --from 2025-01-01 --to 2025-01-31
+
+С флагом --3mf-dir рядом с колонкой "М/ч (₽)" выводится колонка "М/ч (расч.)" -
+суммарное время печати (в часах) по нарезанным 3MF файлам сделки, чтобы были видны
+расхождения с сохраненной стоимостью. Файлы сделки ищутся в директории по имени:
+<ID сделки>.3mf или <ID сделки>_<что угодно>.3mf (также через "-" или пробел),
+несколько файлов одной сделки суммируются. Если файлов нет или они не нарезаны,
+ячейка остается пустой.
+
+В терминале таблица рисуется символами псевдографики, при выводе в файл или
+конвейер - символами +, - и |. Флаг --ascii включает ASCII рамки и в терминале.
+
+Длинные значения текстовых колонок таблицы (воронка, название) обрезаются до 40
+символов с многоточием, числовые колонки не обрезаются. Ширину задает ключ
+report_max_text_width или флаг --max-width (-1 - без ограничения).`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if err := runCRMReport(); err != nil {
 			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
@@ -56,10 +118,36 @@ report_custom_fields:
 }
 
 func runCRMReport() error {
-	// Get webhook URL from config
-	webhookURL := viper.GetString("bitrix_webhook_url")
-	if webhookURL == "" {
-		return fmt.Errorf("bitrix_webhook_url не настроен. Пожалуйста, установите его в конфигурации ~/.farmix-cli")
+	// Parse date range before any network requests
+	dateFrom, dateTo, err := parseReportDateRange(reportDateFrom, reportDateTo)
+	if err != nil {
+		return err
+	}
+
+	if reportLimit < 0 {
+		return fmt.Errorf("--limit не может быть отрицательным: %d", reportLimit)
+	}
+	if !slices.Contains(bitrix.DealReportSortFields, reportSortBy) {
+		return fmt.Errorf("неподдерживаемое поле сортировки: %s (поддерживаются: %s)", reportSortBy, strings.Join(bitrix.DealReportSortFields, ", "))
+	}
+
+	exchangeRates := loadExchangeRates()
+	if reportConvertTo != "" {
+		if _, ok := exchangeRates[strings.ToUpper(reportConvertTo)]; !ok {
+			return fmt.Errorf("не задан курс валюты %s в exchange_rates (%s)", strings.ToUpper(reportConvertTo), configDisplayPath)
+		}
+	}
+
+	if report3MFDir != "" {
+		if info, err := os.Stat(report3MFDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("директория с 3MF файлами не найдена: %s", report3MFDir)
+		}
+	}
+
+	// Create Bitrix24 client (no requests yet, a missing or malformed webhook URL is reported here)
+	client, err := newBitrixClient()
+	if err != nil {
+		return err
 	}
 
 	// Get custom fields configuration
@@ -75,7 +163,7 @@ func runCRMReport() error {
 	if customFields.MachineCost == "" && customFields.HumanCost == "" &&
 		customFields.MaterialCost == "" && customFields.TotalCost == "" &&
 		customFields.PaymentReceived == "" {
-		return fmt.Errorf("не настроены коды кастомных полей в ~/.farmix-cli\n\n" +
+		return fmt.Errorf("не настроены коды кастомных полей в " + configDisplayPath + "\n\n" +
 			"Добавьте в конфигурационный файл секцию:\n\n" +
 			"report_custom_fields:\n" +
 			"  machine_cost: \"UF_CRM_XXXXX\"\n" +
@@ -87,16 +175,13 @@ func runCRMReport() error {
 	}
 
 	// Get excluded statuses from config (default to WON and LOST)
-	excludedStatuses := viper.GetStringSlice("report_excluded_statuses")
-	if len(excludedStatuses) == 0 {
-		excludedStatuses = []string{"WON", "LOST"}
+	exclusions, err := parseExcludedStatuses(viper.Get("report_excluded_statuses"))
+	if err != nil {
+		return err
 	}
 
-	// Create Bitrix24 client
-	client := bitrix.NewClient(webhookURL)
-
 	// Load deal categories (funnels) from Bitrix24
-	fmt.Println("Загрузка списка воронок...")
+	fmt.Fprintln(os.Stderr, "Загрузка списка воронок...")
 	categoryMap, err := client.ListDealCategories()
 	if err != nil {
 		return fmt.Errorf("не удалось загрузить список воронок: %v", err)
@@ -110,44 +195,287 @@ func runCRMReport() error {
 		for i := range categoryIDs {
 			categoryIDs[i] = strings.TrimSpace(categoryIDs[i])
 		}
-		fmt.Printf("Фильтрация по воронкам: %v\n", categoryIDs)
+		fmt.Fprintf(os.Stderr, "Фильтрация по воронкам: %v\n", categoryIDs)
 	}
 
-	fmt.Println("Получение списка сделок из Bitrix24...")
+	if reportDateFrom != "" || reportDateTo != "" {
+		fmt.Fprintf(os.Stderr, "Период создания сделок: %s — %s\n", formatReportDateBound(reportDateFrom), formatReportDateBound(reportDateTo))
+	}
+
+	fmt.Fprintln(os.Stderr, "Получение списка сделок из Bitrix24...")
 
 	// Get deals with custom fields
-	deals, err := client.ListDealsWithCustomFields(customFields, excludedStatuses, categoryIDs)
+	deals, err := client.ListDealsWithCustomFields(customFields, exclusions, categoryIDs, dateFrom, dateTo)
 	if err != nil {
 		return fmt.Errorf("не удалось получить список сделок: %v", err)
 	}
 
 	if len(deals) == 0 {
-		fmt.Println("Нет активных сделок для отображения")
+		fmt.Fprintln(os.Stderr, "Нет активных сделок для отображения")
 		return nil
 	}
 
-	fmt.Printf("Найдено %d активных сделок\n\n", len(deals))
+	fmt.Fprintf(os.Stderr, "Найдено %d активных сделок\n", len(deals))
+
+	total := len(deals)
+	deals, err = sortAndLimitDeals(deals, reportSortBy, reportSortDesc, reportLimit)
+	if err != nil {
+		return err
+	}
+	if len(deals) < total {
+		fmt.Fprintf(os.Stderr, "Показаны первые %d\n", len(deals))
+	}
+	fmt.Fprintln(os.Stderr)
+
+	// Box-drawing borders only on a terminal: files and pipes get plain ASCII
+	options := formatter.DealReportOptions{
+		ASCII:         reportASCII || !isTerminal(os.Stdout),
+		MaxTextWidth:  reportMaxWidth,
+		ConvertTo:     reportConvertTo,
+		ExchangeRates: exchangeRates,
+	}
+	if options.MaxTextWidth == 0 {
+		options.MaxTextWidth = viper.GetInt("report_max_text_width")
+	}
+	if reportConvertTo != "" && hasDealWithoutCurrency(deals) {
+		// Deals without CURRENCY_ID are in the portal base currency
+		options.BaseCurrency, err = client.GetBaseCurrency()
+		if err != nil {
+			return fmt.Errorf("не удалось получить базовую валюту портала: %v", err)
+		}
+	}
+	if report3MFDir != "" {
+		options.MachineHours, err = computeDealMachineHours(report3MFDir, deals, os.Stderr)
+		if err != nil {
+			return err
+		}
+	}
 
 	// Format and output report
 	switch reportFormat {
 	case "csv":
-		if err := formatter.FormatReportAsCSV(deals, categoryMap, os.Stdout); err != nil {
+		if err := formatter.FormatReportAsCSV(deals, categoryMap, os.Stdout, options); err != nil {
 			return fmt.Errorf("не удалось сформировать CSV отчет: %v", err)
 		}
 	case "text":
-		if err := formatter.FormatReportAsTable(deals, categoryMap, os.Stdout); err != nil {
+		if err := formatter.FormatReportAsTable(deals, categoryMap, os.Stdout, options); err != nil {
 			return fmt.Errorf("не удалось сформировать текстовый отчет: %v", err)
 		}
+	case "json":
+		if err := formatter.FormatReportAsJSON(deals, categoryMap, os.Stdout, options); err != nil {
+			return fmt.Errorf("не удалось сформировать JSON отчет: %v", err)
+		}
 	default:
-		return fmt.Errorf("неподдерживаемый формат вывода: %s (поддерживаются: text, csv)", reportFormat)
+		return fmt.Errorf("неподдерживаемый формат вывода: %s (поддерживаются: text, csv, json)", reportFormat)
 	}
 
 	return nil
 }
 
+// sortAndLimitDeals orders deals by sortBy and keeps the first limit of them (0 - all)
+func sortAndLimitDeals(deals []bitrix.DealReportRow, sortBy string, desc bool, limit int) ([]bitrix.DealReportRow, error) {
+	if err := bitrix.SortDealReportRows(deals, sortBy, desc); err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(deals) > limit {
+		deals = deals[:limit]
+	}
+	return deals, nil
+}
+
+// hasDealWithoutCurrency reports whether some deal has an empty CURRENCY_ID
+func hasDealWithoutCurrency(deals []bitrix.DealReportRow) bool {
+	for _, deal := range deals {
+		if strings.TrimSpace(deal.CurrencyID) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// loadExchangeRates reads exchange_rates from config: currency code -> rate in a common
+// base currency; codes are upper-cased (viper lower-cases map keys), non-positive rates are skipped
+func loadExchangeRates() map[string]float64 {
+	rates := make(map[string]float64)
+	for currency, rate := range getConfigFloatMap("exchange_rates") {
+		if rate <= 0 {
+			fmt.Fprintf(os.Stderr, "Warning: invalid value for exchange_rates.%s: %v\n", currency, rate)
+			continue
+		}
+		rates[strings.ToUpper(currency)] = rate
+	}
+	return rates
+}
+
+// findDeal3MFFiles groups .3mf files of dir by deal ID taken from the file name:
+// "<dealID>.3mf" or "<dealID>" followed by "_", "-" or a space and any suffix
+func findDeal3MFFiles(dir string) (map[string][]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("не удалось прочитать директорию %s: %v", dir, err)
+	}
+
+	files := make(map[string][]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(name), ".3mf") {
+			continue
+		}
+
+		base := strings.TrimSuffix(name, filepath.Ext(name))
+		end := 0
+		for end < len(base) && base[end] >= '0' && base[end] <= '9' {
+			end++
+		}
+		if end == 0 || (end < len(base) && !strings.ContainsRune("_- ", rune(base[end]))) {
+			continue
+		}
+
+		dealID := base[:end]
+		files[dealID] = append(files[dealID], filepath.Join(dir, name))
+	}
+	return files, nil
+}
+
+// computeDealMachineHours returns the total print time of each deal's 3MF files from dir.
+// Deals without files are left out; a file that cannot be parsed is reported to warnings
+// and leaves the deal out, so that its cell stays empty instead of showing a partial time
+func computeDealMachineHours(dir string, deals []bitrix.DealReportRow, warnings io.Writer) (map[string]time.Duration, error) {
+	files, err := findDeal3MFFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	hours := make(map[string]time.Duration)
+	for _, deal := range deals {
+		var total time.Duration
+		parsed := len(files[deal.ID]) > 0
+		for _, file := range files[deal.ID] {
+			data, err := parser.Parse3MF(file)
+			if err != nil {
+				fmt.Fprintf(warnings, "Предупреждение: не удалось разобрать %s: %v\n", file, err)
+				parsed = false
+				break
+			}
+			total += data.TotalPrintTime()
+		}
+		if parsed {
+			hours[deal.ID] = total
+		}
+	}
+	return hours, nil
+}
+
+// defaultExcludedStatuses are excluded when report_excluded_statuses does not set them
+var defaultExcludedStatuses = []string{"WON", "LOST"}
+
+// parseExcludedStatuses reads report_excluded_statuses: either a list of stage IDs for all
+// funnels, or a map of category ID -> stage IDs where the "default" entry applies to the
+// funnels that are not listed (WON and LOST when it is missing)
+func parseExcludedStatuses(raw interface{}) (bitrix.ReportStatusExclusions, error) {
+	exclusions := bitrix.ReportStatusExclusions{Default: defaultExcludedStatuses}
+	if raw == nil {
+		return exclusions, nil
+	}
+
+	categories, isMap := raw.(map[string]interface{})
+	if !isMap {
+		statuses, ok := configStringList(raw)
+		if !ok {
+			return exclusions, fmt.Errorf("report_excluded_statuses в %s должен быть списком статусов или картой ID воронки -> список статусов", configDisplayPath)
+		}
+		if len(statuses) > 0 {
+			exclusions.Default = statuses
+		}
+		return exclusions, nil
+	}
+
+	exclusions.ByCategory = make(map[string][]string)
+	for categoryID, value := range categories {
+		statuses, ok := configStringList(value)
+		if !ok {
+			return exclusions, fmt.Errorf("report_excluded_statuses.%s в %s должен быть списком статусов", categoryID, configDisplayPath)
+		}
+		if categoryID == "default" {
+			exclusions.Default = statuses
+			continue
+		}
+		exclusions.ByCategory[categoryID] = statuses
+	}
+	return exclusions, nil
+}
+
+// configStringList converts a config list to strings; a single value is split on whitespace
+// like viper.GetStringSlice, so "WON LOST" from the environment is two statuses
+func configStringList(value interface{}) ([]string, bool) {
+	switch v := value.(type) {
+	case []string:
+		return v, true
+	case []interface{}:
+		result := make([]string, 0, len(v))
+		for _, item := range v {
+			result = append(result, strings.TrimSpace(fmt.Sprint(item)))
+		}
+		return result, true
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, true
+		}
+		return strings.Fields(v), true
+	default:
+		return nil, false
+	}
+}
+
+// parseReportDateRange parses --from/--to values in YYYY-MM-DD format
+// Empty values are returned as zero time (unbounded). The upper bound is moved
+// to the end of the day so that deals created on the --to date are included
+func parseReportDateRange(from, to string) (time.Time, time.Time, error) {
+	var dateFrom, dateTo time.Time
+
+	if from != "" {
+		parsed, err := time.ParseInLocation(reportDateFlagLayout, from, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("неверный формат даты --from: %s (ожидается ГГГГ-ММ-ДД)", from)
+		}
+		dateFrom = parsed
+	}
+
+	if to != "" {
+		parsed, err := time.ParseInLocation(reportDateFlagLayout, to, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("неверный формат даты --to: %s (ожидается ГГГГ-ММ-ДД)", to)
+		}
+		dateTo = parsed.AddDate(0, 0, 1).Add(-time.Second)
+	}
+
+	if !dateFrom.IsZero() && !dateTo.IsZero() && dateFrom.After(dateTo) {
+		return time.Time{}, time.Time{}, fmt.Errorf("дата --from (%s) не может быть позже даты --to (%s)", from, to)
+	}
+
+	return dateFrom, dateTo, nil
+}
+
+// formatReportDateBound returns date bound for display, "…" for open bound
+func formatReportDateBound(value string) string {
+	if value == "" {
+		return "…"
+	}
+	return value
+}
+
 func init() {
-	crmReportCmd.Flags().StringVarP(&reportFormat, "format", "f", "text", "Формат вывода (text, csv)")
+	crmReportCmd.Flags().StringVarP(&reportFormat, "format", "f", "text", "Формат вывода (text, csv, json)")
 	crmReportCmd.Flags().StringVarP(&reportCategoryID, "category-id", "c", "", "ID воронки (или несколько через запятую, например: 1,3,5)")
+	crmReportCmd.Flags().StringVar(&reportDateFrom, "from", "", "Начало периода по дате создания сделки (ГГГГ-ММ-ДД, включительно)")
+	crmReportCmd.Flags().StringVar(&reportDateTo, "to", "", "Конец периода по дате создания сделки (ГГГГ-ММ-ДД, включительно)")
+	crmReportCmd.Flags().BoolVar(&reportASCII, "ascii", false, "Рисовать рамки таблицы символами +, - и | (по умолчанию так только при выводе не в терминал)")
+	crmReportCmd.Flags().IntVar(&reportMaxWidth, "max-width", 0, "Максимальная ширина текстовых колонок таблицы (воронка, название), -1 - без ограничения (по умолчанию report_max_text_width или 40)")
+	crmReportCmd.Flags().IntVar(&reportLimit, "limit", 0, "Вывести только первые N сделок после сортировки (0 - все)")
+	crmReportCmd.Flags().StringVar(&reportSortBy, "sort-by", "id", "Сортировка: "+strings.Join(bitrix.DealReportSortFields, ", "))
+	crmReportCmd.Flags().BoolVar(&reportSortDesc, "desc", false, "Сортировать по убыванию")
+	crmReportCmd.Flags().StringVar(&reportConvertTo, "convert-to", "", "Добавить общий итог в валюте по курсам exchange_rates (например, RUB)")
+	crmReportCmd.Flags().StringVar(&report3MFDir, "3mf-dir", "", "Директория с 3MF файлами сделок (<ID сделки>.3mf) для колонки расчетных машино-часов")
 
 	rootCmd.AddCommand(crmReportCmd)
 }
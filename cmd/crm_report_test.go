@@ -1,9 +1,20 @@
 package cmd
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"farmix-cli/internal/bitrix"
+
+	"github.com/spf13/viper"
 )
 
 // TestParseCustomFieldValue tests the parsing of custom field values
@@ -73,6 +84,46 @@ func TestParseCustomFieldValue(t *testing.T) {
 			input:    "Just a text",
 			expected: "Just a text",
 		},
+		{
+			name:     "Negative float",
+			input:    float64(-250.5),
+			expected: "-250.50",
+		},
+		{
+			name:     "Monetary value with decimals and currency USD",
+			input:    "1234.5|USD",
+			expected: "1234.5",
+		},
+		{
+			name:     "Negative monetary value",
+			input:    "-1000|RUB",
+			expected: "-1000",
+		},
+		{
+			name:     "Thousand-separated with comma",
+			input:    "1,234,567.89|RUB",
+			expected: "1234567.89",
+		},
+		{
+			name:     "Thousand-separated with spaces and decimal comma",
+			input:    "1 234,5|RUB",
+			expected: "1234.5",
+		},
+		{
+			name:     "Thousand-separated with comma only",
+			input:    "12,000",
+			expected: "12000",
+		},
+		{
+			name:     "Multiple values",
+			input:    []interface{}{"1000|RUB", float64(2000), nil},
+			expected: "1000, 2000",
+		},
+		{
+			name:     "Empty multiple values",
+			input:    []interface{}{},
+			expected: "",
+		},
 	}
 
 	for _, tt := range tests {
@@ -154,3 +205,251 @@ func TestFormatValidation(t *testing.T) {
 		})
 	}
 }
+
+// TestParseReportDateRange tests parsing of --from/--to flags
+func TestParseReportDateRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		wantFrom time.Time
+		wantTo   time.Time
+		wantErr  bool
+	}{
+		{
+			name: "No bounds",
+		},
+		{
+			name:     "Both bounds",
+			from:     "2025-01-01",
+			to:       "2025-01-31",
+			wantFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.Local),
+			wantTo:   time.Date(2025, 1, 31, 23, 59, 59, 0, time.Local),
+		},
+		{
+			name:     "Only from",
+			from:     "2025-03-15",
+			wantFrom: time.Date(2025, 3, 15, 0, 0, 0, 0, time.Local),
+		},
+		{
+			name:   "Only to",
+			to:     "2025-03-15",
+			wantTo: time.Date(2025, 3, 15, 23, 59, 59, 0, time.Local),
+		},
+		{
+			name:     "Same day",
+			from:     "2025-03-15",
+			to:       "2025-03-15",
+			wantFrom: time.Date(2025, 3, 15, 0, 0, 0, 0, time.Local),
+			wantTo:   time.Date(2025, 3, 15, 23, 59, 59, 0, time.Local),
+		},
+		{
+			name:    "From after to",
+			from:    "2025-02-01",
+			to:      "2025-01-31",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid from format",
+			from:    "01.02.2025",
+			wantErr: true,
+		},
+		{
+			name:    "Invalid to date",
+			to:      "2025-02-30",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from, to, err := parseReportDateRange(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseReportDateRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !from.Equal(tt.wantFrom) {
+				t.Errorf("parseReportDateRange() from = %v, want %v", from, tt.wantFrom)
+			}
+			if !to.Equal(tt.wantTo) {
+				t.Errorf("parseReportDateRange() to = %v, want %v", to, tt.wantTo)
+			}
+		})
+	}
+}
+
+func TestParseExcludedStatuses(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      interface{}
+		expected bitrix.ReportStatusExclusions
+		wantErr  bool
+	}{
+		{
+			name:     "Not configured",
+			raw:      nil,
+			expected: bitrix.ReportStatusExclusions{Default: []string{"WON", "LOST"}},
+		},
+		{
+			name:     "Flat list",
+			raw:      []interface{}{"WON", "LOSE"},
+			expected: bitrix.ReportStatusExclusions{Default: []string{"WON", "LOSE"}},
+		},
+		{
+			name:     "Flat list from environment",
+			raw:      "WON  LOSE",
+			expected: bitrix.ReportStatusExclusions{Default: []string{"WON", "LOSE"}},
+		},
+		{
+			name: "Per category with default",
+			raw: map[string]interface{}{
+				"default": []interface{}{"WON", "LOSE"},
+				"5":       []interface{}{"C5:WON", "C5:LOSE"},
+			},
+			expected: bitrix.ReportStatusExclusions{
+				Default:    []string{"WON", "LOSE"},
+				ByCategory: map[string][]string{"5": {"C5:WON", "C5:LOSE"}},
+			},
+		},
+		{
+			name: "Per category without default",
+			raw:  map[string]interface{}{"5": "C5:WON"},
+			expected: bitrix.ReportStatusExclusions{
+				Default:    []string{"WON", "LOST"},
+				ByCategory: map[string][]string{"5": {"C5:WON"}},
+			},
+		},
+		{
+			name:    "Invalid value",
+			raw:     42,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid category value",
+			raw:     map[string]interface{}{"5": map[string]interface{}{"a": "b"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseExcludedStatuses(tt.raw)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseExcludedStatuses() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseExcludedStatuses() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestComputeDealMachineHours(t *testing.T) {
+	project, err := os.ReadFile(writeQuoteProject(t)) // sliced to 1 h
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{
+		"101.3mf":        project,
+		"101_cover.3MF":  project,
+		"1010x.3mf":      project, // no separator after the ID: not a deal file
+		"103 broken.3mf": []byte("not a zip"),
+		"104.txt":        project,
+		"notes-105.3mf":  project,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deals := []bitrix.DealReportRow{{ID: "101"}, {ID: "102"}, {ID: "103"}, {ID: "104"}, {ID: "105"}}
+	var warnings bytes.Buffer
+	hours, err := computeDealMachineHours(dir, deals, &warnings)
+	if err != nil {
+		t.Fatalf("computeDealMachineHours() error = %v", err)
+	}
+
+	expected := map[string]time.Duration{"101": 2 * time.Hour}
+	if !reflect.DeepEqual(hours, expected) {
+		t.Errorf("computeDealMachineHours() = %v, want %v", hours, expected)
+	}
+	if !strings.Contains(warnings.String(), "103 broken.3mf") {
+		t.Errorf("expected a warning about the broken file, got %q", warnings.String())
+	}
+}
+
+func TestSortAndLimitDeals(t *testing.T) {
+	deals := []bitrix.DealReportRow{
+		{ID: "10", TotalCost: "500|RUB"},
+		{ID: "2", TotalCost: float64(900)},
+		{ID: "7"}, // no total cost
+		{ID: "5", TotalCost: "900"},
+		{ID: "3", TotalCost: "1 200,50"},
+		{ID: "1", TotalCost: "100"},
+	}
+
+	result, err := sortAndLimitDeals(deals, "total_cost", true, 4)
+	if err != nil {
+		t.Fatalf("sortAndLimitDeals() error = %v", err)
+	}
+
+	// Equal totals (2 and 5) stay in ID order
+	var ids []string
+	for _, deal := range result {
+		ids = append(ids, deal.ID)
+	}
+	if expected := []string{"3", "2", "5", "10"}; !reflect.DeepEqual(ids, expected) {
+		t.Errorf("sortAndLimitDeals() IDs = %v, want %v", ids, expected)
+	}
+
+	if _, err := sortAndLimitDeals(deals, "title", false, 0); err == nil {
+		t.Error("expected error for unsupported sort field")
+	}
+}
+
+// TestRunCRMReportJSONStdout checks that only the report goes to stdout, so -f json can be piped
+func TestRunCRMReportJSONStdout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/crm.category.list"):
+			w.Write([]byte(`{"result":{"categories":[{"id":0,"name":"Общая"}]}}`))
+		case strings.HasSuffix(r.URL.Path, "/crm.deal.list"):
+			w.Write([]byte(`{"result":[{"ID":"7","TITLE":"Кронштейны","CATEGORY_ID":"0","OPPORTUNITY":"1500","CURRENCY_ID":"RUB","UF_CRM_TOTAL":"900"}]}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	viper.Set("bitrix_webhook_url", server.URL+"/rest/1/token/")
+	defer viper.Set("bitrix_webhook_url", "")
+	viper.Set("report_custom_fields.total_cost", "UF_CRM_TOTAL")
+	defer viper.Set("report_custom_fields.total_cost", "")
+
+	originalFormat := reportFormat
+	reportFormat = "json"
+	defer func() { reportFormat = originalFormat }()
+
+	var runErr error
+	output := captureStdout(t, func() { runErr = runCRMReport() })
+	if runErr != nil {
+		t.Fatalf("runCRMReport() error = %v", runErr)
+	}
+
+	var report interface{}
+	if err := json.Unmarshal([]byte(output), &report); err != nil {
+		t.Fatalf("stdout is not valid JSON: %v\n%s", err, output)
+	}
+	if !strings.Contains(output, "Кронштейны") {
+		t.Errorf("report does not contain the deal: %s", output)
+	}
+}
\ No newline at end of file
@@ -7,7 +7,6 @@ import (
 	"farmix-cli/internal/bitrix"
 
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 var (
@@ -25,7 +24,8 @@ This command will:
 1. Get deal information and total amount from Bitrix24
 2. Get all products in the deal
 3. Calculate proportional prices based on the method
-4. Update product unit prices with proper rounding
+4. Update product unit prices; leftover rounding cents are spread one by one
+   across products with the largest remainders, so totals match to the cent
 
 Available methods:
   count  - Distribute based on product quantities (default)
@@ -52,10 +52,10 @@ func runCRMSpreadPrice() error {
 		return fmt.Errorf("method '%s' is not supported yet. Only 'count' is currently available", spreadMethod)
 	}
 
-	// Get webhook URL from config
-	webhookURL := viper.GetString("bitrix_webhook_url")
-	if webhookURL == "" {
-		return fmt.Errorf("bitrix_webhook_url not configured. Please set it in ~/.farmix-cli config")
+	// Create Bitrix24 client (no requests yet, a missing or malformed webhook URL is reported here)
+	client, err := newBitrixClient()
+	if err != nil {
+		return err
 	}
 
 	if spreadDryRun {
@@ -64,9 +64,6 @@ func runCRMSpreadPrice() error {
 		fmt.Printf("Processing deal %s with method '%s'...\n", spreadDealID, spreadMethod)
 	}
 
-	// Create Bitrix24 client
-	client := bitrix.NewClient(webhookURL)
-
 	// Get deal information with amount
 	if spreadDryRun {
 		fmt.Printf("[DRY RUN] Getting deal information...\n")
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -12,52 +13,111 @@ import (
 )
 
 var (
-	outputFormat string
+	outputFormat       string
+	listLang           string
+	listShowTime       bool
+	listShowDimensions bool
+	listPlates         []int
+	listMaterial       string
+	listNonPrintable   bool
+	listKeepExtracted  bool
 )
 
 var listCmd = &cobra.Command{
 	Use:   "list [file]",
 	Short: "List information about a 3MF file",
-	Long:  `Display detailed information about objects and plates in a 3MF file.`,
-	Args:  cobra.ExactArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		filePath := args[0]
+	Long: `Display detailed information about objects and plates in a 3MF file.
 
-		if !strings.HasSuffix(strings.ToLower(filePath), ".3mf") {
-			fmt.Fprintf(os.Stderr, "Error: File must have .3mf extension: %s\n", filePath)
-			os.Exit(1)
-		}
+--plate and --material narrow the output in every format: --plate keeps the
+given plates (repeat the flag or use a comma list), --material keeps objects
+whose material (without the trailing "(...)" groups) contains the value,
+case-insensitive. Plate weight and print time always refer to the whole plate.
 
-		if _, err := os.Stat(filePath); os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Error: File does not exist: %s\n", filePath)
-			os.Exit(1)
-		}
+--show-dimensions adds the object bounding box (width x depth x height, mm) computed
+from the mesh as placed on the plate; it reads every mesh, so large projects parse slower.
 
-		data, err := parser.Parse3MF(filePath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error: Failed to parse 3MF file: %v\n", err)
-			os.Exit(1)
-		}
+--keep-extracted unpacks the archive to a temp directory, parses it from there and
+leaves it in place for inspecting the XML; the path is printed to stderr.
+
+Non-printable objects (helper geometry) are skipped unless --include-non-printable
+is set or include_non_printable: true is in ~/.farmix-cli.
 
-		switch strings.ToLower(outputFormat) {
-		case "csv":
-			if err := formatter.FormatAsCSV(data, os.Stdout); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: Failed to format output as CSV: %v\n", err)
-				os.Exit(1)
-			}
-		case "text", "":
-			if err := formatter.FormatAsText(data, os.Stdout); err != nil {
-				fmt.Fprintf(os.Stderr, "Error: Failed to format output as text: %v\n", err)
-				os.Exit(1)
-			}
-		default:
-			fmt.Fprintf(os.Stderr, "Error: Unsupported output format: %s. Supported formats: text, csv\n", outputFormat)
+Examples:
+  farmix-cli list model.3mf
+  farmix-cli list --plate 3 model.3mf
+  farmix-cli list --plate 1,4 --material petg --format csv model.3mf
+  farmix-cli list --show-dimensions model.3mf`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		listNonPrintable = includeNonPrintable(cmd, listNonPrintable)
+		if err := runListCommand(args[0], os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
 		}
 	},
 }
 
+func runListCommand(filePath string, writer io.Writer) error {
+	lang, err := formatter.ParseLang(listLang)
+	if err != nil {
+		return err
+	}
+
+	if !strings.HasSuffix(strings.ToLower(filePath), ".3mf") {
+		return fmt.Errorf("File must have .3mf extension: %s", filePath)
+	}
+
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("File does not exist: %s", filePath)
+	}
+
+	data, err := parser.Parse3MF(filePath, parser.WithDimensions(listShowDimensions), parser.WithPaintedMaterials(true), parser.WithKeepExtracted(listKeepExtracted))
+	if err != nil {
+		return fmt.Errorf("Failed to parse 3MF file: %v", err)
+	}
+	if data.ExtractDir != "" {
+		fmt.Fprintf(os.Stderr, "Extracted 3MF kept in: %s\n", data.ExtractDir)
+	}
+
+	filter := parser.PlateFilter{
+		PlateIDs:      listPlates,
+		Material:      listMaterial,
+		PrintableOnly: !listNonPrintable,
+	}
+	data = data.Filter(filter)
+	if (len(filter.PlateIDs) > 0 || filter.Material != "") && len(data.Plates) == 0 {
+		fmt.Fprintln(os.Stderr, "Warning: no plates match --plate/--material")
+	}
+
+	options := formatter.ListOptions{Lang: lang, ShowTime: listShowTime, ShowDimensions: listShowDimensions}
+
+	switch strings.ToLower(outputFormat) {
+	case "csv":
+		if err := formatter.FormatAsCSV(data, writer, options); err != nil {
+			return fmt.Errorf("Failed to format output as CSV: %v", err)
+		}
+	case "json":
+		if err := formatter.FormatAsJSON(data, writer); err != nil {
+			return fmt.Errorf("Failed to format output as JSON: %v", err)
+		}
+	case "text", "":
+		if err := formatter.FormatAsText(data, writer, options); err != nil {
+			return fmt.Errorf("Failed to format output as text: %v", err)
+		}
+	default:
+		return fmt.Errorf("Unsupported output format: %s. Supported formats: text, csv, json", outputFormat)
+	}
+	return nil
+}
+
 func init() {
-	listCmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format (text, csv)")
+	listCmd.Flags().StringVarP(&outputFormat, "format", "f", "text", "Output format (text, csv, json)")
+	listCmd.Flags().StringVar(&listLang, "lang", "en", "Text output labels language (ru, en)")
+	listCmd.Flags().BoolVar(&listShowTime, "show-time", false, "Show per-plate and total print time from slicer data (text, csv)")
+	listCmd.Flags().BoolVar(&listKeepExtracted, "keep-extracted", false, "Keep the extracted 3MF directory for debugging and print its path")
+	listCmd.Flags().BoolVar(&listShowDimensions, "show-dimensions", false, "Show object dimensions W x D x H in mm from the mesh (text, csv; json includes them)")
+	listCmd.Flags().IntSliceVar(&listPlates, "plate", nil, "Show only these plate IDs (repeatable or comma list)")
+	listCmd.Flags().BoolVar(&listNonPrintable, "include-non-printable", false, "Include non-printable objects (default from include_non_printable in config)")
+	listCmd.Flags().StringVar(&listMaterial, "material", "", "Show only objects whose material contains this value (case-insensitive)")
 	rootCmd.AddCommand(listCmd)
 }
\ No newline at end of file
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunListCommandFilter(t *testing.T) {
+	filePath := filepath.Join("..", "samples", "22d.3mf")
+	defer func() { outputFormat, listPlates, listMaterial = "text", nil, "" }()
+
+	t.Run("csv plate", func(t *testing.T) {
+		outputFormat, listPlates, listMaterial = "csv", []int{2}, ""
+		var out bytes.Buffer
+		if err := runListCommand(filePath, &out); err != nil {
+			t.Fatalf("runListCommand() error = %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		if len(lines) != 2 || !strings.HasPrefix(lines[1], "2,") {
+			t.Errorf("expected header and one row of plate 2, got:\n%s", out.String())
+		}
+	})
+
+	t.Run("text plate", func(t *testing.T) {
+		outputFormat, listPlates, listMaterial = "text", []int{1}, ""
+		var out bytes.Buffer
+		if err := runListCommand(filePath, &out); err != nil {
+			t.Fatalf("runListCommand() error = %v", err)
+		}
+
+		if !strings.Contains(out.String(), "Левая.stl") || strings.Contains(out.String(), "cutting_jig") {
+			t.Errorf("text output should contain only plate 1 objects:\n%s", out.String())
+		}
+	})
+
+	t.Run("json material", func(t *testing.T) {
+		outputFormat, listPlates, listMaterial = "json", nil, "no-such-material"
+		var out bytes.Buffer
+		if err := runListCommand(filePath, &out); err != nil {
+			t.Fatalf("runListCommand() error = %v", err)
+		}
+
+		var result struct {
+			Plates []json.RawMessage `json:"plates"`
+		}
+		if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, out.String())
+		}
+		if len(result.Plates) != 0 {
+			t.Errorf("expected no plates for unknown material, got %d", len(result.Plates))
+		}
+	})
+}
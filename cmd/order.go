@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"farmix-cli/internal/bitrix"
@@ -15,9 +17,20 @@ import (
 )
 
 var (
-	orderDealID string
+	orderDealID        string
+	orderOutputDir     string
+	orderOverwrite     bool
+	orderDryRun        bool
+	orderLang          string
+	orderFormat        string
+	orderNonPrintable  bool
+	orderKeepExtracted bool
+	orderRepeats       []string
 )
 
+// assignedUserNotFound is shown in reports when the deal's assigned user is not returned by Bitrix24
+const assignedUserNotFound = "Ответственный не найден"
+
 var orderCmd = &cobra.Command{
 	Use:   "order [file]",
 	Short: "Generate order and assignment Excel reports for a 3MF file with Bitrix24 integration",
@@ -30,9 +43,37 @@ This command creates two Excel files:
 The command integrates with Bitrix24 CRM to include:
 - Deal information and responsible person
 - Customer company name and contact details
-- Direct links to CRM records`,
+- Direct links to CRM records
+
+Reports are written to the current directory by default. Use --output-dir
+to choose another destination (it will be created if missing). Existing
+reports are not overwritten unless --overwrite is specified.
+
+Use --format csv to write the order data (plates, parts and materials)
+to a single flat [filename]-order.csv instead, one row per part of a plate,
+for tools that only import CSV.
+
+Use --dry-run to resolve the deal, customer and assigned user and parse
+the 3MF file without writing any reports.
+
+Use --repeat <plateID>=<n> (repeatable) when a plate is printed several
+times: part counts and material weights of the order report and the
+assignment totals are multiplied by n. A "repetitions" metadata entry of
+the plate in Metadata/model_settings.config is used when --repeat is not given.
+
+Non-printable objects (helper geometry) are left out of the reports unless
+--include-non-printable is set or include_non_printable: true is in ~/.farmix-cli.
+
+The deal's project is added to the report header when its custom field
+is set as order_custom_fields.project in ~/.farmix-cli.
+
+Use --keep-extracted to keep the unpacked 3MF in a temp directory for
+inspecting its XML; the directory path is printed.
+
+Report labels are in Russian by default; use --lang en for English.`,
 	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
+		orderNonPrintable = includeNonPrintable(cmd, orderNonPrintable)
 		if err := runOrderCommand(args[0]); err != nil {
 			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 			os.Exit(1)
@@ -46,6 +87,21 @@ func runOrderCommand(filePath string) error {
 		return fmt.Errorf("invalid deal ID: %v", err)
 	}
 
+	lang, err := formatter.ParseLang(orderLang)
+	if err != nil {
+		return err
+	}
+
+	format := strings.ToLower(orderFormat)
+	if format != "xlsx" && format != "csv" {
+		return fmt.Errorf("unsupported output format: %s. Supported formats: xlsx, csv", orderFormat)
+	}
+
+	plateRepeats, err := parsePlateRepeats(orderRepeats)
+	if err != nil {
+		return err
+	}
+
 	// Validate file extension
 	if !strings.HasSuffix(strings.ToLower(filePath), ".3mf") {
 		return fmt.Errorf("file must have .3mf extension: %s", filePath)
@@ -56,23 +112,42 @@ func runOrderCommand(filePath string) error {
 		return fmt.Errorf("file does not exist: %s", filePath)
 	}
 
-	// Get webhook URL from config
-	webhookURL := viper.GetString("bitrix_webhook_url")
-	if webhookURL == "" {
-		return fmt.Errorf("bitrix_webhook_url not configured. Please set it in ~/.farmix-cli config")
+	// Create Bitrix24 client (no requests yet, a missing or malformed webhook URL is reported here)
+	client, err := newBitrixClient()
+	if err != nil {
+		return err
+	}
+
+	// Prepare output paths before doing any work
+	orderPath, assignmentPath := buildOrderOutputPaths(filePath, orderOutputDir)
+	outputPaths := []string{orderPath, assignmentPath}
+	if format == "csv" {
+		outputPaths = []string{buildOrderCSVPath(filePath, orderOutputDir)}
+	}
+	if err := checkOutputPaths(orderOverwrite || orderDryRun, outputPaths...); err != nil {
+		return err
+	}
+	if !orderDryRun {
+		if err := os.MkdirAll(orderOutputDir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory %s: %v", orderOutputDir, err)
+		}
 	}
 
 	fmt.Printf("Processing 3MF file: %s\n", filePath)
 	fmt.Printf("Deal ID: %s\n", orderDealID)
 
 	// Parse 3MF file
-	data, err := parser.Parse3MF(filePath)
+	data, err := parser.Parse3MF(filePath, parser.WithPaintedMaterials(true), parser.WithKeepExtracted(orderKeepExtracted))
 	if err != nil {
 		return fmt.Errorf("failed to parse 3MF file: %v", err)
 	}
-
-	// Create Bitrix24 client
-	client := bitrix.NewClient(webhookURL)
+	if data.ExtractDir != "" {
+		fmt.Printf("Extracted 3MF kept in: %s\n", data.ExtractDir)
+	}
+	data = data.Filter(parser.PlateFilter{PrintableOnly: !orderNonPrintable})
+	if err := applyPlateRepeats(data, plateRepeats); err != nil {
+		return err
+	}
 
 	// Get deal information
 	fmt.Println("Getting deal information from Bitrix24...")
@@ -89,28 +164,66 @@ func runOrderCommand(filePath string) error {
 
 	// Get assigned user name
 	assignedUser, err := client.GetUser(deal.AssignedByID)
-	if err != nil {
+	if errors.Is(err, bitrix.ErrUserNotFound) {
+		// Deactivated employees are not returned by user.get; the report is still useful without them
+		fmt.Printf("Warning: assigned user %s not found in Bitrix24\n", deal.AssignedByID)
+		assignedUser = &bitrix.User{ID: deal.AssignedByID, FullName: assignedUserNotFound}
+	} else if err != nil {
 		return fmt.Errorf("failed to get assigned user information: %v", err)
 	}
 
+	// Get project from the configured deal custom field
+	project, err := orderProject(client, orderDealID)
+	if err != nil {
+		return err
+	}
+
 	fmt.Printf("Deal: %s\n", deal.Title)
 	fmt.Printf("Customer: %s\n", customerName)
+	if project != "" {
+		fmt.Printf("Project: %s\n", project)
+	}
 	fmt.Printf("Assigned to: %s\n", assignedUser.FullName)
 
-	// Generate output file names
-	baseName := strings.TrimSuffix(filepath.Base(filePath), ".3mf")
-	orderPath := baseName + "-order.xlsx"
-	assignmentPath := baseName + "-assignment.xlsx"
+	if orderDryRun {
+		if format == "csv" {
+			fmt.Printf("[DRY RUN] Would create order CSV: %s\n", outputPaths[0])
+		} else {
+			fmt.Printf("[DRY RUN] Would create order report: %s\n", orderPath)
+			fmt.Printf("[DRY RUN] Would create assignment report: %s\n", assignmentPath)
+		}
+		if err := checkOutputPaths(orderOverwrite, outputPaths...); err != nil {
+			fmt.Printf("[DRY RUN] Warning: %v\n", err)
+		}
+		return nil
+	}
+
+	orderOptions := formatter.OrderReportOptions{
+		MaterialPrices:     loadMaterialPrices(),
+		PrinterAssignments: viper.GetStringMapString("printer_assignments"),
+		Lang:               lang,
+		Project:            project,
+	}
+
+	if format == "csv" {
+		csvPath := outputPaths[0]
+		fmt.Printf("Creating order CSV: %s\n", csvPath)
+		if err := writeOrderCSV(csvPath, data, deal, customerName, orderOptions); err != nil {
+			return fmt.Errorf("failed to create order CSV: %v", err)
+		}
+		fmt.Printf("Order CSV created successfully: %s\n", csvPath)
+		return nil
+	}
 
 	// Create order report
 	fmt.Printf("Creating order report: %s\n", orderPath)
-	if err := formatter.FormatAsOrderExcel(data, deal, assignedUser, customerName, client, orderPath); err != nil {
+	if err := formatter.FormatAsOrderExcel(data, deal, assignedUser, customerName, client, orderPath, orderOptions); err != nil {
 		return fmt.Errorf("failed to create order report: %v", err)
 	}
 
 	// Create assignment report
 	fmt.Printf("Creating assignment report: %s\n", assignmentPath)
-	if err := formatter.FormatAsAssignmentExcel(data, deal, assignedUser, customerName, client, assignmentPath); err != nil {
+	if err := formatter.FormatAsAssignmentExcel(data, deal, assignedUser, customerName, client, assignmentPath, orderOptions); err != nil {
 		return fmt.Errorf("failed to create assignment report: %v", err)
 	}
 
@@ -121,8 +234,117 @@ func runOrderCommand(filePath string) error {
 	return nil
 }
 
+// orderProject reads the deal's project from the custom field set as order_custom_fields.project
+// in config; without it no request is made and the report header has no project row
+func orderProject(client *bitrix.Client, dealID string) (string, error) {
+	field := viper.GetString("order_custom_fields.project")
+	if field == "" {
+		return "", nil
+	}
+
+	fields, err := client.GetDealFields(dealID, []string{"ID", field})
+	if err != nil {
+		return "", fmt.Errorf("failed to get deal project field %s: %v", field, err)
+	}
+	project, _ := bitrix.DealFieldString(fields, field)
+	return project, nil
+}
+
+// parsePlateRepeats parses --repeat values "<plateID>=<n>" into plate ID -> repetitions
+func parsePlateRepeats(values []string) (map[int]int, error) {
+	repeats := make(map[int]int)
+	for _, value := range values {
+		plateText, countText, found := strings.Cut(value, "=")
+		plateID, plateErr := strconv.Atoi(strings.TrimSpace(plateText))
+		count, countErr := strconv.Atoi(strings.TrimSpace(countText))
+		if !found || plateErr != nil || countErr != nil {
+			return nil, fmt.Errorf("invalid --repeat value %q, expected <plateID>=<n>", value)
+		}
+		if count < 1 {
+			return nil, fmt.Errorf("invalid --repeat value %q: repetitions must be at least 1", value)
+		}
+		repeats[plateID] = count
+	}
+	return repeats, nil
+}
+
+// applyPlateRepeats sets the repetitions of plates, overriding the 3MF metadata
+func applyPlateRepeats(data *parser.Parser3MF, repeats map[int]int) error {
+	found := make(map[int]bool)
+	for i := range data.Plates {
+		if count, ok := repeats[data.Plates[i].PlateID]; ok {
+			data.Plates[i].Repetitions = count
+			found[data.Plates[i].PlateID] = true
+		}
+	}
+	for plateID := range repeats {
+		if !found[plateID] {
+			return fmt.Errorf("--repeat: plate %d not found in the 3MF file", plateID)
+		}
+	}
+	return nil
+}
+
+// loadMaterialPrices reads material_prices (material name -> price per kg) from config
+func loadMaterialPrices() map[string]float64 {
+	return getConfigFloatMap("material_prices")
+}
+
+// buildOrderOutputPaths returns order and assignment report paths inside outputDir
+func buildOrderOutputPaths(filePath, outputDir string) (string, string) {
+	baseName := filepath.Base(filePath)
+	baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
+
+	orderPath := filepath.Join(outputDir, baseName+"-order.xlsx")
+	assignmentPath := filepath.Join(outputDir, baseName+"-assignment.xlsx")
+	return orderPath, assignmentPath
+}
+
+// buildOrderCSVPath returns the order CSV path inside outputDir
+func buildOrderCSVPath(filePath, outputDir string) string {
+	baseName := filepath.Base(filePath)
+	baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
+	return filepath.Join(outputDir, baseName+"-order.csv")
+}
+
+// writeOrderCSV writes the flat order CSV to path
+func writeOrderCSV(path string, data *parser.Parser3MF, deal *bitrix.Deal, customerName string, options formatter.OrderReportOptions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := formatter.FormatAsOrderCSV(data, deal, customerName, file, options); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// checkOutputPaths refuses to clobber existing files unless overwrite is set
+func checkOutputPaths(overwrite bool, paths ...string) error {
+	if overwrite {
+		return nil
+	}
+
+	for _, path := range paths {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("output file already exists: %s (use --overwrite to replace it)", path)
+		}
+	}
+
+	return nil
+}
+
 func init() {
 	orderCmd.Flags().StringVar(&orderDealID, "deal-id", "", "Bitrix24 deal ID (required)")
 	orderCmd.MarkFlagRequired("deal-id")
+	orderCmd.Flags().StringVarP(&orderOutputDir, "output-dir", "o", ".", "Directory for generated reports (created if missing)")
+	orderCmd.Flags().BoolVar(&orderOverwrite, "overwrite", false, "Overwrite existing report files")
+	orderCmd.Flags().BoolVar(&orderDryRun, "dry-run", false, "Resolve deal data and parse the file without writing reports")
+	orderCmd.Flags().StringVar(&orderLang, "lang", "ru", "Report labels language (ru, en)")
+	orderCmd.Flags().StringVarP(&orderFormat, "format", "f", "xlsx", "Output format (xlsx, csv)")
+	orderCmd.Flags().StringSliceVar(&orderRepeats, "repeat", nil, "Plate repetitions as <plateID>=<n> (repeatable, overrides 3MF metadata)")
+	orderCmd.Flags().BoolVar(&orderKeepExtracted, "keep-extracted", false, "Keep the extracted 3MF directory for debugging and print its path")
+	orderCmd.Flags().BoolVar(&orderNonPrintable, "include-non-printable", false, "Include non-printable objects (default from include_non_printable in config)")
 	rootCmd.AddCommand(orderCmd)
 }
\ No newline at end of file
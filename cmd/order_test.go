@@ -0,0 +1,291 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"farmix-cli/internal/parser"
+
+	"github.com/spf13/viper"
+)
+
+func TestBuildOrderOutputPaths(t *testing.T) {
+	tests := []struct {
+		name               string
+		filePath           string
+		outputDir          string
+		expectedOrder      string
+		expectedAssignment string
+	}{
+		{
+			name:               "default output dir",
+			filePath:           "model.3mf",
+			outputDir:          ".",
+			expectedOrder:      "model-order.xlsx",
+			expectedAssignment: "model-assignment.xlsx",
+		},
+		{
+			name:               "input in subdirectory",
+			filePath:           filepath.Join("projects", "client", "model.3mf"),
+			outputDir:          ".",
+			expectedOrder:      "model-order.xlsx",
+			expectedAssignment: "model-assignment.xlsx",
+		},
+		{
+			name:               "custom output dir",
+			filePath:           "model.3mf",
+			outputDir:          filepath.Join("out", "orders"),
+			expectedOrder:      filepath.Join("out", "orders", "model-order.xlsx"),
+			expectedAssignment: filepath.Join("out", "orders", "model-assignment.xlsx"),
+		},
+		{
+			name:               "uppercase extension",
+			filePath:           "MODEL.3MF",
+			outputDir:          ".",
+			expectedOrder:      "MODEL-order.xlsx",
+			expectedAssignment: "MODEL-assignment.xlsx",
+		},
+		{
+			name:               "name with dots",
+			filePath:           "8+2+12.v2.3mf",
+			outputDir:          "out",
+			expectedOrder:      filepath.Join("out", "8+2+12.v2-order.xlsx"),
+			expectedAssignment: filepath.Join("out", "8+2+12.v2-assignment.xlsx"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orderPath, assignmentPath := buildOrderOutputPaths(tt.filePath, tt.outputDir)
+			if orderPath != tt.expectedOrder {
+				t.Errorf("order path = %q, want %q", orderPath, tt.expectedOrder)
+			}
+			if assignmentPath != tt.expectedAssignment {
+				t.Errorf("assignment path = %q, want %q", assignmentPath, tt.expectedAssignment)
+			}
+		})
+	}
+}
+
+func TestCheckOutputPaths(t *testing.T) {
+	tempDir := t.TempDir()
+	existing := filepath.Join(tempDir, "model-order.xlsx")
+	missing := filepath.Join(tempDir, "model-assignment.xlsx")
+	if err := os.WriteFile(existing, []byte("old report"), 0644); err != nil {
+		t.Fatalf("failed to create existing file: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		overwrite bool
+		paths     []string
+		wantErr   bool
+	}{
+		{
+			name:      "no existing files",
+			overwrite: false,
+			paths:     []string{missing},
+			wantErr:   false,
+		},
+		{
+			name:      "existing file without overwrite",
+			overwrite: false,
+			paths:     []string{existing, missing},
+			wantErr:   true,
+		},
+		{
+			name:      "existing file with overwrite",
+			overwrite: true,
+			paths:     []string{existing, missing},
+			wantErr:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkOutputPaths(tt.overwrite, tt.paths...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkOutputPaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "--overwrite") {
+				t.Errorf("expected error to mention --overwrite, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOrderCommandFlags(t *testing.T) {
+	outputDirFlag := orderCmd.Flags().Lookup("output-dir")
+	if outputDirFlag == nil {
+		t.Fatal("expected --output-dir flag to be defined")
+	}
+	if outputDirFlag.DefValue != "." {
+		t.Errorf("expected --output-dir default to be '.', got %q", outputDirFlag.DefValue)
+	}
+
+	overwriteFlag := orderCmd.Flags().Lookup("overwrite")
+	if overwriteFlag == nil {
+		t.Fatal("expected --overwrite flag to be defined")
+	}
+	if overwriteFlag.DefValue != "false" {
+		t.Errorf("expected --overwrite default to be false, got %q", overwriteFlag.DefValue)
+	}
+}
+
+// newOrderTestServer serves the deal, company and user requests of the order command
+func newOrderTestServer(t *testing.T) *httptest.Server {
+	return newOrderTestServerWithUsers(t, `[{"ID":"1","NAME":"Ivan","LAST_NAME":"Petrov"}]`)
+}
+
+// newOrderTestServerWithUsers is newOrderTestServer with the given user.get result
+func newOrderTestServerWithUsers(t *testing.T, users string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/crm.deal.get"):
+			w.Write([]byte(`{"result":{"ID":"123","TITLE":"Test deal","COMPANY_ID":"7","ASSIGNED_BY_ID":"1"}}`))
+		case strings.HasSuffix(r.URL.Path, "/crm.company.get"):
+			w.Write([]byte(`{"result":{"ID":"7","TITLE":"ACME"}}`))
+		case strings.HasSuffix(r.URL.Path, "/user.get"):
+			w.Write([]byte(`{"result":` + users + `}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestRunOrderCommandDryRun(t *testing.T) {
+	server := newOrderTestServer(t)
+	defer server.Close()
+
+	viper.Set("bitrix_webhook_url", server.URL+"/rest/1/token/")
+	defer viper.Set("bitrix_webhook_url", "")
+
+	outputDir := filepath.Join(t.TempDir(), "reports")
+	orderDealID, orderOutputDir, orderDryRun = "123", outputDir, true
+	defer func() {
+		orderDealID, orderOutputDir, orderDryRun = "", ".", false
+	}()
+
+	if err := runOrderCommand(filepath.Join("..", "samples", "22d.3mf")); err != nil {
+		t.Fatalf("runOrderCommand() error = %v", err)
+	}
+
+	if _, err := os.Stat(outputDir); !os.IsNotExist(err) {
+		t.Errorf("dry run must not create output directory %s", outputDir)
+	}
+}
+
+// captureStdout runs fn and returns everything it printed to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	original := os.Stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	os.Stdout = writer
+	defer func() { os.Stdout = original }()
+
+	fn()
+
+	writer.Close()
+	output, _ := io.ReadAll(reader)
+	return string(output)
+}
+
+func TestRunOrderCommandAssignedUserNotFound(t *testing.T) {
+	server := newOrderTestServerWithUsers(t, `[]`)
+	defer server.Close()
+
+	viper.Set("bitrix_webhook_url", server.URL+"/rest/1/token/")
+	defer viper.Set("bitrix_webhook_url", "")
+
+	orderDealID, orderOutputDir, orderDryRun = "123", t.TempDir(), true
+	defer func() {
+		orderDealID, orderOutputDir, orderDryRun = "", ".", false
+	}()
+
+	var err error
+	output := captureStdout(t, func() {
+		err = runOrderCommand(filepath.Join("..", "samples", "22d.3mf"))
+	})
+	if err != nil {
+		t.Fatalf("runOrderCommand() error = %v", err)
+	}
+	if !strings.Contains(output, "Assigned to: "+assignedUserNotFound) {
+		t.Errorf("expected placeholder for missing assigned user, got:\n%s", output)
+	}
+}
+
+func TestBuildOrderCSVPath(t *testing.T) {
+	got := buildOrderCSVPath(filepath.Join("projects", "8+2+12.v2.3mf"), "out")
+	if want := filepath.Join("out", "8+2+12.v2-order.csv"); got != want {
+		t.Errorf("buildOrderCSVPath() = %q, want %q", got, want)
+	}
+}
+
+func TestRunOrderCommandCSV(t *testing.T) {
+	server := newOrderTestServer(t)
+	defer server.Close()
+
+	viper.Set("bitrix_webhook_url", server.URL+"/rest/1/token/")
+	defer viper.Set("bitrix_webhook_url", "")
+
+	outputDir := t.TempDir()
+	orderDealID, orderOutputDir, orderFormat = "123", outputDir, "csv"
+	defer func() {
+		orderDealID, orderOutputDir, orderFormat = "", ".", "xlsx"
+	}()
+
+	if err := runOrderCommand(filepath.Join("..", "samples", "22d.3mf")); err != nil {
+		t.Fatalf("runOrderCommand() error = %v", err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(outputDir, "22d-order.csv"))
+	if err != nil {
+		t.Fatalf("expected order CSV to be created: %v", err)
+	}
+	if !strings.HasPrefix(string(content), "DealID,Customer,Plate,") || !strings.Contains(string(content), "123,ACME,") {
+		t.Errorf("unexpected order CSV:\n%s", content)
+	}
+	if entries, _ := os.ReadDir(outputDir); len(entries) != 1 {
+		t.Errorf("expected only the CSV file in %s, got %d files", outputDir, len(entries))
+	}
+}
+
+func TestParsePlateRepeats(t *testing.T) {
+	repeats, err := parsePlateRepeats([]string{"1=3", " 2 = 2 "})
+	if err != nil {
+		t.Fatalf("parsePlateRepeats() error = %v", err)
+	}
+	if len(repeats) != 2 || repeats[1] != 3 || repeats[2] != 2 {
+		t.Errorf("parsePlateRepeats() = %v, want map[1:3 2:2]", repeats)
+	}
+
+	for _, value := range []string{"1", "a=3", "1=x", "1=0", "1=-2"} {
+		if _, err := parsePlateRepeats([]string{value}); err == nil {
+			t.Errorf("parsePlateRepeats(%q) expected error", value)
+		}
+	}
+}
+
+func TestApplyPlateRepeats(t *testing.T) {
+	data := &parser.Parser3MF{Plates: []parser.PlateInfo{{PlateID: 1, Repetitions: 2}, {PlateID: 2}}}
+
+	if err := applyPlateRepeats(data, map[int]int{2: 4}); err != nil {
+		t.Fatalf("applyPlateRepeats() error = %v", err)
+	}
+	if data.Plates[0].Repetitions != 2 || data.Plates[1].Repetitions != 4 {
+		t.Errorf("repetitions = %d, %d; want metadata 2 kept and 4 applied", data.Plates[0].Repetitions, data.Plates[1].Repetitions)
+	}
+
+	if err := applyPlateRepeats(data, map[int]int{5: 2}); err == nil || !strings.Contains(err.Error(), "plate 5") {
+		t.Errorf("applyPlateRepeats() for a missing plate error = %v", err)
+	}
+}
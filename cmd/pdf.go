@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"farmix-cli/internal/formatter"
+	"farmix-cli/internal/parser"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	pdfOutput      string
+	pdfPageSize    string
+	pdfOrientation string
+	pdfLang        string
+	// pdfNonPrintable includes non-printable objects (--include-non-printable or include_non_printable)
+	pdfNonPrintable bool
+)
+
+var pdfCmd = &cobra.Command{
+	Use:   "pdf [file]",
+	Short: "Generate a PDF analysis report for a 3MF file",
+	Long: `Generate a PDF report with plates, objects and materials of a 3MF file.
+
+The report is written to [filename]_analysis.pdf in the current directory
+unless --output is specified. Use --page-size (A4, Letter) and
+--orientation (P, L) to change the page layout; landscape gives wide
+tables with long part names more room. Labels are in English by default;
+use --lang ru for Russian.
+
+Non-printable objects (helper geometry) are left out of the report unless
+--include-non-printable is set or include_non_printable: true is in ~/.farmix-cli.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		pdfNonPrintable = includeNonPrintable(cmd, pdfNonPrintable)
+		if err := runPDFCommand(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func runPDFCommand(filePath string) error {
+	// Validate file extension
+	if !strings.HasSuffix(strings.ToLower(filePath), ".3mf") {
+		return fmt.Errorf("file must have .3mf extension: %s", filePath)
+	}
+
+	// Check if file exists
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("file does not exist: %s", filePath)
+	}
+
+	lang, err := formatter.ParseLang(pdfLang)
+	if err != nil {
+		return err
+	}
+
+	outputPath := pdfOutput
+	if outputPath == "" {
+		outputPath = buildPDFOutputPath(filePath)
+	}
+
+	data, err := parser.Parse3MF(filePath, parser.WithPaintedMaterials(true))
+	if err != nil {
+		return fmt.Errorf("failed to parse 3MF file: %v", err)
+	}
+	data = data.Filter(parser.PlateFilter{PrintableOnly: !pdfNonPrintable})
+
+	options := formatter.PDFOptions{
+		PageSize:    pdfPageSize,
+		Orientation: pdfOrientation,
+		Lang:        lang,
+	}
+	if err := formatter.FormatAsPDF(data, outputPath, options); err != nil {
+		return fmt.Errorf("failed to create PDF report: %v", err)
+	}
+
+	fmt.Printf("PDF report created: %s\n", outputPath)
+	return nil
+}
+
+// buildPDFOutputPath returns [basename]_analysis.pdf in the current directory
+func buildPDFOutputPath(filePath string) string {
+	baseName := filepath.Base(filePath)
+	baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
+	return baseName + "_analysis.pdf"
+}
+
+func init() {
+	pdfCmd.Flags().StringVarP(&pdfOutput, "output", "o", "", "Output PDF path (default: [filename]_analysis.pdf)")
+	pdfCmd.Flags().StringVar(&pdfPageSize, "page-size", "A4", "Page size (A4, Letter)")
+	pdfCmd.Flags().StringVar(&pdfOrientation, "orientation", "P", "Page orientation (P - portrait, L - landscape)")
+	pdfCmd.Flags().StringVar(&pdfLang, "lang", "en", "Report labels language (ru, en)")
+	pdfCmd.Flags().BoolVar(&pdfNonPrintable, "include-non-printable", false, "Include non-printable objects (default from include_non_printable in config)")
+	rootCmd.AddCommand(pdfCmd)
+}
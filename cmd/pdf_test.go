@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBuildPDFOutputPath(t *testing.T) {
+	tests := []struct {
+		filePath string
+		expected string
+	}{
+		{"model.3mf", "model_analysis.pdf"},
+		{filepath.Join("projects", "MODEL.3MF"), "MODEL_analysis.pdf"},
+		{"8+2+12.v2.3mf", "8+2+12.v2_analysis.pdf"},
+	}
+
+	for _, tt := range tests {
+		if got := buildPDFOutputPath(tt.filePath); got != tt.expected {
+			t.Errorf("buildPDFOutputPath(%q) = %q, want %q", tt.filePath, got, tt.expected)
+		}
+	}
+}
+
+func TestRunPDFCommand(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "report.pdf")
+	pdfOutput, pdfPageSize, pdfOrientation = outputPath, "A4", "L"
+	defer func() {
+		pdfOutput, pdfPageSize, pdfOrientation = "", "A4", "P"
+	}()
+
+	if err := runPDFCommand(filepath.Join("..", "samples", "22d.3mf")); err != nil {
+		t.Fatalf("runPDFCommand() error = %v", err)
+	}
+
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		t.Fatalf("expected PDF file to be created: %v", err)
+	}
+	if info.Size() == 0 {
+		t.Error("expected non-empty PDF file")
+	}
+}
+
+func TestRunPDFCommandValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+	}{
+		{"wrong extension", filepath.Join("..", "samples", "test_cube.stl")},
+		{"missing file", "missing.3mf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runPDFCommand(tt.filePath); err == nil {
+				t.Errorf("expected error for %s", tt.filePath)
+			}
+		})
+	}
+}
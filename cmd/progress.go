@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"farmix-cli/internal/bitrix"
+)
+
+// progressLineInterval is how often (in items) progress is printed when output is not a terminal
+const progressLineInterval = 10
+
+// newProgressPrinter returns a progress callback printing "label: done/total" to w.
+// On a terminal the counter is updated in place, otherwise a line is printed every
+// progressLineInterval items and at the end.
+func newProgressPrinter(w io.Writer, label string, isTerminal bool) bitrix.ProgressFunc {
+	return func(done, total int) {
+		if isTerminal {
+			fmt.Fprintf(w, "\r%s: %d/%d", label, done, total)
+			if done == total {
+				fmt.Fprintln(w)
+			}
+			return
+		}
+
+		if done%progressLineInterval == 0 || done == total {
+			fmt.Fprintf(w, "%s: %d/%d\n", label, done, total)
+		}
+	}
+}
+
+// isTerminal reports whether f is attached to a terminal (character device)
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
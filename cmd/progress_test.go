@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProgressPrinter(t *testing.T) {
+	tests := []struct {
+		name       string
+		isTerminal bool
+		total      int
+		expected   string
+	}{
+		{
+			name:       "terminal updates in place",
+			isTerminal: true,
+			total:      3,
+			expected:   "\rCreating products: 1/3\rCreating products: 2/3\rCreating products: 3/3\n",
+		},
+		{
+			name:       "non-terminal prints every interval and at the end",
+			isTerminal: false,
+			total:      23,
+			expected:   "Creating products: 10/23\nCreating products: 20/23\nCreating products: 23/23\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			progress := newProgressPrinter(&buf, "Creating products", tt.isTerminal)
+			for done := 1; done <= tt.total; done++ {
+				progress(done, tt.total)
+			}
+
+			if buf.String() != tt.expected {
+				t.Errorf("output = %q, want %q", buf.String(), tt.expected)
+			}
+		})
+	}
+}
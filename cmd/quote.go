@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"farmix-cli/internal/formatter"
+	"farmix-cli/internal/parser"
+	"farmix-cli/internal/slicer"
+	"farmix-cli/internal/stl"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	quoteSTLDir   string
+	quoteOrcaPath string
+	quoteInfill   float64
+	quoteFormat   string
+	quoteLang     string
+	// quoteNonPrintable includes non-printable objects (--include-non-printable or include_non_printable)
+	quoteNonPrintable bool
+)
+
+var quoteCmd = &cobra.Command{
+	Use:   "quote [file.3mf]",
+	Short: "Consolidated weight, print time and material cost estimate for a 3MF project",
+	Long: `Combine list, volume and slice into one quote for a 3MF project.
+
+For every distinct part (name and material) the weight is estimated from the
+volume of [part name].stl in --stl-dir when it exists (objects imported
+from STL already have the extension in their name), otherwise from the
+plate filament usage stored in the sliced project. With --orca-path each
+found STL is also sliced with OrcaSlicer to estimate its print time.
+
+The total print time comes from the sliced project; for unsliced projects it
+is the sum of the OrcaSlicer estimates. Material cost uses material_prices
+from ~/.farmix-cli:
+  material_prices:
+    PLA: 1500
+    PETG: 1800
+
+Non-printable objects (helper geometry) are left out of the quote unless
+--include-non-printable is set or include_non_printable: true is in ~/.farmix-cli.
+
+Examples:
+  farmix-cli quote model.3mf
+  farmix-cli quote --stl-dir parts --infill 20 model.3mf
+  farmix-cli quote --stl-dir parts --orca-path /path/to/OrcaSlicer --format json model.3mf`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		quoteNonPrintable = includeNonPrintable(cmd, quoteNonPrintable)
+		if err := runQuoteCommand(args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func runQuoteCommand(filePath string) error {
+	if !strings.HasSuffix(strings.ToLower(filePath), ".3mf") {
+		return fmt.Errorf("file must have .3mf extension: %s", filePath)
+	}
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return fmt.Errorf("file does not exist: %s", filePath)
+	}
+
+	lang, err := formatter.ParseLang(quoteLang)
+	if err != nil {
+		return err
+	}
+	format := strings.ToLower(quoteFormat)
+	if format != "text" && format != "json" && format != "" {
+		return fmt.Errorf("unsupported output format: %s. Supported formats: text, json", quoteFormat)
+	}
+	if quoteInfill < 0 || quoteInfill > 100 {
+		return fmt.Errorf("infill must be between 0 and 100: %.2f", quoteInfill)
+	}
+
+	quote, err := buildProjectQuote(filePath)
+	if err != nil {
+		return err
+	}
+
+	if format == "json" {
+		return formatter.FormatQuoteAsJSON(quote, os.Stdout)
+	}
+	return formatter.FormatQuoteAsText(quote, os.Stdout, lang)
+}
+
+// buildProjectQuote parses the 3MF project and combines it with STL and slicer estimates
+func buildProjectQuote(filePath string) (*formatter.Quote, error) {
+	data, err := parser.Parse3MF(filePath, parser.WithPaintedMaterials(true))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse 3MF file: %v", err)
+	}
+	data = data.Filter(parser.PlateFilter{PrintableOnly: !quoteNonPrintable})
+
+	options := formatter.QuoteOptions{
+		Estimates:      estimateParts(data),
+		MaterialPrices: getConfigFloatMap("material_prices"),
+	}
+	return formatter.BuildQuote(data, options), nil
+}
+
+// estimateParts estimates weight (volume of the STL) and print time (OrcaSlicer) of every
+// distinct part and material that has [name].stl in --stl-dir; failures are reported as warnings.
+// The weight depends on the material density, the print time is sliced once per part
+func estimateParts(data *parser.Parser3MF) map[string]formatter.PartEstimate {
+	estimates := make(map[string]formatter.PartEstimate)
+	if quoteSTLDir == "" {
+		return estimates
+	}
+
+	densities := loadMaterialDensities()
+	printTimes := make(map[string]time.Duration)
+	for _, plate := range data.Plates {
+		for _, obj := range plate.Objects {
+			key := formatter.PartEstimateKey(obj.Name, obj.Material)
+			if _, done := estimates[key]; done {
+				continue
+			}
+
+			stlFile := partSTLPath(quoteSTLDir, obj.Name)
+			if _, err := os.Stat(stlFile); err != nil {
+				continue
+			}
+
+			var estimate formatter.PartEstimate
+			config := stl.VolumeConfig{
+				Material:  parser.CleanMaterialName(obj.Material),
+				Densities: densities,
+				Infill: &stl.InfillConfig{
+					Percent:        quoteInfill,
+					ShellThickness: 2 * wallLineWidth, // two perimeters, as volume --shell-thickness default
+				},
+			}
+			if result, err := stl.CalculateVolume(stlFile, config); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to calculate volume of %s: %v\n", stlFile, err)
+			} else if result.Density == 0 {
+				fmt.Fprintf(os.Stderr, "Warning: unknown density of %q for %s, set it in material_densities in %s\n", config.Material, stlFile, configDisplayPath)
+			} else {
+				estimate.WeightGrams = result.AdjustedWeight
+			}
+
+			if printTime, sliced := printTimes[obj.Name]; sliced {
+				estimate.PrintTime = printTime
+			} else if quoteOrcaPath != "" {
+				result, err := slicer.SliceSTL(slicer.CreateDefaultConfig(quoteOrcaPath, stlFile))
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: failed to slice %s: %v\n", stlFile, err)
+				} else {
+					estimate.PrintTime = result.PrintTime
+				}
+				printTimes[obj.Name] = estimate.PrintTime
+			}
+
+			estimates[key] = estimate
+		}
+	}
+	return estimates
+}
+
+// partSTLPath returns the STL file of a part; objects imported from STL keep the extension in their name
+func partSTLPath(dir, name string) string {
+	if strings.HasSuffix(strings.ToLower(name), ".stl") {
+		return filepath.Join(dir, name)
+	}
+	return filepath.Join(dir, name+".stl")
+}
+
+func init() {
+	quoteCmd.Flags().StringVar(&quoteSTLDir, "stl-dir", "", "Directory with [part name].stl files for volume-based weight")
+	quoteCmd.Flags().StringVar(&quoteOrcaPath, "orca-path", "", "Path to OrcaSlicer to estimate print time of STL parts")
+	quoteCmd.Flags().Float64Var(&quoteInfill, "infill", 100, "Infill percent for STL weight estimate (0-100)")
+	quoteCmd.Flags().StringVarP(&quoteFormat, "format", "f", "text", "Output format (text, json)")
+	quoteCmd.Flags().StringVar(&quoteLang, "lang", "en", "Text output labels language (ru, en)")
+	quoteCmd.Flags().BoolVar(&quoteNonPrintable, "include-non-printable", false, "Include non-printable objects (default from include_non_printable in config)")
+	rootCmd.AddCommand(quoteCmd)
+}
\ No newline at end of file
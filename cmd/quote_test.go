@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"archive/zip"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"farmix-cli/internal/formatter"
+
+	"github.com/hschendel/stl"
+	"github.com/spf13/viper"
+)
+
+const quoteModel = `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter">
+ <resources>
+  <object id="1" type="model"><components><component objectid="10" path="/3D/Objects/body.model"/></components></object>
+  <object id="2" type="model"><components><component objectid="20" path="/3D/Objects/body.model"/></components></object>
+  <object id="3" type="model"><components><component objectid="30" path="/3D/Objects/clip.model"/></components></object>
+ </resources>
+ <build>
+  <item objectid="1" transform="1 0 0 0 1 0 0 0 1 0 0 0"/>
+  <item objectid="2" transform="1 0 0 0 1 0 0 0 1 20 0 0"/>
+  <item objectid="3" transform="1 0 0 0 1 0 0 0 1 40 0 0"/>
+ </build>
+</model>`
+
+const quoteSettings = `<?xml version="1.0" encoding="UTF-8"?>
+<config>
+ <object id="1"><metadata key="name" value="Body"/><metadata key="extruder" value="1"/></object>
+ <object id="2"><metadata key="name" value="Body"/><metadata key="extruder" value="1"/></object>
+ <object id="3"><metadata key="name" value="Clip"/><metadata key="extruder" value="1"/></object>
+ <plate>
+  <metadata key="plater_id" value="1"/>
+  <model_instance><metadata key="object_id" value="1"/></model_instance>
+  <model_instance><metadata key="object_id" value="2"/></model_instance>
+  <model_instance><metadata key="object_id" value="3"/></model_instance>
+ </plate>
+</config>`
+
+const quoteSliceInfo = `<?xml version="1.0" encoding="UTF-8"?>
+<config>
+ <plate>
+  <metadata key="index" value="1"/>
+  <metadata key="prediction" value="3600"/>
+  <metadata key="weight" value="30"/>
+  <filament id="1" type="PLA" used_g="30"/>
+ </plate>
+</config>`
+
+// writeQuoteProject writes a one-plate PLA project (2 x Body, 1 x Clip) sliced to 30 g and 1 h
+func writeQuoteProject(t *testing.T) string {
+	t.Helper()
+	return writeQuoteProjectModel(t, quoteModel)
+}
+
+// writeQuoteProjectModel writes the quote project with another 3dmodel.model
+func writeQuoteProjectModel(t *testing.T, model string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "quote.3mf")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	archive := zip.NewWriter(out)
+	files := map[string]string{
+		"3D/3dmodel.model":                    model,
+		"Metadata/model_settings.config":      quoteSettings,
+		"Metadata/slice_info.config":          quoteSliceInfo,
+		"Metadata/filament_settings_1.config": `{"name": "PLA"}`,
+	}
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// writeCubeSTL writes a cube with the given edge (mm) to dir/name.stl
+func writeCubeSTL(t *testing.T, dir, name string, size float32) {
+	t.Helper()
+
+	v := func(x, y, z float32) stl.Vec3 { return stl.Vec3{x * size, y * size, z * size} }
+	faces := [][4]stl.Vec3{
+		{v(0, 0, 0), v(0, 1, 0), v(1, 1, 0), v(1, 0, 0)},
+		{v(0, 0, 1), v(1, 0, 1), v(1, 1, 1), v(0, 1, 1)},
+		{v(0, 0, 0), v(1, 0, 0), v(1, 0, 1), v(0, 0, 1)},
+		{v(0, 1, 0), v(0, 1, 1), v(1, 1, 1), v(1, 1, 0)},
+		{v(0, 0, 0), v(0, 0, 1), v(0, 1, 1), v(0, 1, 0)},
+		{v(1, 0, 0), v(1, 1, 0), v(1, 1, 1), v(1, 0, 1)},
+	}
+
+	solid := &stl.Solid{Name: name}
+	for _, c := range faces {
+		solid.Triangles = append(solid.Triangles,
+			stl.Triangle{Vertices: [3]stl.Vec3{c[0], c[1], c[2]}},
+			stl.Triangle{Vertices: [3]stl.Vec3{c[0], c[2], c[3]}},
+		)
+	}
+	if err := solid.WriteFile(filepath.Join(dir, name+".stl")); err != nil {
+		t.Fatalf("failed to write STL: %v", err)
+	}
+}
+
+func TestBuildProjectQuote(t *testing.T) {
+	stlDir := t.TempDir()
+	writeCubeSTL(t, stlDir, "Body", 10) // 1 cm³ PLA = 1.24 g
+
+	quoteSTLDir, quoteOrcaPath, quoteInfill = stlDir, "", 100
+	viper.Set("material_prices", map[string]interface{}{"PLA": 2000})
+	defer func() {
+		quoteSTLDir, quoteInfill = "", 100
+		viper.Set("material_prices", nil)
+	}()
+
+	quote, err := buildProjectQuote(writeQuoteProject(t))
+	if err != nil {
+		t.Fatalf("buildProjectQuote() error = %v", err)
+	}
+
+	want := map[string]struct {
+		count  int
+		weight float64
+		source string
+	}{
+		"Body": {2, 2 * 1.24, formatter.WeightSourceSTL},
+		"Clip": {1, 10, formatter.WeightSourceSlice}, // 30 g plate shared by 3 objects
+	}
+	if len(quote.Parts) != len(want) {
+		t.Fatalf("got %d parts, want %d: %+v", len(quote.Parts), len(want), quote.Parts)
+	}
+	for _, part := range quote.Parts {
+		w := want[part.Name]
+		if part.Count != w.count || math.Abs(part.WeightGrams-w.weight) > 1e-6 || part.WeightSource != w.source {
+			t.Errorf("part %s = %d pcs, %.4f g from %q; want %d pcs, %.4f g from %q",
+				part.Name, part.Count, part.WeightGrams, part.WeightSource, w.count, w.weight, w.source)
+		}
+	}
+
+	if math.Abs(quote.TotalWeightGrams-12.48) > 1e-6 {
+		t.Errorf("TotalWeightGrams = %v, want 12.48", quote.TotalWeightGrams)
+	}
+	if quote.TotalPrintTime.Seconds() != 3600 {
+		t.Errorf("TotalPrintTime = %v, want 1h", quote.TotalPrintTime)
+	}
+	if math.Abs(quote.TotalCost-24.96) > 1e-9 {
+		t.Errorf("TotalCost = %v, want 24.96", quote.TotalCost)
+	}
+}
+
+func TestBuildProjectQuoteSkipsNonPrintable(t *testing.T) {
+	path := writeQuoteProjectModel(t, strings.Replace(quoteModel, `<item objectid="3"`, `<item objectid="3" printable="0"`, 1))
+	defer func() { quoteNonPrintable = false }()
+
+	tests := []struct {
+		includeNonPrintable bool
+		wantParts           int
+	}{
+		{false, 1}, // Clip is helper geometry
+		{true, 2},
+	}
+
+	for _, tt := range tests {
+		quoteNonPrintable = tt.includeNonPrintable
+		quote, err := buildProjectQuote(path)
+		if err != nil {
+			t.Fatalf("buildProjectQuote() error = %v", err)
+		}
+		if len(quote.Parts) != tt.wantParts {
+			t.Errorf("include non-printable %v: got %d parts, want %d: %+v", tt.includeNonPrintable, len(quote.Parts), tt.wantParts, quote.Parts)
+		}
+	}
+}
+
+func TestPartSTLPath(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Body", filepath.Join("parts", "Body.stl")},
+		{"Левая.stl", filepath.Join("parts", "Левая.stl")},
+		{"Clip.STL", filepath.Join("parts", "Clip.STL")},
+	}
+
+	for _, tt := range tests {
+		if got := partSTLPath("parts", tt.name); got != tt.want {
+			t.Errorf("partSTLPath(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRunQuoteCommandValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+	}{
+		{"wrong extension", filepath.Join("..", "samples", "test_cube.stl")},
+		{"missing file", "missing.3mf"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := runQuoteCommand(tt.filePath); err == nil {
+				t.Errorf("expected error for %s", tt.filePath)
+			}
+		})
+	}
+}
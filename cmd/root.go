@@ -3,15 +3,53 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"farmix-cli/internal/bitrix"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
 
+// configFileName is the YAML config file in the home directory read by all commands
+const configFileName = ".farmix-cli"
+
+// legacyConfigFileName is also read when ~/.farmix-cli does not exist: earlier versions
+// looked the config up by name, which found ~/.farmix-cli.yaml too
+const legacyConfigFileName = configFileName + ".yaml"
+
+// configDisplayPath is the config location shown in messages
+const configDisplayPath = "~/" + configFileName
+
+// envPrefix prefixes environment variables that override config keys (FARMIX_BITRIX_WEBHOOK_URL)
+const envPrefix = "FARMIX"
+
+// envConfigKeys are bound to FARMIX_<KEY> explicitly, so that secrets can come from CI
+// environment instead of the config file; other keys are picked up by AutomaticEnv.
+// The unprefixed <KEY> of earlier versions is still read, with a deprecation warning
+var envConfigKeys = []string{"bitrix_webhook_url", "catalog_id", "store_id"}
+
+var (
+	// verbose enables debug logging (--verbose)
+	verbose bool
+	// configFile is the config path from --config, empty means ~/.farmix-cli
+	configFile string
+)
+
 var rootCmd = &cobra.Command{
 	Use:   "farmix-cli",
 	Short: "Farmix CLI - инструмент для 3D печати и анализа файлов",
 	Long:  `farmix-cli - консольная утилита для анализа 3MF файлов, слайсинга STL моделей, расчета объемов и интеграции с Bitrix24 CRM.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		if err := checkConfigFlag(cmd); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+			os.Exit(1)
+		}
+	},
 }
 
 func Execute() {
@@ -23,24 +61,272 @@ func Execute() {
 
 func init() {
 	cobra.OnInitialize(initConfig)
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Включить отладочный вывод")
+	rootCmd.PersistentFlags().StringVar(&configFile, "config", "", "Путь к файлу конфигурации (по умолчанию "+configDisplayPath+")")
+	rootCmd.PersistentFlags().String("webhook-url", "", "URL вебхука Bitrix24, переопределяет bitrix_webhook_url из конфигурации")
+	// A changed flag wins over the config value
+	viper.BindPFlag("bitrix_webhook_url", rootCmd.PersistentFlags().Lookup("webhook-url"))
+}
+
+// checkConfigFlag reports a --config file that does not exist;
+// "config init" is allowed to create it
+func checkConfigFlag(cmd *cobra.Command) error {
+	if configFile == "" || cmd == configInitCmd {
+		return nil
+	}
+	if _, err := os.Stat(configFile); err != nil {
+		return fmt.Errorf("файл конфигурации не найден: %s", configFile)
+	}
+	return nil
+}
+
+// includeNonPrintable returns --include-non-printable when it is set explicitly,
+// otherwise include_non_printable from the config (false - non-printable objects are skipped)
+func includeNonPrintable(cmd *cobra.Command, flagValue bool) bool {
+	if cmd.Flags().Changed("include-non-printable") {
+		return flagValue
+	}
+	return viper.GetBool("include_non_printable")
+}
+
+// newBitrixClient creates the Bitrix24 client used by all CRM commands: the webhook URL is
+// bitrix_webhook_url (config, FARMIX_BITRIX_WEBHOOK_URL or --webhook-url), the logger honours --verbose,
+// timeout, retries and rate limit come from the bitrix section of the config (see bitrixClientOptions).
+// A missing or malformed webhook URL is reported before any request is made, opts are applied after the config
+func newBitrixClient(opts ...bitrix.ClientOption) (*bitrix.Client, error) {
+	webhookURL := viper.GetString("bitrix_webhook_url")
+	if webhookURL == "" {
+		return nil, errNotConfigured("bitrix_webhook_url")
+	}
+
+	level := bitrix.LogLevelInfo
+	if verbose {
+		level = bitrix.LogLevelDebug
+	}
+	configOpts, err := bitrixClientOptions(viper.GetViper())
+	if err != nil {
+		return nil, fmt.Errorf("%v (in %s)", err, configFileDisplayPath())
+	}
+	opts = append(append([]bitrix.ClientOption{bitrix.WithLogger(bitrix.NewLogger(os.Stderr, level))}, configOpts...), opts...)
+	client, err := bitrix.NewClientValidated(webhookURL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("bitrix_webhook_url from %s: %v", configSource("bitrix_webhook_url"), err)
+	}
+	return client, nil
+}
+
+// configSource names where the value of key comes from, following the precedence of bindConfigEnv:
+// the --webhook-url flag, the environment variable or the config file
+func configSource(key string) string {
+	if key == "bitrix_webhook_url" {
+		if flag := rootCmd.PersistentFlags().Lookup("webhook-url"); flag != nil && flag.Changed {
+			return "--webhook-url"
+		}
+	}
+	if slices.Contains(envConfigKeys, key) {
+		for _, name := range []string{envVarName(key), legacyEnvVarName(key)} {
+			if _, set := os.LookupEnv(name); set {
+				return name + " environment variable"
+			}
+		}
+	}
+	return configFileDisplayPath()
+}
+
+// configFileDisplayPath returns the config file that was read, or ~/.farmix-cli before initConfig
+func configFileDisplayPath() string {
+	if path := viper.ConfigFileUsed(); path != "" {
+		return path
+	}
+	return configDisplayPath
 }
 
 func initConfig() {
 	// Set config file path
-	home, err := os.UserHomeDir()
+	path, err := configFilePath()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
 		return
 	}
 	
-	// Look for config file in home directory
-	viper.AddConfigPath(home)
-	viper.SetConfigName(".farmix-cli")
+	warnIgnoredLegacyConfig(path)
+
+	// Read the single config file if it exists
+	viper.SetConfigFile(path)
 	viper.SetConfigType("yaml")
+	if _, err := os.Stat(path); err == nil {
+		if err := viper.ReadInConfig(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to read %s: %v\n", path, err)
+		}
+	}
 	
-	// Read config file if it exists
-	viper.ReadInConfig()
-	
-	// Also read from environment variables
+	bindConfigEnv()
+}
+
+// bindConfigEnv makes FARMIX_* environment variables override config file values.
+// Precedence: flag (--webhook-url) > environment (FARMIX_<KEY>, then deprecated <KEY>) > config file
+func bindConfigEnv() {
+	viper.SetEnvPrefix(envPrefix)
+	for _, key := range envConfigKeys {
+		viper.BindEnv(key, envVarName(key), legacyEnvVarName(key))
+		warnLegacyEnv(key)
+	}
 	viper.AutomaticEnv()
-}
\ No newline at end of file
+}
+
+// legacyEnvVarName is the unprefixed environment variable of a key read by earlier versions
+func legacyEnvVarName(key string) string {
+	return strings.ToUpper(key)
+}
+
+// warnLegacyEnv warns when a key comes from its deprecated unprefixed environment variable
+func warnLegacyEnv(key string) {
+	if _, set := os.LookupEnv(envVarName(key)); set {
+		return
+	}
+	if _, set := os.LookupEnv(legacyEnvVarName(key)); set {
+		fmt.Fprintf(os.Stderr, "Warning: %s is deprecated, use %s\n", legacyEnvVarName(key), envVarName(key))
+	}
+}
+
+// bitrixClientOptions reads the Bitrix24 HTTP client settings from the bitrix section of the config:
+// timeout_seconds, max_retries and rate_limit (requests per second, 0 - no limit).
+// Unset keys keep the client defaults (30s timeout, no retries, no limit)
+func bitrixClientOptions(v *viper.Viper) ([]bitrix.ClientOption, error) {
+	var opts []bitrix.ClientOption
+
+	timeout, ok, err := configNumber(v, "bitrix.timeout_seconds")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if timeout <= 0 {
+			return nil, fmt.Errorf("bitrix.timeout_seconds must be positive, got %v", timeout)
+		}
+		opts = append(opts, bitrix.WithTimeout(time.Duration(timeout*float64(time.Second))))
+	}
+
+	retries, ok, err := configNumber(v, "bitrix.max_retries")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if retries < 0 || retries != float64(int(retries)) {
+			return nil, fmt.Errorf("bitrix.max_retries must be a non-negative integer, got %v", retries)
+		}
+		opts = append(opts, bitrix.WithMaxRetries(int(retries)))
+	}
+
+	rateLimit, ok, err := configNumber(v, "bitrix.rate_limit")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if rateLimit < 0 {
+			return nil, fmt.Errorf("bitrix.rate_limit must not be negative, got %v", rateLimit)
+		}
+		opts = append(opts, bitrix.WithRateLimit(rateLimit))
+	}
+
+	return opts, nil
+}
+
+// configNumber reads a numeric config value; ok is false when the key is not set
+func configNumber(v *viper.Viper, key string) (value float64, ok bool, err error) {
+	if !v.IsSet(key) {
+		return 0, false, nil
+	}
+
+	switch raw := v.Get(key).(type) {
+	case float64:
+		return raw, true, nil
+	case int:
+		return float64(raw), true, nil
+	case string:
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("%s must be a number, got %q", key, raw)
+		}
+		return parsed, true, nil
+	default:
+		return 0, false, fmt.Errorf("%s must be a number, got %v", key, raw)
+	}
+}
+
+// configFilePath returns the path of the config file: --config or ~/.farmix-cli
+// (~/.farmix-cli.yaml when only that one exists). config init writes the same file
+func configFilePath() (string, error) {
+	if configFile != "" {
+		return configFile, nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(home, configFileName)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		legacyPath := filepath.Join(home, legacyConfigFileName)
+		if _, err := os.Stat(legacyPath); err == nil {
+			return legacyPath, nil
+		}
+	}
+	return path, nil
+}
+
+// warnIgnoredLegacyConfig warns when ~/.farmix-cli.yaml exists next to the config that is read
+func warnIgnoredLegacyConfig(path string) {
+	if configFile != "" || filepath.Base(path) != configFileName {
+		return
+	}
+	legacyPath := filepath.Join(filepath.Dir(path), legacyConfigFileName)
+	if _, err := os.Stat(legacyPath); err == nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s is ignored, settings are read from %s; merge them into one file\n", legacyPath, path)
+	}
+}
+
+// errNotConfigured reports a missing config key
+// Keys that can come from the environment also name their variable
+func errNotConfigured(key string) error {
+	if slices.Contains(envConfigKeys, key) {
+		return fmt.Errorf("%s not configured. Please set it in %s config (see farmix-cli config init) or in %s environment variable",
+			key, configDisplayPath, envVarName(key))
+	}
+	return fmt.Errorf("%s not configured. Please set it in %s config (see farmix-cli config init)", key, configDisplayPath)
+}
+
+// envVarName returns the environment variable bound to a config key
+func envVarName(key string) string {
+	return envPrefix + "_" + strings.ToUpper(key)
+}
+
+// getConfigFloatMap reads a "name -> number" map (e.g. material_prices) from config
+func getConfigFloatMap(key string) map[string]float64 {
+	return parseConfigFloatMap(key, viper.GetStringMap(key))
+}
+
+// parseConfigFloatMap converts raw config values to numbers, skipping invalid entries with a warning
+func parseConfigFloatMap(key string, raw map[string]interface{}) map[string]float64 {
+	result := make(map[string]float64)
+	for name, value := range raw {
+		var number float64
+		switch v := value.(type) {
+		case float64:
+			number = v
+		case int:
+			number = float64(v)
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: invalid value for %s.%s: %s\n", key, name, v)
+				continue
+			}
+			number = parsed
+		default:
+			fmt.Fprintf(os.Stderr, "Warning: invalid value for %s.%s: %v\n", key, name, value)
+			continue
+		}
+		result[name] = number
+	}
+	return result
+}
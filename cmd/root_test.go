@@ -0,0 +1,376 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func TestParseConfigFloatMap(t *testing.T) {
+	raw := map[string]interface{}{
+		"pla basic": 1800,
+		"petg":      2100.5,
+		"asa":       "2500",
+		"tpu":       "free",
+		"abs":       []string{"1"},
+	}
+
+	prices := parseConfigFloatMap("material_prices", raw)
+
+	expected := map[string]float64{
+		"pla basic": 1800,
+		"petg":      2100.5,
+		"asa":       2500,
+	}
+	if len(prices) != len(expected) {
+		t.Fatalf("expected %d prices, got %d: %v", len(expected), len(prices), prices)
+	}
+	for name, want := range expected {
+		if got, ok := prices[name]; !ok || got != want {
+			t.Errorf("price for %q = %v (found %v), want %v", name, got, ok, want)
+		}
+	}
+}
+
+func TestNotConfiguredMessagesNameConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	modelPath := filepath.Join(dir, "model.3mf")
+	if err := os.WriteFile(modelPath, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dealID, projectName, stlDir = "1", "Project", dir
+	clearDealID, spreadDealID, orderDealID, addStoreDealID = "1", "1", "1", "1"
+	defer func() {
+		dealID, projectName, stlDir = "", "", ""
+		clearDealID, spreadDealID, orderDealID, addStoreDealID = "", "", "", ""
+	}()
+
+	tests := []struct {
+		name    string
+		webhook string
+		key     string
+		run     func() error
+	}{
+		{"crm-add-items", "", "bitrix_webhook_url", runCRMAddItems},
+		{"crm-add-items catalog", "https://example.bitrix24.ru/rest/1/token/", "catalog_id", runCRMAddItems},
+		{"crm-add-store", "", "bitrix_webhook_url", func() error { return runCRMAddStore(false) }},
+		{"crm-clear-deal-items", "", "bitrix_webhook_url", func() error { return runCRMClearDealItems(strings.NewReader("")) }},
+		{"crm-list-stores", "", "bitrix_webhook_url", func() error { return runCRMListStores(os.Stdout) }},
+		{"crm-report", "", "bitrix_webhook_url", runCRMReport},
+		{"crm-spread-price", "", "bitrix_webhook_url", runCRMSpreadPrice},
+		{"order", "", "bitrix_webhook_url", func() error { return runOrderCommand(modelPath) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("bitrix_webhook_url", tt.webhook)
+			viper.Set("catalog_id", "")
+			defer viper.Set("bitrix_webhook_url", "")
+
+			err := tt.run()
+			if err == nil {
+				t.Fatal("expected not configured error")
+			}
+			want := errNotConfigured(tt.key).Error()
+			if err.Error() != want {
+				t.Errorf("error = %q, want %q", err.Error(), want)
+			}
+			if !strings.Contains(err.Error(), configDisplayPath) {
+				t.Errorf("error %q does not name %s", err.Error(), configDisplayPath)
+			}
+		})
+	}
+}
+
+func TestIncludeNonPrintable(t *testing.T) {
+	defer viper.Set("include_non_printable", nil)
+
+	newCmd := func(args ...string) (*cobra.Command, bool) {
+		var value bool
+		cmd := &cobra.Command{}
+		cmd.Flags().BoolVar(&value, "include-non-printable", false, "")
+		if err := cmd.Flags().Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		return cmd, value
+	}
+
+	tests := []struct {
+		name   string
+		config interface{}
+		args   []string
+		want   bool
+	}{
+		{"default skips", nil, nil, false},
+		{"config includes", true, nil, true},
+		{"flag overrides config", true, []string{"--include-non-printable=false"}, false},
+		{"flag includes", nil, []string{"--include-non-printable"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("include_non_printable", tt.config)
+			cmd, value := newCmd(tt.args...)
+			if got := includeNonPrintable(cmd, value); got != tt.want {
+				t.Errorf("includeNonPrintable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFlags(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "portal.yaml")
+	if err := os.WriteFile(path, []byte("report_max_text_width: 33\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	flags := rootCmd.PersistentFlags()
+	defer func() {
+		configFile = ""
+		for _, name := range []string{"config", "webhook-url"} {
+			flags.Set(name, "")
+			flags.Lookup(name).Changed = false
+		}
+	}()
+
+	// Other tests override the key with viper.Set, nil lets the bound flag through
+	viper.Set("bitrix_webhook_url", nil)
+	defer viper.Set("bitrix_webhook_url", "")
+
+	webhook := "https://other.bitrix24.ru/rest/1/token/"
+	if err := flags.Parse([]string{"--config", path, "--webhook-url", webhook}); err != nil {
+		t.Fatal(err)
+	}
+	initConfig()
+
+	if got := viper.GetInt("report_max_text_width"); got != 33 {
+		t.Errorf("report_max_text_width = %d, want 33 from %s", got, path)
+	}
+	if got := viper.GetString("bitrix_webhook_url"); got != webhook {
+		t.Errorf("bitrix_webhook_url = %q, want --webhook-url %q", got, webhook)
+	}
+	if got, err := configFilePath(); err != nil || got != path {
+		t.Errorf("configFilePath() = %q, %v; want %q", got, err, path)
+	}
+	if err := checkConfigFlag(crmReportCmd); err != nil {
+		t.Errorf("checkConfigFlag() error = %v", err)
+	}
+
+	configFile = filepath.Join(t.TempDir(), "missing.yaml")
+	if err := checkConfigFlag(crmReportCmd); err == nil || !strings.Contains(err.Error(), "missing.yaml") {
+		t.Errorf("expected missing config error, got %v", err)
+	}
+	if err := checkConfigFlag(configInitCmd); err != nil {
+		t.Errorf("config init must be allowed to create the --config file, got %v", err)
+	}
+}
+
+func TestConfigFilePathLegacyYAML(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	legacyPath := filepath.Join(home, ".farmix-cli.yaml")
+	path := filepath.Join(home, ".farmix-cli")
+
+	if got, err := configFilePath(); err != nil || got != path {
+		t.Errorf("configFilePath() without config = %q, %v; want %q", got, err, path)
+	}
+
+	// Only ~/.farmix-cli.yaml exists: it is read, and config init would find it
+	if err := os.WriteFile(legacyPath, []byte("catalog_id: \"23\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := configFilePath(); err != nil || got != legacyPath {
+		t.Errorf("configFilePath() with legacy config = %q, %v; want %q", got, err, legacyPath)
+	}
+	if err := writeConfigTemplate(legacyPath, false); err == nil {
+		t.Error("config init must not overwrite the legacy config")
+	}
+
+	// Both exist: ~/.farmix-cli wins
+	if err := os.WriteFile(path, []byte("catalog_id: \"24\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := configFilePath(); err != nil || got != path {
+		t.Errorf("configFilePath() with both configs = %q, %v; want %q", got, err, path)
+	}
+}
+
+func TestConfigFromEnvironment(t *testing.T) {
+	var requested string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result":{"stores":[{"id":1,"title":"Main","active":"Y"}]}}`))
+	}))
+	defer server.Close()
+
+	// Other tests override the keys with viper.Set, nil lets the environment through
+	for _, key := range envConfigKeys {
+		viper.Set(key, nil)
+		defer viper.Set(key, "")
+	}
+	bindConfigEnv()
+
+	if err := runCRMListStores(io.Discard); err == nil || !strings.Contains(err.Error(), "FARMIX_BITRIX_WEBHOOK_URL") {
+		t.Fatalf("expected not configured error naming the environment variable, got %v", err)
+	}
+
+	t.Setenv("FARMIX_BITRIX_WEBHOOK_URL", server.URL+"/rest/1/token/")
+	t.Setenv("FARMIX_CATALOG_ID", "14")
+	t.Setenv("FARMIX_STORE_ID", "3")
+
+	if err := runCRMListStores(io.Discard); err != nil {
+		t.Fatalf("runCRMListStores() error = %v", err)
+	}
+	if requested != "/rest/1/token/catalog.store.list" {
+		t.Errorf("expected request to the webhook from the environment, got %q", requested)
+	}
+	if viper.GetString("catalog_id") != "14" || viper.GetString("store_id") != "3" {
+		t.Errorf("catalog_id = %q, store_id = %q; want values from the environment", viper.GetString("catalog_id"), viper.GetString("store_id"))
+	}
+}
+
+func TestConfigFromLegacyEnvironment(t *testing.T) {
+	for _, key := range envConfigKeys {
+		viper.Set(key, nil)
+		defer viper.Set(key, "")
+	}
+
+	t.Setenv("CATALOG_ID", "15")
+	t.Setenv("STORE_ID", "4")
+	t.Setenv("FARMIX_STORE_ID", "5")
+	bindConfigEnv()
+
+	if got := viper.GetString("catalog_id"); got != "15" {
+		t.Errorf("catalog_id = %q, want 15 from deprecated CATALOG_ID", got)
+	}
+	if got := viper.GetString("store_id"); got != "5" {
+		t.Errorf("store_id = %q, want FARMIX_STORE_ID to win over STORE_ID", got)
+	}
+}
+
+func TestBitrixClientOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantOpts int
+		wantErr  string
+	}{
+		{name: "defaults", content: "catalog_id: \"23\"\n"},
+		{name: "all settings", content: "bitrix:\n  timeout_seconds: 60\n  max_retries: 3\n  rate_limit: 1.5\n", wantOpts: 3},
+		{name: "string numbers", content: "bitrix:\n  timeout_seconds: \"10\"\n  rate_limit: \"0\"\n", wantOpts: 2},
+		{name: "zero timeout", content: "bitrix:\n  timeout_seconds: 0\n", wantErr: "bitrix.timeout_seconds"},
+		{name: "fractional retries", content: "bitrix:\n  max_retries: 1.5\n", wantErr: "bitrix.max_retries"},
+		{name: "negative rate limit", content: "bitrix:\n  rate_limit: -2\n", wantErr: "bitrix.rate_limit"},
+		{name: "not a number", content: "bitrix:\n  timeout_seconds: soon\n", wantErr: "bitrix.timeout_seconds must be a number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := bitrixClientOptions(newConfigFromYAML(t, tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("bitrixClientOptions() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("bitrixClientOptions() error = %v", err)
+			}
+			if len(opts) != tt.wantOpts {
+				t.Errorf("bitrixClientOptions() returned %d options, want %d", len(opts), tt.wantOpts)
+			}
+		})
+	}
+}
+
+func TestNewBitrixClient(t *testing.T) {
+	defer viper.Set("bitrix_webhook_url", "")
+
+	tests := []struct {
+		name    string
+		webhook string
+		wantErr string
+	}{
+		{name: "missing webhook", webhook: "", wantErr: "bitrix_webhook_url not configured"},
+		{name: "malformed webhook", webhook: "://bad", wantErr: "bitrix_webhook_url"},
+		{name: "valid webhook", webhook: "https://example.bitrix24.ru/rest/1/token/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("bitrix_webhook_url", tt.webhook)
+
+			client, err := newBitrixClient()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("newBitrixClient() error = %v, want it to mention %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newBitrixClient() error = %v", err)
+			}
+			if client == nil {
+				t.Fatal("newBitrixClient() returned nil client")
+			}
+		})
+	}
+}
+
+func TestNewBitrixClientReportsWebhookSource(t *testing.T) {
+	viper.Set("bitrix_webhook_url", nil)
+	defer viper.Set("bitrix_webhook_url", "")
+
+	configPath := filepath.Join(t.TempDir(), "farmix.yaml")
+	viper.SetConfigFile(configPath)
+	defer viper.SetConfigFile("")
+
+	tests := []struct {
+		name       string
+		env        map[string]string
+		flag       string
+		wantSource string
+	}{
+		{name: "environment", env: map[string]string{"FARMIX_BITRIX_WEBHOOK_URL": "://bad"}, wantSource: "FARMIX_BITRIX_WEBHOOK_URL environment variable"},
+		{name: "deprecated environment", env: map[string]string{"BITRIX_WEBHOOK_URL": "://bad"}, wantSource: "BITRIX_WEBHOOK_URL environment variable"},
+		{name: "flag", env: map[string]string{"FARMIX_BITRIX_WEBHOOK_URL": "https://example.bitrix24.ru/rest/1/token/"}, flag: "://bad", wantSource: "--webhook-url"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			bindConfigEnv()
+			if tt.flag != "" {
+				flag := rootCmd.PersistentFlags().Lookup("webhook-url")
+				flag.Value.Set(tt.flag)
+				flag.Changed = true
+				defer func() {
+					flag.Value.Set("")
+					flag.Changed = false
+				}()
+			}
+
+			_, err := newBitrixClient()
+			if err == nil || !strings.Contains(err.Error(), "from "+tt.wantSource+":") {
+				t.Fatalf("newBitrixClient() error = %v, want it to name %s", err, tt.wantSource)
+			}
+		})
+	}
+
+	// A value from the config file names the file that was read
+	viper.Set("bitrix_webhook_url", "://bad")
+	if _, err := newBitrixClient(); err == nil || !strings.Contains(err.Error(), configPath) {
+		t.Errorf("newBitrixClient() error = %v, want it to name %s", err, configPath)
+	}
+}
@@ -1,10 +1,14 @@
 package cmd
 
 import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
-	"time"
 
 	"farmix-cli/internal/slicer"
 
@@ -12,18 +16,28 @@ import (
 )
 
 var (
-	orcaPath        string
-	outputDir       string
-	printerProfile  string
-	materialProfile string
-	printProfile    string
-	formatOutput    string
-	keepGcode      bool
+	orcaPath         string
+	outputDir        string
+	printerProfile   string
+	materialProfile  string
+	printProfile     string
+	formatOutput     string
+	keepGcode        bool
+	sliceOutput      string
+	sliceEstimate    bool
+	filamentDiameter float64
 )
 
+// sliceExitFailed - код выхода, когда слайсинг не удался (в том числе если выведена оценка --estimate);
+// 1 - неверные параметры или ошибка вывода, 0 - успех, в том числе с предупреждениями OrcaSlicer
+const sliceExitFailed = 2
+
+// errSliceFailed возвращается runSlice, если OrcaSlicer не смог нарезать модель
+var errSliceFailed = errors.New("слайсинг не удался")
+
 var sliceCmd = &cobra.Command{
-	Use:   "slice [STL файл]",
-	Short: "Слайсинг STL файла через OrcaSlicer и получение расхода филамента",
+	Use:   "slice [STL файлы или директории...]",
+	Short: "Слайсинг STL файлов через OrcaSlicer и получение расхода филамента",
 	Long: `Обрабатывает STL файл через OrcaSlicer и извлекает информацию о расходе филамента.
 Для работы команды необходим установленный OrcaSlicer.
 
@@ -32,74 +46,148 @@ var sliceCmd = &cobra.Command{
 2. Настройте профили в графическом интерфейсе OrcaSlicer
 3. Рассмотрите возможность использования графического режима
 
+Если G-code содержит только длину филамента, вес считается по длине,
+диаметру прутка (--filament-diameter, по умолчанию 1.75 мм) и плотности
+материала из G-code (filament_type).
+
+Результат (text, csv, json) выводится в stdout или в файл --output,
+сообщения о ходе работы и предупреждения - в stderr.
+
+Если слайсинг не удался, команда завершается с ошибкой и ничего не выводит.
+С --estimate вместо этого выводится оценка по объему модели (сплошная модель
+из PLA, пруток 1.75 мм, слои 0.2 мм, без времени печати) со статусом ОЦЕНКА
+во всех форматах. Оценка - не результат слайсинга, для расчета цены ее
+использовать нельзя.
+
+Коды выхода: 0 - успех (в том числе с предупреждениями OrcaSlicer),
+1 - неверные параметры или ошибка вывода, 2 - слайсинг не удался
+(в том числе если выведена оценка --estimate).
+
+Пакетный режим: если передано несколько файлов или директория (STL ищутся
+рекурсивно), каждый файл нарезается отдельно, не более --concurrency
+одновременно (по умолчанию 1, OrcaSlicer ресурсоемкий). Выводятся результаты
+по файлам и итог: вес, длина филамента и время печати. Ошибка одного файла
+не прерывает пакет, такие файлы не входят в итог, а команда завершается
+с кодом 2. С --output-dir G-code каждого файла сохраняется в отдельную
+поддиректорию.
+
 Примеры использования:
   farmix-cli slice --orca-path /Applications/OrcaSlicer.app/Contents/MacOS/OrcaSlicer модель.stl
-  farmix-cli slice --orca-path /path/to/OrcaSlicer --format json модель.stl
-  farmix-cli slice --orca-path /path/to/OrcaSlicer --keep-gcode модель.stl`,
-	Args: cobra.ExactArgs(1),
-	Run:  runSliceCommand,
+  farmix-cli slice --orca-path /path/to/OrcaSlicer --format json модель.stl | jq .
+  farmix-cli slice --orca-path /path/to/OrcaSlicer --format csv --output result.csv модель.stl
+  farmix-cli slice --orca-path /path/to/OrcaSlicer --keep-gcode модель.stl
+  farmix-cli slice --orca-path /path/to/OrcaSlicer --estimate модель.stl
+  farmix-cli slice --orca-path /path/to/OrcaSlicer --concurrency 2 корпус.stl крышка.stl
+  farmix-cli slice --orca-path /path/to/OrcaSlicer --format csv ./детали`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runSliceArgs(args, os.Stdout, os.Stderr); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
+			os.Exit(sliceExitCode(err))
+		}
+	},
 }
 
-func runSliceCommand(cmd *cobra.Command, args []string) {
-	stlFile := args[0]
+// sliceExitCode возвращает код выхода slice для ошибки runSlice или runSliceBatch
+func sliceExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errSliceFailed):
+		return sliceExitFailed
+	default:
+		return 1
+	}
+}
 
+// runSlice нарезает STL и выводит результат в stdout (или в файл --output), сообщения - в stderr.
+// Если слайсинг не удался, возвращается errSliceFailed; с --estimate перед этим выводится оценка по объему модели
+func runSlice(stlFile string, stdout, stderr io.Writer) error {
 	// Валидация входных параметров
 	if err := validateSliceParams(stlFile); err != nil {
-		fmt.Fprintf(os.Stderr, "Ошибка: %v\n", err)
-		os.Exit(1)
+		return err
+	}
+
+	result, err := sliceFile(stlFile, outputDir, stderr)
+	if result == nil {
+		return err
+	}
+
+	// Выводим результат
+	if err := writeSliceOutput(stdout, func(w io.Writer) error { return outputSliceResult(result, w) }); err != nil {
+		return fmt.Errorf("ошибка форматирования вывода: %v", err)
 	}
+	if sliceOutput != "" {
+		fmt.Fprintf(stderr, "Результат сохранен: %s\n", sliceOutput)
+	}
+	return err
+}
 
+// sliceFile нарезает один STL в директорию dir (пустая - временная папка) и удаляет G-code без --keep-gcode.
+// Если слайсинг не удался, возвращается ошибка errSliceFailed, а с --estimate вместе с ней - оценка по объему модели
+func sliceFile(stlFile, dir string, stderr io.Writer) (*slicer.SliceResult, error) {
 	// Создаем конфигурацию для слайсинга
 	config := slicer.CreateDefaultConfig(orcaPath, stlFile)
-	config.OutputDir = outputDir
+	config.OutputDir = dir
 	config.PrinterProfile = printerProfile
 	config.MaterialProfile = materialProfile
 	config.PrintProfile = printProfile
+	config.FilamentDiameterMM = filamentDiameter
 
 	// Выполняем слайсинг
-	fmt.Printf("Обработка %s через OrcaSlicer...\n", stlFile)
-	result, err := slicer.SliceSTL(config)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Предупреждение: Ошибка слайсинга OrcaSlicer: %v\n", err)
-		fmt.Fprintf(os.Stderr, "Примечание: Командный режим OrcaSlicer имеет ограничения. Рассмотрите использование графического режима.\n")
+	fmt.Fprintf(stderr, "Обработка %s через OrcaSlicer...\n", stlFile)
+	result, sliceErr := slicer.SliceSTL(config)
+	if sliceErr != nil {
+		fmt.Fprintf(stderr, "Примечание: Командный режим OrcaSlicer имеет ограничения. Рассмотрите использование графического режима.\n")
+		if !sliceEstimate {
+			return nil, fmt.Errorf("%w: %v (--estimate выводит оценку по объему модели)", errSliceFailed, sliceErr)
+		}
 		
-		// Создаем mock результат для демонстрации функциональности
-		result = &slicer.SliceResult{
-			FilamentUsed: slicer.FilamentUsage{
-				LengthMM:     100.0,
-				WeightGrams:  0.24,
-				MaterialType: "PLA (estimated)",
-			},
-			PrintTime:      time.Duration(10) * time.Minute,
-			LayerCount:     50,
-			LayerHeight:    0.2,
-			OutputFile:     "",
-			SlicingSuccess: false,
-			ErrorMessage:   "CLI mode limitations - showing estimated values",
+		fmt.Fprintf(stderr, "Предупреждение: Ошибка слайсинга OrcaSlicer (%s): %v\n", stlFile, sliceErr)
+		estimate, err := slicer.EstimateFromModel(stlFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w, оценка по модели тоже не удалась: %v", errSliceFailed, err)
 		}
-		fmt.Printf("Показ приблизительных значений на основе размера модели...\n")
-	}
-
-	// Выводим результат
-	if err := outputSliceResult(result); err != nil {
-		fmt.Fprintf(os.Stderr, "Ошибка форматирования вывода: %v\n", err)
-		os.Exit(1)
+		estimate.ErrorMessage = sliceErr.Error()
+		result = estimate
+		fmt.Fprintf(stderr, "Предупреждение: для %s выводится ОЦЕНКА по объему модели, это не результат слайсинга\n", stlFile)
 	}
 
 	// Удаляем G-code файл если не нужно сохранять
 	if !keepGcode && result.OutputFile != "" {
 		if err := os.Remove(result.OutputFile); err != nil {
-			fmt.Fprintf(os.Stderr, "Предупреждение: Не удалось удалить временный G-code файл: %v\n", err)
+			fmt.Fprintf(stderr, "Предупреждение: Не удалось удалить временный G-code файл: %v\n", err)
 		}
 	} else if result.OutputFile != "" {
-		fmt.Printf("\nG-code файл сохранен: %s\n", result.OutputFile)
+		fmt.Fprintf(stderr, "\nG-code файл сохранен: %s\n", result.OutputFile)
+	}
+
+	if sliceErr != nil {
+		return result, errSliceFailed
+	}
+	return result, nil
+}
+
+// writeSliceOutput передает write файл --output, если он задан, иначе stdout
+func writeSliceOutput(stdout io.Writer, write func(w io.Writer) error) error {
+	if sliceOutput == "" {
+		return write(stdout)
+	}
+
+	file, err := os.Create(sliceOutput)
+	if err != nil {
+		return fmt.Errorf("не удалось создать файл %s: %v", sliceOutput, err)
+	}
+	if err := write(file); err != nil {
+		file.Close()
+		return err
 	}
+	return file.Close()
 }
 
 func validateSliceParams(stlFile string) error {
-	// Проверяем OrcaSlicer path
-	if orcaPath == "" {
-		return fmt.Errorf("путь к OrcaSlicer обязателен (используйте --orca-path)")
+	if err := validateSliceTool(); err != nil {
+		return err
 	}
 
 	// Проверяем STL файл
@@ -111,6 +199,20 @@ func validateSliceParams(stlFile string) error {
 		return fmt.Errorf("STL файл не найден: %s", stlFile)
 	}
 
+	return nil
+}
+
+// validateSliceTool проверяет параметры, общие для всех файлов: путь к OrcaSlicer и диаметр прутка
+func validateSliceTool() error {
+	if filamentDiameter <= 0 {
+		return fmt.Errorf("диаметр прутка должен быть положительным: %.2f", filamentDiameter)
+	}
+
+	// Проверяем OrcaSlicer path
+	if orcaPath == "" {
+		return fmt.Errorf("путь к OrcaSlicer обязателен (используйте --orca-path)")
+	}
+
 	// Проверяем OrcaSlicer
 	if _, err := os.Stat(orcaPath); os.IsNotExist(err) {
 		return fmt.Errorf("OrcaSlicer не найден по пути: %s", orcaPath)
@@ -119,107 +221,132 @@ func validateSliceParams(stlFile string) error {
 	return nil
 }
 
-func outputSliceResult(result *slicer.SliceResult) error {
+func outputSliceResult(result *slicer.SliceResult, w io.Writer) error {
 	switch strings.ToLower(formatOutput) {
 	case "json":
-		return outputJSON(result)
+		return outputJSON(result, w)
 	case "csv":
-		return outputCSV(result)
+		return outputCSV(result, w)
 	case "text", "":
-		return outputText(result)
+		return outputText(result, w)
 	default:
 		return fmt.Errorf("неподдерживаемый формат вывода: %s. Поддерживаемые форматы: text, csv, json", formatOutput)
 	}
 }
 
-func outputText(result *slicer.SliceResult) error {
-	fmt.Println("=== Результаты слайсинга ===")
-	fmt.Printf("Статус: %s\n", getStatusText(result.SlicingSuccess))
+func outputText(result *slicer.SliceResult, w io.Writer) error {
+	fmt.Fprintln(w, "=== Результаты слайсинга ===")
+	writeTextResult(result, w)
+	return nil
+}
+
+// writeTextResult выводит результат одного файла без заголовка (используется и в пакетном режиме)
+func writeTextResult(result *slicer.SliceResult, w io.Writer) {
+	fmt.Fprintf(w, "Статус: %s\n", getStatusText(result))
 	
-	if !result.SlicingSuccess {
-		fmt.Printf("Ошибка: %s\n", result.ErrorMessage)
-		return nil
+	if result.Estimated {
+		fmt.Fprintln(w, "Внимание: значения оценены по объему модели (сплошная модель, PLA), это не результат слайсинга")
+		fmt.Fprintf(w, "Ошибка слайсинга: %s\n", result.ErrorMessage)
+	} else if !result.SlicingSuccess {
+		fmt.Fprintf(w, "Ошибка: %s\n", result.ErrorMessage)
+		return
 	}
 
-	fmt.Printf("Вес филамента: %.2f граммов\n", result.FilamentUsed.WeightGrams)
-	fmt.Printf("Длина филамента: %.2f мм\n", result.FilamentUsed.LengthMM)
+	fmt.Fprintf(w, "Вес филамента: %.2f граммов\n", result.FilamentUsed.WeightGrams)
+	fmt.Fprintf(w, "Длина филамента: %.2f мм\n", result.FilamentUsed.LengthMM)
 	
 	if result.FilamentUsed.MaterialType != "" {
-		fmt.Printf("Тип материала: %s\n", result.FilamentUsed.MaterialType)
+		fmt.Fprintf(w, "Тип материала: %s\n", result.FilamentUsed.MaterialType)
 	}
 	
 	if result.PrintTime > 0 {
-		fmt.Printf("Время печати: %v\n", result.PrintTime)
+		fmt.Fprintf(w, "Время печати: %v\n", result.PrintTime)
 	}
 	
 	if result.LayerCount > 0 {
-		fmt.Printf("Количество слоев: %d\n", result.LayerCount)
+		fmt.Fprintf(w, "Количество слоев: %d\n", result.LayerCount)
 	}
 	
 	if result.LayerHeight > 0 {
-		fmt.Printf("Высота слоя: %.2f мм\n", result.LayerHeight)
+		fmt.Fprintf(w, "Высота слоя: %.2f мм\n", result.LayerHeight)
 	}
+}
 
-	return nil
+// sliceCSVHeader - колонки CSV результата слайсинга
+var sliceCSVHeader = []string{"статус", "вес_граммы", "длина_мм", "тип_материала", "время_печати_секунды", "количество_слоев", "высота_слоя", "сообщение_ошибки"}
+
+func outputCSV(result *slicer.SliceResult, w io.Writer) error {
+	// Сообщение об ошибке OrcaSlicer может содержать запятые и переводы строк, поэтому csv.Writer
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write(sliceCSVHeader)
+	csvWriter.Write(sliceCSVRow(result))
+	csvWriter.Flush()
+	return csvWriter.Error()
 }
 
-func outputCSV(result *slicer.SliceResult) error {
-	// CSV заголовок
-	fmt.Println("статус,вес_граммы,длина_мм,тип_материала,время_печати_секунды,количество_слоев,высота_слоя,сообщение_ошибки")
-	
-	// CSV данные
-	printTimeSeconds := int(result.PrintTime.Seconds())
-	fmt.Printf("%s,%.2f,%.2f,%s,%d,%d,%.2f,%s\n",
-		getStatusText(result.SlicingSuccess),
-		result.FilamentUsed.WeightGrams,
-		result.FilamentUsed.LengthMM,
+// sliceCSVRow возвращает значения колонок sliceCSVHeader для результата
+func sliceCSVRow(result *slicer.SliceResult) []string {
+	return []string{
+		getStatusText(result),
+		fmt.Sprintf("%.2f", result.FilamentUsed.WeightGrams),
+		fmt.Sprintf("%.2f", result.FilamentUsed.LengthMM),
 		result.FilamentUsed.MaterialType,
-		printTimeSeconds,
-		result.LayerCount,
-		result.LayerHeight,
+		strconv.Itoa(int(result.PrintTime.Seconds())),
+		strconv.Itoa(result.LayerCount),
+		fmt.Sprintf("%.2f", result.LayerHeight),
 		result.ErrorMessage,
-	)
-
-	return nil
+	}
 }
 
-func outputJSON(result *slicer.SliceResult) error {
-	// Простой JSON вывод без использования библиотеки encoding/json
-	// для минимизации зависимостей
-	fmt.Printf(`{
+func outputJSON(result *slicer.SliceResult, w io.Writer) error {
+	// JSON собирается вручную для фиксированного порядка полей, строки экранируются jsonQuote
+	fmt.Fprintf(w, `{
   "status": "%s",
   "slicing_success": %t,
+  "estimated": %t,
   "filament_used": {
     "weight_grams": %.2f,
     "length_mm": %.2f,
-    "material_type": "%s"
+    "material_type": %s
   },
   "print_time_seconds": %d,
   "layer_count": %d,
   "layer_height": %.2f`,
-		getStatusText(result.SlicingSuccess),
+		getStatusText(result),
 		result.SlicingSuccess,
+		result.Estimated,
 		result.FilamentUsed.WeightGrams,
 		result.FilamentUsed.LengthMM,
-		result.FilamentUsed.MaterialType,
+		jsonQuote(result.FilamentUsed.MaterialType),
 		int(result.PrintTime.Seconds()),
 		result.LayerCount,
 		result.LayerHeight)
 
 	if !result.SlicingSuccess {
-		fmt.Printf(`,
-  "error_message": "%s"`, result.ErrorMessage)
+		fmt.Fprintf(w, `,
+  "error_message": %s`, jsonQuote(result.ErrorMessage))
 	}
 
-	fmt.Println("\n}")
+	fmt.Fprintln(w, "\n}")
 	return nil
 }
 
-func getStatusText(success bool) string {
-	if success {
+// jsonQuote возвращает строку в кавычках с экранированием JSON (сообщения OrcaSlicer многострочные)
+func jsonQuote(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
+
+// getStatusText возвращает статус результата: УСПЕХ, ОЦЕНКА (--estimate) или ОШИБКА
+func getStatusText(result *slicer.SliceResult) string {
+	switch {
+	case result.SlicingSuccess:
 		return "УСПЕХ"
+	case result.Estimated:
+		return "ОЦЕНКА"
+	default:
+		return "ОШИБКА"
 	}
-	return "ОШИБКА"
 }
 
 func init() {
@@ -230,6 +357,10 @@ func init() {
 	sliceCmd.Flags().StringVar(&printProfile, "print-profile", "", "Путь к файлу профиля печати")
 	sliceCmd.Flags().StringVarP(&formatOutput, "format", "f", "text", "Формат вывода (text, csv, json)")
 	sliceCmd.Flags().BoolVarP(&keepGcode, "keep-gcode", "k", false, "Сохранить созданный G-code файл")
+	sliceCmd.Flags().Float64Var(&filamentDiameter, "filament-diameter", slicer.DefaultFilamentDiameterMM, "Диаметр прутка в мм для расчета веса, если G-code содержит только длину")
+	sliceCmd.Flags().BoolVar(&sliceEstimate, "estimate", false, "Если слайсинг не удался, вывести оценку по объему модели (помечается как ОЦЕНКА)")
+	sliceCmd.Flags().StringVar(&sliceOutput, "output", "", "Записать результат в файл вместо stdout")
+	sliceCmd.Flags().IntVar(&sliceConcurrency, "concurrency", 1, "Сколько файлов нарезать одновременно в пакетном режиме")
 	
 	// Помечаем обязательные флаги
 	sliceCmd.MarkFlagRequired("orca-path")
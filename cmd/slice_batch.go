@@ -0,0 +1,349 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"farmix-cli/internal/slicer"
+)
+
+// sliceConcurrency - сколько файлов нарезается одновременно в пакетном режиме (OrcaSlicer ресурсоемкий)
+var sliceConcurrency int
+
+// sliceBatchItem - результат одного файла пакета. Для неудачного слайсинга без --estimate
+// Result содержит только SlicingSuccess=false и сообщение об ошибке
+type sliceBatchItem struct {
+	File   string
+	Result *slicer.SliceResult
+}
+
+// sliceBatchTotal - итог пакета. Вес, длина и время суммируются по нарезанным и оцененным файлам,
+// файлы с ошибкой в сумму не входят
+type sliceBatchTotal struct {
+	WeightGrams float64
+	LengthMM    float64
+	PrintTime   time.Duration
+	Sliced      int
+	Estimated   int
+	Failed      int
+}
+
+// runSliceArgs выбирает режим по аргументам: один STL файл - обычный вывод runSlice,
+// несколько файлов или директории - пакетный режим с итогом
+func runSliceArgs(args []string, stdout, stderr io.Writer) error {
+	if len(args) == 1 {
+		if info, err := os.Stat(args[0]); err != nil || !info.IsDir() {
+			return runSlice(args[0], stdout, stderr)
+		}
+	}
+	return runSliceBatch(args, stdout, stderr)
+}
+
+// runSliceBatch нарезает все файлы пакета и выводит результаты по файлам и итог.
+// Ошибка одного файла не прерывает пакет; если хотя бы один файл не нарезан, возвращается errSliceFailed
+func runSliceBatch(args []string, stdout, stderr io.Writer) error {
+	if err := validateSliceTool(); err != nil {
+		return err
+	}
+	if sliceConcurrency < 1 {
+		return fmt.Errorf("--concurrency должен быть не меньше 1: %d", sliceConcurrency)
+	}
+
+	files, err := collectSliceFiles(args)
+	if err != nil {
+		return err
+	}
+
+	items := sliceBatch(files, &syncWriter{w: stderr})
+	total := sumSliceBatch(items)
+
+	if err := writeSliceOutput(stdout, func(w io.Writer) error { return outputSliceBatch(items, total, w) }); err != nil {
+		return fmt.Errorf("ошибка форматирования вывода: %v", err)
+	}
+	if sliceOutput != "" {
+		fmt.Fprintf(stderr, "Результат сохранен: %s\n", sliceOutput)
+	}
+
+	if total.Failed > 0 || total.Estimated > 0 {
+		return fmt.Errorf("%w: %d из %d файлов", errSliceFailed, total.Failed+total.Estimated, len(items))
+	}
+	return nil
+}
+
+// collectSliceFiles возвращает STL файлы из аргументов; директории обходятся рекурсивно,
+// STL файлы из директорий сортируются по пути
+func collectSliceFiles(args []string) ([]string, error) {
+	var files []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, fmt.Errorf("файл или директория не найдены: %s", arg)
+		}
+
+		if !info.IsDir() {
+			if !strings.HasSuffix(strings.ToLower(arg), ".stl") {
+				return nil, fmt.Errorf("файл должен иметь расширение .stl: %s", arg)
+			}
+			files = append(files, arg)
+			continue
+		}
+
+		var dirFiles []string
+		err = filepath.WalkDir(arg, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(strings.ToLower(path), ".stl") {
+				dirFiles = append(dirFiles, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения директории %s: %v", arg, err)
+		}
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("STL файлы не найдены: %s", strings.Join(args, ", "))
+	}
+	return files, nil
+}
+
+// sliceBatch нарезает файлы не более чем в sliceConcurrency потоков, порядок результатов совпадает с files
+func sliceBatch(files []string, stderr io.Writer) []sliceBatchItem {
+	items := make([]sliceBatchItem, len(files))
+	semaphore := make(chan struct{}, sliceConcurrency)
+	var wg sync.WaitGroup
+
+	for i, file := range files {
+		wg.Add(1)
+		go func(i int, file string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			result, err := sliceBatchFile(file, batchOutputDir(i, file), stderr)
+			if result == nil {
+				fmt.Fprintf(stderr, "Ошибка (%s): %v\n", file, err)
+				result = &slicer.SliceResult{ErrorMessage: err.Error()}
+			}
+			items[i] = sliceBatchItem{File: file, Result: result}
+		}(i, file)
+	}
+
+	wg.Wait()
+	return items
+}
+
+// sliceBatchFile создает поддиректорию для G-code файла пакета и нарезает его
+func sliceBatchFile(file, dir string, stderr io.Writer) (*slicer.SliceResult, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("не удалось создать директорию %s: %v", dir, err)
+		}
+	}
+	return sliceFile(file, dir, stderr)
+}
+
+// batchOutputDir возвращает отдельную поддиректорию --output-dir для каждого файла пакета:
+// OrcaSlicer может назвать G-code plate_1.gcode, и параллельные файлы перезаписали бы друг друга.
+// Без --output-dir каждый файл и так нарезается в свою временную папку
+func batchOutputDir(index int, file string) string {
+	if outputDir == "" {
+		return ""
+	}
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	return filepath.Join(outputDir, fmt.Sprintf("%d_%s", index+1, name))
+}
+
+func sumSliceBatch(items []sliceBatchItem) sliceBatchTotal {
+	var total sliceBatchTotal
+	for _, item := range items {
+		switch {
+		case item.Result.SlicingSuccess:
+			total.Sliced++
+		case item.Result.Estimated:
+			total.Estimated++
+		default:
+			total.Failed++
+			continue
+		}
+		total.WeightGrams += item.Result.FilamentUsed.WeightGrams
+		total.LengthMM += item.Result.FilamentUsed.LengthMM
+		total.PrintTime += item.Result.PrintTime
+	}
+	return total
+}
+
+// getTotalStatusText возвращает статус итога: ОШИБКА, если есть файлы с ошибкой, ОЦЕНКА, если в итог вошли оценки
+func getTotalStatusText(total sliceBatchTotal) string {
+	switch {
+	case total.Failed > 0:
+		return "ОШИБКА"
+	case total.Estimated > 0:
+		return "ОЦЕНКА"
+	default:
+		return "УСПЕХ"
+	}
+}
+
+func outputSliceBatch(items []sliceBatchItem, total sliceBatchTotal, w io.Writer) error {
+	switch strings.ToLower(formatOutput) {
+	case "json":
+		return outputBatchJSON(items, total, w)
+	case "csv":
+		return outputBatchCSV(items, total, w)
+	case "text", "":
+		return outputBatchText(items, total, w)
+	default:
+		return fmt.Errorf("неподдерживаемый формат вывода: %s. Поддерживаемые форматы: text, csv, json", formatOutput)
+	}
+}
+
+func outputBatchText(items []sliceBatchItem, total sliceBatchTotal, w io.Writer) error {
+	fmt.Fprintf(w, "=== Результаты слайсинга, файлов: %d ===\n", len(items))
+	for _, item := range items {
+		fmt.Fprintf(w, "\n--- %s ---\n", item.File)
+		writeTextResult(item.Result, w)
+	}
+
+	fmt.Fprintln(w, "\n=== Итого ===")
+	fmt.Fprintf(w, "Статус: %s\n", getTotalStatusText(total))
+	fmt.Fprintf(w, "Файлов: нарезано %d, оценено %d, с ошибкой %d\n", total.Sliced, total.Estimated, total.Failed)
+	fmt.Fprintf(w, "Вес филамента: %.2f граммов\n", total.WeightGrams)
+	fmt.Fprintf(w, "Длина филамента: %.2f мм\n", total.LengthMM)
+	fmt.Fprintf(w, "Время печати: %v\n", total.PrintTime)
+
+	if total.Estimated > 0 {
+		fmt.Fprintln(w, "Внимание: итог включает оценки по объему модели, это не результат слайсинга")
+	}
+	if total.Failed > 0 {
+		fmt.Fprintln(w, "Внимание: файлы с ошибкой не учтены в итоге")
+	}
+	return nil
+}
+
+func outputBatchCSV(items []sliceBatchItem, total sliceBatchTotal, w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Write(append([]string{"файл"}, sliceCSVHeader...))
+	for _, item := range items {
+		csvWriter.Write(append([]string{item.File}, sliceCSVRow(item.Result)...))
+	}
+	csvWriter.Write([]string{
+		"ИТОГО",
+		getTotalStatusText(total),
+		fmt.Sprintf("%.2f", total.WeightGrams),
+		fmt.Sprintf("%.2f", total.LengthMM),
+		"",
+		strconv.Itoa(int(total.PrintTime.Seconds())),
+		"",
+		"",
+		"",
+	})
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// sliceBatchFileJSON повторяет поля JSON вывода одного файла (outputJSON) и добавляет имя файла
+type sliceBatchFileJSON struct {
+	File           string                 `json:"file"`
+	Status         string                 `json:"status"`
+	SlicingSuccess bool                   `json:"slicing_success"`
+	Estimated      bool                   `json:"estimated"`
+	FilamentUsed   sliceBatchFilamentJSON `json:"filament_used"`
+	PrintTimeSec   int                    `json:"print_time_seconds"`
+	LayerCount     int                    `json:"layer_count"`
+	LayerHeight    float64                `json:"layer_height"`
+	ErrorMessage   string                 `json:"error_message,omitempty"`
+}
+
+type sliceBatchFilamentJSON struct {
+	WeightGrams  float64 `json:"weight_grams"`
+	LengthMM     float64 `json:"length_mm"`
+	MaterialType string  `json:"material_type"`
+}
+
+type sliceBatchTotalJSON struct {
+	Status       string  `json:"status"`
+	Files        int     `json:"files"`
+	Sliced       int     `json:"sliced"`
+	Estimated    int     `json:"estimated"`
+	Failed       int     `json:"failed"`
+	WeightGrams  float64 `json:"weight_grams"`
+	LengthMM     float64 `json:"length_mm"`
+	PrintTimeSec int     `json:"print_time_seconds"`
+}
+
+func outputBatchJSON(items []sliceBatchItem, total sliceBatchTotal, w io.Writer) error {
+	output := struct {
+		Files []sliceBatchFileJSON `json:"files"`
+		Total sliceBatchTotalJSON  `json:"total"`
+	}{
+		Total: sliceBatchTotalJSON{
+			Status:       getTotalStatusText(total),
+			Files:        len(items),
+			Sliced:       total.Sliced,
+			Estimated:    total.Estimated,
+			Failed:       total.Failed,
+			WeightGrams:  round2(total.WeightGrams),
+			LengthMM:     round2(total.LengthMM),
+			PrintTimeSec: int(total.PrintTime.Seconds()),
+		},
+	}
+
+	for _, item := range items {
+		result := item.Result
+		file := sliceBatchFileJSON{
+			File:           item.File,
+			Status:         getStatusText(result),
+			SlicingSuccess: result.SlicingSuccess,
+			Estimated:      result.Estimated,
+			FilamentUsed: sliceBatchFilamentJSON{
+				WeightGrams:  round2(result.FilamentUsed.WeightGrams),
+				LengthMM:     round2(result.FilamentUsed.LengthMM),
+				MaterialType: result.FilamentUsed.MaterialType,
+			},
+			PrintTimeSec: int(result.PrintTime.Seconds()),
+			LayerCount:   result.LayerCount,
+			LayerHeight:  round2(result.LayerHeight),
+		}
+		if !result.SlicingSuccess {
+			file.ErrorMessage = result.ErrorMessage
+		}
+		output.Files = append(output.Files, file)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// round2 округляет до сотых, как %.2f в выводе одного файла
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// syncWriter сериализует запись в stderr из параллельно нарезаемых файлов
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (s *syncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
\ No newline at end of file
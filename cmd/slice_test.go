@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"farmix-cli/internal/stl"
+)
+
+// writeFakeOrca creates an executable that stands in for OrcaSlicer (the 4th argument is --outputdir)
+func writeFakeOrca(t *testing.T, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake OrcaSlicer is a shell script")
+	}
+
+	path := filepath.Join(t.TempDir(), "orca")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setSliceFlags sets the slice command flags for a test and restores the defaults afterwards
+func setSliceFlags(t *testing.T, orca, format, output string) {
+	t.Helper()
+	orcaPath, formatOutput, sliceOutput, outputDir = orca, format, output, t.TempDir()
+	t.Cleanup(func() {
+		orcaPath, formatOutput, sliceOutput, outputDir = "", "text", "", ""
+	})
+}
+
+func TestRunSliceJSONStdoutIsClean(t *testing.T) {
+	mockGCode, err := filepath.Abs(filepath.Join("..", "samples", "test_gcode_mock.gcode"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orca := writeFakeOrca(t, `echo "slicing..."; cp "`+mockGCode+`" "$4/model.gcode"`)
+	setSliceFlags(t, orca, "json", "")
+
+	var stdout, stderr bytes.Buffer
+	err = runSlice(filepath.Join("..", "samples", "test_cube.stl"), &stdout, &stderr)
+	if err != nil {
+		t.Fatalf("runSlice() error = %v\nstderr: %s", err, stderr.String())
+	}
+	if code := sliceExitCode(err); code != 0 {
+		t.Errorf("exit code = %d, want 0", code)
+	}
+
+	var result map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("stdout is not clean JSON: %v\n%s", err, stdout.String())
+	}
+	if result["slicing_success"] != true {
+		t.Errorf("slicing_success = %v, want true", result["slicing_success"])
+	}
+	if !strings.Contains(stderr.String(), "Обработка") {
+		t.Errorf("progress message should go to stderr, got %q", stderr.String())
+	}
+}
+
+func TestRunSliceFailureExitCode(t *testing.T) {
+	orca := writeFakeOrca(t, `echo "boom" >&2; exit 1`)
+	outputPath := filepath.Join(t.TempDir(), "result.csv")
+	setSliceFlags(t, orca, "csv", outputPath)
+
+	var stdout, stderr bytes.Buffer
+	err := runSlice(filepath.Join("..", "samples", "test_cube.stl"), &stdout, &stderr)
+	if !errors.Is(err, errSliceFailed) {
+		t.Fatalf("runSlice() error = %v, want %v", err, errSliceFailed)
+	}
+	if code := sliceExitCode(err); code != sliceExitFailed {
+		t.Errorf("exit code = %d, want %d", code, sliceExitFailed)
+	}
+	if code := sliceExitCode(errors.New("invalid params")); code != 1 || code == sliceExitFailed {
+		t.Errorf("exit code for other errors = %d, want 1", code)
+	}
+
+	// Без --estimate никаких значений не выводится
+	if stdout.Len() != 0 {
+		t.Errorf("stdout should be empty on failure, got %q", stdout.String())
+	}
+	if _, err := os.Stat(outputPath); !os.IsNotExist(err) {
+		t.Errorf("--output file should not be written on failure (stat error: %v)", err)
+	}
+}
+
+func TestRunSliceEstimateUsesModelVolume(t *testing.T) {
+	orca := writeFakeOrca(t, `exit 1`)
+	setSliceFlags(t, orca, "json", "")
+	sliceEstimate = true
+	defer func() { sliceEstimate = false }()
+
+	cube := filepath.Join("..", "samples", "test_cube.stl")
+	var stdout, stderr bytes.Buffer
+	err := runSlice(cube, &stdout, &stderr)
+	if code := sliceExitCode(err); code != sliceExitFailed {
+		t.Errorf("exit code = %d, want %d (slicing still failed)", code, sliceExitFailed)
+	}
+
+	var result struct {
+		Status       string `json:"status"`
+		Estimated    bool   `json:"estimated"`
+		FilamentUsed struct {
+			WeightGrams float64 `json:"weight_grams"`
+		} `json:"filament_used"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		t.Fatalf("stdout is not clean JSON: %v\n%s", err, stdout.String())
+	}
+	if !result.Estimated || result.Status != "ОЦЕНКА" {
+		t.Errorf("result = %+v, want estimated with status ОЦЕНКА", result)
+	}
+
+	volume, err := stl.CalculateVolume(cube, stl.VolumeConfig{Units: "mm3", Material: "PLA"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := math.Round(volume.Weight*100) / 100; result.FilamentUsed.WeightGrams != want || want == 0.24 {
+		t.Errorf("weight_grams = %v, want %v computed from the model volume", result.FilamentUsed.WeightGrams, want)
+	}
+
+	// Оценка помечается и в текстовом выводе
+	formatOutput = "text"
+	stdout.Reset()
+	runSlice(cube, &stdout, &stderr)
+	if !strings.Contains(stdout.String(), "Статус: ОЦЕНКА") || !strings.Contains(stdout.String(), "не результат слайсинга") {
+		t.Errorf("text output should be labeled as estimate:\n%s", stdout.String())
+	}
+}
+
+func TestRunSliceBatchAggregatesTotals(t *testing.T) {
+	mockGCode, err := filepath.Abs(filepath.Join("..", "samples", "test_gcode_mock.gcode"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// broken.stl не нарезается, остальные файлы получают mock G-code (0.35 г, 150.5 мм, 15m30s)
+	orca := writeFakeOrca(t, `case "$6" in *broken.stl) echo "boom" >&2; exit 1;; esac; cp "`+mockGCode+`" "$4/$(basename "$6" .stl).gcode"`)
+	setSliceFlags(t, orca, "json", "")
+	sliceConcurrency = 2
+	defer func() { sliceConcurrency = 1 }()
+
+	cube, err := os.ReadFile(filepath.Join("..", "samples", "test_cube.stl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"a.stl", "b.stl", "broken.stl"} {
+		if err := os.WriteFile(filepath.Join(dir, name), cube, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = runSliceArgs([]string{dir}, &stdout, &stderr)
+	if code := sliceExitCode(err); code != sliceExitFailed {
+		t.Errorf("exit code = %d, want %d (one file failed)", code, sliceExitFailed)
+	}
+
+	var output struct {
+		Files []struct {
+			File   string `json:"file"`
+			Status string `json:"status"`
+		} `json:"files"`
+		Total struct {
+			Sliced       int     `json:"sliced"`
+			Failed       int     `json:"failed"`
+			WeightGrams  float64 `json:"weight_grams"`
+			LengthMM     float64 `json:"length_mm"`
+			PrintTimeSec int     `json:"print_time_seconds"`
+		} `json:"total"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		t.Fatalf("stdout is not clean JSON: %v\n%s", err, stdout.String())
+	}
+
+	if len(output.Files) != 3 {
+		t.Fatalf("files = %d, want 3\n%s", len(output.Files), stdout.String())
+	}
+	wantStatus := []string{"УСПЕХ", "УСПЕХ", "ОШИБКА"}
+	for i, file := range output.Files {
+		if file.Status != wantStatus[i] {
+			t.Errorf("files[%d] (%s) status = %s, want %s", i, file.File, file.Status, wantStatus[i])
+		}
+	}
+
+	total := output.Total
+	if total.Sliced != 2 || total.Failed != 1 {
+		t.Errorf("sliced/failed = %d/%d, want 2/1", total.Sliced, total.Failed)
+	}
+	if total.WeightGrams != 0.70 || total.LengthMM != 301.0 || total.PrintTimeSec != 31*60 {
+		t.Errorf("total = %.2f g, %.2f mm, %d s, want 0.70 g, 301.00 mm, 1860 s", total.WeightGrams, total.LengthMM, total.PrintTimeSec)
+	}
+}
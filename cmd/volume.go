@@ -8,18 +8,27 @@ import (
 	"farmix-cli/internal/stl"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 var (
 	volumeUnits    string
+	volumeSource   string
 	volumeFormat   string
 	volumeMaterial string
 	volumeDensity  float64
 	showBounds     bool
+	volumeInfill   float64
+	volumeShell    float64
+	volumeWalls    int
+	assumePositive bool
 )
 
+// wallLineWidth - ширина линии периметра в мм (сопло 0.4), используется для --walls
+const wallLineWidth = 0.4
+
 var volumeCmd = &cobra.Command{
-	Use:   "volume [STL файл]",
+	Use:   "volume [STL или STEP файл]",
 	Short: "Вычисление объема и веса 3D модели из STL файла",
 	Long: `Вычисляет объем и приблизительный вес 3D модели из STL файла.
 Команда использует алгоритмы расчета объема mesh для точного вычисления
@@ -33,59 +42,114 @@ var volumeCmd = &cobra.Command{
   farmix-cli volume модель.stl
   farmix-cli volume --units cm3 --material PLA модель.stl
   farmix-cli volume --format json --density 1.04 модель.stl
-  farmix-cli volume --show-bounds модель.stl`,
+  farmix-cli volume --show-bounds модель.stl
+  farmix-cli volume --material PLA --infill 15 --walls 3 модель.stl
+  farmix-cli volume --source-units in --material PLA модель_в_дюймах.stl
+
+STEP файлы (.step, .stp) перед расчетом превращаются в STL внешним тесселятором,
+который задается командой в ~/.farmix-cli ({input} и {output} заменяются путями файлов,
+без них пути добавляются в конец команды, аргументы с пробелами берутся в кавычки):
+  step_tessellator: "freecadcmd step2stl.py {input} {output}"
+  step_tessellator: '"/Applications/Free CAD.app/Contents/MacOS/FreeCADCmd" step2stl.py'
+
+STL не хранит единицы измерения: по умолчанию координаты считаются миллиметрами,
+--source-units cm или in масштабирует модель перед расчетом объема, веса и габаритов.
+
+Вес с учетом заполнения (--infill) оценивается так: оболочка толщиной
+--shell-thickness (или --walls периметров по 0.4 мм) считается сплошной,
+внутренний объем печатается с заданным процентом заполнения.
+
+Плотности материалов можно дополнить или переопределить в ~/.farmix-cli:
+  material_densities:
+    PA-CF: 1.17
+    PLA-GLOW: 1.30`,
 	Args: cobra.ExactArgs(1),
 	Run:  runVolumeCommand,
 }
 
 func runVolumeCommand(cmd *cobra.Command, args []string) {
-	stlFile := args[0]
+	modelFile := args[0]
 
 	// Валидация параметров
-	if err := validateVolumeParams(stlFile); err != nil {
+	if err := validateVolumeParams(modelFile); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
+	// STEP тесселируется один раз, объем и габариты считаются по одному STL
+	stlFile, cleanup, err := stl.PrepareMeshFile(modelFile, viper.GetString("step_tessellator"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка расчета объема: %v\n", err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
 	// Создание конфигурации
 	config := stl.VolumeConfig{
-		Units:    volumeUnits,
-		Material: volumeMaterial,
-		Density:  volumeDensity,
+		Units:       volumeUnits,
+		SourceUnits: volumeSource,
+		Material:    volumeMaterial,
+		Density:     volumeDensity,
+		Densities:   loadMaterialDensities(),
+		Infill: &stl.InfillConfig{
+			Percent:        volumeInfill,
+			ShellThickness: effectiveShellThickness(volumeShell, volumeWalls),
+		},
+		AssumePositive: assumePositive,
 	}
 
 	// Вычисление объема
-	fmt.Printf("Вычисление объема для %s...\n", stlFile)
+	fmt.Printf("Вычисление объема для %s...\n", modelFile)
 	result, err := stl.CalculateVolume(stlFile, config)
 	if err != nil {
+		cleanup()
 		fmt.Fprintf(os.Stderr, "Ошибка расчета объема: %v\n", err)
 		os.Exit(1)
 	}
+	result.FilePath = modelFile
 
 	// Получение размеров если требуется
 	var bbox *stl.BoundingBox
 	if showBounds {
-		bbox, err = stl.GetBoundingBox(stlFile)
+		bbox, err = stl.GetBoundingBox(stlFile, "")
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Предупреждение: Не удалось получить габариты: %v\n", err)
+		} else {
+			// Единицы уже проверены в validateVolumeParams
+			scale, _ := stl.SourceUnitScale(volumeSource)
+			bbox = bbox.Scaled(scale)
 		}
 	}
 
 	// Вывод результатов
 	if err := outputVolumeResult(result, bbox); err != nil {
+		cleanup()
 		fmt.Fprintf(os.Stderr, "Ошибка форматирования вывода: %v\n", err)
 		os.Exit(1)
 	}
 }
 
-func validateVolumeParams(stlFile string) error {
-	// Проверка STL файла
-	if !strings.HasSuffix(strings.ToLower(stlFile), ".stl") {
-		return fmt.Errorf("файл должен иметь расширение .stl: %s", stlFile)
+// effectiveShellThickness возвращает толщину оболочки: --walls (если задан) имеет приоритет над --shell-thickness
+func effectiveShellThickness(shellThickness float64, walls int) float64 {
+	if walls > 0 {
+		return float64(walls) * wallLineWidth
+	}
+	return shellThickness
+}
+
+// loadMaterialDensities объединяет встроенную таблицу плотностей с material_densities из конфигурации
+func loadMaterialDensities() map[string]float64 {
+	return stl.MergeDensities(getConfigFloatMap("material_densities"))
+}
+
+func validateVolumeParams(modelFile string) error {
+	// Проверка STL или STEP файла
+	if !strings.HasSuffix(strings.ToLower(modelFile), ".stl") && !stl.IsSTEPFile(modelFile) {
+		return fmt.Errorf("файл должен иметь расширение .stl, .step или .stp: %s", modelFile)
 	}
 
-	if _, err := os.Stat(stlFile); os.IsNotExist(err) {
-		return fmt.Errorf("STL файл не найден: %s", stlFile)
+	if _, err := os.Stat(modelFile); os.IsNotExist(err) {
+		return fmt.Errorf("файл модели не найден: %s", modelFile)
 	}
 
 	// Проверка единиц измерения
@@ -96,6 +160,11 @@ func validateVolumeParams(stlFile string) error {
 		return fmt.Errorf("неверные единицы измерения: %s. Допустимые единицы: mm3, cm3, in3, m3", volumeUnits)
 	}
 
+	// Проверка единиц координат STL
+	if _, err := stl.SourceUnitScale(volumeSource); err != nil {
+		return fmt.Errorf("неверные единицы STL файла: %s. Допустимые единицы: mm, cm, in", volumeSource)
+	}
+
 	// Проверка формата вывода
 	validFormats := map[string]bool{
 		"text": true, "csv": true, "json": true, "": true,
@@ -109,6 +178,17 @@ func validateVolumeParams(stlFile string) error {
 		return fmt.Errorf("плотность не может быть отрицательной: %f", volumeDensity)
 	}
 
+	// Проверка параметров заполнения
+	if volumeInfill < 0 || volumeInfill > 100 {
+		return fmt.Errorf("процент заполнения должен быть от 0 до 100: %.2f", volumeInfill)
+	}
+	if volumeShell < 0 {
+		return fmt.Errorf("толщина оболочки не может быть отрицательной: %.2f", volumeShell)
+	}
+	if volumeWalls < 0 {
+		return fmt.Errorf("количество периметров не может быть отрицательным: %d", volumeWalls)
+	}
+
 	return nil
 }
 
@@ -131,7 +211,8 @@ func outputVolumeText(result *stl.VolumeResult, bbox *stl.BoundingBox) error {
 	fmt.Printf("File: %s\n", result.FilePath)
 	fmt.Printf("Valid Mesh: %v\n", result.IsValid)
 	if !result.IsValid {
-		fmt.Println("Warning: Mesh may have incorrect winding order")
+		fmt.Printf("Warning: %s\n", result.Diagnostic)
+		fmt.Println("Use --assume-positive to accept the mesh as is")
 	}
 	
 	fmt.Printf("Volume: %.4f %s\n", result.Volume, result.VolumeUnit)
@@ -141,6 +222,8 @@ func outputVolumeText(result *stl.VolumeResult, bbox *stl.BoundingBox) error {
 		fmt.Printf("Material: %s\n", result.Material)
 		fmt.Printf("Density: %.2f g/cm³\n", result.Density)
 		fmt.Printf("Estimated Weight: %.2f grams\n", result.Weight)
+		fmt.Printf("Infill-Adjusted Weight: %.2f grams (infill %.0f%%, shell %.2f mm)\n",
+			result.AdjustedWeight, result.InfillPercent, result.ShellThickness)
 	}
 	
 	if bbox != nil {
@@ -158,16 +241,17 @@ func outputVolumeText(result *stl.VolumeResult, bbox *stl.BoundingBox) error {
 
 func outputVolumeCSV(result *stl.VolumeResult, bbox *stl.BoundingBox) error {
 	// CSV заголовок
-	header := "file,volume,volume_unit,triangles,weight,material,density,is_valid"
+	header := "file,volume,volume_unit,triangles,weight,adjusted_weight,infill_percent,shell_thickness,material,density,is_valid"
 	if bbox != nil {
 		header += ",width,depth,height,min_x,min_y,min_z,max_x,max_y,max_z"
 	}
 	fmt.Println(header)
 	
 	// CSV данные
-	csvLine := fmt.Sprintf("%s,%.4f,%s,%d,%.2f,%s,%.2f,%v",
+	csvLine := fmt.Sprintf("%s,%.4f,%s,%d,%.2f,%.2f,%.2f,%.2f,%s,%.2f,%v",
 		result.FilePath, result.Volume, result.VolumeUnit, 
-		result.Triangles, result.Weight, result.Material, 
+		result.Triangles, result.Weight, result.AdjustedWeight,
+		result.InfillPercent, result.ShellThickness, result.Material, 
 		result.Density, result.IsValid)
 	
 	if bbox != nil {
@@ -190,13 +274,22 @@ func outputVolumeJSON(result *stl.VolumeResult, bbox *stl.BoundingBox) error {
   "volume_unit": "%s",
   "triangles": %d,
   "weight": %.2f,
+  "adjusted_weight": %.2f,
+  "infill_percent": %.2f,
+  "shell_thickness": %.2f,
   "material": "%s",
   "density": %.2f,
   "is_valid": %t`,
 		result.FilePath, result.Volume, result.VolumeUnit,
-		result.Triangles, result.Weight, result.Material,
+		result.Triangles, result.Weight, result.AdjustedWeight,
+		result.InfillPercent, result.ShellThickness, result.Material,
 		result.Density, result.IsValid)
 
+	if result.Diagnostic != "" {
+		fmt.Printf(`,
+  "diagnostic": %q`, result.Diagnostic)
+	}
+
 	if bbox != nil {
 		width, depth, height := bbox.GetDimensions()
 		fmt.Printf(`,
@@ -218,10 +311,15 @@ func outputVolumeJSON(result *stl.VolumeResult, bbox *stl.BoundingBox) error {
 
 func init() {
 	volumeCmd.Flags().StringVarP(&volumeUnits, "units", "u", "mm3", "Единицы объема (mm3, cm3, in3, m3)")
+	volumeCmd.Flags().StringVar(&volumeSource, "source-units", "mm", "Единицы координат в STL файле (mm, cm, in)")
 	volumeCmd.Flags().StringVarP(&volumeFormat, "format", "f", "text", "Формат вывода (text, csv, json)")
 	volumeCmd.Flags().StringVarP(&volumeMaterial, "material", "m", "", "Тип материала (PLA, ABS, PETG и т.д.)")
 	volumeCmd.Flags().Float64VarP(&volumeDensity, "density", "d", 0, "Плотность материала в г/см³ (переопределяет материал)")
 	volumeCmd.Flags().BoolVar(&showBounds, "show-bounds", false, "Включить размеры габаритного параллелепипеда")
+	volumeCmd.Flags().Float64Var(&volumeInfill, "infill", 100, "Процент заполнения для оценки веса (0-100)")
+	volumeCmd.Flags().Float64Var(&volumeShell, "shell-thickness", 0.8, "Толщина сплошной оболочки в мм")
+	volumeCmd.Flags().IntVar(&volumeWalls, "walls", 0, "Количество периметров (по 0.4 мм, переопределяет --shell-thickness)")
+	volumeCmd.Flags().BoolVar(&assumePositive, "assume-positive", false, "Не считать ошибкой вывернутую поверхность (отрицательный объем)")
 	
 	rootCmd.AddCommand(volumeCmd)
 }
\ No newline at end of file
@@ -4,16 +4,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 // FileInfo represents a 3D file with its directory path information
 type FileInfo struct {
-	FileName string // name of the file (e.g., "2x_gear.stl")
-	DirPath  string // relative directory path from base directory (e.g., "arms/mechanisms")
+	FileName string  // name of the file (e.g., "2x_gear.stl")
+	DirPath  string  // relative directory path from base directory (e.g., "arms/mechanisms")
+	Quantity float64 // overrides the quantity parsed from FileName when > 0 (e.g., merged duplicates)
+}
+
+// ParseName returns the clean product name and quantity of the file (see ParseFileName)
+func (f FileInfo) ParseName() (string, float64) {
+	cleanName, quantity := ParseFileName(f.FileName)
+	if f.Quantity > 0 {
+		quantity = f.Quantity
+	}
+	return cleanName, quantity
 }
 
 // PRODUCT_NAME_PREFIX is the prefix added to all product names created from 3D files
@@ -22,36 +34,52 @@ const PRODUCT_NAME_PREFIX = "Изделие "
 // COMPANIES_FOLDER_NAME is the name of the folder where all customer companies are stored
 const COMPANIES_FOLDER_NAME = "Компании"
 
+// Quantity patterns in 3D model file names (checked in this order, first match wins):
+//   - quantityPrefixRegex:  "2x_part", "3х_gear", "1x SMA Hear" (latin or cyrillic x)
+//   - xQuantityPrefixRegex: "x2_bracket", "х3 gear" (exporters that put x first)
+//   - quantitySuffixRegex:  "part(2)", "part (2)" (trailing count in parentheses)
+//
+// Only one pattern is applied, so "2x_part(3)" means 2 pieces of "part(3)".
+// Parentheses with anything but digits are part of the name: "mount(left)".
+var (
+	quantityPrefixRegex  = regexp.MustCompile(`^(\d+)[xх][_\s](.+)$`)
+	xQuantityPrefixRegex = regexp.MustCompile(`^[xх](\d+)[_\s](.+)$`)
+	quantitySuffixRegex  = regexp.MustCompile(`^(.+?)\s*\((\d+)\)$`)
+)
+
 // ParseFileName extracts quantity and clean name from 3D model filename
-// Supports formats: "2x_part.stl", "3х_gear.step", "1x SMA Hear.stl", "simple.stl"
+// Supports formats: "2x_part.stl", "3х_gear.step", "1x SMA Hear.stl", "x2_part.stl",
+// "part(2).stl", "simple.stl" (see quantity patterns above for precedence)
 // Returns clean name without extension and quantity (default 1.0)
+// Zero quantity is not a quantity: the whole name is kept and quantity is 1.0
 func ParseFileName(fileName string) (cleanName string, quantity float64) {
-	// Default quantity
-	quantity = 1.0
-	
 	// Remove file extension
 	nameWithoutExt := strings.TrimSuffix(fileName, filepath.Ext(fileName))
 	
-	// Regex to match quantity prefixes: 2x_, 3х_, 1x SMA, 10x_, etc.
-	// Supports both 'x' and 'х' (cyrillic) with underscore or space separator
-	quantityRegex := regexp.MustCompile(`^(\d+)[xх][_\s](.+)$`)
-	matches := quantityRegex.FindStringSubmatch(nameWithoutExt)
+	if matches := quantityPrefixRegex.FindStringSubmatch(nameWithoutExt); matches != nil {
+		return parseQuantityMatch(nameWithoutExt, matches[1], matches[2])
+	}
 	
-	if len(matches) == 3 {
-		// Found quantity prefix
-		if parsedQuantity, err := strconv.ParseFloat(matches[1], 64); err == nil && parsedQuantity > 0 {
-			quantity = parsedQuantity
-			cleanName = matches[2]
-		} else {
-			// Invalid quantity (0 or negative), treat as regular filename
-			cleanName = nameWithoutExt
-		}
-	} else {
-		// No quantity prefix, use whole name without extension
-		cleanName = nameWithoutExt
+	if matches := xQuantityPrefixRegex.FindStringSubmatch(nameWithoutExt); matches != nil {
+		return parseQuantityMatch(nameWithoutExt, matches[1], matches[2])
 	}
 	
-	return cleanName, quantity
+	if matches := quantitySuffixRegex.FindStringSubmatch(nameWithoutExt); matches != nil {
+		return parseQuantityMatch(nameWithoutExt, matches[2], matches[1])
+	}
+	
+	// No quantity, use whole name without extension
+	return nameWithoutExt, 1.0
+}
+
+// parseQuantityMatch returns name and quantity of a matched pattern
+// Invalid quantity (0) falls back to the whole name with quantity 1.0
+func parseQuantityMatch(nameWithoutExt, quantityText, name string) (string, float64) {
+	parsedQuantity, err := strconv.ParseFloat(quantityText, 64)
+	if err != nil || parsedQuantity <= 0 {
+		return nameWithoutExt, 1.0
+	}
+	return name, parsedQuantity
 }
 
 // formatDirPrefix converts directory path to prefix for product name
@@ -90,7 +118,32 @@ func FormatProductNameWithDir(cleanName string, dirPath string, quantity float64
 }
 
 // ListSections retrieves catalog sections
+// Results are cached per catalog until a section is created or moved (see WithoutSectionCache)
 func (c *Client) ListSections(catalogID string) ([]ProductSection, error) {
+	if sections, cached := c.sectionCache[catalogID]; cached {
+		return sections, nil
+	}
+
+	sections, err := c.fetchSections(catalogID)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.sectionCache != nil {
+		c.sectionCache[catalogID] = sections
+	}
+	return sections, nil
+}
+
+// invalidateSectionCache drops cached section lists after the catalog structure changes
+func (c *Client) invalidateSectionCache() {
+	if c.sectionCache != nil {
+		c.sectionCache = make(map[string][]ProductSection)
+	}
+}
+
+// fetchSections requests catalog sections from the API
+func (c *Client) fetchSections(catalogID string) ([]ProductSection, error) {
 	params := map[string]interface{}{
 		"select": []string{"ID", "NAME", "SECTION_ID"},
 		"filter": map[string]interface{}{
@@ -100,33 +153,23 @@ func (c *Client) ListSections(catalogID string) ([]ProductSection, error) {
 
 	resp, err := c.makeRequest("catalog.section.list", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list sections: %v", err)
+		return nil, fmt.Errorf("failed to list sections: %w", err)
 	}
 
 	// First, let's see what the raw response looks like
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
-	}
-	
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 	
 	// Parse the generic response first
-	var bitrixResp BitrixResponse
-	if err := json.Unmarshal(body, &bitrixResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-	
-	if bitrixResp.Error != nil {
-		return nil, fmt.Errorf("Bitrix24 API error %s: %s", bitrixResp.Error.ErrorCode, bitrixResp.Error.ErrorDescription)
+	bitrixResp, err := decodeResponse(resp.StatusCode, body)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Log the raw result for debugging (remove this line in production)
-	// resultJSON, _ := json.Marshal(bitrixResp.Result)
-	// fmt.Printf("DEBUG: catalog.section.list result: %s\n", string(resultJSON))
+	c.logger.Debug("catalog.section.list response: %s", string(body))
 	
 	// Parse the result object which contains 'sections' field
 	type ListResult struct {
@@ -136,11 +179,11 @@ func (c *Client) ListSections(catalogID string) ([]ProductSection, error) {
 	var listResult ListResult
 	resultBytes, err := json.Marshal(bitrixResp.Result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal result: %v", err)
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
 	}
 	
 	if err := json.Unmarshal(resultBytes, &listResult); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal result into sections: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal result into sections: %w", err)
 	}
 	
 	return listResult.Sections, nil
@@ -148,7 +191,10 @@ func (c *Client) ListSections(catalogID string) ([]ProductSection, error) {
 
 // FindSectionByName finds a section by name (case-insensitive)
 func (c *Client) FindSectionByName(sections []ProductSection, name string, parentID string) *ProductSection {
-	for _, section := range sections {
+	for i := range sections {
+		// Point into the slice, not at the loop variable
+		section := &sections[i]
+
 		// Check if section name matches
 		if !strings.EqualFold(section.Name, name) {
 			continue
@@ -158,12 +204,12 @@ func (c *Client) FindSectionByName(sections []ProductSection, name string, paren
 		if parentID == "" {
 			// Looking for root section (parentID should be null)
 			if section.ParentID == nil {
-				return &section
+				return section
 			}
 		} else {
 			// Looking for section with specific parent
 			if section.ParentID != nil && fmt.Sprintf("%d", *section.ParentID) == parentID {
-				return &section
+				return section
 			}
 		}
 	}
@@ -187,30 +233,22 @@ func (c *Client) CreateSection(name string, parentID string, catalogID string) (
 
 	resp, err := c.makeRequest("catalog.section.add", params)
 	if err != nil {
-		return "", fmt.Errorf("failed to create section: %v", err)
+		return "", fmt.Errorf("failed to create section: %w", err)
 	}
+	c.invalidateSectionCache()
 
-	// Debug: let's see what the API actually returns
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
-	}
-	
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
 	
-	// fmt.Printf("DEBUG: catalog.section.add response: %s\n", string(body))
+	c.logger.Debug("catalog.section.add response: %s", string(body))
 	
 	// Parse the generic response first
-	var bitrixResp BitrixResponse
-	if err := json.Unmarshal(body, &bitrixResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-	
-	if bitrixResp.Error != nil {
-		return "", fmt.Errorf("Bitrix24 API error %s: %s", bitrixResp.Error.ErrorCode, bitrixResp.Error.ErrorDescription)
+	bitrixResp, err := decodeResponse(resp.StatusCode, body)
+	if err != nil {
+		return "", err
 	}
 	
 	// Parse the result which contains a 'section' object
@@ -223,16 +261,39 @@ func (c *Client) CreateSection(name string, parentID string, catalogID string) (
 	var createResult CreateSectionResult
 	resultBytes, err := json.Marshal(bitrixResp.Result)
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal result: %v", err)
+		return "", fmt.Errorf("failed to marshal result: %w", err)
 	}
 	
 	if err := json.Unmarshal(resultBytes, &createResult); err != nil {
-		return "", fmt.Errorf("failed to unmarshal result: %v", err)
+		return "", fmt.Errorf("failed to unmarshal result: %w", err)
 	}
 	
 	return fmt.Sprintf("%d", createResult.Section.ID), nil
 }
 
+// MoveSection moves a catalog section under newParentID via catalog.section.update
+func (c *Client) MoveSection(sectionID string, newParentID string) error {
+	params := map[string]interface{}{
+		"id": sectionID,
+		"fields": map[string]interface{}{
+			"iblockSectionId": newParentID,
+		},
+	}
+
+	resp, err := c.makeRequest("catalog.section.update", params)
+	if err != nil {
+		return fmt.Errorf("failed to move section: %w", err)
+	}
+	c.invalidateSectionCache()
+
+	var result interface{}
+	if err := c.parseResponse(resp, &result); err != nil {
+		return fmt.Errorf("failed to parse move section response: %w", err)
+	}
+
+	return nil
+}
+
 // CreateProduct creates a new catalog product
 func (c *Client) CreateProduct(name string, sectionID string, catalogID string) (string, error) {
 	fields := map[string]interface{}{
@@ -247,30 +308,21 @@ func (c *Client) CreateProduct(name string, sectionID string, catalogID string)
 
 	resp, err := c.makeRequest("catalog.product.add", params)
 	if err != nil {
-		return "", fmt.Errorf("failed to create product: %v", err)
+		return "", fmt.Errorf("failed to create product: %w", err)
 	}
 
-	// Debug: let's see what the API actually returns
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
 	
-	if resp.StatusCode != 200 {
-		return "", fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
-	}
-	
-	// fmt.Printf("DEBUG: catalog.product.add response: %s\n", string(body))
+	c.logger.Debug("catalog.product.add response: %s", string(body))
 	
 	// Parse the generic response first
-	var bitrixResp BitrixResponse
-	if err := json.Unmarshal(body, &bitrixResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-	
-	if bitrixResp.Error != nil {
-		return "", fmt.Errorf("Bitrix24 API error %s: %s", bitrixResp.Error.ErrorCode, bitrixResp.Error.ErrorDescription)
+	bitrixResp, err := decodeResponse(resp.StatusCode, body)
+	if err != nil {
+		return "", err
 	}
 	
 	// Convert result to string (it should be the product ID)
@@ -289,7 +341,7 @@ func (c *Client) CreateProduct(name string, sectionID string, catalogID string)
 		var createResult CreateProductResult
 		resultBytes, err := json.Marshal(bitrixResp.Result)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal result: %v", err)
+			return "", fmt.Errorf("failed to marshal result: %w", err)
 		}
 		
 		if err := json.Unmarshal(resultBytes, &createResult); err != nil {
@@ -316,27 +368,19 @@ func (c *Client) ListProducts(catalogID string, sectionID string) ([]Product, er
 
 	resp, err := c.makeRequest("catalog.product.list", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list products: %v", err)
+		return nil, fmt.Errorf("failed to list products: %w", err)
 	}
 
 	// Parse response like we do for sections
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
-	}
-	
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
-	}
-	
-	var bitrixResp BitrixResponse
-	if err := json.Unmarshal(body, &bitrixResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %v", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 	
-	if bitrixResp.Error != nil {
-		return nil, fmt.Errorf("Bitrix24 API error %s: %s", bitrixResp.Error.ErrorCode, bitrixResp.Error.ErrorDescription)
+	bitrixResp, err := decodeResponse(resp.StatusCode, body)
+	if err != nil {
+		return nil, err
 	}
 	
 	// Parse the result object which contains 'products' field
@@ -347,43 +391,67 @@ func (c *Client) ListProducts(catalogID string, sectionID string) ([]Product, er
 	var listResult ListProductResult
 	resultBytes, err := json.Marshal(bitrixResp.Result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal result: %v", err)
+		return nil, fmt.Errorf("failed to marshal result: %w", err)
 	}
 	
 	if err := json.Unmarshal(resultBytes, &listResult); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal result into products: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal result into products: %w", err)
 	}
 	
 	return listResult.Products, nil
 }
 
 // FindProductByName finds a product by name in the given products list
+// Names are compared case-insensitively; if nothing matches, a second pass compares
+// normalized names (see normalizeProductName) so trivially different names are not duplicated
 func (c *Client) FindProductByName(products []Product, name string) *Product {
-	for _, product := range products {
-		if strings.EqualFold(product.Name, name) {
-			return &product
+	for i := range products {
+		if strings.EqualFold(products[i].Name, name) {
+			return &products[i]
+		}
+	}
+
+	normalizedName := normalizeProductName(name)
+	for i := range products {
+		if strings.EqualFold(normalizeProductName(products[i].Name), normalizedName) {
+			return &products[i]
 		}
 	}
 	return nil
 }
 
+// productNameQuoteReplacer maps typographic quotes to their straight equivalents
+var productNameQuoteReplacer = strings.NewReplacer(
+	"“", "\"", "”", "\"", "„", "\"", "«", "\"", "»", "\"", "″", "\"",
+	"‘", "'", "’", "'", "‚", "'", "′", "'",
+)
+
+// normalizeProductName trims and collapses whitespace and straightens quotes
+// Example: " Изделие  “gear” " -> "Изделие \"gear\""
+func normalizeProductName(name string) string {
+	name = productNameQuoteReplacer.Replace(name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
 // EnsureCompaniesFolder ensures "Компании" folder exists in catalog root, creates if not
 func (c *Client) EnsureCompaniesFolder(catalogID string, dryRun bool) (string, error) {
 	sections, err := c.ListSections(catalogID)
 	if err != nil {
-		return "", fmt.Errorf("failed to list sections: %v", err)
+		return "", fmt.Errorf("failed to list sections: %w", err)
 	}
 
 	// Look for companies folder in root (parentID = "")
 	if section := c.FindSectionByName(sections, COMPANIES_FOLDER_NAME, ""); section != nil {
 		if dryRun {
 			fmt.Printf("[DRY RUN] Companies folder '%s' exists (ID: %d)\n", COMPANIES_FOLDER_NAME, section.ID)
+			c.dryRunPlan.addSection(planCompaniesFolder, COMPANIES_FOLDER_NAME, PlanActionExisting, fmt.Sprintf("%d", section.ID))
 		}
 		return fmt.Sprintf("%d", section.ID), nil
 	}
 
 	if dryRun {
 		fmt.Printf("[DRY RUN] Companies folder '%s' does not exist - would create new folder\n", COMPANIES_FOLDER_NAME)
+		c.dryRunPlan.addSection(planCompaniesFolder, COMPANIES_FOLDER_NAME, PlanActionNew, "")
 		// Return a placeholder ID for dry run
 		return "dry-run-companies-folder-id", nil
 	}
@@ -391,43 +459,59 @@ func (c *Client) EnsureCompaniesFolder(catalogID string, dryRun bool) (string, e
 	// Create companies folder in root
 	sectionID, err := c.CreateSection(COMPANIES_FOLDER_NAME, "", catalogID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create companies folder: %v", err)
+		return "", fmt.Errorf("failed to create companies folder: %w", err)
 	}
 
 	return sectionID, nil
 }
 
 // EnsureCustomerSection ensures customer section exists, creates if not
-func (c *Client) EnsureCustomerSection(customerName string, catalogID string, dryRun bool) (string, error) {
+// A legacy customer section found in the catalog root is moved into the companies folder when migrate is set
+func (c *Client) EnsureCustomerSection(customerName string, catalogID string, dryRun bool, migrate bool) (string, error) {
 	// First, ensure companies folder exists
 	companiesFolderID, err := c.EnsureCompaniesFolder(catalogID, dryRun)
 	if err != nil {
-		return "", fmt.Errorf("failed to ensure companies folder: %v", err)
+		return "", fmt.Errorf("failed to ensure companies folder: %w", err)
 	}
 
 	sections, err := c.ListSections(catalogID)
 	if err != nil {
-		return "", fmt.Errorf("failed to list sections: %v", err)
+		return "", fmt.Errorf("failed to list sections: %w", err)
 	}
 
 	// Look for customer section in companies folder
 	if section := c.FindSectionByName(sections, customerName, companiesFolderID); section != nil {
 		if dryRun {
 			fmt.Printf("[DRY RUN] Customer section '%s' exists in companies folder (ID: %d)\n", customerName, section.ID)
+			c.dryRunPlan.addSection(planCustomerSection, customerName, PlanActionExisting, fmt.Sprintf("%d", section.ID))
 		}
 		return fmt.Sprintf("%d", section.ID), nil
 	}
 
 	// Also check in root for backward compatibility
 	if section := c.FindSectionByName(sections, customerName, ""); section != nil {
-		if dryRun {
-			fmt.Printf("[DRY RUN] Customer section '%s' exists in root (ID: %d) - should migrate to companies folder\n", customerName, section.ID)
+		sectionID := fmt.Sprintf("%d", section.ID)
+		switch {
+		case !migrate:
+			if dryRun {
+				fmt.Printf("[DRY RUN] Customer section '%s' exists in root (ID: %d) - should migrate to companies folder\n", customerName, section.ID)
+				c.dryRunPlan.addSection(planCustomerSection, customerName, PlanActionExisting, sectionID)
+			}
+		case dryRun:
+			fmt.Printf("[DRY RUN] Customer section '%s' exists in root (ID: %d) - would move to companies folder\n", customerName, section.ID)
+			c.dryRunPlan.addSection(planCustomerSection, customerName, PlanActionMove, sectionID)
+		default:
+			fmt.Printf("Moving customer section '%s' (ID: %d) to companies folder...\n", customerName, section.ID)
+			if err := c.MoveSection(sectionID, companiesFolderID); err != nil {
+				return "", fmt.Errorf("failed to migrate customer section: %w", err)
+			}
 		}
-		return fmt.Sprintf("%d", section.ID), nil
+		return sectionID, nil
 	}
 
 	if dryRun {
 		fmt.Printf("[DRY RUN] Customer section '%s' does not exist - would create in companies folder\n", customerName)
+		c.dryRunPlan.addSection(planCustomerSection, customerName, PlanActionNew, "")
 		// Return a placeholder ID for dry run
 		return "dry-run-customer-section-id", nil
 	}
@@ -435,7 +519,7 @@ func (c *Client) EnsureCustomerSection(customerName string, catalogID string, dr
 	// Create customer section in companies folder
 	sectionID, err := c.CreateSection(customerName, companiesFolderID, catalogID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create customer section: %v", err)
+		return "", fmt.Errorf("failed to create customer section: %w", err)
 	}
 
 	return sectionID, nil
@@ -447,19 +531,21 @@ func (c *Client) EnsureProjectSection(projectName, dealID, customerSectionID, ca
 	
 	sections, err := c.ListSections(catalogID)
 	if err != nil {
-		return "", fmt.Errorf("failed to list sections: %v", err)
+		return "", fmt.Errorf("failed to list sections: %w", err)
 	}
 
 	// Look for project section under customer
 	if section := c.FindSectionByName(sections, sectionName, customerSectionID); section != nil {
 		if dryRun {
 			fmt.Printf("[DRY RUN] Project section '%s' exists (ID: %d)\n", sectionName, section.ID)
+			c.dryRunPlan.addSection(planProjectSection, sectionName, PlanActionExisting, fmt.Sprintf("%d", section.ID))
 		}
 		return fmt.Sprintf("%d", section.ID), nil
 	}
 
 	if dryRun {
 		fmt.Printf("[DRY RUN] Project section '%s' does not exist - would create under customer section ID %s\n", sectionName, customerSectionID)
+		c.dryRunPlan.addSection(planProjectSection, sectionName, PlanActionNew, "")
 		// Return a placeholder ID for dry run
 		return "dry-run-project-section-id", nil
 	}
@@ -467,14 +553,19 @@ func (c *Client) EnsureProjectSection(projectName, dealID, customerSectionID, ca
 	// Create project section under customer
 	sectionID, err := c.CreateSection(sectionName, customerSectionID, catalogID)
 	if err != nil {
-		return "", fmt.Errorf("failed to create project section: %v", err)
+		return "", fmt.Errorf("failed to create project section: %w", err)
 	}
 
 	return sectionID, nil
 }
 
+// ProgressFunc is called after each processed item with the running count and the total
+type ProgressFunc func(done, total int)
+
 // CreateProductsFrom3DFiles creates products for 3D model files (.stl and .step) in the specified section
-func (c *Client) CreateProductsFrom3DFiles(files3D []FileInfo, sectionID string, catalogID string, dryRun bool) ([]ProductInfo, error) {
+// If progress is not nil it is called once per file and per-product lines are not printed.
+// Products are created by WithConcurrency workers, see ensureProducts
+func (c *Client) CreateProductsFrom3DFiles(files3D []FileInfo, sectionID string, catalogID string, dryRun bool, progress ProgressFunc) ([]ProductInfo, error) {
 	// First, get existing products in the section
 	if dryRun {
 		fmt.Printf("[DRY RUN] Checking for existing products in section %s...\n", sectionID)
@@ -484,7 +575,7 @@ func (c *Client) CreateProductsFrom3DFiles(files3D []FileInfo, sectionID string,
 	
 	existingProducts, err := c.ListProducts(catalogID, sectionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list existing products: %v", err)
+		return nil, fmt.Errorf("failed to list existing products: %w", err)
 	}
 	
 	if dryRun {
@@ -493,51 +584,143 @@ func (c *Client) CreateProductsFrom3DFiles(files3D []FileInfo, sectionID string,
 		fmt.Printf("Found %d existing products in section\n", len(existingProducts))
 	}
 	
-	var products []ProductInfo
-	var createdCount int
-	var skippedCount int
-	
+	jobs := make([]productJob, 0, len(files3D))
 	for _, fileInfo := range files3D {
 		// Parse filename to extract quantity and clean name
-		cleanName, quantity := ParseFileName(fileInfo.FileName)
-		productName := FormatProductNameWithDir(cleanName, fileInfo.DirPath, quantity)
+		cleanName, quantity := fileInfo.ParseName()
+		jobs = append(jobs, productJob{
+			Name:             FormatProductNameWithDir(cleanName, fileInfo.DirPath, quantity),
+			Quantity:         quantity,
+			Dir:              fileInfo.DirPath,
+			SectionID:        sectionID,
+			ExistingProducts: existingProducts,
+		})
+	}
+	
+	return c.ensureProducts(jobs, catalogID, dryRun, progress)
+}
+
+// CreateProductsInDirSections creates products for 3D model files in sections mirroring their directories
+// Each FileInfo.DirPath gets a matching subsection chain under projectSectionID (see EnsureDirSections),
+// product names don't repeat the directory since the folder tree already shows it.
+// progress and concurrency work as in CreateProductsFrom3DFiles
+func (c *Client) CreateProductsInDirSections(files3D []FileInfo, projectSectionID string, catalogID string, dryRun bool, progress ProgressFunc) ([]ProductInfo, error) {
+	var dirPaths []string
+	for _, fileInfo := range files3D {
+		dirPaths = append(dirPaths, fileInfo.DirPath)
+	}
+	
+	dirSections, err := c.EnsureDirSections(dirPaths, projectSectionID, catalogID, dryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ensure directory sections: %w", err)
+	}
+	
+	// Existing products are listed once per section
+	existingBySection := make(map[string][]Product)
+	
+	jobs := make([]productJob, 0, len(files3D))
+	for _, fileInfo := range files3D {
+		sectionID := dirSections[fileInfo.DirPath]
 		
-		// Check if product already exists
-		if existingProduct := c.FindProductByName(existingProducts, productName); existingProduct != nil {
-			if dryRun {
-				fmt.Printf("[DRY RUN] Product '%s' already exists (ID: %d) - would skip creation (quantity: %.0f)\n", productName, existingProduct.ID, quantity)
-			} else {
-				fmt.Printf("Product '%s' already exists (ID: %d), skipping creation (quantity: %.0f)\n", productName, existingProduct.ID, quantity)
+		existingProducts, listed := existingBySection[sectionID]
+		if !listed {
+			// Sections that would only be created in dry-run mode have no products yet
+			if !isDryRunPlaceholder(sectionID) {
+				existingProducts, err = c.ListProducts(catalogID, sectionID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list existing products in section %s: %v", sectionID, err)
+				}
 			}
-			products = append(products, ProductInfo{
-				ID:       fmt.Sprintf("%d", existingProduct.ID),
-				Quantity: quantity,
-			})
-			skippedCount++
-			continue
+			existingBySection[sectionID] = existingProducts
 		}
 		
-		if dryRun {
-			fmt.Printf("[DRY RUN] Product '%s' does not exist - would create new product (quantity: %.0f)\n", productName, quantity)
-			// Use placeholder ID for dry run
-			products = append(products, ProductInfo{
-				ID:       fmt.Sprintf("dry-run-product-%d", createdCount+1),
-				Quantity: quantity,
-			})
-			createdCount++
-		} else {
-			// Create new product
-			fmt.Printf("Creating product '%s' (quantity: %.0f)...\n", productName, quantity)
-			productID, err := c.CreateProduct(productName, sectionID, catalogID)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create product '%s': %v", productName, err)
+		cleanName, quantity := fileInfo.ParseName()
+		jobs = append(jobs, productJob{
+			Name:             FormatProductName(cleanName, quantity),
+			Quantity:         quantity,
+			Dir:              fileInfo.DirPath,
+			SectionID:        sectionID,
+			ExistingProducts: existingProducts,
+		})
+	}
+	
+	return c.ensureProducts(jobs, catalogID, dryRun, progress)
+}
+
+// productJob is a product to find or create; ExistingProducts is the section listing fetched up front.
+// Dir is the source file's directory, kept for the dry-run plan
+type productJob struct {
+	Name             string
+	Quantity         float64
+	Dir              string
+	SectionID        string
+	ExistingProducts []Product
+}
+
+// ensureProducts finds or creates a product for every job with up to c.concurrency parallel workers.
+// The result keeps the jobs order. A failed product doesn't stop the others: all failures are
+// reported together in one error (products created meanwhile are found as existing on the next run).
+// Dry run makes no requests and numbers placeholders in order, so it runs with a single worker
+func (c *Client) ensureProducts(jobs []productJob, catalogID string, dryRun bool, progress ProgressFunc) ([]ProductInfo, error) {
+	products := make([]ProductInfo, len(jobs))
+	errs := make([]error, len(jobs))
+	
+	workers := c.concurrency
+	if dryRun || workers < 1 {
+		workers = 1
+	}
+	if workers > len(jobs) {
+		workers = len(jobs)
+	}
+	
+	var mu sync.Mutex
+	var done, createdCount, skippedCount int
+	
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				job := jobs[i]
+				
+				mu.Lock()
+				placeholderIndex := createdCount + 1
+				mu.Unlock()
+				
+				product, created, err := c.ensureProduct(job.Name, job.Quantity, job.ExistingProducts, job.SectionID, catalogID, dryRun, progress != nil, placeholderIndex)
+				
+				mu.Lock()
+				products[i], errs[i] = product, err
+				switch {
+				case err != nil:
+				case created:
+					createdCount++
+				default:
+					skippedCount++
+				}
+				if dryRun && err == nil {
+					c.dryRunPlan.addProduct(plannedProduct(job, product, created))
+				}
+				done++
+				if progress != nil {
+					progress(done, len(jobs))
+				}
+				mu.Unlock()
 			}
-			
-			products = append(products, ProductInfo{
-				ID:       productID,
-				Quantity: quantity,
-			})
-			createdCount++
+		}()
+	}
+	for i := range jobs {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	
+	var failures []string
+	for _, err := range errs {
+		if err != nil {
+			failures = append(failures, err.Error())
 		}
 	}
 	
@@ -546,9 +729,142 @@ func (c *Client) CreateProductsFrom3DFiles(files3D []FileInfo, sectionID string,
 	} else {
 		fmt.Printf("Products processed: %d created, %d skipped (already existed)\n", createdCount, skippedCount)
 	}
+	
+	if len(failures) > 0 {
+		return nil, fmt.Errorf("failed to create %d of %d products:\n  - %s", len(failures), len(jobs), strings.Join(failures, "\n  - "))
+	}
 	return products, nil
 }
 
+// plannedProduct describes the dry-run decision for a job: placeholder IDs of products to create are not kept
+func plannedProduct(job productJob, product ProductInfo, created bool) PlannedProduct {
+	planned := PlannedProduct{Name: job.Name, Action: PlanActionSkip, ID: product.ID, Quantity: job.Quantity, Dir: filepath.ToSlash(job.Dir)}
+	if created {
+		planned.Action = PlanActionCreate
+		planned.ID = ""
+	}
+	return planned
+}
+
+// EnsureDirSections ensures a section chain mirroring each directory path exists under parentSectionID
+// Example: "arms/mechanisms" -> parent / "arms" / "mechanisms"
+// Returns a map from directory path to the ID of its deepest section ("" maps to parentSectionID)
+func (c *Client) EnsureDirSections(dirPaths []string, parentSectionID, catalogID string, dryRun bool) (map[string]string, error) {
+	sectionIDs := map[string]string{"": parentSectionID}
+	
+	var sections []ProductSection
+	sectionsLoaded := false
+	
+	for _, dirPath := range dirPaths {
+		currentPath := ""
+		currentID := parentSectionID
+		
+		for _, segment := range strings.Split(filepath.ToSlash(dirPath), "/") {
+			if segment == "" || segment == "." {
+				continue
+			}
+			
+			if currentPath == "" {
+				currentPath = segment
+			} else {
+				currentPath = currentPath + "/" + segment
+			}
+			
+			if id, exists := sectionIDs[currentPath]; exists {
+				currentID = id
+				continue
+			}
+			
+			if !sectionsLoaded {
+				var err error
+				sections, err = c.ListSections(catalogID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to list sections: %w", err)
+				}
+				sectionsLoaded = true
+			}
+			
+			if section := c.FindSectionByName(sections, segment, currentID); section != nil {
+				if dryRun {
+					fmt.Printf("[DRY RUN] Directory section '%s' exists (ID: %d)\n", currentPath, section.ID)
+					c.dryRunPlan.addSection(planDirSection, currentPath, PlanActionExisting, fmt.Sprintf("%d", section.ID))
+				}
+				currentID = fmt.Sprintf("%d", section.ID)
+			} else if dryRun {
+				fmt.Printf("[DRY RUN] Directory section '%s' does not exist - would create under section ID %s\n", currentPath, currentID)
+				c.dryRunPlan.addSection(planDirSection, currentPath, PlanActionNew, "")
+				currentID = "dry-run-dir-section-" + currentPath
+			} else {
+				fmt.Printf("Creating directory section '%s'...\n", currentPath)
+				id, err := c.CreateSection(segment, currentID, catalogID)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create directory section '%s': %v", currentPath, err)
+				}
+				currentID = id
+			}
+			
+			sectionIDs[currentPath] = currentID
+		}
+		
+		sectionIDs[dirPath] = currentID
+	}
+	
+	return sectionIDs, nil
+}
+
+// isDryRunPlaceholder reports whether sectionID is a placeholder returned in dry-run mode
+func isDryRunPlaceholder(sectionID string) bool {
+	return strings.HasPrefix(sectionID, "dry-run-")
+}
+
+// ensureProduct finds productName among existingProducts or creates it in sectionID
+// Returns the product info and whether the product was (or in dry-run mode would be) created.
+// quiet suppresses the per-product log lines (used when the caller renders progress)
+func (c *Client) ensureProduct(productName string, quantity float64, existingProducts []Product, sectionID, catalogID string, dryRun bool, quiet bool, placeholderIndex int) (ProductInfo, bool, error) {
+	// Check if product already exists
+	if existingProduct := c.FindProductByName(existingProducts, productName); existingProduct != nil {
+		if !quiet {
+			if dryRun {
+				fmt.Printf("[DRY RUN] Product '%s' already exists (ID: %d) - would skip creation (quantity: %.0f)\n", productName, existingProduct.ID, quantity)
+			} else {
+				fmt.Printf("Product '%s' already exists (ID: %d), skipping creation (quantity: %.0f)\n", productName, existingProduct.ID, quantity)
+			}
+		}
+		return ProductInfo{
+			ID:       fmt.Sprintf("%d", existingProduct.ID),
+			Name:     productName,
+			Quantity: quantity,
+		}, false, nil
+	}
+	
+	if dryRun {
+		if !quiet {
+			fmt.Printf("[DRY RUN] Product '%s' does not exist - would create new product (quantity: %.0f)\n", productName, quantity)
+		}
+		// Use placeholder ID for dry run
+		return ProductInfo{
+			ID:       fmt.Sprintf("dry-run-product-%d", placeholderIndex),
+			Name:     productName,
+			Quantity: quantity,
+		}, true, nil
+	}
+	
+	// Create new product
+	if !quiet {
+		fmt.Printf("Creating product '%s' (quantity: %.0f)...\n", productName, quantity)
+	}
+	productID, err := c.CreateProduct(productName, sectionID, catalogID)
+	if err != nil {
+		return ProductInfo{}, false, fmt.Errorf("failed to create product '%s': %v", productName, err)
+	}
+	
+	return ProductInfo{
+		ID:       productID,
+		Name:     productName,
+		Quantity: quantity,
+	}, true, nil
+}
+
 // CreateDealProductRows converts ProductInfo to deal product rows
 func CreateDealProductRows(products []ProductInfo) []DealProductRow {
 	var rows []DealProductRow
@@ -563,4 +879,59 @@ func CreateDealProductRows(products []ProductInfo) []DealProductRow {
 	}
 	
 	return rows
+}
+
+// QuantityRounding controls how CreateDealProductRowsWithRounding handles non-integer quantities.
+// Count-tracked catalog products can't be sold in fractions, so the default is to refuse them
+type QuantityRounding string
+
+const (
+	QuantityRoundError QuantityRounding = "error" // fail listing the products with fractional quantities
+	QuantityRoundCeil  QuantityRounding = "ceil"  // round up: 2.5 -> 3
+	QuantityRoundRound QuantityRounding = "round" // round half away from zero: 2.5 -> 3, 2.4 -> 2 (never below 1)
+)
+
+// ParseQuantityRounding validates a --round-quantities value (case-insensitive), empty means QuantityRoundError
+func ParseQuantityRounding(value string) (QuantityRounding, error) {
+	switch mode := QuantityRounding(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return QuantityRoundError, nil
+	case QuantityRoundError, QuantityRoundCeil, QuantityRoundRound:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unsupported quantity rounding %q (supported: error, ceil, round)", value)
+	}
+}
+
+// CreateDealProductRowsWithRounding converts ProductInfo to deal product rows like CreateDealProductRows,
+// handling non-integer quantities according to rounding. With QuantityRoundError any fractional
+// quantity fails the whole conversion with an error listing every offending product
+func CreateDealProductRowsWithRounding(products []ProductInfo, rounding QuantityRounding) ([]DealProductRow, error) {
+	rows := CreateDealProductRows(products)
+	
+	var fractional []string
+	for i := range rows {
+		quantity := rows[i].Quantity
+		if quantity == math.Trunc(quantity) {
+			continue
+		}
+		
+		switch rounding {
+		case QuantityRoundCeil:
+			rows[i].Quantity = math.Ceil(quantity)
+		case QuantityRoundRound:
+			rows[i].Quantity = math.Max(1, math.Round(quantity))
+		default:
+			name := products[i].Name
+			if name == "" {
+				name = "product " + products[i].ID
+			}
+			fractional = append(fractional, fmt.Sprintf("%s (%s)", name, strconv.FormatFloat(quantity, 'f', -1, 64)))
+		}
+	}
+	
+	if len(fractional) > 0 {
+		return nil, fmt.Errorf("non-integer quantities for %d product(s): %s", len(fractional), strings.Join(fractional, ", "))
+	}
+	return rows, nil
 }
\ No newline at end of file
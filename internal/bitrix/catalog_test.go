@@ -1,8 +1,15 @@
 package bitrix
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestParseFileName(t *testing.T) {
@@ -96,6 +103,102 @@ func TestParseFileName(t *testing.T) {
 			expectedCleanName: "Tank Mount Att Radar",
 			expectedQuantity: 5.0,
 		},
+		{
+			name:             "x-first prefix (x2_)",
+			fileName:         "x2_bracket.stl",
+			expectedCleanName: "bracket",
+			expectedQuantity: 2.0,
+		},
+		{
+			name:             "x-first prefix with cyrillic х",
+			fileName:         "х3_gear.step",
+			expectedCleanName: "gear",
+			expectedQuantity: 3.0,
+		},
+		{
+			name:             "x-first prefix with space separator",
+			fileName:         "x4 Tank Mount.stl",
+			expectedCleanName: "Tank Mount",
+			expectedQuantity: 4.0,
+		},
+		{
+			name:             "x-first prefix with zero quantity",
+			fileName:         "x0_part.stl",
+			expectedCleanName: "x0_part",
+			expectedQuantity: 1.0,
+		},
+		{
+			name:             "x-first prefix without separator is a name",
+			fileName:         "x2bracket.stl",
+			expectedCleanName: "x2bracket",
+			expectedQuantity: 1.0,
+		},
+		{
+			name:             "name starting with x and digits is not a prefix",
+			fileName:         "box2_part.stl",
+			expectedCleanName: "box2_part",
+			expectedQuantity: 1.0,
+		},
+		{
+			name:             "trailing count in parentheses",
+			fileName:         "part(2).stl",
+			expectedCleanName: "part",
+			expectedQuantity: 2.0,
+		},
+		{
+			name:             "trailing count with space before parentheses",
+			fileName:         "Tank Mount (12).step",
+			expectedCleanName: "Tank Mount",
+			expectedQuantity: 12.0,
+		},
+		{
+			name:             "trailing zero count is a name",
+			fileName:         "part(0).stl",
+			expectedCleanName: "part(0)",
+			expectedQuantity: 1.0,
+		},
+		{
+			name:             "parentheses with text are a name",
+			fileName:         "mount(left).stl",
+			expectedCleanName: "mount(left)",
+			expectedQuantity: 1.0,
+		},
+		{
+			name:             "parentheses with mixed text are a name",
+			fileName:         "mount(v2).stl",
+			expectedCleanName: "mount(v2)",
+			expectedQuantity: 1.0,
+		},
+		{
+			name:             "count in the middle of name is a name",
+			fileName:         "part(2)_left.stl",
+			expectedCleanName: "part(2)_left",
+			expectedQuantity: 1.0,
+		},
+		{
+			name:             "only count in parentheses is a name",
+			fileName:         "(2).stl",
+			expectedCleanName: "(2)",
+			expectedQuantity: 1.0,
+		},
+		{
+			name:             "prefix takes precedence over trailing count",
+			fileName:         "2x_part(3).stl",
+			expectedCleanName: "part(3)",
+			expectedQuantity: 2.0,
+		},
+		{
+			name:             "x-first prefix takes precedence over trailing count",
+			fileName:         "x2_part(3).stl",
+			expectedCleanName: "part(3)",
+			expectedQuantity: 2.0,
+		},
+		{
+			name:             "trailing count after parentheses with text",
+			fileName:         "mount(left)(2).stl",
+			expectedCleanName: "mount(left)",
+			expectedQuantity: 2.0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -113,6 +216,25 @@ func TestParseFileName(t *testing.T) {
 	}
 }
 
+func TestFileInfoParseName(t *testing.T) {
+	tests := []struct {
+		file         FileInfo
+		wantName     string
+		wantQuantity float64
+	}{
+		{FileInfo{FileName: "2x_gear.stl"}, "gear", 2},
+		{FileInfo{FileName: "2x_gear.stl", Quantity: 5}, "gear", 5},
+		{FileInfo{FileName: "bracket.stl", Quantity: 0}, "bracket", 1},
+	}
+
+	for _, tt := range tests {
+		name, quantity := tt.file.ParseName()
+		if name != tt.wantName || quantity != tt.wantQuantity {
+			t.Errorf("%+v.ParseName() = %q, %v; want %q, %v", tt.file, name, quantity, tt.wantName, tt.wantQuantity)
+		}
+	}
+}
+
 func TestFormatProductName(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -535,4 +657,781 @@ func TestFormatProductNameWithDir(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestCreateProductRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		response      string
+		expectedID    string
+		errorContains string
+	}{
+		{
+			name:       "numeric result",
+			response:   `{"result":42}`,
+			expectedID: "42",
+		},
+		{
+			name:       "string result",
+			response:   `{"result":"43"}`,
+			expectedID: "43",
+		},
+		{
+			name:       "element object result",
+			response:   `{"result":{"element":{"id":44,"name":"gear"}}}`,
+			expectedID: "44",
+		},
+		{
+			name:          "API error",
+			response:      `{"error":"ERROR_CORE","error_description":"Section not found"}`,
+			errorContains: "ERROR_CORE: Section not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, map[string]string{"catalog.product.add": tt.response})
+			productID, err := client.CreateProduct("gear", "10", "14")
+
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateProduct() error = %v", err)
+			}
+			if productID != tt.expectedID {
+				t.Errorf("expected product ID %q, got %q", tt.expectedID, productID)
+			}
+		})
+	}
+}
+
+// fakeCatalog is an in-memory catalog.section.* / catalog.product.* backend for tests
+type fakeCatalog struct {
+	sections        []string // JSON section objects
+	createdSections []string // "parentID/name" of created sections
+	createdProducts []string // "sectionID/name" of created products
+	moves           []string // "sectionID->parentID" of moved sections
+	products        []string // JSON product objects returned by catalog.product.list
+	nextID          int
+}
+
+func (f *fakeCatalog) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		switch strings.TrimPrefix(r.URL.Path, "/") {
+		case "catalog.section.list":
+			fmt.Fprintf(w, `{"result":{"sections":[%s]}}`, strings.Join(f.sections, ","))
+		case "catalog.section.add":
+			f.nextID++
+			name, parentID := r.PostForm.Get("fields[name]"), r.PostForm.Get("fields[iblockSectionId]")
+			f.sections = append(f.sections, fmt.Sprintf(`{"id":%d,"name":%q,"iblockSectionId":%s}`, f.nextID, name, parentID))
+			f.createdSections = append(f.createdSections, parentID+"/"+name)
+			fmt.Fprintf(w, `{"result":{"section":{"id":%d}}}`, f.nextID)
+		case "catalog.section.update":
+			f.moves = append(f.moves, r.PostForm.Get("id")+"->"+r.PostForm.Get("fields[iblockSectionId]"))
+			fmt.Fprintf(w, `{"result":{"section":{"id":%s}}}`, r.PostForm.Get("id"))
+		case "catalog.product.list":
+			fmt.Fprintf(w, `{"result":{"products":[%s]}}`, strings.Join(f.products, ","))
+		case "catalog.product.add":
+			f.nextID++
+			f.createdProducts = append(f.createdProducts, r.PostForm.Get("fields[iblockSectionId]")+"/"+r.PostForm.Get("fields[name]"))
+			fmt.Fprintf(w, `{"result":%d}`, f.nextID)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func newFakeCatalogClient(t *testing.T, catalog *fakeCatalog) *Client {
+	server := httptest.NewServer(catalog.handler(t))
+	t.Cleanup(server.Close)
+	return NewClient(server.URL, WithHTTPClient(server.Client()))
+}
+
+func TestEnsureDirSectionsCreatesNestedChain(t *testing.T) {
+	catalog := &fakeCatalog{
+		sections: []string{`{"id":10,"name":"Project - 1","iblockSectionId":null}`},
+		nextID:   100,
+	}
+	client := newFakeCatalogClient(t, catalog)
+
+	var sectionIDs map[string]string
+	var err error
+	captureStdout(t, func() {
+		sectionIDs, err = client.EnsureDirSections([]string{"", "arms/mechanisms", "arms", "legs"}, "10", "14", false)
+	})
+	if err != nil {
+		t.Fatalf("EnsureDirSections() error = %v", err)
+	}
+
+	expectedCreated := []string{"10/arms", "101/mechanisms", "10/legs"}
+	if !reflect.DeepEqual(catalog.createdSections, expectedCreated) {
+		t.Errorf("created sections = %v, want %v", catalog.createdSections, expectedCreated)
+	}
+
+	expectedIDs := map[string]string{
+		"":                "10",
+		"arms":            "101",
+		"arms/mechanisms": "102",
+		"legs":            "103",
+	}
+	if !reflect.DeepEqual(sectionIDs, expectedIDs) {
+		t.Errorf("section IDs = %v, want %v", sectionIDs, expectedIDs)
+	}
+}
+
+func TestEnsureDirSectionsIdempotent(t *testing.T) {
+	catalog := &fakeCatalog{
+		sections: []string{`{"id":10,"name":"Project - 1","iblockSectionId":null}`},
+		nextID:   100,
+	}
+	client := newFakeCatalogClient(t, catalog)
+	dirPaths := []string{"arms/mechanisms", "legs"}
+
+	var first, second map[string]string
+	var err error
+	captureStdout(t, func() {
+		first, err = client.EnsureDirSections(dirPaths, "10", "14", false)
+	})
+	if err != nil {
+		t.Fatalf("first EnsureDirSections() error = %v", err)
+	}
+	createdAfterFirst := len(catalog.createdSections)
+
+	captureStdout(t, func() {
+		second, err = client.EnsureDirSections(dirPaths, "10", "14", false)
+	})
+	if err != nil {
+		t.Fatalf("second EnsureDirSections() error = %v", err)
+	}
+
+	if len(catalog.createdSections) != createdAfterFirst {
+		t.Errorf("second run created sections: %v", catalog.createdSections[createdAfterFirst:])
+	}
+	if !reflect.DeepEqual(first, second) {
+		t.Errorf("second run returned %v, want %v", second, first)
+	}
+}
+
+func TestEnsureDirSectionsDryRun(t *testing.T) {
+	catalog := &fakeCatalog{
+		sections: []string{`{"id":11,"name":"arms","iblockSectionId":10}`},
+	}
+	client := newFakeCatalogClient(t, catalog)
+
+	var sectionIDs map[string]string
+	var err error
+	captureStdout(t, func() {
+		sectionIDs, err = client.EnsureDirSections([]string{"arms/mechanisms"}, "10", "14", true)
+	})
+	if err != nil {
+		t.Fatalf("EnsureDirSections() error = %v", err)
+	}
+
+	if len(catalog.createdSections) != 0 {
+		t.Errorf("dry run created sections: %v", catalog.createdSections)
+	}
+	if sectionIDs["arms"] != "11" {
+		t.Errorf("expected existing section 11 for 'arms', got %q", sectionIDs["arms"])
+	}
+	if !isDryRunPlaceholder(sectionIDs["arms/mechanisms"]) {
+		t.Errorf("expected placeholder for missing section, got %q", sectionIDs["arms/mechanisms"])
+	}
+}
+
+func TestCreateProductsInDirSections(t *testing.T) {
+	catalog := &fakeCatalog{nextID: 100}
+	client := newFakeCatalogClient(t, catalog)
+
+	files := []FileInfo{
+		{FileName: "base.stl", DirPath: ""},
+		{FileName: "2x_gear.stl", DirPath: "arms/mechanisms"},
+	}
+
+	var products []ProductInfo
+	var err error
+	captureStdout(t, func() {
+		products, err = client.CreateProductsInDirSections(files, "10", "14", false, nil)
+	})
+	if err != nil {
+		t.Fatalf("CreateProductsInDirSections() error = %v", err)
+	}
+
+	expectedProducts := []string{
+		"10/" + FormatProductName("base", 1),
+		"102/" + FormatProductName("gear", 2),
+	}
+	if !reflect.DeepEqual(catalog.createdProducts, expectedProducts) {
+		t.Errorf("created products = %v, want %v", catalog.createdProducts, expectedProducts)
+	}
+	if len(products) != 2 || products[1].Quantity != 2 {
+		t.Errorf("unexpected products: %+v", products)
+	}
+}
+
+func TestCreateProductsFrom3DFilesProgress(t *testing.T) {
+	catalog := &fakeCatalog{nextID: 100}
+	client := newFakeCatalogClient(t, catalog)
+
+	files := []FileInfo{
+		{FileName: "a.stl"},
+		{FileName: "2x_b.stl"},
+		{FileName: "c.step", DirPath: "sub"},
+	}
+
+	var calls [][2]int
+	progress := func(done, total int) {
+		calls = append(calls, [2]int{done, total})
+	}
+
+	var err error
+	output := captureStdout(t, func() {
+		_, err = client.CreateProductsFrom3DFiles(files, "10", "14", false, progress)
+	})
+	if err != nil {
+		t.Fatalf("CreateProductsFrom3DFiles() error = %v", err)
+	}
+
+	expected := [][2]int{{1, 3}, {2, 3}, {3, 3}}
+	if !reflect.DeepEqual(calls, expected) {
+		t.Errorf("progress calls = %v, want %v", calls, expected)
+	}
+	if strings.Contains(output, "Creating product '") {
+		t.Errorf("per-product lines must be suppressed when progress is set, got:\n%s", output)
+	}
+	if len(catalog.createdProducts) != 3 {
+		t.Errorf("expected 3 created products, got %v", catalog.createdProducts)
+	}
+}
+
+// concurrentCatalog is a thread-safe catalog.product.* backend that tracks parallel requests
+// Product "N_name" responds after N*10ms with ID 100+N, names containing "broken" fail
+type concurrentCatalog struct {
+	mu          sync.Mutex
+	inFlight    int
+	maxInFlight int
+}
+
+func (f *concurrentCatalog) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("failed to parse form: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+
+		switch strings.TrimPrefix(r.URL.Path, "/") {
+		case "catalog.product.list":
+			fmt.Fprint(w, `{"result":{"products":[{"id":7,"name":"Изделие \"existing\""}]}}`)
+		case "catalog.product.add":
+			f.mu.Lock()
+			f.inFlight++
+			if f.inFlight > f.maxInFlight {
+				f.maxInFlight = f.inFlight
+			}
+			f.mu.Unlock()
+			defer func() {
+				f.mu.Lock()
+				f.inFlight--
+				f.mu.Unlock()
+			}()
+
+			name := strings.Trim(strings.TrimPrefix(r.PostForm.Get("fields[name]"), PRODUCT_NAME_PREFIX), `"`)
+			var n int
+			fmt.Sscanf(name, "%d_", &n)
+			time.Sleep(time.Duration(n) * 10 * time.Millisecond)
+			if strings.Contains(name, "broken") {
+				fmt.Fprint(w, `{"error":"ERROR_CORE","error_description":"cannot save product"}`)
+				return
+			}
+			fmt.Fprintf(w, `{"result":%d}`, 100+n)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func TestCreateProductsFrom3DFilesConcurrentOrder(t *testing.T) {
+	catalog := &concurrentCatalog{}
+	server := httptest.NewServer(catalog.handler(t))
+	defer server.Close()
+	client := NewClient(server.URL, WithHTTPClient(server.Client()), WithConcurrency(3))
+
+	// Slower products come first, so they finish last
+	files := []FileInfo{{FileName: "5_a.stl"}, {FileName: "4_b.stl"}, {FileName: "existing.stl"}, {FileName: "2_c.stl"}, {FileName: "1_d.stl"}}
+
+	var products []ProductInfo
+	var err error
+	captureStdout(t, func() {
+		products, err = client.CreateProductsFrom3DFiles(files, "10", "14", false, nil)
+	})
+	if err != nil {
+		t.Fatalf("CreateProductsFrom3DFiles() error = %v", err)
+	}
+
+	var ids []string
+	for _, product := range products {
+		ids = append(ids, product.ID)
+	}
+	if want := []string{"105", "104", "7", "102", "101"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("product IDs = %v, want files order %v", ids, want)
+	}
+	if catalog.maxInFlight < 2 || catalog.maxInFlight > 3 {
+		t.Errorf("max parallel requests = %d, want 2..3", catalog.maxInFlight)
+	}
+}
+
+func TestCreateProductsFrom3DFilesCollectsErrors(t *testing.T) {
+	catalog := &concurrentCatalog{}
+	server := httptest.NewServer(catalog.handler(t))
+	defer server.Close()
+	client := NewClient(server.URL, WithHTTPClient(server.Client()), WithConcurrency(2))
+
+	files := []FileInfo{{FileName: "1_broken_a.stl"}, {FileName: "2_ok.stl"}, {FileName: "3_broken_b.stl"}}
+
+	var products []ProductInfo
+	var err error
+	captureStdout(t, func() {
+		products, err = client.CreateProductsFrom3DFiles(files, "10", "14", false, nil)
+	})
+	if err == nil {
+		t.Fatalf("expected error, got products %+v", products)
+	}
+
+	message := err.Error()
+	if !strings.Contains(message, "failed to create 2 of 3 products") {
+		t.Errorf("error should count failures, got: %s", message)
+	}
+	first, second := strings.Index(message, "broken_a"), strings.Index(message, "broken_b")
+	if first < 0 || second < 0 || first > second {
+		t.Errorf("error should list both failed products in files order, got: %s", message)
+	}
+}
+
+func TestMoveSectionRequestEncoding(t *testing.T) {
+	var form map[string][]string
+	var path string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := r.ParseForm(); err != nil {
+			t.Fatalf("failed to parse form: %v", err)
+		}
+		form = r.PostForm
+		w.Write([]byte(`{"result":{"section":{"id":25}}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	if err := client.MoveSection("25", "7"); err != nil {
+		t.Fatalf("MoveSection() error = %v", err)
+	}
+
+	if path != "/catalog.section.update" {
+		t.Errorf("expected catalog.section.update, got %s", path)
+	}
+	expected := map[string][]string{
+		"id":                      {"25"},
+		"fields[iblockSectionId]": {"7"},
+	}
+	if !reflect.DeepEqual(form, expected) {
+		t.Errorf("form = %v, want %v", form, expected)
+	}
+}
+
+func TestEnsureCustomerSectionMigration(t *testing.T) {
+	tests := []struct {
+		name          string
+		dryRun        bool
+		migrate       bool
+		expectedMoves []string
+	}{
+		{
+			name:          "without --migrate root section is left in place",
+			migrate:       false,
+			expectedMoves: nil,
+		},
+		{
+			name:          "dry run does not move",
+			dryRun:        true,
+			migrate:       true,
+			expectedMoves: nil,
+		},
+		{
+			name:          "migrate moves root section into companies folder",
+			migrate:       true,
+			expectedMoves: []string{"25->7"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalog := &fakeCatalog{
+				sections: []string{
+					`{"id":7,"name":"Компании","iblockSectionId":null}`,
+					`{"id":25,"name":"ACME","iblockSectionId":null}`,
+				},
+			}
+			client := newFakeCatalogClient(t, catalog)
+
+			var sectionID string
+			var err error
+			captureStdout(t, func() {
+				sectionID, err = client.EnsureCustomerSection("ACME", "14", tt.dryRun, tt.migrate)
+			})
+			if err != nil {
+				t.Fatalf("EnsureCustomerSection() error = %v", err)
+			}
+
+			if sectionID != "25" {
+				t.Errorf("expected existing section 25, got %q", sectionID)
+			}
+			if !reflect.DeepEqual(catalog.moves, tt.expectedMoves) {
+				t.Errorf("moves = %v, want %v", catalog.moves, tt.expectedMoves)
+			}
+			if len(catalog.createdSections) != 0 {
+				t.Errorf("unexpected created sections: %v", catalog.createdSections)
+			}
+		})
+	}
+}
+
+// catalogSectionResponses serve a fixed section list for countingTransport
+var catalogSectionResponses = map[string]string{
+	"catalog.section.list": `{"result":{"sections":[` +
+		`{"id":7,"name":"Компании","iblockSectionId":null},` +
+		`{"id":8,"name":"ACME","iblockSectionId":7},` +
+		`{"id":9,"name":"Project - 123","iblockSectionId":8}]}}`,
+	"catalog.section.add": `{"result":{"section":{"id":10}}}`,
+}
+
+func TestListSectionsCachedAcrossEnsureChain(t *testing.T) {
+	counts := make(map[string]int)
+	client := NewClient("https://example.bitrix24.ru/rest/1/token", WithHTTPClient(countingTransport(t, counts, catalogSectionResponses)))
+
+	captureStdout(t, func() {
+		customerID, err := client.EnsureCustomerSection("ACME", "14", false, false)
+		if err != nil {
+			t.Fatalf("EnsureCustomerSection() error = %v", err)
+		}
+		if _, err := client.EnsureProjectSection("Project", "123", customerID, "14", false); err != nil {
+			t.Fatalf("EnsureProjectSection() error = %v", err)
+		}
+	})
+
+	if counts["catalog.section.list"] != 1 {
+		t.Errorf("expected 1 catalog.section.list call across ensure chain, got %d", counts["catalog.section.list"])
+	}
+
+	// Creating a section invalidates the cache
+	if _, err := client.CreateSection("New", "9", "14"); err != nil {
+		t.Fatalf("CreateSection() error = %v", err)
+	}
+	if _, err := client.ListSections("14"); err != nil {
+		t.Fatalf("ListSections() error = %v", err)
+	}
+	if counts["catalog.section.list"] != 2 {
+		t.Errorf("expected cache invalidation after create, got %d list calls", counts["catalog.section.list"])
+	}
+}
+
+func TestListSectionsWithoutCache(t *testing.T) {
+	counts := make(map[string]int)
+	client := NewClient("https://example.bitrix24.ru/rest/1/token",
+		WithHTTPClient(countingTransport(t, counts, catalogSectionResponses)), WithoutSectionCache())
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.ListSections("14"); err != nil {
+			t.Fatalf("ListSections() error = %v", err)
+		}
+	}
+
+	if counts["catalog.section.list"] != 3 {
+		t.Errorf("expected 3 catalog.section.list calls with cache disabled, got %d", counts["catalog.section.list"])
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestFindSectionByNameReturnsSliceElement(t *testing.T) {
+	client := &Client{}
+	sections := []ProductSection{
+		{ID: 1, Name: "Компании", ParentID: nil},
+		{ID: 2, Name: "ACME", ParentID: intPtr(1)},
+		{ID: 3, Name: "Other", ParentID: intPtr(1)},
+		{ID: 4, Name: "Last", ParentID: nil},
+	}
+
+	found := client.FindSectionByName(sections, "companies", "")
+	if found != nil {
+		t.Fatalf("expected no match for unknown name, got %+v", found)
+	}
+
+	found = client.FindSectionByName(sections, "Компании", "")
+	if found != &sections[0] {
+		t.Fatalf("expected pointer to sections[0], got %p (want %p)", found, &sections[0])
+	}
+
+	// Keep searching: the captured pointer must still describe the first match
+	for _, section := range sections {
+		client.FindSectionByName(sections, section.Name, "1")
+	}
+	if found.ID != 1 || found.Name != "Компании" {
+		t.Errorf("captured match changed to %+v", *found)
+	}
+
+	acme := client.FindSectionByName(sections, "acme", "1")
+	if acme != &sections[1] || acme.ID != 2 {
+		t.Errorf("expected pointer to sections[1], got %+v", acme)
+	}
+}
+
+func TestFindProductByNameReturnsSliceElement(t *testing.T) {
+	client := &Client{}
+	products := []Product{
+		{ID: 10, Name: "Изделие \"gear\""},
+		{ID: 11, Name: "Изделие \"bracket\""},
+		{ID: 12, Name: "Изделие \"last\""},
+	}
+
+	found := client.FindProductByName(products, "изделие \"gear\"")
+	if found != &products[0] {
+		t.Fatalf("expected pointer to products[0], got %p (want %p)", found, &products[0])
+	}
+
+	for _, product := range products {
+		client.FindProductByName(products, product.Name)
+	}
+	if found.ID != 10 {
+		t.Errorf("captured match changed to %+v", *found)
+	}
+}
+
+func TestFindProductByNameNormalization(t *testing.T) {
+	client := &Client{}
+	products := []Product{
+		{ID: 1, Name: "Изделие \"gear \""},
+		{ID: 2, Name: "Изделие \"bracket\" "},
+		{ID: 3, Name: "Изделие “mount”"},
+		{ID: 4, Name: "Изделие  \"base   plate\""},
+		{ID: 5, Name: "Изделие \"gear\""},
+	}
+
+	tests := []struct {
+		name       string
+		search     string
+		expectedID int // 0 means not found
+	}{
+		{"exact match preferred over normalized", "Изделие \"gear\"", 5},
+		{"trailing space in existing name", "Изделие \"bracket\"", 2},
+		{"curly vs straight quotes", "Изделие \"mount\"", 3},
+		{"collapsed internal whitespace", "Изделие \"base plate\"", 4},
+		{"case-insensitive still works", "изделие \"MOUNT\"", 3},
+		{"different name is not matched", "Изделие \"mount2\"", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			found := client.FindProductByName(products, tt.search)
+			if tt.expectedID == 0 {
+				if found != nil {
+					t.Errorf("expected no match, got %+v", *found)
+				}
+				return
+			}
+			if found == nil || found.ID != tt.expectedID {
+				t.Errorf("FindProductByName(%q) = %+v, want ID %d", tt.search, found, tt.expectedID)
+			}
+		})
+	}
+}
+
+func TestNormalizeProductName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Изделие \"gear\"", "Изделие \"gear\""},
+		{"  Изделие   «gear»  ", "Изделие \"gear\""},
+		{"Изделие ‘gear’", "Изделие 'gear'"},
+		{"Изделие\t\"a b\"", "Изделие \"a b\""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeProductName(tt.input); got != tt.expected {
+			t.Errorf("normalizeProductName(%q) = %q, want %q", tt.input, got, tt.expected)
+		}
+	}
+}
+
+func TestDryRunPlan(t *testing.T) {
+	catalog := &fakeCatalog{
+		sections: []string{
+			`{"id":1,"name":"Компании","iblockSectionId":null}`,
+			`{"id":2,"name":"ACME","iblockSectionId":1}`,
+			`{"id":3,"name":"Robot - 42","iblockSectionId":2}`,
+		},
+		products: []string{`{"id":7,"name":"Изделие \"base\""}`},
+	}
+	server := httptest.NewServer(catalog.handler(t))
+	defer server.Close()
+	plan := &DryRunPlan{DealID: "42"}
+	client := NewClient(server.URL, WithHTTPClient(server.Client()), WithDryRunPlan(plan))
+
+	files := []FileInfo{{FileName: "base.stl"}, {FileName: "2x_gear.stl", DirPath: "arms"}}
+
+	var err error
+	captureStdout(t, func() {
+		var customerID, projectID string
+		if customerID, err = client.EnsureCustomerSection("ACME", "14", true, false); err != nil {
+			return
+		}
+		if projectID, err = client.EnsureProjectSection("Robot", "42", customerID, "14", true); err != nil {
+			return
+		}
+		_, err = client.CreateProductsFrom3DFiles(files, projectID, "14", true, nil)
+	})
+	if err != nil {
+		t.Fatalf("dry run error = %v", err)
+	}
+	if len(catalog.createdSections) != 0 || len(catalog.createdProducts) != 0 {
+		t.Fatalf("dry run changed the catalog: %v %v", catalog.createdSections, catalog.createdProducts)
+	}
+
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded DryRunPlan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded.CustomerSection == nil || *decoded.CustomerSection != (PlannedSection{Name: "ACME", Action: PlanActionExisting, ID: "2"}) {
+		t.Errorf("customer section = %+v", decoded.CustomerSection)
+	}
+	if decoded.ProjectSection == nil || *decoded.ProjectSection != (PlannedSection{Name: "Robot - 42", Action: PlanActionExisting, ID: "3"}) {
+		t.Errorf("project section = %+v", decoded.ProjectSection)
+	}
+
+	expected := []PlannedProduct{
+		{Name: FormatProductName("base", 1), Action: PlanActionSkip, ID: "7", Quantity: 1},
+		{Name: FormatProductNameWithDir("gear", "arms", 2), Action: PlanActionCreate, Quantity: 2, Dir: "arms"},
+	}
+	if !reflect.DeepEqual(decoded.Products, expected) {
+		t.Errorf("products = %+v, want %+v", decoded.Products, expected)
+	}
+}
+
+func TestDryRunPlanNewSections(t *testing.T) {
+	catalog := &fakeCatalog{}
+	plan := &DryRunPlan{}
+	server := httptest.NewServer(catalog.handler(t))
+	defer server.Close()
+	client := NewClient(server.URL, WithHTTPClient(server.Client()), WithDryRunPlan(plan))
+
+	var err error
+	captureStdout(t, func() {
+		var customerID string
+		if customerID, err = client.EnsureCustomerSection("ACME", "14", true, false); err != nil {
+			return
+		}
+		_, err = client.EnsureProjectSection("Robot", "42", customerID, "14", true)
+	})
+	if err != nil {
+		t.Fatalf("dry run error = %v", err)
+	}
+
+	for name, section := range map[string]*PlannedSection{"companies": plan.CompaniesFolder, "customer": plan.CustomerSection, "project": plan.ProjectSection} {
+		if section == nil || section.Action != PlanActionNew || section.ID != "" {
+			t.Errorf("%s section = %+v, want new without ID", name, section)
+		}
+	}
+}
+
+func TestCreateDealProductRowsWithRounding(t *testing.T) {
+	products := []ProductInfo{
+		{ID: "1", Name: "Изделие gear", Quantity: 2},
+		{ID: "2", Name: "Изделие bracket", Quantity: 2.5},
+		{ID: "3", Name: "Изделие clip", Quantity: 0.4},
+		{ID: "4", Name: "Изделие cover", Quantity: 3.2},
+	}
+
+	tests := []struct {
+		rounding QuantityRounding
+		want     []float64
+	}{
+		{QuantityRoundCeil, []float64{2, 3, 1, 4}},
+		{QuantityRoundRound, []float64{2, 3, 1, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.rounding), func(t *testing.T) {
+			rows, err := CreateDealProductRowsWithRounding(products, tt.rounding)
+			if err != nil {
+				t.Fatalf("CreateDealProductRowsWithRounding() error = %v", err)
+			}
+			for i, row := range rows {
+				if row.Quantity != tt.want[i] || row.ProductID != ProductIDString(products[i].ID) {
+					t.Errorf("row %d = %+v, want product %s quantity %v", i, row, products[i].ID, tt.want[i])
+				}
+			}
+		})
+	}
+
+	t.Run("error", func(t *testing.T) {
+		rows, err := CreateDealProductRowsWithRounding(products, QuantityRoundError)
+		if err == nil {
+			t.Fatalf("expected error for fractional quantities, got rows %+v", rows)
+		}
+		for _, want := range []string{"3 product(s)", "Изделие bracket (2.5)", "Изделие clip (0.4)", "Изделие cover (3.2)"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("error %q should contain %q", err, want)
+			}
+		}
+		if strings.Contains(err.Error(), "gear") {
+			t.Errorf("error %q should not list integer quantities", err)
+		}
+	})
+
+	t.Run("integer quantities pass unchanged", func(t *testing.T) {
+		whole := []ProductInfo{{ID: "1", Quantity: 2}, {ID: "2", Quantity: 1}}
+		rows, err := CreateDealProductRowsWithRounding(whole, QuantityRoundError)
+		if err != nil {
+			t.Fatalf("CreateDealProductRowsWithRounding() error = %v", err)
+		}
+		if !reflect.DeepEqual(rows, CreateDealProductRows(whole)) {
+			t.Errorf("rows = %+v, want %+v", rows, CreateDealProductRows(whole))
+		}
+	})
+}
+
+func TestParseQuantityRounding(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    QuantityRounding
+		wantErr bool
+	}{
+		{"", QuantityRoundError, false},
+		{"error", QuantityRoundError, false},
+		{"CEIL", QuantityRoundCeil, false},
+		{" round ", QuantityRoundRound, false},
+		{"floor", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuantityRounding(tt.value)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseQuantityRounding(%q) = (%q, %v), want %q (error %v)", tt.value, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
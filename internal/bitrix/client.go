@@ -7,6 +7,8 @@ import (
 	"io"
 	"net/http"
 	"net/url"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -14,18 +16,197 @@ import (
 type Client struct {
 	webhookURL string
 	httpClient *http.Client
+	logger     Logger
+
+	// sectionCache keeps catalog.section.list results per catalog ID for the client's lifetime
+	// (invalidated by CreateSection/MoveSection); nil disables caching
+	sectionCache map[string][]ProductSection
+
+	// entityCache keeps GetUser, GetCompany and GetContact results keyed by "type:ID"
+	// for the client's lifetime; nil disables caching
+	entityCache map[string]interface{}
+
+	// concurrency is the number of products created in parallel (see WithConcurrency)
+	concurrency int
+
+	// dryRunPlan collects dry-run decisions (see WithDryRunPlan); nil disables collecting
+	dryRunPlan *DryRunPlan
+
+	// oauth authenticates requests with an access token instead of the webhook URL (see NewOAuthClient)
+	oauth *oauthCredentials
+
+	// timeout overrides the HTTP client timeout when set (see WithTimeout)
+	timeout time.Duration
+
+	// maxRetries is how many times a request failing with a network error, 429 or 5xx is repeated
+	// (see WithMaxRetries); retryBackoff is the delay before the first retry
+	maxRetries   int
+	retryBackoff time.Duration
+
+	// limiter spaces requests (see WithRateLimit); nil means no limit
+	limiter *rateLimiter
+}
+
+// ClientOption configures optional Client settings
+type ClientOption func(*Client)
+
+// WithHTTPClient replaces the default HTTP client (e.g. to point tests at an httptest.Server
+// or to plug in a custom http.RoundTripper)
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *Client) {
+		if httpClient != nil {
+			c.httpClient = httpClient
+		}
+	}
+}
+
+// WithLogger replaces the default logger (info level to stderr)
+func WithLogger(logger Logger) ClientOption {
+	return func(c *Client) {
+		if logger != nil {
+			c.logger = logger
+		}
+	}
+}
+
+// WithoutSectionCache disables caching of ListSections results (every call hits the API)
+func WithoutSectionCache() ClientOption {
+	return func(c *Client) {
+		c.sectionCache = nil
+	}
+}
+
+// WithoutEntityCache disables caching of GetUser, GetCompany and GetContact results
+func WithoutEntityCache() ClientOption {
+	return func(c *Client) {
+		c.entityCache = nil
+	}
+}
+
+// WithConcurrency sets how many catalog products are created in parallel (default 1 - serially).
+// Every request still goes through makeRequest, values below 1 are ignored
+func WithConcurrency(workers int) ClientOption {
+	return func(c *Client) {
+		if workers > 0 {
+			c.concurrency = workers
+		}
+	}
+}
+
+// WithTimeout sets the timeout of every HTTP request (default 30s). It also applies to a client
+// passed with WithHTTPClient, which is copied rather than modified; values below or equal to 0 are ignored
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *Client) {
+		if timeout > 0 {
+			c.timeout = timeout
+		}
+	}
+}
+
+// WithMaxRetries repeats a request up to retries times when it is rate limited (HTTP 429,
+// QUERY_LIMIT_EXCEEDED) or the connection fails, waiting 1s, 2s, 4s... between attempts.
+// Other network errors and 5xx are retried only for read methods (*.get, *.list), see isRetryable.
+// Default 0 - no retries; negative values are ignored
+func WithMaxRetries(retries int) ClientOption {
+	return func(c *Client) {
+		if retries >= 0 {
+			c.maxRetries = retries
+		}
+	}
+}
+
+// WithRateLimit limits requests to requestsPerSecond across all goroutines using the client
+// (Bitrix24 allows about 2 per second per portal). Default 0 - no limit; values below or equal to 0 disable it
+func WithRateLimit(requestsPerSecond float64) ClientOption {
+	return func(c *Client) {
+		c.limiter = nil
+		if requestsPerSecond > 0 {
+			c.limiter = newRateLimiter(requestsPerSecond)
+		}
+	}
+}
+
+// WithDryRunPlan makes dry-run calls of the Ensure* and CreateProducts* methods record their
+// decisions (existing or new sections, products to create or skip) into plan
+func WithDryRunPlan(plan *DryRunPlan) ClientOption {
+	return func(c *Client) {
+		c.dryRunPlan = plan
+	}
 }
 
 // NewClient creates a new Bitrix24 client
-func NewClient(webhookURL string) *Client {
+// Trailing slashes are trimmed from the webhook URL, use NewClientValidated to also check its format
+func NewClient(webhookURL string, opts ...ClientOption) *Client {
+	client := newClient(webhookURL)
+	client.apply(opts)
+	return client
+}
+
+// apply applies options in order, then settings that depend on several of them
+func (c *Client) apply(opts []ClientOption) {
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	if c.timeout > 0 && c.httpClient.Timeout != c.timeout {
+		httpClient := *c.httpClient
+		httpClient.Timeout = c.timeout
+		c.httpClient = &httpClient
+	}
+}
+
+// newClient creates a client with default settings, before options are applied
+func newClient(webhookURL string) *Client {
 	return &Client{
-		webhookURL: webhookURL,
+		webhookURL: normalizeWebhookURL(webhookURL),
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: defaultTimeout,
 		},
+		logger:       defaultLogger(),
+		sectionCache: make(map[string][]ProductSection),
+		entityCache:  make(map[string]interface{}),
+		concurrency:  1,
+		retryBackoff: defaultRetryBackoff,
 	}
 }
 
+// NewClientValidated creates a new Bitrix24 client after checking the webhook URL with ValidateWebhookURL
+func NewClientValidated(webhookURL string, opts ...ClientOption) (*Client, error) {
+	if err := ValidateWebhookURL(webhookURL); err != nil {
+		return nil, err
+	}
+	return NewClient(webhookURL, opts...), nil
+}
+
+// ValidateWebhookURL checks that the webhook URL is an absolute http(s) URL with a /rest/ segment,
+// e.g. https://your-domain.bitrix24.ru/rest/1/your-webhook-code/
+func ValidateWebhookURL(webhookURL string) error {
+	normalized := normalizeWebhookURL(webhookURL)
+	if normalized == "" {
+		return fmt.Errorf("webhook URL cannot be empty")
+	}
+
+	parsed, err := url.Parse(normalized)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL %q: %v", webhookURL, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return fmt.Errorf("invalid webhook URL %q: scheme must be http or https", webhookURL)
+	}
+	if parsed.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q: missing host", webhookURL)
+	}
+	if !strings.Contains(parsed.Path+"/", "/rest/") {
+		return fmt.Errorf("invalid webhook URL %q: missing /rest/ segment", webhookURL)
+	}
+	return nil
+}
+
+// normalizeWebhookURL trims whitespace and trailing slashes so that method names can be appended with "/"
+func normalizeWebhookURL(webhookURL string) string {
+	return strings.TrimRight(strings.TrimSpace(webhookURL), "/")
+}
+
 // GetWebhookURL returns the webhook URL (for internal use)
 func (c *Client) GetWebhookURL() string {
 	return c.webhookURL
@@ -122,7 +303,7 @@ func (c *Client) makeRequest(method string, params map[string]interface{}) (*htt
 					} else {
 						jsonValue, err := json.Marshal(item)
 						if err != nil {
-							return nil, fmt.Errorf("failed to marshal row item: %v", err)
+							return nil, fmt.Errorf("failed to marshal row item: %w", err)
 						}
 						formData.Add("rows[]", string(jsonValue))
 					}
@@ -151,28 +332,42 @@ func (c *Client) makeRequest(method string, params map[string]interface{}) (*htt
 		}
 	}
 
-	// Log the request for debugging (remove in production)
-	// fmt.Printf("DEBUG: %s request to %s\n", "POST", requestURL)
-	// fmt.Printf("DEBUG: Form data: %s\n", formData.Encode())
+	// The webhook URL holds the access token and form values carry customer data,
+	// so only the method, the portal host and the field names are logged
+	c.logger.Debug("POST request to %s on %s", method, c.portalHost())
+	c.logger.Debug("Form fields: %s", strings.Join(formKeys(formData), ", "))
 	
-	req, err := http.NewRequest("POST", requestURL, bytes.NewBufferString(formData.Encode()))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
-	}
-
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
 	
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.doRequest(method, requestURL, []byte(formData.Encode()), header)
 	if err != nil {
 		return nil, err
 	}
 	
-	// Log response status (remove in production)
-	// fmt.Printf("DEBUG: Response status: %d\n", resp.StatusCode)
+	c.logger.Debug("Response status: %d", resp.StatusCode)
 	
 	return resp, nil
 }
 
+// portalHost returns the host of the webhook URL without the user ID and token path
+func (c *Client) portalHost() string {
+	if parsed, err := url.Parse(c.webhookURL); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return "Bitrix24"
+}
+
+// formKeys returns sorted form field names (e.g. "fields[TITLE]") without their values
+func formKeys(formData url.Values) []string {
+	keys := make([]string, 0, len(formData))
+	for key := range formData {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // makeJSONRequest makes an HTTP request to Bitrix24 API with JSON payload
 func (c *Client) makeJSONRequest(method string, params map[string]interface{}) (*http.Response, error) {
 	requestURL := fmt.Sprintf("%s/%s", c.webhookURL, method)
@@ -180,23 +375,72 @@ func (c *Client) makeJSONRequest(method string, params map[string]interface{}) (
 	// Prepare JSON payload
 	jsonPayload, err := json.Marshal(params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal params: %v", err)
+		return nil, fmt.Errorf("failed to marshal params: %w", err)
 	}
 
-	req, err := http.NewRequest("POST", requestURL, bytes.NewBuffer(jsonPayload))
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("Accept", "application/json")
+
+	return c.doRequest(method, requestURL, jsonPayload, header)
+}
+
+// doRequest POSTs payload to requestURL. OAuth clients add the access token to the URL
+// and repeat the request once after refreshing an expired token (see NewOAuthClient)
+func (c *Client) doRequest(method, requestURL string, payload []byte, header http.Header) (*http.Response, error) {
+	if c.oauth == nil {
+		return c.post(method, requestURL, payload, header)
+	}
+
+	token := c.oauth.currentToken()
+	resp, err := c.post(method, withAuth(requestURL, token), payload, header)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %v", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
+	expired, err := isTokenExpired(resp)
+	if err != nil || !expired {
+		return resp, err
+	}
+	resp.Body.Close()
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
+	c.logger.Debug("Access token expired, refreshing")
+	if err := c.refreshAccessToken(token); err != nil {
 		return nil, err
 	}
+	return c.post(method, withAuth(requestURL, c.oauth.currentToken()), payload, header)
+}
 
-	return resp, nil
+// post sends a POST request with the given headers, waiting for the rate limiter and
+// repeating it up to maxRetries times while the failure of method is retryable (see isRetryable)
+func (c *Client) post(method, requestURL string, payload []byte, header http.Header) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if c.limiter != nil {
+			c.limiter.wait()
+		}
+
+		req, err := http.NewRequest("POST", requestURL, bytes.NewReader(payload))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header = header
+
+		resp, err := c.httpClient.Do(req)
+		if attempt >= c.maxRetries || !isRetryable(method, resp, err) {
+			if err != nil {
+				return nil, &TransportError{Err: err}
+			}
+			return resp, nil
+		}
+
+		reason := retryReason(resp, err)
+		if resp != nil {
+			resp.Body.Close()
+		}
+		delay := c.retryDelay(attempt + 1)
+		c.logger.Warn("Request %s failed (%s), retrying in %v (%d of %d)", method, reason, delay, attempt+1, c.maxRetries)
+		time.Sleep(delay)
+	}
 }
 
 // ParseResponse parses HTTP response into a generic BitrixResponse (public for testing)
@@ -205,35 +449,28 @@ func (c *Client) ParseResponse(resp *http.Response, target interface{}) error {
 }
 
 // parseResponse parses HTTP response into a generic BitrixResponse
+// Errors are *APIError (reported by Bitrix24) or *TransportError (bad status or non-JSON body)
 func (c *Client) parseResponse(resp *http.Response, target interface{}) error {
 	defer resp.Body.Close()
 	
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %v", err)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
+		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	var bitrixResp BitrixResponse
-	if err := json.Unmarshal(body, &bitrixResp); err != nil {
-		return fmt.Errorf("failed to unmarshal response: %v", err)
-	}
-
-	if bitrixResp.Error != nil {
-		return fmt.Errorf("Bitrix24 API error %s: %s", bitrixResp.Error.ErrorCode, bitrixResp.Error.ErrorDescription)
+	bitrixResp, err := decodeResponse(resp.StatusCode, body)
+	if err != nil {
+		return err
 	}
 
 	// Marshal the result back to JSON and unmarshal into target
 	resultJSON, err := json.Marshal(bitrixResp.Result)
 	if err != nil {
-		return fmt.Errorf("failed to marshal result: %v", err)
+		return fmt.Errorf("failed to marshal result: %w", err)
 	}
 
 	if err := json.Unmarshal(resultJSON, target); err != nil {
-		return fmt.Errorf("failed to unmarshal result into target: %v", err)
+		return fmt.Errorf("failed to unmarshal result into target: %w", err)
 	}
 
 	return nil
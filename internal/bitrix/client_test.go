@@ -0,0 +1,480 @@
+package bitrix
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// roundTripFunc adapts a function to http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// newTestClient starts a server answering each API method with a canned JSON body
+// and returns a client pointed at it
+func newTestClient(t *testing.T, responses map[string]string) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := strings.TrimPrefix(r.URL.Path, "/")
+		body, ok := responses[method]
+		if !ok {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL, WithHTTPClient(server.Client()))
+}
+
+// countingTransport answers each API method with a canned JSON body like newTestClient
+// and counts requests per method, e.g. to check what a cache saves
+func countingTransport(t *testing.T, counts map[string]int, responses map[string]string) *http.Client {
+	t.Helper()
+
+	return &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+			counts[method]++
+
+			body, ok := responses[method]
+			if !ok {
+				t.Errorf("unexpected request: %s", req.URL.Path)
+				return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader(""))}, nil
+			}
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(body)),
+			}, nil
+		}),
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	var requestedURL string
+	httpClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requestedURL = req.URL.String()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"result":{"ID":"1","TITLE":"Injected"}}`)),
+			}, nil
+		}),
+	}
+
+	client := NewClient("https://example.bitrix24.ru/rest/1/token", WithHTTPClient(httpClient))
+	deal, err := client.GetDeal("1")
+	if err != nil {
+		t.Fatalf("GetDeal() error = %v", err)
+	}
+
+	if deal.Title != "Injected" {
+		t.Errorf("expected response from injected transport, got title %q", deal.Title)
+	}
+	if requestedURL != "https://example.bitrix24.ru/rest/1/token/crm.deal.get" {
+		t.Errorf("unexpected request URL: %s", requestedURL)
+	}
+}
+
+func TestWithHTTPClientNilKeepsDefault(t *testing.T) {
+	client := NewClient("https://example.bitrix24.ru/rest/1/token", WithHTTPClient(nil))
+	if client.httpClient == nil {
+		t.Fatal("expected default HTTP client to be kept")
+	}
+}
+
+func TestNewClientTrimsTrailingSlash(t *testing.T) {
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result":{"ID":"1"}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/rest/10/key//", WithHTTPClient(server.Client()))
+	if got, want := client.GetWebhookURL(), server.URL+"/rest/10/key"; got != want {
+		t.Errorf("GetWebhookURL() = %q, want %q", got, want)
+	}
+
+	resp, err := client.makeRequest("crm.deal.get", map[string]interface{}{"id": "1"})
+	if err != nil {
+		t.Fatalf("makeRequest() error = %v", err)
+	}
+	resp.Body.Close()
+
+	if requestedPath != "/rest/10/key/crm.deal.get" {
+		t.Errorf("requested path = %q, want /rest/10/key/crm.deal.get", requestedPath)
+	}
+	if got := client.GetDealURL("42"); got != server.URL+"/crm/deal/details/42/" {
+		t.Errorf("GetDealURL() = %q", got)
+	}
+}
+
+func TestNewClientValidated(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		wantErr string
+	}{
+		{"valid", "https://farmix.bitrix24.ru/rest/10/key", ""},
+		{"trailing slash", "https://farmix.bitrix24.ru/rest/10/key/", ""},
+		{"empty", "", "cannot be empty"},
+		{"missing scheme", "farmix.bitrix24.ru/rest/10/key/", "scheme must be http or https"},
+		{"unsupported scheme", "ftp://farmix.bitrix24.ru/rest/10/key/", "scheme must be http or https"},
+		{"missing host", "https:///rest/10/key/", "missing host"},
+		{"missing rest segment", "https://farmix.bitrix24.ru/10/key/", "missing /rest/ segment"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClientValidated(tt.url)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("NewClientValidated() error = %v", err)
+				}
+				if strings.HasSuffix(client.GetWebhookURL(), "/") {
+					t.Errorf("webhook URL %q keeps trailing slash", client.GetWebhookURL())
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("NewClientValidated() error = %v, want %q", err, tt.wantErr)
+			}
+			if client != nil {
+				t.Error("expected nil client on error")
+			}
+		})
+	}
+}
+
+func TestParseResponseErrors(t *testing.T) {
+	gatewayPage := "<html>\n<head><title>502 Bad Gateway</title></head>\n<body>" + strings.Repeat("nginx ", 100) + "</body>\n</html>"
+
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantAPI     bool
+		wantStatus  int
+		wantMessage string
+	}{
+		{"502 HTML page", http.StatusBadGateway, gatewayPage, false, http.StatusBadGateway, "HTTP error 502: <html> <head><title>502 Bad Gateway</title>"},
+		{"200 API error", http.StatusOK, `{"error":"ACCESS_DENIED","error_description":"Access denied"}`, true, http.StatusOK, "Bitrix24 API error ACCESS_DENIED: Access denied"},
+		{"401 API error", http.StatusUnauthorized, `{"error":"expired_token","error_description":"The access token provided has expired"}`, true, http.StatusUnauthorized, "expired_token"},
+		{"200 non-JSON body", http.StatusOK, "maintenance", false, http.StatusOK, "body: maintenance"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Body: io.NopCloser(strings.NewReader(tt.body))}
+			var result interface{}
+			err := NewClient("https://example.bitrix24.ru/rest/1/token").ParseResponse(resp, &result)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if !strings.Contains(err.Error(), tt.wantMessage) {
+				t.Errorf("error %q should contain %q", err, tt.wantMessage)
+			}
+
+			var apiErr *APIError
+			var transportErr *TransportError
+			switch {
+			case tt.wantAPI && errors.As(err, &apiErr):
+				if apiErr.StatusCode != tt.wantStatus {
+					t.Errorf("APIError.StatusCode = %d, want %d", apiErr.StatusCode, tt.wantStatus)
+				}
+			case !tt.wantAPI && errors.As(err, &transportErr):
+				if transportErr.StatusCode != tt.wantStatus {
+					t.Errorf("TransportError.StatusCode = %d, want %d", transportErr.StatusCode, tt.wantStatus)
+				}
+				if len([]rune(transportErr.Body)) > maxErrorBodySnippet+len("...") {
+					t.Errorf("body snippet is not truncated: %d characters", len([]rune(transportErr.Body)))
+				}
+			default:
+				t.Errorf("error %T (%v) has wrong type, want API error = %v", err, err, tt.wantAPI)
+			}
+		})
+	}
+}
+
+func TestTransportErrorThroughCaller(t *testing.T) {
+	client := NewClient("https://example.bitrix24.ru/rest/1/token", WithHTTPClient(&http.Client{
+		Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("connection reset")
+		}),
+	}))
+
+	_, err := client.GetDeal("1")
+	var transportErr *TransportError
+	if !errors.As(err, &transportErr) || transportErr.StatusCode != 0 {
+		t.Fatalf("GetDeal() error = %v, want wrapped *TransportError without status", err)
+	}
+	if !strings.Contains(err.Error(), "connection reset") {
+		t.Errorf("error %q should keep the transport failure", err)
+	}
+}
+
+func TestAPIErrorCodes(t *testing.T) {
+	sentinels := []error{ErrAccessDenied, ErrQueryLimitExceeded, ErrNotFound, ErrInvalidCredentials}
+
+	tests := []struct {
+		code string
+		want error
+	}{
+		{"ACCESS_DENIED", ErrAccessDenied},
+		{"insufficient_scope", ErrAccessDenied},
+		{"QUERY_LIMIT_EXCEEDED", ErrQueryLimitExceeded},
+		{"NOT_FOUND", ErrNotFound},
+		{"ERROR_NOT_FOUND", ErrNotFound},
+		{"INVALID_CREDENTIALS", ErrInvalidCredentials},
+		{"expired_token", ErrInvalidCredentials},
+		{"ERROR_CORE", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			err := fmt.Errorf("failed to get store: %w", &APIError{Code: tt.code})
+			for _, sentinel := range sentinels {
+				if got := errors.Is(err, sentinel); got != (sentinel == tt.want) {
+					t.Errorf("errors.Is(%s, %v) = %v, want %v", tt.code, sentinel, got, !got)
+				}
+			}
+		})
+	}
+}
+
+func TestRawAPIErrorMatchesSentinel(t *testing.T) {
+	client := newTestClient(t, map[string]string{
+		"catalog.store.list": `{"error":"ACCESS_DENIED","error_description":"Access denied"}`,
+	})
+
+	_, err := client.ListStores()
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("ListStores() error = %v, want ErrAccessDenied", err)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	client := NewClient("https://example.bitrix24.ru/rest/1/token")
+	if client.httpClient.Timeout != defaultTimeout {
+		t.Errorf("default timeout = %v, want %v", client.httpClient.Timeout, defaultTimeout)
+	}
+
+	client = NewClient("https://example.bitrix24.ru/rest/1/token", WithTimeout(5*time.Second))
+	if client.httpClient.Timeout != 5*time.Second {
+		t.Errorf("timeout = %v, want 5s", client.httpClient.Timeout)
+	}
+
+	// The timeout applies to an injected client too, without modifying it
+	transport := roundTripFunc(func(req *http.Request) (*http.Response, error) { return nil, errors.New("unused") })
+	custom := &http.Client{Transport: transport, Timeout: time.Minute}
+	client = NewClient("https://example.bitrix24.ru/rest/1/token", WithTimeout(2*time.Second), WithHTTPClient(custom))
+	if client.httpClient.Timeout != 2*time.Second {
+		t.Errorf("timeout with injected client = %v, want 2s", client.httpClient.Timeout)
+	}
+	if client.httpClient.Transport == nil || custom.Timeout != time.Minute {
+		t.Errorf("injected client should be copied with its transport, got timeout %v on the original", custom.Timeout)
+	}
+}
+
+// newFlakyServer answers the first failures requests with status and the rest with a deal
+func newFlakyServer(t *testing.T, failures, status int) (*httptest.Server, *int) {
+	t.Helper()
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests <= failures {
+			w.WriteHeader(status)
+			w.Write([]byte(`{"error":"QUERY_LIMIT_EXCEEDED","error_description":"Too many requests"}`))
+			return
+		}
+		w.Write([]byte(`{"result":{"ID":"1","TITLE":"Retried"}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestWithMaxRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []ClientOption
+		failures     int
+		status       int
+		wantErr      bool
+		wantRequests int
+	}{
+		{"default does not retry", nil, 1, http.StatusServiceUnavailable, true, 1},
+		{"retries until success", []ClientOption{WithMaxRetries(2)}, 2, http.StatusServiceUnavailable, false, 3},
+		{"gives up after max retries", []ClientOption{WithMaxRetries(1)}, 3, http.StatusServiceUnavailable, true, 2},
+		{"retries 429", []ClientOption{WithMaxRetries(1)}, 1, http.StatusTooManyRequests, false, 2},
+		{"does not retry 4xx API errors", []ClientOption{WithMaxRetries(3)}, 1, http.StatusBadRequest, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := newFlakyServer(t, tt.failures, tt.status)
+			client := NewClient(server.URL, append([]ClientOption{WithHTTPClient(server.Client()), WithLogger(NewLogger(io.Discard, LogLevelInfo))}, tt.opts...)...)
+			client.retryBackoff = time.Millisecond
+
+			deal, err := client.GetDeal("1")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetDeal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && deal.Title != "Retried" {
+				t.Errorf("deal title = %q, want Retried", deal.Title)
+			}
+			if *requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", *requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestWithMaxRetriesNetworkError(t *testing.T) {
+	attempts := 0
+	httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		attempts++
+		body, _ := io.ReadAll(req.Body)
+		if string(body) != "id=1" {
+			t.Errorf("attempt %d body = %q, want the payload resent", attempts, body)
+		}
+		if attempts == 1 {
+			return nil, errors.New("connection reset")
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       io.NopCloser(strings.NewReader(`{"result":{"ID":"1","TITLE":"Retried"}}`)),
+		}, nil
+	})}
+
+	client := NewClient("https://example.bitrix24.ru/rest/1/token", WithHTTPClient(httpClient), WithMaxRetries(1), WithLogger(NewLogger(io.Discard, LogLevelInfo)))
+	client.retryBackoff = time.Millisecond
+	if _, err := client.GetDeal("1"); err != nil {
+		t.Fatalf("GetDeal() error = %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want 2", attempts)
+	}
+}
+
+func TestWithMaxRetriesWriteMethod(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		body         string
+		wantErr      bool
+		wantRequests int
+	}{
+		{"retries QUERY_LIMIT_EXCEEDED", http.StatusServiceUnavailable, `{"error":"QUERY_LIMIT_EXCEEDED","error_description":"Too many requests"}`, false, 2},
+		{"retries 429", http.StatusTooManyRequests, ``, false, 2},
+		{"does not retry other 5xx", http.StatusInternalServerError, `{"error":"INTERNAL_SERVER_ERROR","error_description":"Internal error"}`, true, 1},
+		{"does not retry gateway errors", http.StatusBadGateway, `<html>Bad Gateway</html>`, true, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests == 1 {
+					w.WriteHeader(tt.status)
+					w.Write([]byte(tt.body))
+					return
+				}
+				w.Write([]byte(`{"result":true}`))
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL, WithHTTPClient(server.Client()), WithMaxRetries(3), WithLogger(NewLogger(io.Discard, LogLevelInfo)))
+			client.retryBackoff = time.Millisecond
+
+			err := client.UpdateDealFields("1", map[string]interface{}{"TITLE": "Order"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpdateDealFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestWithMaxRetriesWriteMethodNetworkError(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantAttempts int
+	}{
+		// The connection was not established, so the request was never sent
+		{"retries dial errors", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, 2},
+		// The request may have reached Bitrix24, repeating it could apply the update twice
+		{"does not retry after sending", errors.New("connection reset"), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			httpClient := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				attempts++
+				if attempts == 1 {
+					return nil, tt.err
+				}
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Body:       io.NopCloser(strings.NewReader(`{"result":true}`)),
+				}, nil
+			})}
+
+			var logs bytes.Buffer
+			client := NewClient("https://example.bitrix24.ru/rest/1/secret-token", WithHTTPClient(httpClient), WithMaxRetries(1), WithLogger(NewLogger(&logs, LogLevelInfo)))
+			client.retryBackoff = time.Millisecond
+
+			client.UpdateDealFields("1", map[string]interface{}{"TITLE": "Order"})
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if strings.Contains(logs.String(), "secret-token") {
+				t.Errorf("retry log contains the webhook token: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	if client := NewClient("https://example.bitrix24.ru/rest/1/token"); client.limiter != nil {
+		t.Error("default client should not limit requests")
+	}
+	if client := NewClient("https://example.bitrix24.ru/rest/1/token", WithRateLimit(10), WithRateLimit(0)); client.limiter != nil {
+		t.Error("WithRateLimit(0) should disable the limit")
+	}
+
+	client := newTestClient(t, map[string]string{"crm.deal.get": `{"result":{"ID":"1"}}`})
+	WithRateLimit(20)(client) // 50ms between requests
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetDeal("1"); err != nil {
+			t.Fatalf("GetDeal() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("3 requests at 20/s took %v, want at least 100ms", elapsed)
+	}
+}
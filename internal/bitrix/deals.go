@@ -1,8 +1,10 @@
 package bitrix
 
 import (
+	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -15,12 +17,12 @@ func (c *Client) GetDeal(dealID string) (*Deal, error) {
 
 	resp, err := c.makeRequest("crm.deal.get", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get deal: %v", err)
+		return nil, fmt.Errorf("failed to get deal: %w", err)
 	}
 
 	var deal Deal
 	if err := c.parseResponse(resp, &deal); err != nil {
-		return nil, fmt.Errorf("failed to parse deal response: %v", err)
+		return nil, fmt.Errorf("failed to parse deal response: %w", err)
 	}
 
 	return &deal, nil
@@ -35,13 +37,13 @@ func (c *Client) GetDealWithAmount(dealID string) (*Deal, error) {
 
 	resp, err := c.makeRequest("crm.deal.get", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get deal with amount: %v", err)
+		return nil, fmt.Errorf("failed to get deal with amount: %w", err)
 	}
 
 	// Parse into raw structure first
 	var dealRaw DealRaw
 	if err := c.parseResponse(resp, &dealRaw); err != nil {
-		return nil, fmt.Errorf("failed to parse deal with amount response: %v", err)
+		return nil, fmt.Errorf("failed to parse deal with amount response: %w", err)
 	}
 
 	// Convert to final Deal structure
@@ -72,87 +74,213 @@ func (c *Client) GetDealWithAmount(dealID string) (*Deal, error) {
 	return deal, nil
 }
 
+// GetDealFields retrieves the selected deal fields, including portal-specific custom fields
+// (UF_CRM_*), as returned by the API. Use DealFieldString to read a value
+func (c *Client) GetDealFields(dealID string, fields []string) (map[string]interface{}, error) {
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no deal fields to select")
+	}
+
+	params := map[string]interface{}{
+		"id":     dealID,
+		"select": fields,
+	}
+
+	resp, err := c.makeRequest("crm.deal.get", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get deal fields: %w", err)
+	}
+
+	var values map[string]interface{}
+	if err := c.parseResponse(resp, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse deal fields response: %w", err)
+	}
+
+	return values, nil
+}
+
+// DealFieldString returns a deal field value from GetDealFields as text. Strings are kept as is,
+// numbers and booleans are formatted like ParseCustomFieldValue and multiple-value fields are
+// joined with ", ". The second result is false when the field is missing or empty
+func DealFieldString(fields map[string]interface{}, name string) (string, bool) {
+	value := fieldText(fields[name])
+	return value, value != ""
+}
+
+// fieldText converts a raw field value to trimmed text
+func fieldText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return strings.TrimSpace(v)
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if text := fieldText(item); text != "" {
+				values = append(values, text)
+			}
+		}
+		return strings.Join(values, ", ")
+	default:
+		return ParseCustomFieldValue(v)
+	}
+}
+
 // GetContact retrieves contact information by ID
+// Results are cached for the client's lifetime (see WithoutEntityCache)
 func (c *Client) GetContact(contactID string) (*Contact, error) {
+	cacheKey := "contact:" + contactID
+	if cached, ok := c.entityCache[cacheKey].(Contact); ok {
+		return &cached, nil
+	}
+
 	params := map[string]interface{}{
 		"id": contactID,
 	}
 
 	resp, err := c.makeRequest("crm.contact.get", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get contact: %v", err)
+		return nil, fmt.Errorf("failed to get contact: %w", err)
 	}
 
 	var contact Contact
 	if err := c.parseResponse(resp, &contact); err != nil {
-		return nil, fmt.Errorf("failed to parse contact response: %v", err)
+		return nil, fmt.Errorf("failed to parse contact response: %w", err)
 	}
 
+	c.cacheEntity(cacheKey, contact)
 	return &contact, nil
 }
 
 // GetCompany retrieves company information by ID
+// Results are cached for the client's lifetime (see WithoutEntityCache)
 func (c *Client) GetCompany(companyID string) (*Company, error) {
+	cacheKey := "company:" + companyID
+	if cached, ok := c.entityCache[cacheKey].(Company); ok {
+		return &cached, nil
+	}
+
 	params := map[string]interface{}{
 		"id": companyID,
 	}
 
 	resp, err := c.makeRequest("crm.company.get", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get company: %v", err)
+		return nil, fmt.Errorf("failed to get company: %w", err)
 	}
 
 	var company Company
 	if err := c.parseResponse(resp, &company); err != nil {
-		return nil, fmt.Errorf("failed to parse company response: %v", err)
+		return nil, fmt.Errorf("failed to parse company response: %w", err)
 	}
 
+	c.cacheEntity(cacheKey, company)
 	return &company, nil
 }
 
 // GetUser retrieves user information by ID
+// Found users are cached for the client's lifetime (see WithoutEntityCache)
 func (c *Client) GetUser(userID string) (*User, error) {
+	cacheKey := "user:" + userID
+	if cached, ok := c.entityCache[cacheKey].(User); ok {
+		return &cached, nil
+	}
+
 	params := map[string]interface{}{
 		"id": userID,
 	}
 
 	resp, err := c.makeRequest("user.get", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %v", err)
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	// user.get returns an array, so parse it differently
 	var users []User
 	if err := c.parseResponse(resp, &users); err != nil {
-		return nil, fmt.Errorf("failed to parse user response: %v", err)
+		return nil, fmt.Errorf("failed to parse user response: %w", err)
 	}
 
 	if len(users) == 0 {
-		return nil, fmt.Errorf("user not found: %s", userID)
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, userID)
 	}
 
 	user := users[0]
+	user.FullName = userFullName(user)
+
+	c.cacheEntity(cacheKey, user)
+	return &user, nil
+}
 
-	// If FullName is not provided by API, construct it from Name and LastName
-	if user.FullName == "" && (user.Name != "" || user.LastName != "") {
-		user.FullName = strings.TrimSpace(user.Name + " " + user.LastName)
+// cacheEntity stores a copy of a looked up entity unless caching is disabled.
+// Values are stored by value so that callers cannot modify the cached entity
+func (c *Client) cacheEntity(key string, entity interface{}) {
+	if c.entityCache != nil {
+		c.entityCache[key] = entity
 	}
+}
 
-	return &user, nil
+// userFullName returns FullName when the API provides it, otherwise it is constructed
+// from Name and LastName, falling back to Login and then Email when name fields are empty
+func userFullName(user User) string {
+	if user.FullName != "" {
+		return user.FullName
+	}
+	if name := strings.TrimSpace(user.Name + " " + user.LastName); name != "" {
+		return name
+	}
+	if user.Login != "" {
+		return user.Login
+	}
+	return user.Email
 }
 
-// GetDealURL generates Bitrix24 deal URL
-func (c *Client) GetDealURL(dealID string) string {
-	// Extract base URL from webhook URL
-	// Example: https://farmix.bitrix24.ru/rest/10/jzz2ijynswg1nkur/ -> https://farmix.bitrix24.ru/
-	webhookURL := c.GetWebhookURL()
-	if strings.Contains(webhookURL, "/rest/") {
-		baseURL := webhookURL[:strings.Index(webhookURL, "/rest/")]
-		return fmt.Sprintf("%s/crm/deal/details/%s/", baseURL, dealID)
+// portalBaseURL extracts the portal base URL from the webhook URL
+// Example: https://farmix.bitrix24.ru/rest/10/jzz2ijynswg1nkur/ -> https://farmix.bitrix24.ru
+// (OAuth clients use https://{domain}/rest, see NewOAuthClient)
+func (c *Client) portalBaseURL() (string, bool) {
+	webhookURL := c.GetWebhookURL() + "/"
+	if index := strings.Index(webhookURL, "/rest/"); index >= 0 {
+		return webhookURL[:index], true
+	}
+	return "", false
+}
+
+// portalURL joins path to the portal base URL
+func (c *Client) portalURL(path string) string {
+	if baseURL, ok := c.portalBaseURL(); ok {
+		return baseURL + path
 	}
 	
 	// Fallback if webhook URL format is unexpected
-	return fmt.Sprintf("https://bitrix24.com/crm/deal/details/%s/", dealID)
+	return "https://bitrix24.com" + path
+}
+
+// GetDealURL generates Bitrix24 deal URL
+func (c *Client) GetDealURL(dealID string) string {
+	return c.portalURL(fmt.Sprintf("/crm/deal/details/%s/", dealID))
+}
+
+// GetProductURL generates Bitrix24 catalog product card URL
+// Catalog pages are addressed by the catalog (iblock) ID as well as by the product ID
+func (c *Client) GetProductURL(catalogID, productID string) string {
+	return c.portalURL(fmt.Sprintf("/crm/catalog/%s/product/%s/", catalogID, productID))
+}
+
+// GetSectionURL generates Bitrix24 catalog section (folder) URL
+func (c *Client) GetSectionURL(catalogID, sectionID string) string {
+	return c.portalURL(fmt.Sprintf("/crm/catalog/%s/section/%s/", catalogID, sectionID))
+}
+
+// GetUserURL generates Bitrix24 user profile URL
+// Returns false when the portal is unknown (unexpected webhook URL) or user ID is empty
+func (c *Client) GetUserURL(userID string) (string, bool) {
+	baseURL, ok := c.portalBaseURL()
+	if !ok || userID == "" || userID == "0" {
+		return "", false
+	}
+	return fmt.Sprintf("%s/company/personal/user/%s/", baseURL, userID), true
 }
 
 // GetCustomerName retrieves customer name for a deal
@@ -181,6 +309,85 @@ func (c *Client) GetCustomerName(deal *Deal) (string, error) {
 	return fmt.Sprintf("Deal_%s", deal.ID), nil
 }
 
+// UpdateDealFields updates deal fields via crm.deal.update
+// fields maps Bitrix24 field codes (e.g. UF_CRM_XXXXX) to their new values
+func (c *Client) UpdateDealFields(dealID string, fields map[string]interface{}) error {
+	if len(fields) == 0 {
+		return fmt.Errorf("no fields to update for deal %s", dealID)
+	}
+
+	params := map[string]interface{}{
+		"id":     dealID,
+		"fields": fields,
+	}
+
+	resp, err := c.makeRequest("crm.deal.update", params)
+	if err != nil {
+		return fmt.Errorf("failed to update deal: %w", err)
+	}
+
+	var result bool
+	if err := c.parseResponse(resp, &result); err != nil {
+		return fmt.Errorf("failed to parse update deal response: %w", err)
+	}
+
+	if !result {
+		return fmt.Errorf("failed to update deal: API returned false")
+	}
+
+	return nil
+}
+
+// UpdateDealCosts writes computed costs to the deal custom fields configured in fieldCodes
+// Money values are sent as "amount|currency" when currency is specified
+func (c *Client) UpdateDealCosts(dealID string, fieldCodes ReportCustomFields, costs DealCosts, currency string) error {
+	fields, err := buildDealCostFields(fieldCodes, costs, currency)
+	if err != nil {
+		return err
+	}
+
+	return c.UpdateDealFields(dealID, fields)
+}
+
+// buildDealCostFields maps logical cost names to configured UF_CRM field codes
+func buildDealCostFields(fieldCodes ReportCustomFields, costs DealCosts, currency string) (map[string]interface{}, error) {
+	mappings := []struct {
+		name  string
+		code  string
+		value *float64
+	}{
+		{"machine_cost", fieldCodes.MachineCost, costs.MachineCost},
+		{"human_cost", fieldCodes.HumanCost, costs.HumanCost},
+		{"material_cost", fieldCodes.MaterialCost, costs.MaterialCost},
+		{"total_cost", fieldCodes.TotalCost, costs.TotalCost},
+	}
+
+	fields := make(map[string]interface{})
+	for _, m := range mappings {
+		if m.value == nil {
+			continue
+		}
+		if m.code == "" {
+			return nil, fmt.Errorf("field code for %s is not configured", m.name)
+		}
+		fields[m.code] = formatMoneyValue(*m.value, currency)
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no cost values to update")
+	}
+
+	return fields, nil
+}
+
+// formatMoneyValue formats amount for Bitrix24 money fields
+func formatMoneyValue(amount float64, currency string) string {
+	if currency == "" {
+		return strconv.FormatFloat(amount, 'f', 2, 64)
+	}
+	return fmt.Sprintf("%.2f|%s", amount, currency)
+}
+
 // AddProductsToDeal adds products to a deal
 func (c *Client) AddProductsToDeal(dealID string, products []DealProductRow) error {
 	// Convert DealProductRow slice to []interface{} with map[string]interface{} elements
@@ -200,12 +407,12 @@ func (c *Client) AddProductsToDeal(dealID string, products []DealProductRow) err
 
 	resp, err := c.makeRequest("crm.deal.productrows.set", params)
 	if err != nil {
-		return fmt.Errorf("failed to add products to deal: %v", err)
+		return fmt.Errorf("failed to add products to deal: %w", err)
 	}
 
 	var result bool
 	if err := c.parseResponse(resp, &result); err != nil {
-		return fmt.Errorf("failed to parse add products response: %v", err)
+		return fmt.Errorf("failed to parse add products response: %w", err)
 	}
 
 	if !result {
@@ -223,12 +430,12 @@ func (c *Client) GetExistingProductRows(dealID string) ([]DealProductRow, error)
 
 	resp, err := c.makeRequest("crm.deal.productrows.get", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get existing products: %v", err)
+		return nil, fmt.Errorf("failed to get existing products: %w", err)
 	}
 
 	var products []DealProductRow
 	if err := c.parseResponse(resp, &products); err != nil {
-		return nil, fmt.Errorf("failed to parse existing products response: %v", err)
+		return nil, fmt.Errorf("failed to parse existing products response: %w", err)
 	}
 
 	return products, nil
@@ -275,7 +482,7 @@ func (c *Client) SpreadPriceByCount(dealID string, totalAmount float64, currency
 	// Get existing products in deal
 	products, err := c.GetExistingProductRows(dealID)
 	if err != nil {
-		return fmt.Errorf("failed to get existing products: %v", err)
+		return fmt.Errorf("failed to get existing products: %w", err)
 	}
 
 	if len(products) == 0 {
@@ -300,71 +507,159 @@ func (c *Client) SpreadPriceByCount(dealID string, totalAmount float64, currency
 		fmt.Printf("Distributing price by count method:\n")
 	}
 
-	// Calculate proportional unit prices with rounding
-	distributedSum := 0.0
+	// Apportion deal amount in whole unit-price cents by quantity (Bitrix24 stores prices to the cent),
+	// so rounding error is spread one cent at a time instead of hitting the last product
+	quantities := make([]float64, len(products))
+	for i, product := range products {
+		quantities[i] = product.Quantity
+	}
+	totalCents := int64(math.Round(totalAmount * 100))
+	priceCents, adjusted, distributedCents := apportionUnitPriceCents(quantities, totalCents)
+	
 	for i := range products {
-		if i == len(products)-1 {
-			// Last product - adjust remainder for exact total
-			remainingAmount := totalAmount - distributedSum
-			unitPrice := remainingAmount / products[i].Quantity
-			products[i].Price = math.Round(unitPrice*100) / 100 // Round to 2 decimal places
-			
-			if dryRun {
-				fmt.Printf("  - Product ID %s: %.2f units → %.2f %s per unit → total %.2f %s (remainder adjusted)\n", 
-					products[i].ProductID.String(), products[i].Quantity, products[i].Price, currency, 
-					products[i].Price*products[i].Quantity, currency)
-			} else {
-				fmt.Printf("  - Product ID %s: %.2f units → %.2f %s per unit → total %.2f %s (remainder adjusted)\n", 
-					products[i].ProductID.String(), products[i].Quantity, products[i].Price, currency, 
-					products[i].Price*products[i].Quantity, currency)
-			}
-		} else {
-			// Calculate proportional price and round
-			proportion := products[i].Quantity / totalQuantity
-			unitPrice := (proportion * totalAmount) / products[i].Quantity
-			products[i].Price = math.Round(unitPrice*100) / 100 // Round to 2 decimal places
-			productTotal := products[i].Price * products[i].Quantity
-			distributedSum += productTotal
-			
-			if dryRun {
-				fmt.Printf("  - Product ID %s: %.2f units → %.2f %s per unit → total %.2f %s\n", 
-					products[i].ProductID.String(), products[i].Quantity, products[i].Price, currency, 
-					productTotal, currency)
-			} else {
-				fmt.Printf("  - Product ID %s: %.2f units → %.2f %s per unit → total %.2f %s\n", 
-					products[i].ProductID.String(), products[i].Quantity, products[i].Price, currency, 
-					productTotal, currency)
-			}
+		products[i].Price = float64(priceCents[i]) / 100
+		
+		note := ""
+		if adjusted[i] {
+			note = fmt.Sprintf(" (+0.01 %s rounding)", currency)
 		}
+		fmt.Printf("  - Product ID %s: %.2f units → %.2f %s per unit → total %.2f %s%s\n", 
+			products[i].ProductID.String(), products[i].Quantity, products[i].Price, currency, 
+			products[i].Price*products[i].Quantity, currency, note)
 	}
-
+	
 	// Verify total sum
-	verificationSum := 0.0
-	for _, product := range products {
-		verificationSum += product.Price * product.Quantity
+	prefix := ""
+	if dryRun {
+		prefix = "[DRY RUN] "
+	}
+	distributed := float64(distributedCents) / 100
+	if distributedCents == totalCents {
+		fmt.Printf("%sVerification: total distributed %.2f %s = deal amount %.2f %s ✓\n", 
+			prefix, distributed, currency, totalAmount, currency)
+	} else {
+		// E.g. 100.00 for 3 units: no whole-cent unit price gives exactly 100.00
+		fmt.Printf("%sWarning: total distributed %.2f %s differs from deal amount %.2f %s by %.2f %s: "+
+			"unit prices are whole cents and the quantities do not allow an exact split\n", 
+			prefix, distributed, currency, totalAmount, currency, distributed-totalAmount, currency)
 	}
 
 	if dryRun {
-		fmt.Printf("[DRY RUN] Verification: total distributed %.2f %s = deal amount %.2f %s ✓\n", 
-			verificationSum, currency, totalAmount, currency)
 		fmt.Printf("[DRY RUN] Would update %d product prices\n", len(products))
 		return nil
 	}
 
-	fmt.Printf("Verification: total distributed %.2f %s = deal amount %.2f %s ✓\n", 
-		verificationSum, currency, totalAmount, currency)
-
 	// Update product prices in Bitrix24
 	fmt.Println("Updating product prices...")
 	err = c.AddProductsToDeal(dealID, products)
 	if err != nil {
-		return fmt.Errorf("failed to update product prices: %v", err)
+		return fmt.Errorf("failed to update product prices: %w", err)
 	}
 
 	return nil
 }
 
+// apportionCents splits totalCents proportionally to weights using the largest remainder
+// (Hamilton) method: ideal shares are floored to cents and the leftover cents go one by one
+// to the shares with the largest fractional parts (ties keep the original order).
+// The result always sums to totalCents; adjusted marks shares that received a leftover cent
+func apportionCents(weights []float64, totalCents int64) (shares []int64, adjusted []bool) {
+	shares = make([]int64, len(weights))
+	adjusted = make([]bool, len(weights))
+	
+	totalWeight := 0.0
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+	if len(weights) == 0 || totalWeight <= 0 {
+		return shares, adjusted
+	}
+	
+	fractions := make([]float64, len(weights))
+	distributed := int64(0)
+	for i, weight := range weights {
+		ideal := float64(totalCents) * weight / totalWeight
+		shares[i] = int64(math.Floor(ideal))
+		fractions[i] = ideal - float64(shares[i])
+		distributed += shares[i]
+	}
+	
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return fractions[order[a]] > fractions[order[b]]
+	})
+	
+	for i := int64(0); i < totalCents-distributed; i++ {
+		index := order[i%int64(len(order))]
+		shares[index]++
+		adjusted[index] = true
+	}
+	
+	return shares, adjusted
+}
+
+// apportionUnitPriceCents splits totalCents among rows of the given quantities as whole-cent
+// unit prices. Row totals are apportioned with apportionCents and divided by the quantity;
+// the cents lost by the division are then given back as +1 cent to the unit price of rows
+// whose quantity still fits into the leftover (largest lost remainder first). The result
+// is exact when quantities allow it, otherwise distributedCents is the closest total below.
+// adjusted marks rows priced one cent above the lowest unit price totalCents / total quantity
+func apportionUnitPriceCents(quantities []float64, totalCents int64) (priceCents []int64, adjusted []bool, distributedCents int64) {
+	priceCents = make([]int64, len(quantities))
+	adjusted = make([]bool, len(quantities))
+	
+	totalQuantity := 0.0
+	for _, quantity := range quantities {
+		totalQuantity += quantity
+	}
+	if len(quantities) == 0 || totalQuantity <= 0 {
+		return priceCents, adjusted, 0
+	}
+	
+	rowCents, _ := apportionCents(quantities, totalCents)
+	lost := make([]float64, len(quantities))
+	for i, quantity := range quantities {
+		if quantity <= 0 {
+			continue
+		}
+		priceCents[i] = int64(math.Floor(float64(rowCents[i]) / quantity))
+		lost[i] = float64(rowCents[i]) - float64(priceCents[i])*quantity
+		distributedCents += rowTotalCents(priceCents[i], quantity)
+	}
+	
+	order := make([]int, len(quantities))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return lost[order[a]] > lost[order[b]]
+	})
+	for _, i := range order {
+		increase := rowTotalCents(priceCents[i]+1, quantities[i]) - rowTotalCents(priceCents[i], quantities[i])
+		if quantities[i] > 0 && increase <= totalCents-distributedCents {
+			priceCents[i]++
+			distributedCents += increase
+		}
+	}
+	
+	lowest := int64(math.Floor(float64(totalCents) / totalQuantity))
+	for i := range priceCents {
+		adjusted[i] = priceCents[i] > lowest
+	}
+	
+	return priceCents, adjusted, distributedCents
+}
+
+// rowTotalCents returns the row total price * quantity in cents
+func rowTotalCents(priceCents int64, quantity float64) int64 {
+	return int64(math.Round(float64(priceCents) * quantity))
+}
+
 // ClearDealProductRows removes all product rows from a deal
+// Returns an error if the deal has no products, so a wrong deal ID is not silently accepted
 func (c *Client) ClearDealProductRows(dealID string, dryRun bool) error {
 	// Get existing products first to show what will be cleared
 	if dryRun {
@@ -375,16 +670,11 @@ func (c *Client) ClearDealProductRows(dealID string, dryRun bool) error {
 	
 	existingProducts, err := c.GetExistingProductRows(dealID)
 	if err != nil {
-		return fmt.Errorf("failed to get existing products: %v", err)
+		return fmt.Errorf("failed to get existing products: %w", err)
 	}
 	
 	if len(existingProducts) == 0 {
-		if dryRun {
-			fmt.Printf("[DRY RUN] Deal %s has no products to clear\n", dealID)
-		} else {
-			fmt.Printf("Deal %s has no products to clear\n", dealID)
-		}
-		return nil
+		return fmt.Errorf("deal %s has no products to clear", dealID)
 	}
 	
 	if dryRun {
@@ -399,20 +689,21 @@ func (c *Client) ClearDealProductRows(dealID string, dryRun bool) error {
 	
 	fmt.Printf("Found %d products in deal %s, clearing all products...\n", len(existingProducts), dealID)
 	
-	// Clear products by setting empty rows array
+	// Clear products by setting empty rows array.
+	// Form encoding cannot express an empty array, so send JSON to get a literal "rows": []
 	params := map[string]interface{}{
 		"id":   dealID,
-		"rows": []interface{}{}, // Empty array clears all products
+		"rows": []interface{}{},
 	}
 	
-	resp, err := c.makeRequest("crm.deal.productrows.set", params)
+	resp, err := c.makeJSONRequest("crm.deal.productrows.set", params)
 	if err != nil {
-		return fmt.Errorf("failed to clear products from deal: %v", err)
+		return fmt.Errorf("failed to clear products from deal: %w", err)
 	}
 	
 	var result bool
 	if err := c.parseResponse(resp, &result); err != nil {
-		return fmt.Errorf("failed to parse clear products response: %v", err)
+		return fmt.Errorf("failed to parse clear products response: %w", err)
 	}
 	
 	if !result {
@@ -423,16 +714,23 @@ func (c *Client) ClearDealProductRows(dealID string, dryRun bool) error {
 	return nil
 }
 
-// ValidateDealID checks if deal ID is a valid number
+// ValidateDealID checks if deal ID is a valid non-negative number.
+// IDs are parsed as int64, so large IDs validate the same way on 32-bit builds
 func ValidateDealID(dealID string) error {
 	if dealID == "" {
 		return fmt.Errorf("deal ID cannot be empty")
 	}
 	
-	_, err := strconv.Atoi(dealID)
+	id, err := strconv.ParseInt(dealID, 10, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		return fmt.Errorf("deal ID is too large: %s", dealID)
+	}
 	if err != nil {
 		return fmt.Errorf("deal ID must be a number: %s", dealID)
 	}
+	if id < 0 {
+		return fmt.Errorf("deal ID cannot be negative: %s", dealID)
+	}
 	
 	return nil
 }
\ No newline at end of file
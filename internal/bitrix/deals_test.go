@@ -1,6 +1,14 @@
 package bitrix
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -57,8 +65,14 @@ func TestValidateDealID(t *testing.T) {
 			errorContains: "must be a number",
 		},
 		{
-			name:        "negative number should be valid (strconv.Atoi accepts it)",
+			name:        "negative number should fail",
 			dealID:      "-123",
+			expectError: true,
+			errorContains: "cannot be negative",
+		},
+		{
+			name:        "negative zero is zero",
+			dealID:      "-0",
 			expectError: false,
 		},
 		{
@@ -79,7 +93,7 @@ func TestValidateDealID(t *testing.T) {
 			errorContains: "must be a number",
 		},
 		{
-			name:        "plus sign should be valid (strconv.Atoi accepts it)",
+			name:        "plus sign should be valid (strconv.ParseInt accepts it)",
 			dealID:      "+123",
 			expectError: false,
 		},
@@ -120,15 +134,17 @@ func TestValidateDealID(t *testing.T) {
 }
 
 func TestValidateDealIDEdgeCases(t *testing.T) {
-	// Test with very large number (should still be valid as string conversion)
-	largeNumber := "999999999999999999999999999999"
-	err := ValidateDealID(largeNumber)
-	
-	// This might fail due to integer overflow in strconv.Atoi, which is expected behavior
-	if err != nil {
-		t.Logf("Large number validation failed as expected: %v", err)
-	} else {
-		t.Log("Large number validation passed")
+	// IDs beyond int32 are valid on every platform
+	for _, dealID := range []string{"2147483648", "4294967296", "9223372036854775807"} {
+		if err := ValidateDealID(dealID); err != nil {
+			t.Errorf("ValidateDealID(%s) error = %v, want valid", dealID, err)
+		}
+	}
+
+	// IDs beyond int64 are rejected
+	err := ValidateDealID("999999999999999999999999999999")
+	if err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Errorf("ValidateDealID() for an int64 overflow error = %v, want it to mention %q", err, "too large")
 	}
 }
 
@@ -191,4 +207,708 @@ func TestClearDealProductRowsValidation(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+// newProductRowsServer returns a test server that serves productRows for crm.deal.productrows.get
+// and records crm.deal.productrows.set request bodies
+func newProductRowsServer(t *testing.T, productRows string, setBodies *[]string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/crm.deal.productrows.get"):
+			w.Write([]byte(`{"result":` + productRows + `}`))
+		case strings.HasSuffix(r.URL.Path, "/crm.deal.productrows.set"):
+			body, _ := io.ReadAll(r.Body)
+			*setBodies = append(*setBodies, string(body))
+			w.Write([]byte(`{"result":true}`))
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// captureStdout returns everything fn writes to os.Stdout
+func captureStdout(t *testing.T, fn func()) string {
+	original := os.Stdout
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("failed to create pipe: %v", err)
+	}
+	os.Stdout = writer
+	defer func() { os.Stdout = original }()
+
+	fn()
+
+	writer.Close()
+	output, _ := io.ReadAll(reader)
+	return string(output)
+}
+
+func TestClearDealProductRowsEmptyDeal(t *testing.T) {
+	var setBodies []string
+	server := newProductRowsServer(t, `[]`, &setBodies)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	for _, dryRun := range []bool{true, false} {
+		var err error
+		captureStdout(t, func() {
+			err = client.ClearDealProductRows("123", dryRun)
+		})
+		if err == nil || !strings.Contains(err.Error(), "no products to clear") {
+			t.Errorf("dryRun=%v: expected 'no products to clear' error, got %v", dryRun, err)
+		}
+	}
+
+	if len(setBodies) != 0 {
+		t.Errorf("expected no crm.deal.productrows.set calls, got %d", len(setBodies))
+	}
+}
+
+func TestClearDealProductRowsDryRun(t *testing.T) {
+	var setBodies []string
+	server := newProductRowsServer(t, `[{"PRODUCT_ID":"15","QUANTITY":2,"PRICE":100},{"PRODUCT_ID":16,"QUANTITY":5,"PRICE":0}]`, &setBodies)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	var err error
+	output := captureStdout(t, func() {
+		err = client.ClearDealProductRows("123", true)
+	})
+	if err != nil {
+		t.Fatalf("ClearDealProductRows() error = %v", err)
+	}
+
+	for _, expected := range []string{
+		"Product ID: 15 (Quantity: 2",
+		"Product ID: 16 (Quantity: 5",
+		"Would clear all 2 products from deal 123",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("expected dry-run output to contain %q, got:\n%s", expected, output)
+		}
+	}
+
+	if len(setBodies) != 0 {
+		t.Errorf("dry run must not call crm.deal.productrows.set, got %d calls", len(setBodies))
+	}
+}
+
+func TestClearDealProductRowsSendsEmptyRows(t *testing.T) {
+	var setBodies []string
+	server := newProductRowsServer(t, `[{"PRODUCT_ID":"15","QUANTITY":2,"PRICE":100}]`, &setBodies)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	var err error
+	captureStdout(t, func() {
+		err = client.ClearDealProductRows("123", false)
+	})
+	if err != nil {
+		t.Fatalf("ClearDealProductRows() error = %v", err)
+	}
+
+	if len(setBodies) != 1 {
+		t.Fatalf("expected 1 crm.deal.productrows.set call, got %d", len(setBodies))
+	}
+	if !strings.Contains(setBodies[0], `"rows":[]`) || !strings.Contains(setBodies[0], `"id":"123"`) {
+		t.Errorf("expected empty rows array for deal 123, got %s", setBodies[0])
+	}
+}
+
+func TestGetDealRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		response      string
+		expectedTitle string
+		errorContains string
+	}{
+		{
+			name:          "success",
+			response:      `{"result":{"ID":"123","TITLE":"Order #123","COMPANY_ID":"7","ASSIGNED_BY_ID":"1","CURRENCY_ID":"RUB"}}`,
+			expectedTitle: "Order #123",
+		},
+		{
+			name:          "API error",
+			response:      `{"error":"NOT_FOUND","error_description":"Not found"}`,
+			errorContains: "NOT_FOUND: Not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, map[string]string{"crm.deal.get": tt.response})
+			deal, err := client.GetDeal("123")
+
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetDeal() error = %v", err)
+			}
+			if deal.Title != tt.expectedTitle || deal.CompanyID != "7" || deal.CurrencyID != "RUB" {
+				t.Errorf("unexpected deal: %+v", deal)
+			}
+		})
+	}
+}
+
+func TestGetDealFields(t *testing.T) {
+	var gotForm url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		gotForm, _ = url.ParseQuery(string(body))
+		w.Write([]byte(`{"result":{"ID":"42","UF_CRM_PROJECT":" Drone frame ","UF_CRM_PRIORITY":3,"UF_CRM_TAGS":["urgent","",5.5],"UF_CRM_EMPTY":null}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	fields, err := client.GetDealFields("42", []string{"ID", "UF_CRM_PROJECT", "UF_CRM_PRIORITY"})
+	if err != nil {
+		t.Fatalf("GetDealFields() error = %v", err)
+	}
+
+	// select is sent in Bitrix24 array format: select[]=ID&select[]=UF_CRM_PROJECT...
+	if got := gotForm["select[]"]; !reflect.DeepEqual(got, []string{"ID", "UF_CRM_PROJECT", "UF_CRM_PRIORITY"}) {
+		t.Errorf("select[] = %v", got)
+	}
+	if gotForm.Get("id") != "42" {
+		t.Errorf("id = %q, want 42", gotForm.Get("id"))
+	}
+
+	tests := []struct {
+		field  string
+		want   string
+		wantOK bool
+	}{
+		{"UF_CRM_PROJECT", "Drone frame", true},
+		{"UF_CRM_PRIORITY", "3", true},
+		{"UF_CRM_TAGS", "urgent, 5.50", true},
+		{"UF_CRM_EMPTY", "", false},
+		{"UF_CRM_MISSING", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := DealFieldString(fields, tt.field)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("DealFieldString(%s) = %q, %v; want %q, %v", tt.field, got, ok, tt.want, tt.wantOK)
+		}
+	}
+
+	if _, err := client.GetDealFields("42", nil); err == nil {
+		t.Error("GetDealFields() without fields should fail")
+	}
+}
+
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func TestBuildDealCostFields(t *testing.T) {
+	fieldCodes := ReportCustomFields{
+		MachineCost:  "UF_CRM_MACHINE",
+		HumanCost:    "UF_CRM_HUMAN",
+		MaterialCost: "UF_CRM_MATERIAL",
+		TotalCost:    "UF_CRM_TOTAL",
+	}
+
+	tests := []struct {
+		name        string
+		fieldCodes  ReportCustomFields
+		costs       DealCosts
+		currency    string
+		expected    map[string]interface{}
+		expectError bool
+	}{
+		{
+			name:       "all costs with currency",
+			fieldCodes: fieldCodes,
+			costs: DealCosts{
+				MachineCost:  floatPtr(1200),
+				HumanCost:    floatPtr(350.5),
+				MaterialCost: floatPtr(99.999),
+				TotalCost:    floatPtr(1650.5),
+			},
+			currency: "RUB",
+			expected: map[string]interface{}{
+				"UF_CRM_MACHINE":  "1200.00|RUB",
+				"UF_CRM_HUMAN":    "350.50|RUB",
+				"UF_CRM_MATERIAL": "100.00|RUB",
+				"UF_CRM_TOTAL":    "1650.50|RUB",
+			},
+		},
+		{
+			name:       "only set costs are sent",
+			fieldCodes: fieldCodes,
+			costs:      DealCosts{MaterialCost: floatPtr(0)},
+			expected: map[string]interface{}{
+				"UF_CRM_MATERIAL": "0.00",
+			},
+		},
+		{
+			name:        "cost without configured field code",
+			fieldCodes:  ReportCustomFields{MachineCost: "UF_CRM_MACHINE"},
+			costs:       DealCosts{HumanCost: floatPtr(10)},
+			expectError: true,
+		},
+		{
+			name:        "no costs",
+			fieldCodes:  fieldCodes,
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields, err := buildDealCostFields(tt.fieldCodes, tt.costs, tt.currency)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("expected error, got fields %v", fields)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(fields, tt.expected) {
+				t.Errorf("buildDealCostFields() = %v, want %v", fields, tt.expected)
+			}
+		})
+	}
+}
+
+func TestUpdateDealCostsFormEncoding(t *testing.T) {
+	var gotPath string
+	var gotForm url.Values
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		body, _ := io.ReadAll(r.Body)
+		gotForm, _ = url.ParseQuery(string(body))
+		w.Write([]byte(`{"result": true}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	fieldCodes := ReportCustomFields{
+		MachineCost:  "UF_CRM_1700000001",
+		MaterialCost: "UF_CRM_1700000003",
+	}
+	costs := DealCosts{
+		MachineCost:  floatPtr(500),
+		MaterialCost: floatPtr(75.25),
+	}
+
+	if err := client.UpdateDealCosts("42", fieldCodes, costs, "RUB"); err != nil {
+		t.Fatalf("UpdateDealCosts() error = %v", err)
+	}
+
+	if gotPath != "/crm.deal.update" {
+		t.Errorf("expected request to /crm.deal.update, got %s", gotPath)
+	}
+
+	expected := url.Values{
+		"id":                        {"42"},
+		"fields[UF_CRM_1700000001]": {"500.00|RUB"},
+		"fields[UF_CRM_1700000003]": {"75.25|RUB"},
+	}
+	if !reflect.DeepEqual(gotForm, expected) {
+		t.Errorf("form data = %v, want %v", gotForm, expected)
+	}
+}
+
+func TestUpdateDealFieldsAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result": false}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	err := client.UpdateDealFields("42", map[string]interface{}{"UF_CRM_1": "1"})
+	if err == nil || !strings.Contains(err.Error(), "API returned false") {
+		t.Errorf("expected API returned false error, got %v", err)
+	}
+}
+
+func TestApportionCents(t *testing.T) {
+	tests := []struct {
+		name         string
+		weights      []float64
+		totalCents   int64
+		wantShares   []int64
+		wantAdjusted []bool
+	}{
+		{
+			name:         "even split",
+			weights:      []float64{1, 1},
+			totalCents:   10000,
+			wantShares:   []int64{5000, 5000},
+			wantAdjusted: []bool{false, false},
+		},
+		{
+			name:         "one leftover cent goes to the first of equal remainders",
+			weights:      []float64{1, 1, 1},
+			totalCents:   10000,
+			wantShares:   []int64{3334, 3333, 3333},
+			wantAdjusted: []bool{true, false, false},
+		},
+		{
+			name:         "leftover cents go to the largest remainders",
+			weights:      []float64{1, 2, 4},
+			totalCents:   1000,
+			wantShares:   []int64{143, 286, 571},
+			wantAdjusted: []bool{true, true, false},
+		},
+		{
+			name:         "zero weights",
+			weights:      []float64{0, 0},
+			totalCents:   500,
+			wantShares:   []int64{0, 0},
+			wantAdjusted: []bool{false, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shares, adjusted := apportionCents(tt.weights, tt.totalCents)
+			if !reflect.DeepEqual(shares, tt.wantShares) {
+				t.Errorf("apportionCents(%v, %d) shares = %v, want %v", tt.weights, tt.totalCents, shares, tt.wantShares)
+			}
+			if !reflect.DeepEqual(adjusted, tt.wantAdjusted) {
+				t.Errorf("apportionCents(%v, %d) adjusted = %v, want %v", tt.weights, tt.totalCents, adjusted, tt.wantAdjusted)
+			}
+		})
+	}
+}
+
+func TestSpreadPriceByCountSpreadsRemainder(t *testing.T) {
+	var setBodies []string
+	rows := `[{"PRODUCT_ID":"1","QUANTITY":1},{"PRODUCT_ID":"2","QUANTITY":1},{"PRODUCT_ID":"3","QUANTITY":1},` +
+		`{"PRODUCT_ID":"4","QUANTITY":1},{"PRODUCT_ID":"5","QUANTITY":1},{"PRODUCT_ID":"6","QUANTITY":1},{"PRODUCT_ID":"7","QUANTITY":1}]`
+	server := newProductRowsServer(t, rows, &setBodies)
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	var err error
+	output := captureStdout(t, func() {
+		err = client.SpreadPriceByCount("123", 100, "RUB", false)
+	})
+	if err != nil {
+		t.Fatalf("SpreadPriceByCount() error = %v", err)
+	}
+	if len(setBodies) != 1 {
+		t.Fatalf("expected 1 productrows.set request, got %d", len(setBodies))
+	}
+
+	form, err := url.ParseQuery(setBodies[0])
+	if err != nil {
+		t.Fatalf("failed to parse request body: %v", err)
+	}
+
+	totalCents := int64(0)
+	adjustedCount := 0
+	for i := 0; i < 7; i++ {
+		price := form.Get(fmt.Sprintf("rows[%d][PRICE]", i))
+		var cents int64
+		switch price {
+		case "14.28":
+			cents = 1428
+		case "14.29":
+			cents = 1429
+			adjustedCount++
+		default:
+			t.Fatalf("row %d price = %q, want 14.28 or 14.29", i, price)
+		}
+		totalCents += cents
+	}
+
+	if totalCents != 10000 {
+		t.Errorf("distributed total = %d cents, want 10000", totalCents)
+	}
+	// 100.00 / 7 leaves 4 cents, which must not all go to a single product
+	if adjustedCount != 4 {
+		t.Errorf("expected 4 products with a rounding cent, got %d", adjustedCount)
+	}
+	if !strings.Contains(output, "(+0.01 RUB rounding)") {
+		t.Errorf("expected output to show adjusted cents, got:\n%s", output)
+	}
+}
+
+func TestApportionUnitPriceCents(t *testing.T) {
+	tests := []struct {
+		name            string
+		quantities      []float64
+		totalCents      int64
+		wantPrices      []int64
+		wantAdjusted    []bool
+		wantDistributed int64
+	}{
+		{
+			name:            "single units",
+			quantities:      []float64{1, 1, 1},
+			totalCents:      10000,
+			wantPrices:      []int64{3334, 3333, 3333},
+			wantAdjusted:    []bool{true, false, false},
+			wantDistributed: 10000,
+		},
+		{
+			name:            "leftover cents go to rows whose quantity fits",
+			quantities:      []float64{3, 3, 1},
+			totalCents:      10000,
+			wantPrices:      []int64{1429, 1428, 1429},
+			wantAdjusted:    []bool{true, false, true},
+			wantDistributed: 10000,
+		},
+		{
+			name:            "even split of multiple units",
+			quantities:      []float64{2, 4},
+			totalCents:      6000,
+			wantPrices:      []int64{1000, 1000},
+			wantAdjusted:    []bool{false, false},
+			wantDistributed: 6000,
+		},
+		{
+			name:            "exact split impossible",
+			quantities:      []float64{3},
+			totalCents:      10000,
+			wantPrices:      []int64{3333},
+			wantAdjusted:    []bool{false},
+			wantDistributed: 9999,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices, adjusted, distributed := apportionUnitPriceCents(tt.quantities, tt.totalCents)
+			if !reflect.DeepEqual(prices, tt.wantPrices) || !reflect.DeepEqual(adjusted, tt.wantAdjusted) || distributed != tt.wantDistributed {
+				t.Errorf("apportionUnitPriceCents(%v, %d) = %v, %v, %d; want %v, %v, %d", tt.quantities, tt.totalCents,
+					prices, adjusted, distributed, tt.wantPrices, tt.wantAdjusted, tt.wantDistributed)
+			}
+		})
+	}
+}
+
+func TestSpreadPriceByCountMultipleUnits(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       string
+		amount     float64
+		wantPrices []string
+		wantOutput string
+	}{
+		{
+			name:       "exact to the cent",
+			rows:       `[{"PRODUCT_ID":"1","QUANTITY":3},{"PRODUCT_ID":"2","QUANTITY":3},{"PRODUCT_ID":"3","QUANTITY":1}]`,
+			amount:     100,
+			wantPrices: []string{"14.29", "14.28", "14.29"},
+			wantOutput: "total distributed 100.00 RUB = deal amount 100.00 RUB ✓",
+		},
+		{
+			name:       "achievable total is reported",
+			rows:       `[{"PRODUCT_ID":"1","QUANTITY":3}]`,
+			amount:     100,
+			wantPrices: []string{"33.33"},
+			wantOutput: "Warning: total distributed 99.99 RUB differs from deal amount 100.00 RUB by -0.01 RUB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var setBodies []string
+			server := newProductRowsServer(t, tt.rows, &setBodies)
+			defer server.Close()
+
+			client := NewClient(server.URL)
+			var err error
+			output := captureStdout(t, func() {
+				err = client.SpreadPriceByCount("123", tt.amount, "RUB", false)
+			})
+			if err != nil {
+				t.Fatalf("SpreadPriceByCount() error = %v", err)
+			}
+			if len(setBodies) != 1 {
+				t.Fatalf("expected 1 productrows.set request, got %d", len(setBodies))
+			}
+
+			form, err := url.ParseQuery(setBodies[0])
+			if err != nil {
+				t.Fatalf("failed to parse request body: %v", err)
+			}
+			for i, want := range tt.wantPrices {
+				if got := form.Get(fmt.Sprintf("rows[%d][PRICE]", i)); got != want {
+					t.Errorf("row %d price = %q, want %q", i, got, want)
+				}
+			}
+			if !strings.Contains(output, tt.wantOutput) {
+				t.Errorf("expected output to contain %q, got:\n%s", tt.wantOutput, output)
+			}
+		})
+	}
+}
+
+func TestGetUserURL(t *testing.T) {
+	tests := []struct {
+		webhookURL string
+		userID     string
+		want       string
+		wantOK     bool
+	}{
+		{"https://farmix.bitrix24.ru/rest/10/key/", "7", "https://farmix.bitrix24.ru/company/personal/user/7/", true},
+		{"https://farmix.bitrix24.ru/rest/10/key/", "", "", false},
+		{"https://farmix.bitrix24.ru/rest/10/key/", "0", "", false},
+		{"https://farmix.bitrix24.ru/hook/", "7", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NewClient(tt.webhookURL).GetUserURL(tt.userID)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("GetUserURL(%q) with %s = %q, %v; want %q, %v", tt.userID, tt.webhookURL, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+// entityResponses serve company, contact and user lookups for countingTransport
+var entityResponses = map[string]string{
+	"crm.company.get": `{"result":{"ID":"7","TITLE":"ACME"}}`,
+	"crm.contact.get": `{"result":{"ID":"5","NAME":"Ivan","LAST_NAME":"Petrov"}}`,
+	"user.get":        `{"result":[{"ID":"1","NAME":"Anna"}]}`,
+}
+
+func TestEntityLookupsCached(t *testing.T) {
+	counts := make(map[string]int)
+	client := NewClient("https://example.bitrix24.ru/rest/1/token", WithHTTPClient(countingTransport(t, counts, entityResponses)))
+
+	for i := 0; i < 2; i++ {
+		company, err := client.GetCompany("7")
+		if err != nil {
+			t.Fatalf("GetCompany() error = %v", err)
+		}
+		if company.Title != "ACME" {
+			t.Errorf("GetCompany().Title = %q, want %q", company.Title, "ACME")
+		}
+		// Callers must not be able to change the cached value
+		company.Title = "Changed"
+
+		if _, err := client.GetContact("5"); err != nil {
+			t.Fatalf("GetContact() error = %v", err)
+		}
+		if _, err := client.GetUser("1"); err != nil {
+			t.Fatalf("GetUser() error = %v", err)
+		}
+	}
+
+	if _, err := client.GetCustomerName(&Deal{CompanyID: "7"}); err != nil {
+		t.Fatalf("GetCustomerName() error = %v", err)
+	}
+
+	for _, method := range []string{"crm.company.get", "crm.contact.get", "user.get"} {
+		if counts[method] != 1 {
+			t.Errorf("expected 1 %s call, got %d", method, counts[method])
+		}
+	}
+
+	// A different ID is a separate cache entry
+	if _, err := client.GetCompany("8"); err != nil {
+		t.Fatalf("GetCompany() error = %v", err)
+	}
+	if counts["crm.company.get"] != 2 {
+		t.Errorf("expected a new crm.company.get call for another ID, got %d", counts["crm.company.get"])
+	}
+}
+
+func TestEntityLookupsWithoutCache(t *testing.T) {
+	counts := make(map[string]int)
+	client := NewClient("https://example.bitrix24.ru/rest/1/token",
+		WithHTTPClient(countingTransport(t, counts, entityResponses)), WithoutEntityCache())
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.GetCompany("7"); err != nil {
+			t.Fatalf("GetCompany() error = %v", err)
+		}
+	}
+
+	if counts["crm.company.get"] != 3 {
+		t.Errorf("expected 3 crm.company.get calls with cache disabled, got %d", counts["crm.company.get"])
+	}
+}
+
+func TestGetUserNotFound(t *testing.T) {
+	client := newTestClient(t, map[string]string{"user.get": `{"result":[]}`})
+
+	_, err := client.GetUser("42")
+	if !errors.Is(err, ErrUserNotFound) {
+		t.Fatalf("GetUser() error = %v, want ErrUserNotFound", err)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrUserNotFound to match ErrNotFound, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "42") {
+		t.Errorf("expected error to mention user ID, got %v", err)
+	}
+}
+
+func TestGetUserFullNameFallbacks(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"full name from API", `{"ID":"1","FULL_NAME":"Ivan Petrov","NAME":"Ivan"}`, "Ivan Petrov"},
+		{"name and last name", `{"ID":"1","NAME":"Ivan","LAST_NAME":"Petrov"}`, "Ivan Petrov"},
+		{"last name only", `{"ID":"1","LAST_NAME":"Petrov","LOGIN":"ipetrov"}`, "Petrov"},
+		{"login", `{"ID":"1","NAME":" ","LOGIN":"ipetrov","EMAIL":"ivan@example.com"}`, "ipetrov"},
+		{"email", `{"ID":"1","EMAIL":"ivan@example.com"}`, "ivan@example.com"},
+		{"nothing", `{"ID":"1"}`, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, map[string]string{"user.get": `{"result":[` + tt.body + `]}`})
+
+			user, err := client.GetUser("1")
+			if err != nil {
+				t.Fatalf("GetUser() error = %v", err)
+			}
+			if user.FullName != tt.want {
+				t.Errorf("GetUser().FullName = %q, want %q", user.FullName, tt.want)
+			}
+		})
+	}
+}
+
+func TestCatalogURLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		webhookURL  string
+		wantProduct string
+		wantSection string
+	}{
+		{
+			name:        "portal webhook",
+			webhookURL:  "https://farmix.bitrix24.ru/rest/10/key/",
+			wantProduct: "https://farmix.bitrix24.ru/crm/catalog/23/product/512/",
+			wantSection: "https://farmix.bitrix24.ru/crm/catalog/23/section/64/",
+		},
+		{
+			name:        "unexpected webhook format",
+			webhookURL:  "https://farmix.bitrix24.ru/hook/",
+			wantProduct: "https://bitrix24.com/crm/catalog/23/product/512/",
+			wantSection: "https://bitrix24.com/crm/catalog/23/section/64/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(tt.webhookURL)
+			if got := client.GetProductURL("23", "512"); got != tt.wantProduct {
+				t.Errorf("GetProductURL() = %q, want %q", got, tt.wantProduct)
+			}
+			if got := client.GetSectionURL("23", "64"); got != tt.wantSection {
+				t.Errorf("GetSectionURL() = %q, want %q", got, tt.wantSection)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package bitrix
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxErrorBodySnippet is how many characters of an unexpected response body are kept in a TransportError
+const maxErrorBodySnippet = 200
+
+// Common Bitrix24 API errors; an *APIError matches them with errors.Is by its code
+var (
+	ErrAccessDenied       = errors.New("access denied")
+	ErrQueryLimitExceeded = errors.New("query limit exceeded")
+	ErrNotFound           = errors.New("not found")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// ErrUserNotFound is returned by GetUser when user.get returns no user (e.g. a deactivated
+// employee not visible to the webhook); it also matches ErrNotFound
+var ErrUserNotFound = fmt.Errorf("user %w", ErrNotFound)
+
+// errorCodes maps Bitrix24 error codes (upper-cased) to the common errors
+var errorCodes = map[string]error{
+	"ACCESS_DENIED":        ErrAccessDenied,
+	"INSUFFICIENT_SCOPE":   ErrAccessDenied, // webhook lacks the method's permission (crm, catalog)
+	"QUERY_LIMIT_EXCEEDED": ErrQueryLimitExceeded,
+	"NOT_FOUND":            ErrNotFound,
+	"ERROR_NOT_FOUND":      ErrNotFound,
+	"INVALID_CREDENTIALS":  ErrInvalidCredentials,
+	"INVALID_TOKEN":        ErrInvalidCredentials,
+	"EXPIRED_TOKEN":        ErrInvalidCredentials,
+	"NO_AUTH_FOUND":        ErrInvalidCredentials,
+}
+
+// errorForCode returns the common error for a Bitrix24 error code, or nil for other codes
+func errorForCode(code string) error {
+	return errorCodes[strings.ToUpper(strings.TrimSpace(code))]
+}
+
+// APIError is an error reported by Bitrix24 itself: {"error": "...", "error_description": "..."}.
+// Bitrix24 usually returns it with HTTP 200, some methods with 4xx. Only QUERY_LIMIT_EXCEEDED
+// (with 503) is retried, see WithMaxRetries
+type APIError struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("Bitrix24 API error %s: %s", e.Code, e.Description)
+}
+
+// Is reports whether the error code corresponds to target (ErrAccessDenied, ErrNotFound, ...)
+func (e *APIError) Is(target error) bool {
+	sentinel := errorForCode(e.Code)
+	return sentinel != nil && sentinel == target
+}
+
+// TransportError is a failure below the Bitrix24 API: the request did not complete, or the
+// response is not a Bitrix24 JSON response (e.g. an HTML 502 page from a gateway).
+// StatusCode is 0 when no response was received; Body is a truncated snippet of the response
+type TransportError struct {
+	StatusCode int
+	Body       string
+	Err        error
+}
+
+func (e *TransportError) Error() string {
+	if e.StatusCode == 0 {
+		return e.Err.Error()
+	}
+	if e.StatusCode != http.StatusOK {
+		return fmt.Sprintf("HTTP error %d: %s", e.StatusCode, e.Body)
+	}
+	return fmt.Sprintf("failed to unmarshal response: %v (body: %s)", e.Err, e.Body)
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// decodeResponse parses a Bitrix24 response body and classifies failures:
+// a JSON body with "error" is an *APIError (whatever the status), a non-200 status
+// or a body that is not JSON is a *TransportError
+func decodeResponse(statusCode int, body []byte) (*BitrixResponse, error) {
+	var bitrixResp BitrixResponse
+	if err := json.Unmarshal(body, &bitrixResp); err != nil {
+		return nil, &TransportError{StatusCode: statusCode, Body: bodySnippet(body), Err: err}
+	}
+
+	if bitrixResp.Error != nil {
+		return nil, &APIError{
+			StatusCode:  statusCode,
+			Code:        bitrixResp.Error.ErrorCode,
+			Description: bitrixResp.Error.ErrorDescription,
+		}
+	}
+
+	if statusCode != http.StatusOK {
+		return nil, &TransportError{StatusCode: statusCode, Body: bodySnippet(body)}
+	}
+
+	return &bitrixResp, nil
+}
+
+// bodySnippet collapses whitespace of a response body and truncates it for error messages
+func bodySnippet(body []byte) string {
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if utf8.RuneCountInString(snippet) <= maxErrorBodySnippet {
+		return snippet
+	}
+	return string([]rune(snippet)[:maxErrorBodySnippet]) + "..."
+}
+
+// rawAPIError returns the *APIError of a manually decoded response, or nil if it has no "error"
+func rawAPIError(statusCode int, rawResponse map[string]interface{}) error {
+	code, exists := rawResponse["error"]
+	if !exists {
+		return nil
+	}
+	description, _ := rawResponse["error_description"].(string)
+	return &APIError{StatusCode: statusCode, Code: fmt.Sprint(code), Description: description}
+}
\ No newline at end of file
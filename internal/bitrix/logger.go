@@ -0,0 +1,61 @@
+package bitrix
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// LogLevel defines logger verbosity
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+)
+
+// Logger is a minimal leveled logger used by Client for diagnostic output
+type Logger interface {
+	Debug(format string, args ...interface{})
+	Info(format string, args ...interface{})
+	Warn(format string, args ...interface{})
+}
+
+// levelLogger writes messages at or above its level to a writer
+type levelLogger struct {
+	writer io.Writer
+	level  LogLevel
+}
+
+// NewLogger creates a logger writing messages of the given level and above to writer
+func NewLogger(writer io.Writer, level LogLevel) Logger {
+	return &levelLogger{writer: writer, level: level}
+}
+
+// defaultLogger logs info and warnings to stderr
+func defaultLogger() Logger {
+	return NewLogger(os.Stderr, LogLevelInfo)
+}
+
+// Debug logs a debug message (shown only with LogLevelDebug)
+func (l *levelLogger) Debug(format string, args ...interface{}) {
+	l.log(LogLevelDebug, "DEBUG", format, args...)
+}
+
+// Info logs an informational message
+func (l *levelLogger) Info(format string, args ...interface{}) {
+	l.log(LogLevelInfo, "INFO", format, args...)
+}
+
+// Warn logs a warning
+func (l *levelLogger) Warn(format string, args ...interface{}) {
+	l.log(LogLevelWarn, "WARN", format, args...)
+}
+
+func (l *levelLogger) log(level LogLevel, prefix, format string, args ...interface{}) {
+	if level < l.level {
+		return
+	}
+	fmt.Fprintf(l.writer, prefix+": "+format+"\n", args...)
+}
@@ -0,0 +1,117 @@
+package bitrix
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoggerLevels(t *testing.T) {
+	tests := []struct {
+		name       string
+		level      LogLevel
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:       "info level suppresses debug",
+			level:      LogLevelInfo,
+			expected:   []string{"INFO: info 2", "WARN: warn 3"},
+			unexpected: []string{"DEBUG"},
+		},
+		{
+			name:     "debug level shows everything",
+			level:    LogLevelDebug,
+			expected: []string{"DEBUG: debug 1", "INFO: info 2", "WARN: warn 3"},
+		},
+		{
+			name:       "warn level shows warnings only",
+			level:      LogLevelWarn,
+			expected:   []string{"WARN: warn 3"},
+			unexpected: []string{"DEBUG", "INFO"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := NewLogger(&buf, tt.level)
+			logger.Debug("debug %d", 1)
+			logger.Info("info %d", 2)
+			logger.Warn("warn %d", 3)
+
+			output := buf.String()
+			for _, line := range tt.expected {
+				if !strings.Contains(output, line+"\n") {
+					t.Errorf("expected output to contain %q, got %q", line, output)
+				}
+			}
+			for _, text := range tt.unexpected {
+				if strings.Contains(output, text) {
+					t.Errorf("expected output not to contain %q, got %q", text, output)
+				}
+			}
+		})
+	}
+}
+
+func TestClientDebugSuppressedByDefault(t *testing.T) {
+	responses := map[string]string{"crm.deal.get": `{"result":{"ID":"1","TITLE":"Deal"}}`}
+
+	var quiet bytes.Buffer
+	client := newTestClient(t, responses)
+	WithLogger(NewLogger(&quiet, LogLevelInfo))(client)
+	if _, err := client.GetDeal("1"); err != nil {
+		t.Fatalf("GetDeal() error = %v", err)
+	}
+	if quiet.Len() != 0 {
+		t.Errorf("expected no output at info level, got %q", quiet.String())
+	}
+
+	var verbose bytes.Buffer
+	client = newTestClient(t, responses)
+	WithLogger(NewLogger(&verbose, LogLevelDebug))(client)
+	if _, err := client.GetDeal("1"); err != nil {
+		t.Fatalf("GetDeal() error = %v", err)
+	}
+	if !strings.Contains(verbose.String(), "DEBUG: POST request to") {
+		t.Errorf("expected debug request line at debug level, got %q", verbose.String())
+	}
+}
+
+func TestClientDebugRedactsWebhookAndFormValues(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"result":{"ID":"1","TITLE":"Deal"}}`))
+	}))
+	defer server.Close()
+
+	var output bytes.Buffer
+	client := NewClient(server.URL+"/rest/1/secrettoken", WithLogger(NewLogger(&output, LogLevelDebug)))
+	if _, err := client.GetDealFields("1", []string{"ID", "UF_CRM_CUSTOMER_PHONE"}); err != nil {
+		t.Fatalf("GetDealFields() error = %v", err)
+	}
+
+	logged := output.String()
+	if strings.Contains(logged, "secrettoken") || strings.Contains(logged, "/rest/1") {
+		t.Errorf("debug output must not contain the webhook path, got %q", logged)
+	}
+	if !strings.Contains(logged, "crm.deal.get on "+strings.TrimPrefix(server.URL, "http://")) {
+		t.Errorf("debug output should name the method and portal host, got %q", logged)
+	}
+	if !strings.Contains(logged, "Form fields: id, select[]") || strings.Contains(logged, "UF_CRM_CUSTOMER_PHONE") {
+		t.Errorf("debug output should list field names only, got %q", logged)
+	}
+}
+
+func TestNewClientDefaultLoggerIsInfo(t *testing.T) {
+	client := NewClient("https://example.bitrix24.ru/rest/1/token")
+	logger, ok := client.logger.(*levelLogger)
+	if !ok {
+		t.Fatalf("expected default levelLogger, got %T", client.logger)
+	}
+	if logger.level != LogLevelInfo {
+		t.Errorf("expected default level info, got %v", logger.level)
+	}
+}
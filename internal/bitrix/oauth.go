@@ -0,0 +1,170 @@
+package bitrix
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultOAuthTokenURL is the Bitrix24 OAuth server that issues and refreshes access tokens
+const DefaultOAuthTokenURL = "https://oauth.bitrix.info/oauth/token/"
+
+// OAuthToken is a token pair issued by the OAuth server; ExpiresIn is the access token lifetime in seconds
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// oauthCredentials holds the current token pair of an OAuth client; mu serializes refreshes
+// of parallel requests (see WithConcurrency)
+type oauthCredentials struct {
+	mu           sync.Mutex
+	accessToken  string
+	refreshToken string
+	clientID     string
+	clientSecret string
+	tokenURL     string
+	onRefresh    func(OAuthToken) error
+}
+
+// NewOAuthClient creates a Bitrix24 client authenticated with an OAuth access token instead of
+// an incoming webhook. Requests go to https://{domain}/rest/{method}?auth={token}; when Bitrix24
+// reports expired_token the token is refreshed with refreshToken and the request is repeated.
+// Use WithTokenRefreshCallback to persist the new token pair
+func NewOAuthClient(domain, accessToken, refreshToken, clientID, clientSecret string, opts ...ClientOption) *Client {
+	client := newClient(oauthRestURL(domain))
+	client.oauth = &oauthCredentials{
+		accessToken:  accessToken,
+		refreshToken: refreshToken,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		tokenURL:     DefaultOAuthTokenURL,
+	}
+
+	client.apply(opts)
+
+	return client
+}
+
+// WithTokenRefreshCallback sets a function called with the new token pair after every refresh
+// (Bitrix24 rotates refresh tokens, so it must be saved). Ignored by webhook clients
+func WithTokenRefreshCallback(onRefresh func(OAuthToken) error) ClientOption {
+	return func(c *Client) {
+		if c.oauth != nil {
+			c.oauth.onRefresh = onRefresh
+		}
+	}
+}
+
+// WithOAuthTokenURL replaces DefaultOAuthTokenURL (e.g. to point tests at an httptest.Server).
+// Ignored by webhook clients
+func WithOAuthTokenURL(tokenURL string) ClientOption {
+	return func(c *Client) {
+		if c.oauth != nil && tokenURL != "" {
+			c.oauth.tokenURL = tokenURL
+		}
+	}
+}
+
+// oauthRestURL builds the REST base URL of a portal: "farmix.bitrix24.ru" -> https://farmix.bitrix24.ru/rest
+func oauthRestURL(domain string) string {
+	domain = strings.TrimSpace(domain)
+	domain = strings.TrimPrefix(strings.TrimPrefix(domain, "https://"), "http://")
+	return "https://" + strings.TrimRight(domain, "/") + "/rest"
+}
+
+// withAuth adds the access token to a request URL
+func withAuth(requestURL, accessToken string) string {
+	return requestURL + "?auth=" + url.QueryEscape(accessToken)
+}
+
+// currentToken returns the access token to use for the next request
+func (o *oauthCredentials) currentToken() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.accessToken
+}
+
+// isTokenExpired reports whether the response is an expired_token error.
+// The body is read and replaced, so the response can still be parsed by the caller
+func isTokenExpired(resp *http.Response) (bool, error) {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return false, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	var errorResponse struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(body, &errorResponse) != nil {
+		return false, nil
+	}
+	return strings.EqualFold(errorResponse.Error, "expired_token"), nil
+}
+
+// refreshAccessToken exchanges the refresh token for a new token pair.
+// expiredToken is the token the failed request used: if another request has already
+// replaced it, the new token is reused without a second refresh
+func (c *Client) refreshAccessToken(expiredToken string) error {
+	o := c.oauth
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.accessToken != expiredToken {
+		return nil
+	}
+
+	params := url.Values{}
+	params.Set("grant_type", "refresh_token")
+	params.Set("client_id", o.clientID)
+	params.Set("client_secret", o.clientSecret)
+	params.Set("refresh_token", o.refreshToken)
+
+	resp, err := c.httpClient.Get(o.tokenURL + "?" + params.Encode())
+	if err != nil {
+		return fmt.Errorf("failed to refresh access token: %w", &TransportError{Err: err})
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	var tokenResponse struct {
+		OAuthToken
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return fmt.Errorf("failed to refresh access token: %w", &TransportError{StatusCode: resp.StatusCode, Body: bodySnippet(body), Err: err})
+	}
+	if tokenResponse.Error != "" {
+		return fmt.Errorf("failed to refresh access token: %w", &APIError{StatusCode: resp.StatusCode, Code: tokenResponse.Error, Description: tokenResponse.ErrorDescription})
+	}
+	if tokenResponse.AccessToken == "" {
+		return fmt.Errorf("failed to refresh access token: %w", &TransportError{StatusCode: resp.StatusCode, Body: bodySnippet(body), Err: fmt.Errorf("no access_token in response")})
+	}
+
+	token := tokenResponse.OAuthToken
+	if token.RefreshToken == "" {
+		token.RefreshToken = o.refreshToken
+	}
+	o.accessToken, o.refreshToken = token.AccessToken, token.RefreshToken
+
+	// The refreshed token already works for this run, a failed save only affects the next one
+	if o.onRefresh != nil {
+		if err := o.onRefresh(token); err != nil {
+			c.logger.Warn("failed to save refreshed access token: %v", err)
+		}
+	}
+	return nil
+}
\ No newline at end of file
@@ -0,0 +1,173 @@
+package bitrix
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestOAuthRestURL(t *testing.T) {
+	tests := map[string]string{
+		"farmix.bitrix24.ru":          "https://farmix.bitrix24.ru/rest",
+		" farmix.bitrix24.ru/ ":       "https://farmix.bitrix24.ru/rest",
+		"https://farmix.bitrix24.ru/": "https://farmix.bitrix24.ru/rest",
+		"http://portal.example.com":   "https://portal.example.com/rest",
+	}
+	for domain, want := range tests {
+		if got := oauthRestURL(domain); got != want {
+			t.Errorf("oauthRestURL(%q) = %q, want %q", domain, got, want)
+		}
+	}
+}
+
+func TestOAuthClientRequestURL(t *testing.T) {
+	var requestedURL string
+	httpClient := &http.Client{
+		Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requestedURL = req.URL.String()
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{"Content-Type": []string{"application/json"}},
+				Body:       io.NopCloser(strings.NewReader(`{"result":{"ID":"1","TITLE":"OAuth"}}`)),
+			}, nil
+		}),
+	}
+
+	client := NewOAuthClient("farmix.bitrix24.ru", "access+1", "refresh", "app", "secret", WithHTTPClient(httpClient))
+	deal, err := client.GetDeal("1")
+	if err != nil {
+		t.Fatalf("GetDeal() error = %v", err)
+	}
+
+	if deal.Title != "OAuth" {
+		t.Errorf("deal title = %q, want %q", deal.Title, "OAuth")
+	}
+	if want := "https://farmix.bitrix24.ru/rest/crm.deal.get?auth=access%2B1"; requestedURL != want {
+		t.Errorf("requested URL = %q, want %q", requestedURL, want)
+	}
+	if got := client.GetDealURL("1"); got != "https://farmix.bitrix24.ru/crm/deal/details/1/" {
+		t.Errorf("GetDealURL() = %q", got)
+	}
+}
+
+// oauthPortal is a portal that accepts only its current access token and an OAuth server
+// that issues "new-access" for the "old-refresh" token
+type oauthPortal struct {
+	mu            sync.Mutex
+	validToken    string
+	refreshCalls  int
+	refreshParams string
+}
+
+func (p *oauthPortal) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		switch {
+		case r.URL.Path == "/oauth/token/":
+			p.refreshCalls++
+			p.refreshParams = r.URL.RawQuery
+			if r.URL.Query().Get("refresh_token") != "old-refresh" {
+				w.WriteHeader(http.StatusBadRequest)
+				fmt.Fprint(w, `{"error":"invalid_grant","error_description":"Invalid refresh token"}`)
+				return
+			}
+			p.validToken = "new-access"
+			fmt.Fprint(w, `{"access_token":"new-access","refresh_token":"new-refresh","expires_in":3600}`)
+		case r.URL.Path == "/rest/crm.deal.get":
+			if r.URL.Query().Get("auth") != p.validToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"error":"expired_token","error_description":"The access token provided has expired."}`)
+				return
+			}
+			fmt.Fprint(w, `{"result":{"ID":"7","TITLE":"Refreshed"}}`)
+		default:
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func newOAuthTestClient(t *testing.T, portal *oauthPortal, refreshToken string, opts ...ClientOption) *Client {
+	server := httptest.NewTLSServer(portal.handler(t))
+	t.Cleanup(server.Close)
+
+	domain := strings.TrimPrefix(server.URL, "https://")
+	opts = append([]ClientOption{WithHTTPClient(server.Client()), WithOAuthTokenURL(server.URL + "/oauth/token/")}, opts...)
+	return NewOAuthClient(domain, "old-access", refreshToken, "app.1", "secret", opts...)
+}
+
+func TestOAuthClientRefreshesExpiredToken(t *testing.T) {
+	portal := &oauthPortal{validToken: "new-access"}
+	var saved []OAuthToken
+	client := newOAuthTestClient(t, portal, "old-refresh", WithTokenRefreshCallback(func(token OAuthToken) error {
+		saved = append(saved, token)
+		return nil
+	}))
+
+	deal, err := client.GetDeal("7")
+	if err != nil {
+		t.Fatalf("GetDeal() error = %v", err)
+	}
+	if deal.Title != "Refreshed" {
+		t.Errorf("deal title = %q, want %q", deal.Title, "Refreshed")
+	}
+
+	if portal.refreshCalls != 1 {
+		t.Errorf("token endpoint called %d times, want 1", portal.refreshCalls)
+	}
+	for _, param := range []string{"grant_type=refresh_token", "client_id=app.1", "client_secret=secret", "refresh_token=old-refresh"} {
+		if !strings.Contains(portal.refreshParams, param) {
+			t.Errorf("refresh request %q missing %q", portal.refreshParams, param)
+		}
+	}
+
+	want := OAuthToken{AccessToken: "new-access", RefreshToken: "new-refresh", ExpiresIn: 3600}
+	if len(saved) != 1 || saved[0] != want {
+		t.Errorf("saved tokens = %+v, want [%+v]", saved, want)
+	}
+
+	// The new token is used directly afterwards
+	if _, err := client.GetDeal("7"); err != nil {
+		t.Fatalf("second GetDeal() error = %v", err)
+	}
+	if portal.refreshCalls != 1 {
+		t.Errorf("token endpoint called %d times after second request, want 1", portal.refreshCalls)
+	}
+}
+
+func TestOAuthClientRefreshFailure(t *testing.T) {
+	portal := &oauthPortal{validToken: "new-access"}
+	client := newOAuthTestClient(t, portal, "revoked-refresh")
+
+	_, err := client.GetDeal("7")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != "invalid_grant" {
+		t.Fatalf("GetDeal() error = %v, want invalid_grant APIError", err)
+	}
+	if portal.refreshCalls != 1 {
+		t.Errorf("token endpoint called %d times, want 1", portal.refreshCalls)
+	}
+}
+
+func TestOAuthClientSaveFailureKeepsToken(t *testing.T) {
+	portal := &oauthPortal{validToken: "new-access"}
+	var logs strings.Builder
+	client := newOAuthTestClient(t, portal, "old-refresh",
+		WithLogger(NewLogger(&logs, LogLevelInfo)),
+		WithTokenRefreshCallback(func(OAuthToken) error { return errors.New("disk full") }))
+
+	if _, err := client.GetDeal("7"); err != nil {
+		t.Fatalf("GetDeal() error = %v", err)
+	}
+	if !strings.Contains(logs.String(), "disk full") {
+		t.Errorf("expected a warning about the failed save, got %q", logs.String())
+	}
+}
@@ -0,0 +1,72 @@
+package bitrix
+
+// Dry-run plan actions: sections are "existing", "new" or "move" (legacy customer section
+// moved from the catalog root with --migrate), products are "create" or "skip"
+const (
+	PlanActionExisting = "existing"
+	PlanActionNew      = "new"
+	PlanActionMove     = "move"
+	PlanActionCreate   = "create"
+	PlanActionSkip     = "skip"
+)
+
+// DryRunPlan collects the decisions of a dry run (see WithDryRunPlan) so they can be
+// saved as JSON and reviewed or diffed before the real run
+type DryRunPlan struct {
+	DealID          string           `json:"deal_id,omitempty"`
+	CompaniesFolder *PlannedSection  `json:"companies_folder,omitempty"`
+	CustomerSection *PlannedSection  `json:"customer_section,omitempty"`
+	ProjectSection  *PlannedSection  `json:"project_section,omitempty"`
+	DirSections     []PlannedSection `json:"dir_sections,omitempty"`
+	Products        []PlannedProduct `json:"products"`
+}
+
+// PlannedSection is a catalog section the run would reuse, create or move; ID is empty for new sections
+type PlannedSection struct {
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	ID     string `json:"id,omitempty"`
+}
+
+// PlannedProduct is a product the run would create or skip as already existing (ID is the existing product)
+type PlannedProduct struct {
+	Name     string  `json:"name"`
+	Action   string  `json:"action"`
+	ID       string  `json:"id,omitempty"`
+	Quantity float64 `json:"quantity"`
+	Dir      string  `json:"dir,omitempty"`
+}
+
+// Section levels of a dry-run plan, see DryRunPlan.addSection
+const (
+	planCompaniesFolder = "companies"
+	planCustomerSection = "customer"
+	planProjectSection  = "project"
+	planDirSection      = "dir"
+)
+
+// addSection records a section decision at the given level; a nil plan (no WithDryRunPlan) records nothing
+func (p *DryRunPlan) addSection(level, name, action, id string) {
+	if p == nil {
+		return
+	}
+	section := PlannedSection{Name: name, Action: action, ID: id}
+	switch level {
+	case planCompaniesFolder:
+		p.CompaniesFolder = &section
+	case planCustomerSection:
+		p.CustomerSection = &section
+	case planProjectSection:
+		p.ProjectSection = &section
+	default:
+		p.DirSections = append(p.DirSections, section)
+	}
+}
+
+// addProduct records a product decision
+func (p *DryRunPlan) addProduct(product PlannedProduct) {
+	if p == nil {
+		return
+	}
+	p.Products = append(p.Products, product)
+}
\ No newline at end of file
@@ -2,14 +2,21 @@ package bitrix
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// reportDateLayout is the ISO 8601 layout used for DATE_CREATE filter values
+const reportDateLayout = time.RFC3339
+
 // ListDealsWithCustomFields retrieves deals with custom fields, excluding specified statuses
+// exclusions - excluded stages, per category if needed (one crm.deal.list call per filter, results merged)
 // categoryIDs - optional list of category IDs to filter by (empty = all categories)
-func (c *Client) ListDealsWithCustomFields(customFields ReportCustomFields, excludedStatuses []string, categoryIDs []string) ([]DealReportRow, error) {
+// dateFrom, dateTo - optional creation date bounds, inclusive (zero value = unbounded)
+func (c *Client) ListDealsWithCustomFields(customFields ReportCustomFields, exclusions ReportStatusExclusions, categoryIDs []string, dateFrom, dateTo time.Time) ([]DealReportRow, error) {
 	// Build select fields list - standard fields + custom fields
 	selectFields := []string{
 		"ID",
@@ -17,6 +24,7 @@ func (c *Client) ListDealsWithCustomFields(customFields ReportCustomFields, excl
 		"DATE_CREATE",
 		"CATEGORY_ID",
 		"OPPORTUNITY",
+		"CURRENCY_ID",
 	}
 
 	// Add custom fields to select if they are configured
@@ -36,46 +44,35 @@ func (c *Client) ListDealsWithCustomFields(customFields ReportCustomFields, excl
 		selectFields = append(selectFields, customFields.PaymentReceived)
 	}
 
-	// Build filter to exclude final statuses
-	filter := make(map[string]interface{})
-
-	// Exclude specified statuses (e.g., WON, LOST)
-	// In Bitrix24, to exclude multiple values, we use "!@STAGE_ID" with array
-	if len(excludedStatuses) > 0 {
-		// Convert to interface slice for the filter
-		statusesInterface := make([]interface{}, len(excludedStatuses))
-		for i, status := range excludedStatuses {
-			statusesInterface[i] = status
+	// Merge the results of every filter; a deal can only match one category, IDs are checked anyway
+	var result []map[string]interface{}
+	seen := make(map[string]bool)
+	for _, filter := range buildDealReportFilters(exclusions, categoryIDs, dateFrom, dateTo) {
+		params := map[string]interface{}{
+			"select": selectFields,
+			"filter": filter,
+			"order":  map[string]interface{}{"ID": "ASC"}, // Sort by ID ascending
 		}
-		filter["!@STAGE_ID"] = statusesInterface
-	}
 
-	// Filter by category IDs if specified
-	// In Bitrix24, to filter by multiple values, we use "@CATEGORY_ID" with array
-	if len(categoryIDs) > 0 {
-		// Convert to interface slice for the filter
-		categoriesInterface := make([]interface{}, len(categoryIDs))
-		for i, categoryID := range categoryIDs {
-			categoriesInterface[i] = categoryID
+		resp, err := c.makeRequest("crm.deal.list", params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list deals: %w", err)
 		}
-		filter["@CATEGORY_ID"] = categoriesInterface
-	}
-
-	params := map[string]interface{}{
-		"select": selectFields,
-		"filter": filter,
-		"order":  map[string]interface{}{"ID": "ASC"}, // Sort by ID ascending
-	}
 
-	resp, err := c.makeRequest("crm.deal.list", params)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list deals: %v", err)
-	}
+		// Parse response as array of maps
+		var page []map[string]interface{}
+		if err := c.parseResponse(resp, &page); err != nil {
+			return nil, fmt.Errorf("failed to parse deals response: %w", err)
+		}
 
-	// Parse response as array of maps
-	var result []map[string]interface{}
-	if err := c.parseResponse(resp, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse deals response: %v", err)
+		for _, dealMap := range page {
+			id := getStringValue(dealMap, "ID")
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			result = append(result, dealMap)
+		}
 	}
 
 	// Convert to DealReportRow structs
@@ -86,6 +83,7 @@ func (c *Client) ListDealsWithCustomFields(customFields ReportCustomFields, excl
 			Title:      getStringValue(dealMap, "TITLE"),
 			DateCreate: getStringValue(dealMap, "DATE_CREATE"),
 			CategoryID: getStringValue(dealMap, "CATEGORY_ID"),
+			CurrencyID: getStringValue(dealMap, "CURRENCY_ID"),
 		}
 
 		// Map custom fields
@@ -114,20 +112,170 @@ func (c *Client) ListDealsWithCustomFields(customFields ReportCustomFields, excl
 
 	// Sort by ID (converting string to int for proper numeric sorting)
 	sort.Slice(deals, func(i, j int) bool {
-		idI, errI := strconv.Atoi(deals[i].ID)
-		idJ, errJ := strconv.Atoi(deals[j].ID)
-		if errI != nil || errJ != nil {
-			// Fallback to string comparison if conversion fails
-			return deals[i].ID < deals[j].ID
-		}
-		return idI < idJ
+		return dealIDLess(deals[i].ID, deals[j].ID)
 	})
 
 	return deals, nil
 }
 
+// dealIDLess compares deal IDs as numbers, falling back to string comparison
+func dealIDLess(a, b string) bool {
+	idA, errA := strconv.Atoi(a)
+	idB, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return idA < idB
+}
+
+// DealReportSortFields are the fields the deals report can be sorted by
+var DealReportSortFields = []string{"id", "date", "total_cost", "opportunity"}
+
+// SortDealReportRows orders deals by field (see DealReportSortFields), ascending or descending.
+// Sorting is done locally because server-side ordering by custom fields is unreliable.
+// Deals with equal values stay in ID ascending order, deals without a value go last
+func SortDealReportRows(deals []DealReportRow, field string, desc bool) error {
+	var key func(deal DealReportRow) (float64, bool)
+	switch field {
+	case "id":
+		sort.SliceStable(deals, func(i, j int) bool {
+			if desc {
+				return dealIDLess(deals[j].ID, deals[i].ID)
+			}
+			return dealIDLess(deals[i].ID, deals[j].ID)
+		})
+		return nil
+	case "date":
+		key = func(deal DealReportRow) (float64, bool) {
+			created, err := time.Parse(reportDateLayout, deal.DateCreate)
+			if err != nil {
+				return 0, false
+			}
+			return float64(created.Unix()), true
+		}
+	case "total_cost":
+		key = func(deal DealReportRow) (float64, bool) { return dealAmount(deal.TotalCost) }
+	case "opportunity":
+		key = func(deal DealReportRow) (float64, bool) { return dealAmount(deal.Opportunity) }
+	default:
+		return fmt.Errorf("unsupported sort field %q (supported: %s)", field, strings.Join(DealReportSortFields, ", "))
+	}
+
+	sort.SliceStable(deals, func(i, j int) bool {
+		valueI, okI := key(deals[i])
+		valueJ, okJ := key(deals[j])
+		switch {
+		case okI != okJ:
+			return okI
+		case !okI || valueI == valueJ:
+			return dealIDLess(deals[i].ID, deals[j].ID)
+		case desc:
+			return valueI > valueJ
+		default:
+			return valueI < valueJ
+		}
+	})
+	return nil
+}
+
+// dealAmount parses a monetary field value with ParseCustomFieldValue
+func dealAmount(value interface{}) (float64, bool) {
+	amount, err := strconv.ParseFloat(ParseCustomFieldValue(value), 64)
+	return amount, err == nil
+}
+
+// buildDealReportFilters builds the crm.deal.list filters for the deals report.
+// Without per-category exclusions this is a single filter with the Default stages.
+// Otherwise every configured category gets its own filter, and one more filter covers
+// the remaining categories (of categoryIDs, or all other ones) with the Default stages
+func buildDealReportFilters(exclusions ReportStatusExclusions, categoryIDs []string, dateFrom, dateTo time.Time) []map[string]interface{} {
+	if len(exclusions.ByCategory) == 0 {
+		return []map[string]interface{}{buildDealReportFilter(exclusions.Default, categoryIDs, dateFrom, dateTo)}
+	}
+
+	configured := make([]string, 0, len(exclusions.ByCategory))
+	for categoryID := range exclusions.ByCategory {
+		configured = append(configured, categoryID)
+	}
+	sort.Strings(configured)
+
+	requested := make(map[string]bool, len(categoryIDs))
+	for _, categoryID := range categoryIDs {
+		requested[categoryID] = true
+	}
+
+	var filters []map[string]interface{}
+	for _, categoryID := range configured {
+		if len(requested) > 0 && !requested[categoryID] {
+			continue
+		}
+		filters = append(filters, buildDealReportFilter(exclusions.ByCategory[categoryID], []string{categoryID}, dateFrom, dateTo))
+	}
+
+	if len(requested) == 0 {
+		// All categories that have no own exclusions
+		rest := buildDealReportFilter(exclusions.Default, nil, dateFrom, dateTo)
+		others := make([]interface{}, len(configured))
+		for i, categoryID := range configured {
+			others[i] = categoryID
+		}
+		rest["!@CATEGORY_ID"] = others
+		return append(filters, rest)
+	}
+
+	var rest []string
+	for _, categoryID := range categoryIDs {
+		if _, exists := exclusions.ByCategory[categoryID]; !exists {
+			rest = append(rest, categoryID)
+		}
+	}
+	if len(rest) > 0 {
+		filters = append(filters, buildDealReportFilter(exclusions.Default, rest, dateFrom, dateTo))
+	}
+	return filters
+}
+
+// buildDealReportFilter builds crm.deal.list filter for the deals report
+// Zero dateFrom/dateTo values leave the corresponding bound open
+func buildDealReportFilter(excludedStatuses []string, categoryIDs []string, dateFrom, dateTo time.Time) map[string]interface{} {
+	filter := make(map[string]interface{})
+
+	// Exclude specified statuses (e.g., WON, LOST)
+	// In Bitrix24, to exclude multiple values, we use "!@STAGE_ID" with array
+	if len(excludedStatuses) > 0 {
+		// Convert to interface slice for the filter
+		statusesInterface := make([]interface{}, len(excludedStatuses))
+		for i, status := range excludedStatuses {
+			statusesInterface[i] = status
+		}
+		filter["!@STAGE_ID"] = statusesInterface
+	}
+
+	// Filter by category IDs if specified
+	// In Bitrix24, to filter by multiple values, we use "@CATEGORY_ID" with array
+	if len(categoryIDs) > 0 {
+		// Convert to interface slice for the filter
+		categoriesInterface := make([]interface{}, len(categoryIDs))
+		for i, categoryID := range categoryIDs {
+			categoriesInterface[i] = categoryID
+		}
+		filter["@CATEGORY_ID"] = categoriesInterface
+	}
+
+	// Filter by creation date range if bounds are specified
+	if !dateFrom.IsZero() {
+		filter[">=DATE_CREATE"] = dateFrom.Format(reportDateLayout)
+	}
+	if !dateTo.IsZero() {
+		filter["<=DATE_CREATE"] = dateTo.Format(reportDateLayout)
+	}
+
+	return filter
+}
+
 // ParseCustomFieldValue converts a custom field value to a standardized format
-// Returns string representation of the value, handling numbers, strings, and booleans
+// Returns string representation of the value, handling numbers, strings, booleans
+// and multiple-value fields (values joined with ", ")
 func ParseCustomFieldValue(value interface{}) string {
 	if value == nil {
 		return ""
@@ -136,11 +284,8 @@ func ParseCustomFieldValue(value interface{}) string {
 	switch v := value.(type) {
 	case string:
 		// Remove currency suffix from monetary fields (e.g., "1000|RUB" -> "1000")
-		if strings.Contains(v, "|") {
-			parts := strings.Split(v, "|")
-			return parts[0]
-		}
-		return v
+		amount, _, _ := strings.Cut(v, "|")
+		return normalizeAmount(amount)
 	case float64:
 		// Check if it's an integer
 		if v == float64(int64(v)) {
@@ -156,11 +301,52 @@ func ParseCustomFieldValue(value interface{}) string {
 			return "Да"
 		}
 		return "Нет"
+	case []interface{}:
+		// Multiple-value fields come as arrays, e.g. ["1000|RUB", "2000|RUB"]
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if parsed := ParseCustomFieldValue(item); parsed != "" {
+				values = append(values, parsed)
+			}
+		}
+		return strings.Join(values, ", ")
 	default:
 		return fmt.Sprintf("%v", v)
 	}
 }
 
+// normalizeAmount removes thousand separators from numeric strings ("1 234,5" -> "1234.5",
+// "-1,234.50" -> "-1234.50"); strings that are not numbers are returned unchanged
+func normalizeAmount(value string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '\u00a0', '\u202f':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(value))
+
+	switch {
+	case strings.Contains(cleaned, ",") && strings.Contains(cleaned, "."):
+		// "1,234.50": comma is the thousand separator
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	case thousandsCommaRegex.MatchString(cleaned):
+		// "1,234" or "1,234,567": comma groups of three digits
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	default:
+		// "1234,5": comma is the decimal separator
+		cleaned = strings.Replace(cleaned, ",", ".", 1)
+	}
+
+	if _, err := strconv.ParseFloat(cleaned, 64); err != nil {
+		return value
+	}
+	return cleaned
+}
+
+// thousandsCommaRegex matches numbers with comma-separated thousand groups
+var thousandsCommaRegex = regexp.MustCompile(`^-?\d{1,3}(,\d{3})+$`)
+
 // getStringValue safely extracts a string value from a map
 func getStringValue(m map[string]interface{}, key string) string {
 	if val, ok := m[key]; ok {
@@ -172,6 +358,21 @@ func getStringValue(m map[string]interface{}, key string) string {
 	return ""
 }
 
+// GetBaseCurrency returns the portal base currency code (crm.currency.base.get),
+// the currency of deals without CURRENCY_ID
+func (c *Client) GetBaseCurrency() (string, error) {
+	resp, err := c.makeRequest("crm.currency.base.get", map[string]interface{}{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get base currency: %w", err)
+	}
+
+	var currency string
+	if err := c.parseResponse(resp, &currency); err != nil {
+		return "", fmt.Errorf("failed to parse base currency response: %w", err)
+	}
+	return currency, nil
+}
+
 // ListDealCategories retrieves deal categories (funnels) from Bitrix24
 // Returns a map of category ID to category name for quick lookups
 func (c *Client) ListDealCategories() (map[string]string, error) {
@@ -181,20 +382,20 @@ func (c *Client) ListDealCategories() (map[string]string, error) {
 
 	resp, err := c.makeRequest("crm.category.list", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list categories: %v", err)
+		return nil, fmt.Errorf("failed to list categories: %w", err)
 	}
 
-	// Parse response
+	// Parse response (parseResponse unwraps "result", categories are nested inside it)
 	var result struct {
-		Result []DealCategory `json:"result"`
+		Categories []DealCategory `json:"categories"`
 	}
 	if err := c.parseResponse(resp, &result); err != nil {
-		return nil, fmt.Errorf("failed to parse categories response: %v", err)
+		return nil, fmt.Errorf("failed to parse categories response: %w", err)
 	}
 
 	// Convert to map: ID -> Name
 	categoryMap := make(map[string]string)
-	for _, category := range result.Result {
+	for _, category := range result.Categories {
 		categoryMap[fmt.Sprintf("%d", category.ID)] = category.Name
 	}
 
@@ -0,0 +1,189 @@
+package bitrix
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGetBaseCurrency(t *testing.T) {
+	client := newTestClient(t, map[string]string{"crm.currency.base.get": `{"result":"RUB"}`})
+	currency, err := client.GetBaseCurrency()
+	if err != nil {
+		t.Fatalf("GetBaseCurrency() error = %v", err)
+	}
+	if currency != "RUB" {
+		t.Errorf("GetBaseCurrency() = %q, want RUB", currency)
+	}
+}
+
+func TestBuildDealReportFilter(t *testing.T) {
+	loc := time.FixedZone("MSK", 3*60*60)
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, loc)
+	to := time.Date(2025, 1, 31, 23, 59, 59, 0, loc)
+
+	tests := []struct {
+		name             string
+		excludedStatuses []string
+		categoryIDs      []string
+		dateFrom         time.Time
+		dateTo           time.Time
+		expected         map[string]interface{}
+	}{
+		{
+			name:     "empty filter",
+			expected: map[string]interface{}{},
+		},
+		{
+			name:             "statuses and categories",
+			excludedStatuses: []string{"WON", "LOST"},
+			categoryIDs:      []string{"1", "3"},
+			expected: map[string]interface{}{
+				"!@STAGE_ID":   []interface{}{"WON", "LOST"},
+				"@CATEGORY_ID": []interface{}{"1", "3"},
+			},
+		},
+		{
+			name:     "closed date range",
+			dateFrom: from,
+			dateTo:   to,
+			expected: map[string]interface{}{
+				">=DATE_CREATE": "2025-01-01T00:00:00+03:00",
+				"<=DATE_CREATE": "2025-01-31T23:59:59+03:00",
+			},
+		},
+		{
+			name:     "open-ended upper bound",
+			dateFrom: from,
+			expected: map[string]interface{}{
+				">=DATE_CREATE": "2025-01-01T00:00:00+03:00",
+			},
+		},
+		{
+			name:   "open-ended lower bound",
+			dateTo: to,
+			expected: map[string]interface{}{
+				"<=DATE_CREATE": "2025-01-31T23:59:59+03:00",
+			},
+		},
+		{
+			name:             "all filters combined",
+			excludedStatuses: []string{"WON"},
+			dateFrom:         from,
+			dateTo:           to,
+			expected: map[string]interface{}{
+				"!@STAGE_ID":    []interface{}{"WON"},
+				">=DATE_CREATE": "2025-01-01T00:00:00+03:00",
+				"<=DATE_CREATE": "2025-01-31T23:59:59+03:00",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := buildDealReportFilter(tt.excludedStatuses, tt.categoryIDs, tt.dateFrom, tt.dateTo)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("buildDealReportFilter() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildDealReportFilters(t *testing.T) {
+	exclusions := ReportStatusExclusions{
+		Default: []string{"WON", "LOSE"},
+		ByCategory: map[string][]string{
+			"7": {"C7:WON"},
+			"5": {"C5:WON", "C5:LOSE"},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		exclusions  ReportStatusExclusions
+		categoryIDs []string
+		expected    []map[string]interface{}
+	}{
+		{
+			name:        "flat list",
+			exclusions:  ReportStatusExclusions{Default: []string{"WON", "LOST"}},
+			categoryIDs: []string{"1"},
+			expected: []map[string]interface{}{
+				{"!@STAGE_ID": []interface{}{"WON", "LOST"}, "@CATEGORY_ID": []interface{}{"1"}},
+			},
+		},
+		{
+			name:       "per category, all categories",
+			exclusions: exclusions,
+			expected: []map[string]interface{}{
+				{"!@STAGE_ID": []interface{}{"C5:WON", "C5:LOSE"}, "@CATEGORY_ID": []interface{}{"5"}},
+				{"!@STAGE_ID": []interface{}{"C7:WON"}, "@CATEGORY_ID": []interface{}{"7"}},
+				{"!@STAGE_ID": []interface{}{"WON", "LOSE"}, "!@CATEGORY_ID": []interface{}{"5", "7"}},
+			},
+		},
+		{
+			name:        "per category, requested categories",
+			exclusions:  exclusions,
+			categoryIDs: []string{"0", "5"},
+			expected: []map[string]interface{}{
+				{"!@STAGE_ID": []interface{}{"C5:WON", "C5:LOSE"}, "@CATEGORY_ID": []interface{}{"5"}},
+				{"!@STAGE_ID": []interface{}{"WON", "LOSE"}, "@CATEGORY_ID": []interface{}{"0"}},
+			},
+		},
+		{
+			name:        "per category, only configured categories requested",
+			exclusions:  exclusions,
+			categoryIDs: []string{"7"},
+			expected: []map[string]interface{}{
+				{"!@STAGE_ID": []interface{}{"C7:WON"}, "@CATEGORY_ID": []interface{}{"7"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := buildDealReportFilters(tt.exclusions, tt.categoryIDs, time.Time{}, time.Time{})
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("buildDealReportFilters() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSortDealReportRows(t *testing.T) {
+	deals := []DealReportRow{
+		{ID: "3", DateCreate: "2025-02-01T10:00:00+03:00", Opportunity: "100"},
+		{ID: "12", DateCreate: "2025-01-01T10:00:00+03:00"},
+		{ID: "4", DateCreate: "2025-03-01T10:00:00+03:00", Opportunity: "100|RUB"},
+		{ID: "1", DateCreate: "", Opportunity: "50"},
+	}
+
+	tests := []struct {
+		field    string
+		desc     bool
+		expected []string
+	}{
+		{"id", false, []string{"1", "3", "4", "12"}},
+		{"id", true, []string{"12", "4", "3", "1"}},
+		{"date", true, []string{"4", "3", "12", "1"}},
+		{"opportunity", false, []string{"1", "3", "4", "12"}},
+		{"opportunity", true, []string{"3", "4", "1", "12"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s desc=%v", tt.field, tt.desc), func(t *testing.T) {
+			sorted := append([]DealReportRow(nil), deals...)
+			if err := SortDealReportRows(sorted, tt.field, tt.desc); err != nil {
+				t.Fatalf("SortDealReportRows() error = %v", err)
+			}
+			ids := make([]string, len(sorted))
+			for i, deal := range sorted {
+				ids[i] = deal.ID
+			}
+			if !reflect.DeepEqual(ids, tt.expected) {
+				t.Errorf("SortDealReportRows() IDs = %v, want %v", ids, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package bitrix
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultTimeout is the HTTP timeout of the default client (see WithTimeout)
+const defaultTimeout = 30 * time.Second
+
+// defaultRetryBackoff is the delay before the first retry, doubled for every next one (see WithMaxRetries)
+const defaultRetryBackoff = time.Second
+
+// rateLimiter spaces requests at least interval apart; it is safe for concurrent use
+type rateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func newRateLimiter(requestsPerSecond float64) *rateLimiter {
+	return &rateLimiter{interval: time.Duration(float64(time.Second) / requestsPerSecond)}
+}
+
+// wait blocks until the next request is allowed. The lock is held while sleeping,
+// so concurrent callers are released one by one in order
+func (l *rateLimiter) wait() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if l.next.After(now) {
+		time.Sleep(l.next.Sub(now))
+		now = l.next
+	}
+	l.next = now.Add(l.interval)
+}
+
+// isRetryable reports whether a failed attempt of method may succeed when repeated without
+// doing its work twice. Rate limiting (429, QUERY_LIMIT_EXCEEDED with 503) and connection
+// failures before the request was sent are retried for every method. Other network errors and
+// 5xx may come after Bitrix24 has applied a write, so they are retried only for read methods.
+// Other API errors come with 200 or 4xx and are not retried
+func isRetryable(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		return isDialError(err) || isReadMethod(method)
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.StatusCode < http.StatusInternalServerError {
+		return false
+	}
+	return isReadMethod(method) || isQueryLimitExceeded(resp)
+}
+
+// isReadMethod reports whether a Bitrix24 method only reads data (crm.deal.get, crm.deal.list, ...)
+func isReadMethod(method string) bool {
+	return strings.HasSuffix(method, ".get") || strings.HasSuffix(method, ".list")
+}
+
+// isDialError reports whether the connection could not be established, so the request was never sent
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// isQueryLimitExceeded reports whether resp is a QUERY_LIMIT_EXCEEDED error.
+// The body is read and replaced, so the response can still be parsed
+func isQueryLimitExceeded(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	_, err = decodeResponse(resp.StatusCode, body)
+	return errors.Is(err, ErrQueryLimitExceeded)
+}
+
+// retryReason describes a failed attempt for the log without the request URL (it holds the webhook token)
+func retryReason(resp *http.Response, err error) string {
+	if err == nil {
+		return resp.Status
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err.Error()
+	}
+	return err.Error()
+}
+
+// retryDelay returns the backoff before retry number attempt (1-based)
+func (c *Client) retryDelay(attempt int) time.Duration {
+	return c.retryBackoff << (attempt - 1)
+}
\ No newline at end of file
@@ -4,7 +4,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,29 +17,25 @@ func (c *Client) CheckStoreDocumentMode() (bool, error) {
 
 	resp, err := c.makeJSONRequest("catalog.document.mode.status", params)
 	if err != nil {
-		return false, fmt.Errorf("failed to check warehouse mode: %v", err)
+		return false, fmt.Errorf("failed to check warehouse mode: %w", err)
 	}
 
 	// Read raw response for parsing
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return false, fmt.Errorf("failed to read response body: %v", err)
+		return false, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Parse response manually
 	var rawResponse map[string]interface{}
 	if err := json.Unmarshal(body, &rawResponse); err != nil {
-		return false, fmt.Errorf("failed to unmarshal raw response: %v", err)
+		return false, fmt.Errorf("failed to unmarshal raw response: %w", err)
 	}
 
 	// Check if there's an error in response
-	if errorObj, exists := rawResponse["error"]; exists {
-		errorDesc := ""
-		if desc, ok := rawResponse["error_description"].(string); ok {
-			errorDesc = fmt.Sprintf(": %s", desc)
-		}
-		return false, fmt.Errorf("Bitrix24 API error %v%s", errorObj, errorDesc)
+	if apiErr := rawAPIError(resp.StatusCode, rawResponse); apiErr != nil {
+		return false, apiErr
 	}
 
 	// Extract result
@@ -54,7 +53,9 @@ func (c *Client) CheckStoreDocumentMode() (bool, error) {
 }
 
 // CreateStoreDocument creates a new warehouse receipt document
-func (c *Client) CreateStoreDocument(deal *Deal, currency, commentary string) (string, error) {
+// dealField is the portal-specific custom field code that links the document to the deal
+// (e.g. UF_CAT_STORE_DOCUMENT_S_1758649547); the link is not set when dealField is empty
+func (c *Client) CreateStoreDocument(deal *Deal, currency, commentary, dealField string) (string, error) {
 	// Use current date in Bitrix24 format
 	currentDate := time.Now().Format(time.RFC3339)
 
@@ -71,9 +72,11 @@ func (c *Client) CreateStoreDocument(deal *Deal, currency, commentary string) (s
 		"dateDocument": currentDate,
 		"commentary":   commentary,
 		"title":        fmt.Sprintf("Оприходование изделий по сделке %s", deal.ID), // Document title (will be updated with ID)
-		"UF_CAT_STORE_DOCUMENT_S_1758649547": deal.ID, // Link to deal (custom field)
 		// Do not set status field - let API use default status
 	}
+	if dealField != "" {
+		fields[dealField] = deal.ID // Link to deal (custom field)
+	}
 
 	params := map[string]interface{}{
 		"fields": fields,
@@ -81,29 +84,25 @@ func (c *Client) CreateStoreDocument(deal *Deal, currency, commentary string) (s
 
 	resp, err := c.makeJSONRequest("catalog.document.add", params)
 	if err != nil {
-		return "", fmt.Errorf("failed to create warehouse document: %v", err)
+		return "", fmt.Errorf("failed to create warehouse document: %w", err)
 	}
 
 	// Read raw response for parsing
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response body: %v", err)
+		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Parse response manually
 	var rawResponse map[string]interface{}
 	if err := json.Unmarshal(body, &rawResponse); err != nil {
-		return "", fmt.Errorf("failed to unmarshal raw response: %v", err)
+		return "", fmt.Errorf("failed to unmarshal raw response: %w", err)
 	}
 
 	// Check if there's an error in response
-	if errorObj, exists := rawResponse["error"]; exists {
-		errorDesc := ""
-		if desc, ok := rawResponse["error_description"].(string); ok {
-			errorDesc = fmt.Sprintf(": %s", desc)
-		}
-		return "", fmt.Errorf("Bitrix24 API error %v%s", errorObj, errorDesc)
+	if apiErr := rawAPIError(resp.StatusCode, rawResponse); apiErr != nil {
+		return "", apiErr
 	}
 
 	// Extract result
@@ -139,8 +138,7 @@ func (c *Client) CreateStoreDocument(deal *Deal, currency, commentary string) (s
 						return "", fmt.Errorf("unexpected nested document ID type: %T", docIdVal)
 					}
 				} else {
-					// Debug: show what's in the document object
-					fmt.Printf("DEBUG: Document object contents: %+v\n", idVal)
+					c.logger.Debug("Document object contents: %+v", idVal)
 					return "", fmt.Errorf("no 'id' field found in document object")
 				}
 			default:
@@ -156,8 +154,7 @@ func (c *Client) CreateStoreDocument(deal *Deal, currency, commentary string) (s
 				return "", fmt.Errorf("unexpected ID type in result object: %T", idVal)
 			}
 		} else {
-			// Debug: show what's in the result object
-			fmt.Printf("DEBUG: Result object contents: %+v\n", v)
+			c.logger.Debug("Result object contents: %+v", v)
 			return "", fmt.Errorf("no 'document' or 'id' field found in result object")
 		}
 	default:
@@ -168,19 +165,39 @@ func (c *Client) CreateStoreDocument(deal *Deal, currency, commentary string) (s
 }
 
 
-// AddElementsToStoreDocument adds product elements to a warehouse document
-func (c *Client) AddElementsToStoreDocument(documentID string, products []DealProductRow, storeID string) error {
+// AddElementsToStoreDocument adds product elements to a warehouse document and returns
+// the number of elements actually added. Every product is attempted; an add counts as
+// successful only when Bitrix24 returns the new element ID. If fewer elements were added
+// than products, strict mode returns an error (the document must not be confirmed),
+// otherwise the mismatch is logged as a warning.
+func (c *Client) AddElementsToStoreDocument(documentID string, products []DealProductRow, storeID string, strict bool) (int, error) {
+	added := 0
+	var failures []string
 	for _, product := range products {
-		err := c.addSingleElementToDocument(documentID, product, storeID)
+		elementID, err := c.addSingleElementToDocument(documentID, product, storeID)
 		if err != nil {
-			return fmt.Errorf("failed to add product %s to document: %v", product.ProductID.String(), err)
+			failures = append(failures, fmt.Sprintf("product %s: %v", product.ProductID.String(), err))
+			continue
 		}
+		c.logger.Debug("Товар %s добавлен в документ %s (элемент %s)", product.ProductID.String(), documentID, elementID)
+		added++
 	}
-	return nil
+
+	if added != len(products) {
+		mismatch := fmt.Errorf("added %d of %d elements to document %s: %s",
+			added, len(products), documentID, strings.Join(failures, "; "))
+		if strict {
+			return added, mismatch
+		}
+		c.logger.Warn("%v", mismatch)
+	}
+
+	return added, nil
 }
 
 // addSingleElementToDocument adds a single product element to warehouse document
-func (c *Client) addSingleElementToDocument(documentID string, product DealProductRow, storeID string) error {
+// and returns the ID of the created element
+func (c *Client) addSingleElementToDocument(documentID string, product DealProductRow, storeID string) (string, error) {
 	// Convert documentID to integer if it's a numeric string
 	var docID interface{} = documentID
 	if id, err := strconv.Atoi(documentID); err == nil {
@@ -203,7 +220,7 @@ func (c *Client) addSingleElementToDocument(documentID string, product DealProdu
 		"sellingPrice":   product.Price,              // Selling price from deal
 	}
 
-	fmt.Printf("DEBUG: Добавляем товар %s (количество: %.2f, цена продажи: %.2f) в документ %v на склад %v\n",
+	c.logger.Debug("Добавляем товар %s (количество: %.2f, цена продажи: %.2f) в документ %v на склад %v",
 		product.ProductID.String(), product.Quantity, product.Price, docID, storeToID)
 
 	params := map[string]interface{}{
@@ -212,33 +229,61 @@ func (c *Client) addSingleElementToDocument(documentID string, product DealProdu
 
 	resp, err := c.makeRequest("catalog.document.element.add", params)
 	if err != nil {
-		return fmt.Errorf("failed to add element to document: %v", err)
+		return "", fmt.Errorf("failed to add element to document: %w", err)
 	}
 
 	// Read raw response for parsing
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %v", err)
+		return "", fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Parse response manually since structure changed
 	var rawResponse map[string]interface{}
 	if err := json.Unmarshal(body, &rawResponse); err != nil {
-		return fmt.Errorf("failed to unmarshal raw response: %v", err)
+		return "", fmt.Errorf("failed to unmarshal raw response: %w", err)
 	}
 
 	// Check if there's an error in response
-	if errorObj, exists := rawResponse["error"]; exists {
-		errorDesc := ""
-		if desc, ok := rawResponse["error_description"].(string); ok {
-			errorDesc = fmt.Sprintf(": %s", desc)
-		}
-		return fmt.Errorf("Bitrix24 API error %v%s", errorObj, errorDesc)
+	if apiErr := rawAPIError(resp.StatusCode, rawResponse); apiErr != nil {
+		return "", apiErr
 	}
 
-	// result contains the element ID, we don't need to use it here
-	return nil
+	// result contains the created element; without its ID the add silently did nothing
+	elementID := extractElementID(rawResponse["result"])
+	if elementID == "" {
+		return "", fmt.Errorf("no element ID returned (result: %v)", rawResponse["result"])
+	}
+
+	return elementID, nil
+}
+
+// extractElementID returns the element ID from catalog.document.element.add result
+// Supports {"documentElement": {"id": N}}, {"element": {"id": N}}, {"id": N} and plain N
+func extractElementID(result interface{}) string {
+	switch value := result.(type) {
+	case float64:
+		if value > 0 {
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	case string:
+		if value != "" && value != "0" {
+			return value
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"documentElement", "element"} {
+			if nested, ok := value[key]; ok {
+				return extractElementID(nested)
+			}
+		}
+		for _, key := range []string{"id", "ID"} {
+			if id, ok := value[key]; ok {
+				return extractElementID(id)
+			}
+		}
+	}
+	return ""
 }
 
 // ConfirmStoreDocument confirms (проводит) the warehouse document to update inventory
@@ -255,29 +300,25 @@ func (c *Client) ConfirmStoreDocument(documentID string) error {
 
 	resp, err := c.makeJSONRequest("catalog.document.confirm", params)
 	if err != nil {
-		return fmt.Errorf("failed to confirm warehouse document: %v", err)
+		return fmt.Errorf("failed to confirm warehouse document: %w", err)
 	}
 
 	// Read raw response for parsing
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response body: %v", err)
+		return fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Parse response manually
 	var rawResponse map[string]interface{}
 	if err := json.Unmarshal(body, &rawResponse); err != nil {
-		return fmt.Errorf("failed to unmarshal raw response: %v", err)
+		return fmt.Errorf("failed to unmarshal raw response: %w", err)
 	}
 
 	// Check if there's an error in response
-	if errorObj, exists := rawResponse["error"]; exists {
-		errorDesc := ""
-		if desc, ok := rawResponse["error_description"].(string); ok {
-			errorDesc = fmt.Sprintf(": %s", desc)
-		}
-		return fmt.Errorf("Bitrix24 API error %v%s", errorObj, errorDesc)
+	if apiErr := rawAPIError(resp.StatusCode, rawResponse); apiErr != nil {
+		return apiErr
 	}
 
 	// Extract result
@@ -306,25 +347,25 @@ func (c *Client) GetStore(storeID string) (*Store, error) {
 
 	resp, err := c.makeJSONRequest("catalog.store.get", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get store: %v", err)
+		return nil, fmt.Errorf("failed to get store: %w", err)
 	}
 
 	// Read raw response for parsing
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Parse response manually
 	var rawResponse map[string]interface{}
 	if err := json.Unmarshal(body, &rawResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal raw response: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal raw response: %w", err)
 	}
 
 	// Check if there's an error in response
-	if errorObj, exists := rawResponse["error"]; exists {
-		return nil, fmt.Errorf("Bitrix24 API error: %v", errorObj)
+	if apiErr := rawAPIError(resp.StatusCode, rawResponse); apiErr != nil {
+		return nil, apiErr
 	}
 
 	// Extract result
@@ -346,12 +387,12 @@ func (c *Client) GetStore(storeID string) (*Store, error) {
 	// Convert to JSON and back to parse into Store struct
 	storeJSON, err := json.Marshal(storeData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal store data: %v", err)
+		return nil, fmt.Errorf("failed to marshal store data: %w", err)
 	}
 
 	var store Store
 	if err := json.Unmarshal(storeJSON, &store); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal store: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal store: %w", err)
 	}
 
 	return &store, nil
@@ -365,25 +406,25 @@ func (c *Client) ListStores() ([]Store, error) {
 
 	resp, err := c.makeJSONRequest("catalog.store.list", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list stores: %v", err)
+		return nil, fmt.Errorf("failed to list stores: %w", err)
 	}
 
 	// Read raw response body for parsing
 	defer resp.Body.Close()
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %v", err)
+		return nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
 	// Parse response manually
 	var rawResponse map[string]interface{}
 	if err := json.Unmarshal(body, &rawResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal raw response: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal raw response: %w", err)
 	}
 
 	// Check if there's an error in response
-	if errorObj, exists := rawResponse["error"]; exists {
-		return nil, fmt.Errorf("Bitrix24 API error: %v", errorObj)
+	if apiErr := rawAPIError(resp.StatusCode, rawResponse); apiErr != nil {
+		return nil, apiErr
 	}
 
 	// Extract result
@@ -405,13 +446,157 @@ func (c *Client) ListStores() ([]Store, error) {
 	// Convert to JSON and back to parse into Store structs
 	storesJSON, err := json.Marshal(storesData)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal stores data: %v", err)
+		return nil, fmt.Errorf("failed to marshal stores data: %w", err)
 	}
 
 	var stores []Store
 	if err := json.Unmarshal(storesJSON, &stores); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal stores: %v", err)
+		return nil, fmt.Errorf("failed to unmarshal stores: %w", err)
 	}
 
 	return stores, nil
+}
+
+// ListStoreDocumentsForDeal returns receipt documents linked to the deal via the dealField custom field
+func (c *Client) ListStoreDocumentsForDeal(dealID, dealField string) ([]StoreDocument, error) {
+	if dealField == "" {
+		return nil, fmt.Errorf("deal link field is not set")
+	}
+
+	params := map[string]interface{}{
+		"select": []string{"id", "title", "docType", "status", "currency", "dateDocument", dealField},
+		"filter": map[string]interface{}{
+			"docType": "S",
+			dealField: dealID,
+		},
+		"order": map[string]interface{}{"id": "ASC"},
+	}
+
+	resp, err := c.makeJSONRequest("catalog.document.list", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list store documents: %w", err)
+	}
+
+	var result struct {
+		Documents []StoreDocument `json:"documents"`
+	}
+	if err := c.parseResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse store documents response: %w", err)
+	}
+
+	return result.Documents, nil
+}
+
+// GetStoreDocument returns the warehouse document by ID, or an error if it does not exist
+func (c *Client) GetStoreDocument(documentID string) (*StoreDocument, error) {
+	params := map[string]interface{}{
+		"select": []string{"id", "title", "docType", "status", "currency", "dateDocument"},
+		"filter": map[string]interface{}{"id": documentID},
+	}
+
+	resp, err := c.makeJSONRequest("catalog.document.list", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get store document: %w", err)
+	}
+
+	var result struct {
+		Documents []StoreDocument `json:"documents"`
+	}
+	if err := c.parseResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse store document response: %w", err)
+	}
+	if len(result.Documents) == 0 {
+		return nil, fmt.Errorf("store document %s not found", documentID)
+	}
+
+	return &result.Documents[0], nil
+}
+
+// ListStoreDocumentElements returns the product elements already added to the warehouse document
+func (c *Client) ListStoreDocumentElements(documentID string) ([]StoreDocumentElementRow, error) {
+	params := map[string]interface{}{
+		"select": []string{"id", "docId", "storeTo", "elementId", "amount"},
+		"filter": map[string]interface{}{"docId": documentID},
+		"order":  map[string]interface{}{"id": "ASC"},
+	}
+
+	resp, err := c.makeJSONRequest("catalog.document.element.list", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list store document elements: %w", err)
+	}
+
+	var result struct {
+		DocumentElements []StoreDocumentElementRow `json:"documentElements"`
+	}
+	if err := c.parseResponse(resp, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse store document elements response: %w", err)
+	}
+
+	return result.DocumentElements, nil
+}
+
+// MissingStoreDocumentProducts returns the deal product rows that have no element in the document yet.
+// Rows are matched by product ID; each existing element covers one row, so a product listed
+// twice in the deal needs two elements
+func MissingStoreDocumentProducts(products []DealProductRow, elements []StoreDocumentElementRow) []DealProductRow {
+	existing := make(map[string]int)
+	for _, element := range elements {
+		existing[element.ElementID.String()]++
+	}
+
+	var missing []DealProductRow
+	for _, product := range products {
+		id := product.ProductID.String()
+		if existing[id] > 0 {
+			existing[id]--
+			continue
+		}
+		missing = append(missing, product)
+	}
+	return missing
+}
+
+// quantityTolerance absorbs float noise when deal and document quantities are compared
+const quantityTolerance = 1e-6
+
+// StoreReconciliationRow compares the deal quantity of a product with the amount in the store document
+type StoreReconciliationRow struct {
+	ProductID        string
+	DealQuantity     float64
+	DocumentQuantity float64
+}
+
+// Matches reports whether the document has exactly the deal quantity of the product
+func (r StoreReconciliationRow) Matches() bool {
+	return math.Abs(r.DealQuantity-r.DocumentQuantity) < quantityTolerance
+}
+
+// ReconcileStoreDocument sums deal and document quantities per product ID.
+// Rows follow the deal order; products that are only in the document come last, sorted by ID
+func ReconcileStoreDocument(products []DealProductRow, elements []StoreDocumentElementRow) []StoreReconciliationRow {
+	index := make(map[string]int)
+	var rows []StoreReconciliationRow
+
+	row := func(productID string) *StoreReconciliationRow {
+		if i, exists := index[productID]; exists {
+			return &rows[i]
+		}
+		index[productID] = len(rows)
+		rows = append(rows, StoreReconciliationRow{ProductID: productID})
+		return &rows[len(rows)-1]
+	}
+
+	for _, product := range products {
+		row(product.ProductID.String()).DealQuantity += product.Quantity
+	}
+	dealRows := len(rows)
+
+	for _, element := range elements {
+		row(element.ElementID.String()).DocumentQuantity += element.Amount
+	}
+
+	extra := rows[dealRows:]
+	sort.Slice(extra, func(i, j int) bool { return extra[i].ProductID < extra[j].ProductID })
+
+	return rows
 }
\ No newline at end of file
@@ -1,6 +1,11 @@
 package bitrix
 
 import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 )
 
@@ -237,4 +242,361 @@ func TestStoreStructure(t *testing.T) {
 	if !isActive {
 		t.Errorf("Expected store to be active")
 	}
-}
\ No newline at end of file
+}
+
+func TestListStoresRoundTrip(t *testing.T) {
+	tests := []struct {
+		name          string
+		response      string
+		expectedIDs   []StoreID
+		errorContains string
+	}{
+		{
+			name:        "success",
+			response:    `{"result":{"stores":[{"id":1,"title":"Основной склад","active":"Y","sort":100},{"id":2,"title":"Резерв","active":"N","sort":200}]}}`,
+			expectedIDs: []StoreID{1, 2},
+		},
+		{
+			name:        "string IDs",
+			response:    `{"result":{"stores":[{"id":"3","title":"Склад","active":"Y","sort":100}]}}`,
+			expectedIDs: []StoreID{3},
+		},
+		{
+			name:        "empty list",
+			response:    `{"result":{"stores":[]}}`,
+			expectedIDs: []StoreID{},
+		},
+		{
+			name:          "API error",
+			response:      `{"error":"ACCESS_DENIED","error_description":"Access denied"}`,
+			errorContains: "ACCESS_DENIED",
+		},
+		{
+			name:          "missing stores field",
+			response:      `{"result":{}}`,
+			errorContains: "no 'stores' field",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(t, map[string]string{"catalog.store.list": tt.response})
+			stores, err := client.ListStores()
+
+			if tt.errorContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errorContains) {
+					t.Fatalf("expected error containing %q, got %v", tt.errorContains, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ListStores() error = %v", err)
+			}
+			if len(stores) != len(tt.expectedIDs) {
+				t.Fatalf("expected %d stores, got %d", len(tt.expectedIDs), len(stores))
+			}
+			for i, id := range tt.expectedIDs {
+				if stores[i].ID != id {
+					t.Errorf("store %d: expected ID %d, got %d", i, id, stores[i].ID)
+				}
+			}
+		})
+	}
+}
+
+// newElementAddServer returns a test server for catalog.document.element.add
+// that responds with responses[elementId] for each added product
+func newElementAddServer(t *testing.T, responses map[string]string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/catalog.document.element.add") {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(responses[r.Form.Get("fields[elementId]")]))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestAddElementsToStoreDocumentMismatch(t *testing.T) {
+	responses := map[string]string{
+		"10": `{"result":{"documentElement":{"id":501}}}`,
+		"11": `{"error":"ERROR_PRODUCT_NOT_FOUND","error_description":"Product not found"}`,
+		"12": `{"result":{"documentElement":{"id":502}}}`,
+	}
+	products := []DealProductRow{
+		{ProductID: ProductIDString("10"), Quantity: 1},
+		{ProductID: ProductIDString("11"), Quantity: 2},
+		{ProductID: ProductIDString("12"), Quantity: 3},
+	}
+
+	t.Run("strict returns error", func(t *testing.T) {
+		server := newElementAddServer(t, responses)
+		client := NewClient(server.URL)
+
+		added, err := client.AddElementsToStoreDocument("7", products, "1", true)
+		if err == nil {
+			t.Fatal("expected error for partially added document")
+		}
+		if added != 2 {
+			t.Errorf("added = %d, want 2", added)
+		}
+		if !strings.Contains(err.Error(), "added 2 of 3") || !strings.Contains(err.Error(), "product 11") {
+			t.Errorf("error %q should report the count and the failed product", err)
+		}
+	})
+
+	t.Run("non-strict only warns", func(t *testing.T) {
+		server := newElementAddServer(t, responses)
+		var logs bytes.Buffer
+		client := NewClient(server.URL, WithLogger(NewLogger(&logs, LogLevelWarn)))
+
+		added, err := client.AddElementsToStoreDocument("7", products, "1", false)
+		if err != nil {
+			t.Fatalf("expected no error without strict mode, got %v", err)
+		}
+		if added != 2 {
+			t.Errorf("added = %d, want 2", added)
+		}
+		if !strings.Contains(logs.String(), "WARN: added 2 of 3") {
+			t.Errorf("expected mismatch warning, got %q", logs.String())
+		}
+	})
+}
+
+func TestAddElementsToStoreDocumentMissingElementID(t *testing.T) {
+	server := newElementAddServer(t, map[string]string{"10": `{"result":null}`})
+	client := NewClient(server.URL)
+
+	products := []DealProductRow{{ProductID: ProductIDString("10"), Quantity: 1}}
+	added, err := client.AddElementsToStoreDocument("7", products, "1", true)
+	if err == nil || added != 0 {
+		t.Errorf("AddElementsToStoreDocument() = (%d, %v), want silent no-op to be reported", added, err)
+	}
+}
+
+func TestExtractElementID(t *testing.T) {
+	tests := []struct {
+		name   string
+		result interface{}
+		want   string
+	}{
+		{"document element object", map[string]interface{}{"documentElement": map[string]interface{}{"id": float64(501)}}, "501"},
+		{"element object", map[string]interface{}{"element": map[string]interface{}{"ID": "502"}}, "502"},
+		{"plain id object", map[string]interface{}{"id": float64(503)}, "503"},
+		{"plain number", float64(504), "504"},
+		{"zero", float64(0), ""},
+		{"nil", nil, ""},
+		{"boolean", true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractElementID(tt.result); got != tt.want {
+				t.Errorf("extractElementID(%v) = %q, want %q", tt.result, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreIDUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    StoreID
+		wantErr bool
+	}{
+		{"number", `{"id":1}`, 1, false},
+		{"string", `{"id":"1"}`, 1, false},
+		{"null", `{"id":null}`, 0, false},
+		{"non-numeric string", `{"id":"main"}`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var store Store
+			err := json.Unmarshal([]byte(tt.input), &store)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("json.Unmarshal(%s) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+			if !tt.wantErr && store.ID != tt.want {
+				t.Errorf("json.Unmarshal(%s) ID = %d, want %d", tt.input, store.ID, tt.want)
+			}
+		})
+	}
+}
+
+func TestListStoreDocumentsForDeal(t *testing.T) {
+	var requestBody map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/catalog.document.list") {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"result":{"documents":[{"id":17,"title":"Оприходование","docType":"S","status":"Y"},{"id":"18","docType":"S","status":"N"}]}}`))
+	}))
+	defer server.Close()
+
+	documents, err := NewClient(server.URL).ListStoreDocumentsForDeal("123", "UF_CAT_STORE_DOCUMENT_S_1")
+	if err != nil {
+		t.Fatalf("ListStoreDocumentsForDeal() error = %v", err)
+	}
+
+	filter, ok := requestBody["filter"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected filter object in request, got %v", requestBody["filter"])
+	}
+	if filter["UF_CAT_STORE_DOCUMENT_S_1"] != "123" || filter["docType"] != "S" {
+		t.Errorf("unexpected filter: %v", filter)
+	}
+
+	if len(documents) != 2 {
+		t.Fatalf("expected 2 documents, got %d", len(documents))
+	}
+	if documents[0].ID.String() != "17" || !documents[0].IsConfirmed() {
+		t.Errorf("first document = %+v, want confirmed document 17", documents[0])
+	}
+	if documents[1].ID.String() != "18" || documents[1].IsConfirmed() {
+		t.Errorf("second document = %+v, want draft document 18", documents[1])
+	}
+}
+
+func TestCreateStoreDocumentDealField(t *testing.T) {
+	tests := []struct {
+		name      string
+		dealField string
+	}{
+		{"configured field", "UF_CAT_STORE_DOCUMENT_S_42"},
+		{"unconfigured field", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requestBody struct {
+				Fields map[string]interface{} `json:"fields"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+					t.Errorf("request body is not JSON: %v", err)
+				}
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"result":{"document":{"id":42}}}`))
+			}))
+			defer server.Close()
+
+			deal := &Deal{ID: "123", AssignedByID: "7"}
+			documentID, err := NewClient(server.URL).CreateStoreDocument(deal, "RUB", "test", tt.dealField)
+			if err != nil {
+				t.Fatalf("CreateStoreDocument() error = %v", err)
+			}
+			if documentID != "42" {
+				t.Errorf("documentID = %q, want 42", documentID)
+			}
+
+			for key, value := range requestBody.Fields {
+				if strings.HasPrefix(key, "UF_") && key != tt.dealField {
+					t.Errorf("unexpected custom field %s=%v in request", key, value)
+				}
+			}
+			if tt.dealField != "" && requestBody.Fields[tt.dealField] != "123" {
+				t.Errorf("expected %s=123 in request, got %v", tt.dealField, requestBody.Fields)
+			}
+		})
+	}
+}
+
+func TestListStoreDocumentElements(t *testing.T) {
+	client := newTestClient(t, map[string]string{
+		"catalog.document.element.list": `{"result":{"documentElements":[{"id":501,"docId":7,"storeTo":1,"elementId":10,"amount":2},{"id":"502","docId":"7","storeTo":"1","elementId":"12","amount":1}]}}`,
+	})
+
+	elements, err := client.ListStoreDocumentElements("7")
+	if err != nil {
+		t.Fatalf("ListStoreDocumentElements() error = %v", err)
+	}
+	if len(elements) != 2 {
+		t.Fatalf("expected 2 elements, got %d", len(elements))
+	}
+	if elements[0].ElementID.String() != "10" || elements[0].Amount != 2 || elements[1].ElementID.String() != "12" {
+		t.Errorf("unexpected elements: %+v", elements)
+	}
+}
+
+func TestMissingStoreDocumentProducts(t *testing.T) {
+	products := []DealProductRow{
+		{ProductID: "10", Quantity: 2},
+		{ProductID: "11", Quantity: 1},
+		{ProductID: "12", Quantity: 3},
+		{ProductID: "12", Quantity: 4},
+	}
+
+	tests := []struct {
+		name     string
+		elements []StoreDocumentElementRow
+		want     []string
+	}{
+		{"empty document", nil, []string{"10", "11", "12", "12"}},
+		{"partially added", []StoreDocumentElementRow{{ElementID: "10"}, {ElementID: "12"}}, []string{"11", "12"}},
+		{"complete document", []StoreDocumentElementRow{{ElementID: "12"}, {ElementID: "11"}, {ElementID: "10"}, {ElementID: "12"}}, nil},
+		{"foreign element ignored", []StoreDocumentElementRow{{ElementID: "99"}}, []string{"10", "11", "12", "12"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			missing := MissingStoreDocumentProducts(products, tt.elements)
+			var got []string
+			for _, product := range missing {
+				got = append(got, product.ProductID.String())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("MissingStoreDocumentProducts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReconcileStoreDocument(t *testing.T) {
+	products := []DealProductRow{
+		{ProductID: "12", Quantity: 3},
+		{ProductID: "10", Quantity: 1},
+		{ProductID: "12", Quantity: 1},
+	}
+	elements := []StoreDocumentElementRow{
+		{ElementID: "10", Amount: 1},
+		{ElementID: "12", Amount: 3},
+		{ElementID: "99", Amount: 2},
+		{ElementID: "50", Amount: 1},
+	}
+
+	rows := ReconcileStoreDocument(products, elements)
+
+	want := []StoreReconciliationRow{
+		{ProductID: "12", DealQuantity: 4, DocumentQuantity: 3},
+		{ProductID: "10", DealQuantity: 1, DocumentQuantity: 1},
+		{ProductID: "50", DealQuantity: 0, DocumentQuantity: 1},
+		{ProductID: "99", DealQuantity: 0, DocumentQuantity: 2},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d: %+v", len(rows), len(want), rows)
+	}
+	for i := range want {
+		if rows[i] != want[i] {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want[i])
+		}
+	}
+
+	if rows[0].Matches() || !rows[1].Matches() {
+		t.Errorf("Matches() = %v, %v; want false, true", rows[0].Matches(), rows[1].Matches())
+	}
+	if !(StoreReconciliationRow{DealQuantity: 0.3, DocumentQuantity: 0.1 + 0.2}).Matches() {
+		t.Error("float noise must not count as a mismatch")
+	}
+}
@@ -3,6 +3,7 @@ package bitrix
 import (
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -20,7 +21,7 @@ func (p *ProductIDString) UnmarshalJSON(data []byte) error {
 		// It's a number, convert to string
 		var num float64
 		if err := json.Unmarshal(data, &num); err != nil {
-			return fmt.Errorf("failed to unmarshal ProductID as number: %v", err)
+			return fmt.Errorf("failed to unmarshal ProductID as number: %w", err)
 		}
 		*p = ProductIDString(fmt.Sprintf("%.0f", num))
 	} else {
@@ -36,6 +37,25 @@ func (p ProductIDString) String() string {
 	return string(p)
 }
 
+// StoreID is a warehouse ID that can unmarshal both string ("1") and number (1) IDs from JSON
+type StoreID int
+
+// UnmarshalJSON implements custom JSON unmarshaling for StoreID
+func (id *StoreID) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	if s == "" || s == "null" {
+		*id = 0
+		return nil
+	}
+
+	value, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal store ID %s: %v", string(data), err)
+	}
+	*id = StoreID(value)
+	return nil
+}
+
 // Deal represents a Bitrix24 deal
 type Deal struct {
 	ID            string  `json:"ID"`
@@ -76,6 +96,8 @@ type User struct {
 	Name     string `json:"NAME"`
 	LastName string `json:"LAST_NAME"`
 	FullName string `json:"FULL_NAME"`
+	Login    string `json:"LOGIN"`
+	Email    string `json:"EMAIL"`
 }
 
 // ProductSection represents a catalog section (folder)
@@ -95,6 +117,7 @@ type Product struct {
 // ProductInfo represents product information extracted from filename
 type ProductInfo struct {
 	ID       string  // Product ID from Bitrix24
+	Name     string  // Catalog product name, used in quantity errors
 	Quantity float64 // Quantity extracted from filename or default 1.0
 }
 
@@ -109,7 +132,31 @@ type DealProductRow struct {
 // BitrixResponse represents a generic API response
 type BitrixResponse struct {
 	Result interface{} `json:"result"`
-	Error  *BitrixError `json:"error"`
+	Error  *BitrixError `json:"-"` // Filled from top-level "error"/"error_description"
+}
+
+// UnmarshalJSON implements custom JSON unmarshaling for BitrixResponse.
+// Bitrix24 reports errors as top-level siblings of "result":
+// {"error": "NOT_FOUND", "error_description": "Not found"}
+func (r *BitrixResponse) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Result           interface{} `json:"result"`
+		Error            string      `json:"error"`
+		ErrorDescription string      `json:"error_description"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	r.Result = raw.Result
+	r.Error = nil
+	if raw.Error != "" {
+		r.Error = &BitrixError{
+			ErrorCode:        raw.Error,
+			ErrorDescription: raw.ErrorDescription,
+		}
+	}
+	return nil
 }
 
 // BitrixError represents an API error
@@ -158,14 +205,21 @@ type ListSectionsResponse struct {
 
 // StoreDocument represents a warehouse document
 type StoreDocument struct {
-	ID           string `json:"id"`
+	ID           ProductIDString `json:"id"`   // Returned as number by catalog.document.list
+	Title        string `json:"title"`
 	DocType      string `json:"docType"`      // 'S' for receipt
+	Status       string `json:"status"`       // 'Y' confirmed, 'N' draft, 'C' cancelled
 	Currency     string `json:"currency"`
 	DateDocument string `json:"dateDocument"`
 	Commentary   string `json:"commentary"`
 	ResponsibleID string `json:"responsibleId"`
 }
 
+// IsConfirmed reports whether the document is confirmed (проведен) and affects inventory
+func (d StoreDocument) IsConfirmed() bool {
+	return d.Status == "Y"
+}
+
 // StoreDocumentElement represents an element in a warehouse document
 type StoreDocumentElement struct {
 	DocID           string  `json:"docId"`
@@ -176,6 +230,15 @@ type StoreDocumentElement struct {
 	PurchasingPrice float64 `json:"purchasingPrice"` // Price per unit
 }
 
+// StoreDocumentElementRow is an element of an existing warehouse document (catalog.document.element.list)
+type StoreDocumentElementRow struct {
+	ID        ProductIDString `json:"id"`
+	DocID     ProductIDString `json:"docId"`
+	StoreTo   ProductIDString `json:"storeTo"`
+	ElementID ProductIDString `json:"elementId"` // Product ID
+	Amount    float64         `json:"amount"`
+}
+
 // StoreDocumentModeResponse represents the response from catalog.document.mode.status
 // The API returns result as a direct string value, not as an object
 type StoreDocumentModeResponse string
@@ -200,12 +263,12 @@ type StoreImage struct {
 
 // Store represents a warehouse in Bitrix24
 type Store struct {
-	ID          int         `json:"id"`
+	ID          StoreID     `json:"id"`
 	Title       string      `json:"title"`
 	Active      string      `json:"active"`      // "Y" or "N"
 	Code        *string     `json:"code"`        // Store code (can be null)
 	Sort        int         `json:"sort"`        // Sort order
-	Address     string      `json:"address"`     // Store address
+	Address     *string     `json:"address"`     // Store address (can be null)
 	Description *string     `json:"description"` // Store description (can be null)
 	ImageID     *StoreImage `json:"imageId"`     // Image info (can be null)
 	Phone       *string     `json:"phone"`       // Phone number (can be null)
@@ -241,6 +304,7 @@ type DealReportRow struct {
 	MaterialCost    interface{} `json:"material_cost"`    // Custom field - can be string or number
 	TotalCost       interface{} `json:"total_cost"`       // Custom field - can be string or number
 	Opportunity     interface{} `json:"OPPORTUNITY"`      // Deal amount - can be string or number
+	CurrencyID      string      `json:"CURRENCY_ID"`      // Currency of the deal amount (RUB, EUR, ...)
 	PaymentReceived interface{} `json:"payment_received"` // Custom field - can be string or number
 }
 
@@ -259,6 +323,23 @@ type ReportCustomFields struct {
 	PaymentReceived string `json:"payment_received"`
 }
 
+// ReportStatusExclusions lists the deal stages left out of the report.
+// Stage IDs differ between funnels (C5:WON, C7:WON), so ByCategory sets the excluded stages
+// of a category ID; Default applies to every category that is not in ByCategory
+type ReportStatusExclusions struct {
+	Default    []string
+	ByCategory map[string][]string
+}
+
+// DealCosts contains locally computed cost values to be written back to a deal
+// Nil values are not sent, so the corresponding deal fields stay untouched
+type DealCosts struct {
+	MachineCost  *float64
+	HumanCost    *float64
+	MaterialCost *float64
+	TotalCost    *float64
+}
+
 // DealCategory represents a deal category (funnel) in Bitrix24
 type DealCategory struct {
 	ID           int    `json:"id"`
@@ -20,6 +20,7 @@ type ExcelColors struct {
 	AltRowBg     string // Фон четных строк
 	BorderColor  string // Цвет границ
 	SummaryBg    string // Фон итоговых ячеек
+	LinkText     string // Текст гиперссылок
 }
 
 // DefaultExcelColors возвращает стандартную цветовую схему
@@ -30,11 +31,13 @@ func DefaultExcelColors() ExcelColors {
 		AltRowBg:    "#F2F2F2",  // Светло-серый
 		BorderColor: "#D9D9D9",  // Серый
 		SummaryBg:   "#E7E6E6",  // Светло-серый для итогов
+		LinkText:    "#0563C1",  // Синий цвет ссылок Excel
 	}
 }
 
-// FormatAsExcel создает Excel отчет из данных 3MF файла
-func FormatAsExcel(data *parser.Parser3MF, outputPath string) error {
+// FormatAsExcel создает Excel отчет из данных 3MF файла.
+// С options.ShowDimensions на лист объектов добавляется колонка габаритов
+func FormatAsExcel(data *parser.Parser3MF, outputPath string, options ListOptions) error {
 	// Создаем новый Excel файл
 	f := excelize.NewFile()
 	colors := DefaultExcelColors()
@@ -51,7 +54,7 @@ func FormatAsExcel(data *parser.Parser3MF, outputPath string) error {
 		return fmt.Errorf("failed to create plates sheet: %w", err)
 	}
 	
-	if err := createObjectsSheet(f, data, colors); err != nil {
+	if err := createObjectsSheet(f, data, colors, options.ShowDimensions); err != nil {
 		return fmt.Errorf("failed to create objects sheet: %w", err)
 	}
 	
@@ -143,9 +146,8 @@ func createSummarySheet(f *excelize.File, data *parser.Parser3MF, colors ExcelCo
 	materialsSet := make(map[string]bool)
 	for _, plate := range data.Plates {
 		for _, obj := range plate.Objects {
-			if obj.Material != "" {
-				cleanMaterial := cleanMaterialName(obj.Material)
-				materialsSet[cleanMaterial] = true
+			for _, material := range obj.MaterialNames() {
+				materialsSet[parser.CleanMaterialName(material)] = true
 			}
 		}
 	}
@@ -239,7 +241,7 @@ func createPlatesSheet(f *excelize.File, data *parser.Parser3MF, colors ExcelCol
 	for _, plate := range data.Plates {
 		groups := parser.GroupObjectsByName(plate.Objects)
 		for _, group := range groups {
-			cleanMaterial := cleanMaterialName(group.Material)
+			cleanMaterial := group.MaterialLabel()
 			objectType := group.Type
 			if objectType == "assembly" {
 				objectType = "assembly"
@@ -293,7 +295,7 @@ func createPlatesSheet(f *excelize.File, data *parser.Parser3MF, colors ExcelCol
 }
 
 // createObjectsSheet создает лист с полной информацией по объектам
-func createObjectsSheet(f *excelize.File, data *parser.Parser3MF, colors ExcelColors) error {
+func createObjectsSheet(f *excelize.File, data *parser.Parser3MF, colors ExcelColors, showDimensions bool) error {
 	sheetName := "Objects"
 	_, err := f.NewSheet(sheetName)
 	if err != nil {
@@ -301,7 +303,11 @@ func createObjectsSheet(f *excelize.File, data *parser.Parser3MF, colors ExcelCo
 	}
 	
 	// Заголовки
-	headers := []string{"Object Name", "Type", "Material", "Total Count", "Plates"}
+	headers := []string{"Object Name", "Type", "Material", "Total Count", "Plate Count", "Plates"}
+	if showDimensions {
+		headers = append(headers, "Dimensions, mm")
+	}
+	lastCol := string(rune('A' + len(headers) - 1))
 	for i, header := range headers {
 		col := string(rune('A' + i))
 		f.SetCellValue(sheetName, col+"1", header)
@@ -326,31 +332,7 @@ func createObjectsSheet(f *excelize.File, data *parser.Parser3MF, colors ExcelCo
 		},
 	})
 	
-	f.SetCellStyle(sheetName, "A1", "E1", headerStyle)
-	
-	// Собираем статистику по объектам
-	objectStats := make(map[string]*ObjectStat)
-	
-	for _, plate := range data.Plates {
-		groups := parser.GroupObjectsByName(plate.Objects)
-		for _, group := range groups {
-			cleanMaterial := cleanMaterialName(group.Material)
-			key := group.Name + "|" + cleanMaterial
-			
-			if stat, exists := objectStats[key]; exists {
-				stat.Count += group.Count
-				stat.Plates = append(stat.Plates, plate.PlateID)
-			} else {
-				objectStats[key] = &ObjectStat{
-					Name:     group.Name,
-					Type:     group.Type,
-					Material: cleanMaterial,
-					Count:    group.Count,
-					Plates:   []int{plate.PlateID},
-				}
-			}
-		}
-	}
+	f.SetCellStyle(sheetName, "A1", lastCol+"1", headerStyle)
 	
 	// Стили для данных
 	dataStyle, _ := f.NewStyle(&excelize.Style{
@@ -376,14 +358,8 @@ func createObjectsSheet(f *excelize.File, data *parser.Parser3MF, colors ExcelCo
 		},
 	})
 	
-	// Сортируем объекты по имени
-	var sortedObjects []*ObjectStat
-	for _, stat := range objectStats {
-		sortedObjects = append(sortedObjects, stat)
-	}
-	sort.Slice(sortedObjects, func(i, j int) bool {
-		return sortedObjects[i].Name < sortedObjects[j].Name
-	})
+	// Собираем статистику по объектам (отсортирована по имени)
+	sortedObjects := collectObjectStats(data)
 	
 	row := 2
 	for _, stat := range sortedObjects {
@@ -392,32 +368,176 @@ func createObjectsSheet(f *excelize.File, data *parser.Parser3MF, colors ExcelCo
 			style = altRowStyle
 		}
 		
-		// Формируем список столов
-		var plateStrs []string
-		for _, plateID := range stat.Plates {
-			plateStrs = append(plateStrs, strconv.Itoa(plateID))
-		}
-		platesText := strings.Join(plateStrs, ", ")
-		
 		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), stat.Name)
 		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), stat.Type)
 		f.SetCellValue(sheetName, "C"+strconv.Itoa(row), stat.Material)
 		f.SetCellValue(sheetName, "D"+strconv.Itoa(row), stat.Count)
-		f.SetCellValue(sheetName, "E"+strconv.Itoa(row), platesText)
-		f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "E"+strconv.Itoa(row), style)
+		f.SetCellValue(sheetName, "E"+strconv.Itoa(row), len(stat.Plates))
+		f.SetCellValue(sheetName, "F"+strconv.Itoa(row), formatPlateList(stat.Plates))
+		if showDimensions && stat.Dimensions != nil {
+			f.SetCellValue(sheetName, "G"+strconv.Itoa(row), stat.Dimensions.String())
+		}
+		f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), lastCol+strconv.Itoa(row), style)
 		row++
 	}
 	
+	// Итоговый блок под таблицей объектов
+	createObjectsSummary(f, sheetName, sortedObjects, collectMaterialWeights(data), row+1, colors)
+	
 	// Настройка ширины колонок
 	f.SetColWidth(sheetName, "A", "A", 40)
 	f.SetColWidth(sheetName, "B", "B", 15)
 	f.SetColWidth(sheetName, "C", "C", 20)
 	f.SetColWidth(sheetName, "D", "D", 12)
-	f.SetColWidth(sheetName, "E", "E", 15)
+	f.SetColWidth(sheetName, "E", "E", 12)
+	f.SetColWidth(sheetName, "F", "F", 15)
+	if showDimensions {
+		f.SetColWidth(sheetName, "G", "G", 22)
+	}
 	
 	return nil
 }
 
+// collectObjectStats собирает статистику по объектам всех столов.
+// Объекты объединяются по имени и очищенному материалу, количество суммируется,
+// номера столов не повторяются и отсортированы по возрастанию
+func collectObjectStats(data *parser.Parser3MF) []*ObjectStat {
+	return aggregateObjectStats(data, false)
+}
+
+// collectRepeatedObjectStats - как collectObjectStats, но количество на столе умножается
+// на число повторений стола (сводная спецификация наряд-заказа)
+func collectRepeatedObjectStats(data *parser.Parser3MF) []*ObjectStat {
+	return aggregateObjectStats(data, true)
+}
+
+// aggregateObjectStats объединяет объекты столов по имени и материалу, withRepeats учитывает повторения столов
+func aggregateObjectStats(data *parser.Parser3MF, withRepeats bool) []*ObjectStat {
+	objectStats := make(map[string]*ObjectStat)
+	plateSets := make(map[string]map[int]bool)
+	
+	for _, plate := range data.Plates {
+		repeats := 1
+		if withRepeats {
+			repeats = plate.RepeatCount()
+		}
+		
+		groups := parser.GroupObjectsByName(plate.Objects)
+		for _, group := range groups {
+			cleanMaterial := group.MaterialLabel()
+			key := group.Name + "|" + cleanMaterial
+			
+			if stat, exists := objectStats[key]; exists {
+				stat.Count += group.Count * repeats
+			} else {
+				objectStats[key] = &ObjectStat{
+					Name:       group.Name,
+					Type:       group.Type,
+					Material:   cleanMaterial,
+					Count:      group.Count * repeats,
+					Dimensions: group.Dimensions,
+				}
+				plateSets[key] = make(map[int]bool)
+			}
+			
+			if !plateSets[key][plate.PlateID] {
+				plateSets[key][plate.PlateID] = true
+				objectStats[key].Plates = append(objectStats[key].Plates, plate.PlateID)
+			}
+		}
+	}
+	
+	// Сортируем объекты по имени, затем по материалу
+	var sortedObjects []*ObjectStat
+	for _, stat := range objectStats {
+		sort.Ints(stat.Plates)
+		sortedObjects = append(sortedObjects, stat)
+	}
+	sort.Slice(sortedObjects, func(i, j int) bool {
+		if sortedObjects[i].Name != sortedObjects[j].Name {
+			return sortedObjects[i].Name < sortedObjects[j].Name
+		}
+		return sortedObjects[i].Material < sortedObjects[j].Material
+	})
+	
+	return sortedObjects
+}
+
+// createObjectsSummary добавляет итоги: количество уникальных объектов, общее число деталей
+// и расчетный вес по материалам (если известен из данных нарезки)
+func createObjectsSummary(f *excelize.File, sheetName string, stats []*ObjectStat, materialWeights map[string]float64, startRow int, colors ExcelColors) int {
+	summaryStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+		},
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Color:   []string{colors.SummaryBg},
+			Pattern: 1,
+		},
+		Border: []excelize.Border{
+			{Type: "left", Color: colors.BorderColor, Style: 1},
+			{Type: "top", Color: colors.BorderColor, Style: 1},
+			{Type: "bottom", Color: colors.BorderColor, Style: 1},
+			{Type: "right", Color: colors.BorderColor, Style: 1},
+		},
+	})
+	
+	totalParts := 0
+	for _, stat := range stats {
+		totalParts += stat.Count
+	}
+	
+	row := startRow
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Total Objects")
+	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), len(stats))
+	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "B"+strconv.Itoa(row), summaryStyle)
+	row++
+	
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Total Parts")
+	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), totalParts)
+	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "B"+strconv.Itoa(row), summaryStyle)
+	row++
+	
+	// Вес по материалам в алфавитном порядке
+	materials := make([]string, 0, len(materialWeights))
+	for material := range materialWeights {
+		materials = append(materials, material)
+	}
+	sort.Strings(materials)
+	
+	for _, material := range materials {
+		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), fmt.Sprintf("Estimated Weight, g (%s)", material))
+		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), roundMoney(materialWeights[material]))
+		f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "B"+strconv.Itoa(row), summaryStyle)
+		row++
+	}
+	
+	return row
+}
+
+// collectMaterialWeights суммирует расход материала по всем столам (по очищенному названию материала)
+func collectMaterialWeights(data *parser.Parser3MF) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, plate := range data.Plates {
+		for material, weight := range plate.MaterialWeights {
+			if weight > 0 {
+				weights[parser.CleanMaterialName(material)] += weight
+			}
+		}
+	}
+	return weights
+}
+
+// formatPlateList формирует список столов через запятую
+func formatPlateList(plates []int) string {
+	plateStrs := make([]string, len(plates))
+	for i, plateID := range plates {
+		plateStrs[i] = strconv.Itoa(plateID)
+	}
+	return strings.Join(plateStrs, ", ")
+}
+
 // ObjectStat содержит статистику по объекту
 type ObjectStat struct {
 	Name     string
@@ -425,4 +545,6 @@ type ObjectStat struct {
 	Material string
 	Count    int
 	Plates   []int
+	// Dimensions - габариты первого найденного объекта (nil, если не считались)
+	Dimensions *parser.Dimensions
 }
\ No newline at end of file
@@ -0,0 +1,179 @@
+package formatter
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"farmix-cli/internal/parser"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// twoPlateData содержит объект, который повторяется на первом столе
+// (с разными исходными названиями материала) и встречается на втором столе
+func twoPlateData() *parser.Parser3MF {
+	return &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{
+				PlateID: 2,
+				Objects: []parser.PlateObject{
+					{ID: 5, Name: "Bracket", Type: "model", Material: "PLA"},
+				},
+			},
+			{
+				PlateID: 1,
+				Objects: []parser.PlateObject{
+					{ID: 1, Name: "Bracket", Type: "model", Material: "PLA"},
+					{ID: 2, Name: "Bracket", Type: "model", Material: "PLA"},
+					{ID: 3, Name: "Bracket", Type: "model", Material: "PLA (Red)"},
+					{ID: 4, Name: "Cover", Type: "model", Material: "PETG"},
+				},
+			},
+		},
+	}
+}
+
+func TestCollectObjectStats(t *testing.T) {
+	stats := collectObjectStats(twoPlateData())
+
+	if len(stats) != 2 {
+		t.Fatalf("expected 2 object stats, got %d", len(stats))
+	}
+
+	bracket := stats[0]
+	if bracket.Name != "Bracket" || bracket.Material != "PLA" {
+		t.Fatalf("expected first stat to be Bracket/PLA, got %s/%s", bracket.Name, bracket.Material)
+	}
+	if bracket.Count != 4 {
+		t.Errorf("expected Bracket count 4 across plates, got %d", bracket.Count)
+	}
+	if got := formatPlateList(bracket.Plates); got != "1, 2" {
+		t.Errorf("expected Bracket plates \"1, 2\", got %q", got)
+	}
+
+	cover := stats[1]
+	if cover.Count != 1 || formatPlateList(cover.Plates) != "1" {
+		t.Errorf("expected Cover count 1 on plate 1, got count %d plates %v", cover.Count, cover.Plates)
+	}
+}
+
+func TestObjectsSheetPlatesColumn(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "report.xlsx")
+	if err := FormatAsExcel(twoPlateData(), outputPath, ListOptions{}); err != nil {
+		t.Fatalf("FormatAsExcel() error = %v", err)
+	}
+
+	f, err := excelize.OpenFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to open generated file: %v", err)
+	}
+	defer f.Close()
+
+	expected := map[string]string{
+		"D1": "Total Count",
+		"E1": "Plate Count",
+		"F1": "Plates",
+		"A2": "Bracket",
+		"D2": "4",
+		"E2": "2",
+		"F2": "1, 2",
+	}
+	for cell, want := range expected {
+		got, err := f.GetCellValue("Objects", cell)
+		if err != nil {
+			t.Fatalf("failed to read cell %s: %v", cell, err)
+		}
+		if got != want {
+			t.Errorf("cell %s = %q, want %q", cell, got, want)
+		}
+	}
+}
+
+func TestObjectsSheetSummary(t *testing.T) {
+	data := twoPlateData()
+	data.Plates[0].MaterialWeights = map[string]float64{"PLA": 12.5}
+	data.Plates[1].MaterialWeights = map[string]float64{"PLA (Red)": 30, "PETG": 8.25}
+
+	outputPath := filepath.Join(t.TempDir(), "report.xlsx")
+	if err := FormatAsExcel(data, outputPath, ListOptions{}); err != nil {
+		t.Fatalf("FormatAsExcel() error = %v", err)
+	}
+
+	f, err := excelize.OpenFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to open generated file: %v", err)
+	}
+	defer f.Close()
+
+	// Две строки объектов (2-3), пустая строка, затем итоги с 5-й строки
+	expected := map[string]string{
+		"A5": "Total Objects",
+		"B5": "2",
+		"A6": "Total Parts",
+		"B6": "5",
+		"A7": "Estimated Weight, g (PETG)",
+		"B7": "8.25",
+		"A8": "Estimated Weight, g (PLA)",
+		"B8": "42.5",
+	}
+	for cell, want := range expected {
+		got, err := f.GetCellValue("Objects", cell)
+		if err != nil {
+			t.Fatalf("failed to read cell %s: %v", cell, err)
+		}
+		if got != want {
+			t.Errorf("cell %s = %q, want %q", cell, got, want)
+		}
+	}
+
+	// Итоговые ячейки должны использовать цвет SummaryBg
+	styleID, err := f.GetCellStyle("Objects", "A5")
+	if err != nil {
+		t.Fatalf("failed to read style: %v", err)
+	}
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		t.Fatalf("failed to get style: %v", err)
+	}
+	wantFill := strings.TrimPrefix(DefaultExcelColors().SummaryBg, "#")
+	if len(style.Fill.Color) == 0 || strings.TrimPrefix(style.Fill.Color[0], "#") != wantFill {
+		t.Errorf("expected summary fill %s, got %v", DefaultExcelColors().SummaryBg, style.Fill.Color)
+	}
+
+	// После весов по материалам других строк нет
+	value, _ := f.GetCellValue("Objects", "A9")
+	if value != "" {
+		t.Errorf("expected no extra summary rows, got %q", value)
+	}
+}
+
+func TestObjectsSheetDimensionsColumn(t *testing.T) {
+	outputPath := filepath.Join(t.TempDir(), "report.xlsx")
+	if err := FormatAsExcel(dimensionedPlateData(), outputPath, ListOptions{ShowDimensions: true}); err != nil {
+		t.Fatalf("FormatAsExcel() error = %v", err)
+	}
+
+	f, err := excelize.OpenFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to open generated file: %v", err)
+	}
+	defer f.Close()
+
+	expected := map[string]string{
+		"G1": "Dimensions, mm",
+		"A2": "Bracket",
+		"G2": "20.0 x 10.2 x 5.0",
+		"A3": "Cover",
+		"G3": "",
+	}
+	for cell, want := range expected {
+		got, err := f.GetCellValue("Objects", cell)
+		if err != nil {
+			t.Fatalf("failed to read cell %s: %v", cell, err)
+		}
+		if got != want {
+			t.Errorf("cell %s = %q, want %q", cell, got, want)
+		}
+	}
+}
@@ -2,93 +2,200 @@ package formatter
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
-	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"farmix-cli/internal/parser"
 )
 
-// cleanMaterialName удаляет часть в скобках из названия материала
-// Например: "Eryone ASA-GF(opengrid-9x9.3mf)" -> "Eryone ASA-GF"
-func cleanMaterialName(material string) string {
-	// Удаляем содержимое в скобках в конце строки
-	re := regexp.MustCompile(`\([^)]*\)$`)
-	cleaned := re.ReplaceAllString(material, "")
-	// Убираем лишние пробелы
-	return strings.TrimSpace(cleaned)
+// ListOptions - настройки вывода команды list
+type ListOptions struct {
+	// Lang - язык подписей текстового вывода (пустой - английский)
+	Lang Lang
+	// ShowTime добавляет время печати по столам и общее время (из slice_info.config)
+	ShowTime bool
+	// ShowDimensions добавляет габариты объектов Ш x Г x В в мм (нужен разбор с parser.WithDimensions)
+	ShowDimensions bool
 }
 
-func FormatAsText(data *parser.Parser3MF, writer io.Writer) error {
-	fmt.Fprintf(writer, "3MF File Analysis\n")
-	fmt.Fprintf(writer, "=================\n\n")
+// FormatAsText выводит текстовый отчет по столам и материалам
+func FormatAsText(data *parser.Parser3MF, writer io.Writer, options ListOptions) error {
+	lang := options.Lang.orDefault(LangEN)
+	title := lang.T("analysis.title")
+	fmt.Fprintf(writer, "%s\n", title)
+	fmt.Fprintf(writer, "%s\n\n", underline(title))
 
 	if len(data.Plates) == 0 {
-		fmt.Fprintf(writer, "No plates found in the file.\n")
+		fmt.Fprintf(writer, "%s\n", lang.T("analysis.no_plates"))
 		return nil
 	}
 
 	for _, plate := range data.Plates {
-		fmt.Fprintf(writer, "Plate %d: %s\n", plate.PlateID, plate.PlateName)
+		fmt.Fprintf(writer, lang.T("analysis.plate")+"\n", plate.PlateID, plate.PlateName)
+		if options.ShowTime {
+			fmt.Fprintf(writer, "  %s: %s\n", lang.T("analysis.print_time"), formatPrintTime(plate.PrintTime, lang))
+		}
 
 		if len(plate.Objects) == 0 {
-			fmt.Fprintf(writer, "  No objects on this plate.\n")
+			fmt.Fprintf(writer, "  %s\n", lang.T("analysis.no_objects"))
 			continue
 		}
 
 		groups := parser.GroupObjectsByName(plate.Objects)
 		for _, group := range groups {
-			cleanMaterial := cleanMaterialName(group.Material)
+			cleanMaterial := group.MaterialLabel()
+			dimensions := ""
+			if options.ShowDimensions && group.Dimensions != nil {
+				dimensions = fmt.Sprintf("; %s %s", group.Dimensions, lang.T("analysis.mm"))
+			}
 			if group.Type == "assembly" {
-				fmt.Fprintf(writer, "  %d x %s; %s (assembly)\n", group.Count, group.Name, cleanMaterial)
+				fmt.Fprintf(writer, "  %d x %s; %s (%s)%s\n", group.Count, group.Name, cleanMaterial, lang.T("analysis.assembly"), dimensions)
 			} else {
-				fmt.Fprintf(writer, "  %d x %s; %s\n", group.Count, group.Name, cleanMaterial)
+				fmt.Fprintf(writer, "  %d x %s; %s%s\n", group.Count, group.Name, cleanMaterial, dimensions)
 			}
 
 			if group.Type == "assembly" && len(group.Components) > 0 {
-				fmt.Fprintf(writer, "    Components:\n")
+				fmt.Fprintf(writer, "    %s\n", lang.T("analysis.components"))
 				for _, comp := range group.Components {
-					fmt.Fprintf(writer, "      - %s (ID: %d, Source: %s)\n", comp.Name, comp.ID, comp.SourceFile)
+					fmt.Fprintf(writer, "      "+lang.T("analysis.component")+"\n", comp.Name, comp.ID, comp.SourceFile)
 				}
 			}
 		}
 		fmt.Fprintf(writer, "\n")
 	}
 
-	// Собираем список уникальных материалов
-	materialsSet := make(map[string]bool)
-	for _, plate := range data.Plates {
-		for _, obj := range plate.Objects {
-			if obj.Material != "" {
-				cleanMaterial := cleanMaterialName(obj.Material)
-				materialsSet[cleanMaterial] = true
-			}
-		}
-	}
-
-	// Преобразуем в отсортированный список
-	var materials []string
-	for material := range materialsSet {
-		materials = append(materials, material)
-	}
-	sort.Strings(materials)
+	// Собираем отсортированный список уникальных материалов
+	materials := collectMaterials(data)
 
 	// Выводим список материалов
 	if len(materials) > 0 {
-		fmt.Fprintf(writer, "Materials Used:\n")
-		fmt.Fprintf(writer, "===============\n")
+		materialsTitle := lang.T("analysis.materials") + ":"
+		fmt.Fprintf(writer, "%s\n", materialsTitle)
+		fmt.Fprintf(writer, "%s\n", underline(materialsTitle))
 		for _, material := range materials {
 			fmt.Fprintf(writer, "- %s\n", material)
 		}
 	}
 
+	if options.ShowTime {
+		fmt.Fprintf(writer, "\n%s: %s\n", lang.T("analysis.total_print_time"), formatPrintTime(data.TotalPrintTime(), lang))
+	}
+
+	writePartSummary(writer, summarizeParts(data), lang)
+
 	return nil
 }
 
-func FormatAsCSV(data *parser.Parser3MF, writer io.Writer) error {
+// materialCount - количество деталей одного материала
+type materialCount struct {
+	Material string
+	Count    int
+}
+
+// partSummary - итоги по деталям проекта для текстового вывода list
+type partSummary struct {
+	// UniqueParts - число разных деталей (название и очищенный материал) на всех столах
+	UniqueParts int
+	// TotalParts - общее число деталей (сумма количеств групп)
+	TotalParts int
+	// Materials - количество деталей по материалам, отсортированное по названию
+	Materials []materialCount
+}
+
+// summarizeParts считает итоги по деталям всех столов
+func summarizeParts(data *parser.Parser3MF) partSummary {
+	stats := collectObjectStats(data)
+	summary := partSummary{UniqueParts: len(stats)}
+
+	counts := make(map[string]int)
+	for _, stat := range stats {
+		summary.TotalParts += stat.Count
+		counts[stat.Material] += stat.Count
+	}
+
+	for material, count := range counts {
+		summary.Materials = append(summary.Materials, materialCount{Material: material, Count: count})
+	}
+	sort.Slice(summary.Materials, func(i, j int) bool {
+		return summary.Materials[i].Material < summary.Materials[j].Material
+	})
+
+	return summary
+}
+
+// writePartSummary выводит итоги: число уникальных деталей, общее количество и таблицу по материалам
+func writePartSummary(writer io.Writer, summary partSummary, lang Lang) {
+	title := lang.T("summary.title") + ":"
+	fmt.Fprintf(writer, "\n%s\n", title)
+	fmt.Fprintf(writer, "%s\n", underline(title))
+	fmt.Fprintf(writer, "%s: %d\n", lang.T("summary.unique_parts"), summary.UniqueParts)
+	fmt.Fprintf(writer, "%s: %d\n", lang.T("summary.total_parts"), summary.TotalParts)
+
+	if len(summary.Materials) == 0 {
+		return
+	}
+
+	headers := []string{lang.T("analysis.material"), lang.T("analysis.count")}
+	rows := make([][]string, len(summary.Materials))
+	colWidths := []int{utf8.RuneCountInString(headers[0]), utf8.RuneCountInString(headers[1])}
+	for i, item := range summary.Materials {
+		material := item.Material
+		if material == "" {
+			material = lang.T("summary.no_material")
+		}
+		rows[i] = []string{material, strconv.Itoa(item.Count)}
+		for j, cell := range rows[i] {
+			if width := utf8.RuneCountInString(cell); width > colWidths[j] {
+				colWidths[j] = width
+			}
+		}
+	}
+
+	// Количество выравнивается вправо
+	rightAligned := func(i int) bool { return i == 1 }
+
+	fmt.Fprintln(writer)
+	printBorder(writer, colWidths, "┌", "┬", "┐")
+	printAlignedRow(writer, headers, colWidths, rightAligned)
+	printBorder(writer, colWidths, "├", "┼", "┤")
+	for _, row := range rows {
+		printAlignedRow(writer, row, colWidths, rightAligned)
+	}
+	printBorder(writer, colWidths, "└", "┴", "┘")
+}
+
+// formatPrintTime выводит время печати в часах и минутах; нулевое время - нет данных нарезки
+func formatPrintTime(d time.Duration, lang Lang) string {
+	if d <= 0 {
+		return lang.T("analysis.no_print_time")
+	}
+	minutes := int(d.Round(time.Minute).Minutes())
+	return fmt.Sprintf(lang.T("analysis.duration"), minutes/60, minutes%60)
+}
+
+// printTimeSeconds возвращает время печати в секундах для CSV, пустая строка - нет данных нарезки
+func printTimeSeconds(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return strconv.Itoa(int(d.Seconds()))
+}
+
+// underline возвращает линию из "=" длиной в заголовок (в символах, а не байтах)
+func underline(title string) string {
+	return strings.Repeat("=", utf8.RuneCountInString(title))
+}
+
+// FormatAsCSV выводит сгруппированные объекты по столам в CSV.
+// С options.ShowTime добавляется колонка PrintTimeSeconds (время печати стола),
+// с options.ShowDimensions - колонки WidthMM, DepthMM, HeightMM (пустые, если габариты неизвестны)
+func FormatAsCSV(data *parser.Parser3MF, writer io.Writer, options ListOptions) error {
 	csvWriter := csv.NewWriter(writer)
 	defer csvWriter.Flush()
 
@@ -96,6 +203,12 @@ func FormatAsCSV(data *parser.Parser3MF, writer io.Writer) error {
 		"PlateID", "PlateName", "ObjectName", "ObjectType", "Material", "Count",
 		"ComponentCount", "ComponentNames", "ComponentFiles",
 	}
+	if options.ShowTime {
+		headers = append(headers, "PrintTimeSeconds")
+	}
+	if options.ShowDimensions {
+		headers = append(headers, "WidthMM", "DepthMM", "HeightMM")
+	}
 
 	if err := csvWriter.Write(headers); err != nil {
 		return fmt.Errorf("failed to write CSV headers: %w", err)
@@ -109,6 +222,12 @@ func FormatAsCSV(data *parser.Parser3MF, writer io.Writer) error {
 				"", "", "", "0",
 				"0", "", "",
 			}
+			if options.ShowTime {
+				record = append(record, printTimeSeconds(plate.PrintTime))
+			}
+			if options.ShowDimensions {
+				record = append(record, dimensionColumns(nil)...)
+			}
 			if err := csvWriter.Write(record); err != nil {
 				return fmt.Errorf("failed to write CSV record: %w", err)
 			}
@@ -123,7 +242,7 @@ func FormatAsCSV(data *parser.Parser3MF, writer io.Writer) error {
 				componentFiles = append(componentFiles, comp.SourceFile)
 			}
 
-			cleanMaterial := cleanMaterialName(group.Material)
+			cleanMaterial := group.MaterialLabel()
 			record := []string{
 				strconv.Itoa(plate.PlateID),
 				plate.PlateName,
@@ -135,6 +254,12 @@ func FormatAsCSV(data *parser.Parser3MF, writer io.Writer) error {
 				strings.Join(componentNames, ";"),
 				strings.Join(componentFiles, ";"),
 			}
+			if options.ShowTime {
+				record = append(record, printTimeSeconds(plate.PrintTime))
+			}
+			if options.ShowDimensions {
+				record = append(record, dimensionColumns(group.Dimensions)...)
+			}
 
 			if err := csvWriter.Write(record); err != nil {
 				return fmt.Errorf("failed to write CSV record: %w", err)
@@ -145,3 +270,104 @@ func FormatAsCSV(data *parser.Parser3MF, writer io.Writer) error {
 	return nil
 }
 
+// dimensionColumns возвращает ширину, глубину и высоту в мм для CSV (пустые ячейки без габаритов)
+func dimensionColumns(dimensions *parser.Dimensions) []string {
+	if dimensions == nil {
+		return []string{"", "", ""}
+	}
+	return []string{
+		strconv.FormatFloat(dimensions.Width, 'f', 1, 64),
+		strconv.FormatFloat(dimensions.Depth, 'f', 1, 64),
+		strconv.FormatFloat(dimensions.Height, 'f', 1, 64),
+	}
+}
+
+// jsonReport - структура JSON вывода команды list
+type jsonReport struct {
+	Plates    []jsonPlate `json:"plates"`
+	Materials []string    `json:"materials"`
+}
+
+// jsonPlate - стол со сгруппированными объектами
+type jsonPlate struct {
+	PlateID   int                    `json:"plate_id"`
+	PlateName string                 `json:"plate_name"`
+	Objects   []parser.GroupedObject `json:"objects"`
+}
+
+// FormatAsJSON выводит сгруппированные объекты по столам в формате JSON с отступами.
+// Названия материалов очищаются так же, как в текстовом выводе
+func FormatAsJSON(data *parser.Parser3MF, writer io.Writer) error {
+	report := jsonReport{
+		Plates:    make([]jsonPlate, 0, len(data.Plates)),
+		Materials: collectMaterials(data),
+	}
+
+	for _, plate := range data.Plates {
+		groups := sortedGroups(plate.Objects)
+		for i := range groups {
+			groups[i].Material = parser.CleanMaterialName(groups[i].Material)
+			groups[i].Materials = parser.CleanMaterialNames(groups[i].Materials)
+		}
+
+		report.Plates = append(report.Plates, jsonPlate{
+			PlateID:   plate.PlateID,
+			PlateName: plate.PlateName,
+			Objects:   groups,
+		})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	return nil
+}
+
+// sortedGroups группирует объекты стола и возвращает группы в стабильном порядке (по имени, типу, материалу)
+func sortedGroups(objects []parser.PlateObject) []parser.GroupedObject {
+	return sortGroupMap(parser.GroupObjectsByName(objects))
+}
+
+// sortGroupMap возвращает группы, отсортированные по имени, типу и материалу
+func sortGroupMap(groupsMap map[string]parser.GroupedObject) []parser.GroupedObject {
+	groups := make([]parser.GroupedObject, 0, len(groupsMap))
+	for _, group := range groupsMap {
+		groups = append(groups, group)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Name != groups[j].Name {
+			return groups[i].Name < groups[j].Name
+		}
+		if groups[i].Type != groups[j].Type {
+			return groups[i].Type < groups[j].Type
+		}
+		return groups[i].Material < groups[j].Material
+	})
+
+	return groups
+}
+
+// collectMaterials возвращает отсортированный список уникальных очищенных названий материалов
+func collectMaterials(data *parser.Parser3MF) []string {
+	materialsSet := make(map[string]bool)
+	for _, plate := range data.Plates {
+		for _, obj := range plate.Objects {
+			for _, material := range obj.MaterialNames() {
+				materialsSet[parser.CleanMaterialName(material)] = true
+			}
+		}
+	}
+
+	materials := make([]string, 0, len(materialsSet))
+	for material := range materialsSet {
+		materials = append(materials, material)
+	}
+	sort.Strings(materials)
+
+	return materials
+}
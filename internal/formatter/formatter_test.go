@@ -0,0 +1,292 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"farmix-cli/internal/parser"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files")
+
+// sampleListData возвращает набор данных с повторяющимися объектами, сборкой и пустым столом
+func sampleListData() *parser.Parser3MF {
+	return &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{
+				PlateID:   1,
+				PlateName: "Корпус",
+				Objects: []parser.PlateObject{
+					{ID: 3, Name: "Крышка", Type: "model", Material: "Eryone ASA-GF(opengrid-9x9.3mf)", Printable: true},
+					{ID: 1, Name: "Bracket", Type: "model", Material: "Bambu PLA Basic", Printable: true},
+					{ID: 2, Name: "Bracket", Type: "model", Material: "Bambu PLA Basic", Printable: true},
+					{
+						ID:        4,
+						Name:      "Hinge",
+						Type:      "assembly",
+						Material:  "Bambu PETG HF (Black)",
+						Printable: true,
+						Components: []parser.ComponentInfo{
+							{ID: 10, Name: "Pin", SourceFile: "3D/Objects/pin.model"},
+							{ID: 11, Name: "Leaf", SourceFile: "3D/Objects/leaf.model"},
+						},
+					},
+				},
+			},
+			{
+				PlateID:   2,
+				PlateName: "",
+				Objects:   []parser.PlateObject{},
+			},
+		},
+	}
+}
+
+func TestFormatAsJSONGolden(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FormatAsJSON(sampleListData(), &buf); err != nil {
+		t.Fatalf("FormatAsJSON() error = %v", err)
+	}
+
+	goldenPath := filepath.Join("testdata", "list.golden.json")
+	if *updateGolden {
+		if err := os.WriteFile(goldenPath, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("failed to update golden file: %v", err)
+		}
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to read golden file: %v", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), expected) {
+		t.Errorf("JSON output does not match %s\ngot:\n%s\nwant:\n%s", goldenPath, buf.String(), string(expected))
+	}
+}
+
+func TestFormatAsJSONIsValid(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FormatAsJSON(&parser.Parser3MF{}, &buf); err != nil {
+		t.Fatalf("FormatAsJSON() error = %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if plates, ok := decoded["plates"].([]interface{}); !ok || len(plates) != 0 {
+		t.Errorf("expected empty plates array, got %v", decoded["plates"])
+	}
+	if materials, ok := decoded["materials"].([]interface{}); !ok || len(materials) != 0 {
+		t.Errorf("expected empty materials array, got %v", decoded["materials"])
+	}
+}
+
+// timedPlateData - два стола, второй без данных нарезки
+func timedPlateData() *parser.Parser3MF {
+	return &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{
+				PlateID:   1,
+				PrintTime: 90 * time.Minute,
+				Objects:   []parser.PlateObject{{ID: 1, Name: "Bracket", Type: "model", Material: "PLA"}},
+			},
+			{
+				PlateID: 2,
+				Objects: []parser.PlateObject{{ID: 2, Name: "Cover", Type: "model", Material: "PETG"}},
+			},
+		},
+	}
+}
+
+func TestFormatAsCSVShowTime(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FormatAsCSV(timedPlateData(), &buf, ListOptions{ShowTime: true}); err != nil {
+		t.Fatalf("FormatAsCSV() error = %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %d:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasSuffix(lines[0], ",PrintTimeSeconds") {
+		t.Errorf("header = %q, want PrintTimeSeconds column", lines[0])
+	}
+	if !strings.HasSuffix(lines[1], ",5400") {
+		t.Errorf("plate 1 row = %q, want print time 5400", lines[1])
+	}
+	if !strings.HasSuffix(lines[2], ",") {
+		t.Errorf("plate 2 row = %q, want empty print time", lines[2])
+	}
+
+	buf.Reset()
+	if err := FormatAsCSV(timedPlateData(), &buf, ListOptions{}); err != nil {
+		t.Fatalf("FormatAsCSV() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "PrintTimeSeconds") {
+		t.Error("PrintTimeSeconds column should be added only with ShowTime")
+	}
+}
+
+func TestFormatAsTextShowTime(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FormatAsText(timedPlateData(), &buf, ListOptions{ShowTime: true}); err != nil {
+		t.Fatalf("FormatAsText() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"  Print time: 1h 30m\n",
+		"  Print time: n/a (project not sliced)\n",
+		"Total print time: 1h 30m\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestFormatAsTextSummary(t *testing.T) {
+	data := twoPlateData()
+
+	summary := summarizeParts(data)
+	if summary.UniqueParts != 2 || summary.TotalParts != 5 {
+		t.Errorf("summary = %d unique / %d total parts, want 2 / 5", summary.UniqueParts, summary.TotalParts)
+	}
+	wantMaterials := []materialCount{{"PETG", 1}, {"PLA", 4}}
+	if len(summary.Materials) != len(wantMaterials) {
+		t.Fatalf("materials = %+v, want %+v", summary.Materials, wantMaterials)
+	}
+	for i, want := range wantMaterials {
+		if summary.Materials[i] != want {
+			t.Errorf("materials[%d] = %+v, want %+v", i, summary.Materials[i], want)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := FormatAsText(data, &buf, ListOptions{}); err != nil {
+		t.Fatalf("FormatAsText() error = %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"Summary:\n========\n",
+		"Unique parts: 2\n",
+		"Total parts: 5\n",
+		"│ PETG     │     1 │\n",
+		"│ PLA      │     4 │\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output missing %q:\n%s", want, output)
+		}
+	}
+	if strings.Index(output, "Plate 1:") > strings.Index(output, "Summary:") {
+		t.Errorf("summary should follow the plates:\n%s", output)
+	}
+}
+
+func TestFormatPrintTime(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		lang     Lang
+		want     string
+	}{
+		{0, LangEN, "n/a (project not sliced)"},
+		{45 * time.Second, LangEN, "0h 01m"},
+		{26*time.Hour + 5*time.Minute, LangEN, "26h 05m"},
+		{90 * time.Minute, LangRU, "1 ч 30 мин"},
+	}
+
+	for _, tt := range tests {
+		if got := formatPrintTime(tt.duration, tt.lang); got != tt.want {
+			t.Errorf("formatPrintTime(%v, %s) = %q, want %q", tt.duration, tt.lang, got, tt.want)
+		}
+	}
+}
+
+// dimensionedPlateData - стол с объектом с известными габаритами и объектом без них
+func dimensionedPlateData() *parser.Parser3MF {
+	return &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{
+				PlateID: 1,
+				Objects: []parser.PlateObject{
+					{ID: 1, Name: "Bracket", Type: "model", Material: "PLA", Dimensions: &parser.Dimensions{Width: 20, Depth: 10.25, Height: 5}},
+					{ID: 2, Name: "Cover", Type: "model", Material: "PLA"},
+				},
+			},
+		},
+	}
+}
+
+func TestFormatShowDimensions(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FormatAsText(dimensionedPlateData(), &buf, ListOptions{ShowDimensions: true}); err != nil {
+		t.Fatalf("FormatAsText() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "  1 x Bracket; PLA; 20.0 x 10.2 x 5.0 mm\n") {
+		t.Errorf("text output missing Bracket dimensions:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "  1 x Cover; PLA\n") {
+		t.Errorf("object without dimensions should have no dimensions suffix:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := FormatAsCSV(dimensionedPlateData(), &buf, ListOptions{ShowDimensions: true}); err != nil {
+		t.Fatalf("FormatAsCSV() error = %v", err)
+	}
+	output := buf.String()
+	for _, want := range []string{",WidthMM,DepthMM,HeightMM\n", ",Bracket,model,PLA,1,0,,,20.0,10.2,5.0\n", ",Cover,model,PLA,1,0,,,,,\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("CSV output missing %q:\n%s", want, output)
+		}
+	}
+
+	buf.Reset()
+	if err := FormatAsCSV(dimensionedPlateData(), &buf, ListOptions{}); err != nil {
+		t.Fatalf("FormatAsCSV() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "WidthMM") {
+		t.Error("dimension columns should be added only with ShowDimensions")
+	}
+}
+
+func TestFormatPaintedObjectMaterials(t *testing.T) {
+	data := &parser.Parser3MF{Plates: []parser.PlateInfo{{
+		PlateID: 1,
+		Objects: []parser.PlateObject{{
+			ID:        1,
+			Name:      "Vase",
+			Type:      "model",
+			Material:  "PLA (Red)",
+			Materials: []string{"PLA (Red)", "TPU 95A"},
+		}},
+	}}}
+
+	var buf bytes.Buffer
+	if err := FormatAsText(data, &buf, ListOptions{}); err != nil {
+		t.Fatalf("FormatAsText() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), "  1 x Vase; PLA, TPU 95A\n") {
+		t.Errorf("text output should list all painted materials:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := FormatAsCSV(data, &buf, ListOptions{}); err != nil {
+		t.Fatalf("FormatAsCSV() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), `,Vase,model,"PLA, TPU 95A",1,`) {
+		t.Errorf("CSV material column should list all painted materials:\n%s", buf.String())
+	}
+
+	if got := collectMaterials(data); !reflect.DeepEqual(got, []string{"PLA", "TPU 95A"}) {
+		t.Errorf("collectMaterials() = %v, want [PLA TPU 95A]", got)
+	}
+}
@@ -0,0 +1,133 @@
+package formatter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lang - язык подписей в отчетах
+type Lang string
+
+const (
+	LangRU Lang = "ru"
+	LangEN Lang = "en"
+)
+
+// ParseLang разбирает значение флага --lang (ru, en), пустая строка - язык по умолчанию
+func ParseLang(value string) (Lang, error) {
+	switch Lang(strings.ToLower(strings.TrimSpace(value))) {
+	case "":
+		return "", nil
+	case LangRU:
+		return LangRU, nil
+	case LangEN:
+		return LangEN, nil
+	}
+	return "", fmt.Errorf("unsupported language %q (supported: ru, en)", value)
+}
+
+// orDefault возвращает язык или def, если язык не задан
+func (l Lang) orDefault(def Lang) Lang {
+	if l == "" {
+		return def
+	}
+	return l
+}
+
+// T возвращает подпись по ключу сообщения
+// Для отсутствующего перевода используется английский вариант, затем сам ключ
+func (l Lang) T(key string) string {
+	translations, ok := messages[key]
+	if !ok {
+		return key
+	}
+	if text, ok := translations[l]; ok {
+		return text
+	}
+	if text, ok := translations[LangEN]; ok {
+		return text
+	}
+	return key
+}
+
+// messages - подписи отчетов по ключам сообщений
+var messages = map[string]map[Lang]string{
+	// Наряд-заказ и сменное задание (Excel)
+	"order.sheet":               {LangRU: "Наряд-заказ", LangEN: "Work order"},
+	"order.title":               {LangRU: "НАРЯД-ЗАКАЗ", LangEN: "WORK ORDER"},
+	"assignment.sheet":          {LangRU: "Сменное задание", LangEN: "Shift assignment"},
+	"assignment.title":          {LangRU: "СМЕННОЕ ЗАДАНИЕ", LangEN: "SHIFT ASSIGNMENT"},
+	"assignment.by_material":    {LangRU: "По материалам", LangEN: "By material"},
+	"assignment.distinct_parts": {LangRU: "Деталей", LangEN: "Distinct parts"},
+	"deal.responsible":          {LangRU: "Ответственный:", LangEN: "Responsible:"},
+	"deal.customer":             {LangRU: "Заказчик:", LangEN: "Customer:"},
+	"deal.deal":                 {LangRU: "Сделка:", LangEN: "Deal:"},
+	"deal.project":              {LangRU: "Проект:", LangEN: "Project:"},
+	"deal.link":                 {LangRU: "Ссылка:", LangEN: "Link:"},
+	"deal.open":                 {LangRU: "Открыть в Bitrix24", LangEN: "Open in Bitrix24"},
+	"deal.date":                 {LangRU: "Дата:", LangEN: "Date:"},
+	"plate.label":               {LangRU: "Стол", LangEN: "Plate"},
+	"plate.alt":                 {LangRU: "Стол %d", LangEN: "Plate %d"},
+	"plate.repeats":             {LangRU: "Повторений", LangEN: "Repeats"},
+	"plate.material":            {LangRU: "Материал", LangEN: "Material"},
+	"plate.printer":             {LangRU: "Принтер", LangEN: "Printer"},
+	"plate.any_printer":         {LangRU: "любой", LangEN: "any"},
+	"plate.total_weight":        {LangRU: "Общий вес, г", LangEN: "Total weight, g"},
+	"plate.support_weight":      {LangRU: "Вес поддержек, г", LangEN: "Support weight, g"},
+	"plate.print_time":          {LangRU: "Время печати, ч", LangEN: "Print time, h"},
+	"parts.name":                {LangRU: "Название детали", LangEN: "Part name"},
+	"parts.count":               {LangRU: "Количество", LangEN: "Quantity"},
+	"parts.count_on_plate":      {LangRU: "Количество на столе", LangEN: "Quantity on plate"},
+	"parts.count_all_repeats":   {LangRU: "Количество (все повторения)", LangEN: "Quantity (all repeats)"},
+	"parts.approx_weight":       {LangRU: "Примерный вес", LangEN: "Approximate weight"},
+	"bom.title":                 {LangRU: "Сводная спецификация", LangEN: "Bill of materials"},
+	"bom.total_quantity":        {LangRU: "Всего", LangEN: "Total quantity"},
+	"bom.plates":                {LangRU: "Столы", LangEN: "Plates"},
+	"materials.name":            {LangRU: "Название", LangEN: "Name"},
+	"materials.weight":          {LangRU: "Вес", LangEN: "Weight"},
+	"materials.price_per_kg":    {LangRU: "Стоимость за кг", LangEN: "Price per kg"},
+	"materials.cost":            {LangRU: "Стоимость", LangEN: "Cost"},
+	"materials.total":           {LangRU: "Итого", LangEN: "Total"},
+	"hours.work_type":           {LangRU: "Тип работ", LangEN: "Work type"},
+	"hours.hours":               {LangRU: "Часы", LangEN: "Hours"},
+	"hours.rate":                {LangRU: "Ставка", LangEN: "Rate"},
+	"hours.machine":             {LangRU: "Машино-часы", LangEN: "Machine hours"},
+	"hours.operator":            {LangRU: "Работа оператора", LangEN: "Operator work"},
+
+	// Анализ 3MF файла (текст и PDF)
+	"analysis.title":       {LangRU: "Анализ 3MF файла", LangEN: "3MF File Analysis"},
+	"analysis.generated":   {LangRU: "Создан: %s", LangEN: "Generated: %s"},
+	"analysis.no_plates":   {LangRU: "В файле нет печатных столов.", LangEN: "No plates found in the file."},
+	"analysis.plate":       {LangRU: "Стол %d: %s", LangEN: "Plate %d: %s"},
+	"analysis.no_objects":  {LangRU: "На столе нет объектов.", LangEN: "No objects on this plate."},
+	"analysis.assembly":    {LangRU: "сборка", LangEN: "assembly"},
+	"analysis.components":  {LangRU: "Компоненты:", LangEN: "Components:"},
+	"analysis.component":   {LangRU: "- %s (ID: %d, файл: %s)", LangEN: "- %s (ID: %d, Source: %s)"},
+	"analysis.materials":   {LangRU: "Использованные материалы", LangEN: "Materials Used"},
+	"analysis.object_name": {LangRU: "Название объекта", LangEN: "Object Name"},
+	"analysis.count":       {LangRU: "Кол-во", LangEN: "Count"},
+	"analysis.type":        {LangRU: "Тип", LangEN: "Type"},
+	"analysis.material":    {LangRU: "Материал", LangEN: "Material"},
+
+	// Время печати (list --show-time)
+	"analysis.print_time":       {LangRU: "Время печати", LangEN: "Print time"},
+	"analysis.total_print_time": {LangRU: "Общее время печати", LangEN: "Total print time"},
+	"analysis.no_print_time":    {LangRU: "нет данных (проект не нарезан)", LangEN: "n/a (project not sliced)"},
+	"analysis.duration":         {LangRU: "%d ч %02d мин", LangEN: "%dh %02dm"},
+
+	// Габариты объектов (list --show-dimensions)
+	"analysis.mm": {LangRU: "мм", LangEN: "mm"},
+
+	// Итоги по деталям (list)
+	"summary.title":        {LangRU: "Итого", LangEN: "Summary"},
+	"summary.unique_parts": {LangRU: "Уникальных деталей", LangEN: "Unique parts"},
+	"summary.total_parts":  {LangRU: "Всего деталей", LangEN: "Total parts"},
+	"summary.no_material":  {LangRU: "без материала", LangEN: "no material"},
+
+	// Сводная оценка (quote)
+	"quote.title":        {LangRU: "Оценка заказа", LangEN: "Job Quote"},
+	"quote.weight":       {LangRU: "Вес, г", LangEN: "Weight, g"},
+	"quote.source":       {LangRU: "Источник веса", LangEN: "Weight source"},
+	"quote.total_weight": {LangRU: "Общий вес, г", LangEN: "Total weight, g"},
+	"quote.total_cost":   {LangRU: "Стоимость материала", LangEN: "Total material cost"},
+}
\ No newline at end of file
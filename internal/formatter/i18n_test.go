@@ -0,0 +1,111 @@
+package formatter
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"farmix-cli/internal/bitrix"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestParseLang(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Lang
+		wantErr bool
+	}{
+		{"", "", false},
+		{"ru", LangRU, false},
+		{" EN ", LangEN, false},
+		{"de", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseLang(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseLang(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLang(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestMessagesHaveAllLanguages(t *testing.T) {
+	for key, translations := range messages {
+		for _, lang := range []Lang{LangRU, LangEN} {
+			if translations[lang] == "" {
+				t.Errorf("message %q has no %s translation", key, lang)
+			}
+		}
+	}
+}
+
+func TestFormatAsOrderExcelLang(t *testing.T) {
+	tests := []struct {
+		lang         Lang
+		wantSheet    string
+		wantTitle    string
+		wantCustomer string
+	}{
+		{"", "Наряд-заказ", "НАРЯД-ЗАКАЗ", "Заказчик:"},
+		{LangRU, "Наряд-заказ", "НАРЯД-ЗАКАЗ", "Заказчик:"},
+		{LangEN, "Work order", "WORK ORDER", "Customer:"},
+	}
+
+	deal := &bitrix.Deal{ID: "42", Title: "Brackets"}
+	user := &bitrix.User{FullName: "Ivan Petrov"}
+	client := bitrix.NewClient("https://example.bitrix24.ru/rest/1/token/")
+
+	for _, tt := range tests {
+		t.Run(string(tt.lang), func(t *testing.T) {
+			outputPath := filepath.Join(t.TempDir(), "order.xlsx")
+			options := OrderReportOptions{Lang: tt.lang}
+			if err := FormatAsOrderExcel(twoPlateData(), deal, user, "ACME", client, outputPath, options); err != nil {
+				t.Fatalf("FormatAsOrderExcel() error = %v", err)
+			}
+
+			f, err := excelize.OpenFile(outputPath)
+			if err != nil {
+				t.Fatalf("failed to open generated file: %v", err)
+			}
+			defer f.Close()
+
+			if index, err := f.GetSheetIndex(tt.wantSheet); err != nil || index < 0 {
+				t.Fatalf("sheet %q not found in %v", tt.wantSheet, f.GetSheetList())
+			}
+			for cell, want := range map[string]string{"A1": tt.wantTitle, "A4": tt.wantCustomer} {
+				got, _ := f.GetCellValue(tt.wantSheet, cell)
+				if got != want {
+					t.Errorf("cell %s = %q, want %q", cell, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFormatAsTextLang(t *testing.T) {
+	tests := []struct {
+		lang  Lang
+		wants []string
+	}{
+		{"", []string{"3MF File Analysis\n=================\n", "Plate 1: ", "Materials Used:\n"}},
+		{LangRU, []string{"Анализ 3MF файла\n================\n", "Стол 1: ", "Использованные материалы:\n"}},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := FormatAsText(twoPlateData(), &buf, ListOptions{Lang: tt.lang}); err != nil {
+			t.Fatalf("FormatAsText(%q) error = %v", tt.lang, err)
+		}
+		for _, want := range tt.wants {
+			if !strings.Contains(buf.String(), want) {
+				t.Errorf("FormatAsText(%q) output missing %q:\n%s", tt.lang, want, buf.String())
+			}
+		}
+	}
+}
\ No newline at end of file
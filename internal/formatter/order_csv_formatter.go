@@ -0,0 +1,75 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"farmix-cli/internal/bitrix"
+	"farmix-cli/internal/parser"
+)
+
+// orderCSVHeaders are the columns of the flat order CSV, one row per part of a plate
+var orderCSVHeaders = []string{
+	"DealID", "Customer", "Plate", "PlateMaterials", "PlateWeightGrams",
+	"SupportWeightGrams", "PrintTimeHours", "PartName", "PartType", "Count",
+	"Material", "MaterialPricePerKg",
+}
+
+// FormatAsOrderCSV writes the order data as a denormalized CSV for MRP import.
+// Every row is a part of a plate and repeats the deal and plate columns;
+// parts are grouped the same way as in the order Excel report.
+// Weights, print time and price are empty when slicing data or price is missing
+func FormatAsOrderCSV(data *parser.Parser3MF, deal *bitrix.Deal, customerName string, writer io.Writer, options OrderReportOptions) error {
+	csvWriter := csv.NewWriter(writer)
+
+	if err := csvWriter.Write(orderCSVHeaders); err != nil {
+		return fmt.Errorf("failed to write CSV headers: %w", err)
+	}
+
+	for _, plate := range data.Plates {
+		// Material variants do not split a part into several rows
+		groups := sortGroupMap(parser.GroupObjectsByNameAndMaterial(plate.Objects))
+		materials := plateMaterialsLabel(plate)
+
+		for _, group := range groups {
+			price := ""
+			if value, ok := lookupMaterialPrice(options.MaterialPrices, group.Material); ok {
+				price = formatCSVNumber(value)
+			}
+
+			record := []string{
+				deal.ID,
+				customerName,
+				strconv.Itoa(plate.PlateID),
+				materials,
+				formatCSVNumber(plate.WeightGrams),
+				formatCSVNumber(plate.SupportWeightGrams),
+				formatCSVNumber(plate.PrintTime.Hours()),
+				group.Name,
+				group.Type,
+				strconv.Itoa(group.Count),
+				group.MaterialLabel(),
+				price,
+			}
+			if err := csvWriter.Write(record); err != nil {
+				return fmt.Errorf("failed to write CSV record: %w", err)
+			}
+		}
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	return nil
+}
+
+// formatCSVNumber rounds value to 2 decimal places, zero (no data) is written as empty cell
+func formatCSVNumber(value float64) string {
+	if value <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(roundMoney(value), 'f', -1, 64)
+}
\ No newline at end of file
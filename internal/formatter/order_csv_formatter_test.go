@@ -0,0 +1,41 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"testing"
+	"time"
+
+	"farmix-cli/internal/bitrix"
+)
+
+func TestFormatAsOrderCSV(t *testing.T) {
+	data := twoPlateData()
+	data.Plates[1].WeightGrams = 42.345
+	data.Plates[1].PrintTime = 90 * time.Minute
+
+	var buf bytes.Buffer
+	options := OrderReportOptions{MaterialPrices: map[string]float64{"pla": 1500}}
+	if err := FormatAsOrderCSV(data, &bitrix.Deal{ID: "42"}, "ACME", &buf, options); err != nil {
+		t.Fatalf("FormatAsOrderCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV: %v", err)
+	}
+	if !reflect.DeepEqual(records[0], orderCSVHeaders) {
+		t.Fatalf("headers = %v, want %v", records[0], orderCSVHeaders)
+	}
+
+	// One row per part of a plate; PLA variants stay in one row, plates keep file order
+	want := [][]string{
+		{"42", "ACME", "2", "PLA", "", "", "", "Bracket", "model", "1", "PLA", "1500"},
+		{"42", "ACME", "1", "PETG, PLA", "42.35", "", "1.5", "Bracket", "model", "3", "PLA", "1500"},
+		{"42", "ACME", "1", "PETG, PLA", "42.35", "", "1.5", "Cover", "model", "1", "PETG", ""},
+	}
+	if !reflect.DeepEqual(records[1:], want) {
+		t.Errorf("rows =\n%v\nwant\n%v", records[1:], want)
+	}
+}
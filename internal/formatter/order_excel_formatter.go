@@ -1,8 +1,15 @@
 package formatter
 
 import (
+	"bytes"
 	"fmt"
+	"image"
+	_ "image/png"
+	"math"
+	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"farmix-cli/internal/bitrix"
@@ -11,8 +18,46 @@ import (
 	"github.com/xuri/excelize/v2"
 )
 
+// OrderReportOptions contains optional settings for the order report
+type OrderReportOptions struct {
+	// MaterialPrices maps material name to price per kg (case-insensitive)
+	MaterialPrices map[string]float64
+	// PrinterAssignments maps material name to the printer it must be printed on
+	// (matched like MaterialPrices); plates of unmapped materials go to any printer
+	PrinterAssignments map[string]string
+	// Lang selects report labels language (empty means Russian)
+	Lang Lang
+	// Project is the deal's project from a custom field, shown in the header when set
+	Project string
+}
+
+const (
+	// thumbnailHeightPx is the height of plate preview in order report
+	thumbnailHeightPx = 160
+	// defaultRowHeightPx is the default Excel row height (15pt)
+	defaultRowHeightPx = 20.0
+)
+
+// materialCostRow is a single row of the order materials section
+type materialCostRow struct {
+	Name        string
+	WeightGrams float64
+	HasWeight   bool
+	PricePerKg  float64
+	HasPrice    bool
+	Cost        float64
+	HasCost     bool
+}
+
+// materialAssignmentRow is a single row of the assignment "by material" section
+type materialAssignmentRow struct {
+	Material      string
+	DistinctParts int
+	Quantity      int
+}
+
 // FormatAsOrderExcel creates the main order report Excel file
-func FormatAsOrderExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.User, customerName string, client *bitrix.Client, outputPath string) error {
+func FormatAsOrderExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.User, customerName string, client *bitrix.Client, outputPath string, options OrderReportOptions) error {
 	// Create new Excel file
 	f := excelize.NewFile()
 	colors := DefaultExcelColors()
@@ -21,7 +66,8 @@ func FormatAsOrderExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.
 	f.DeleteSheet("Sheet1")
 	
 	// Create the order sheet
-	sheetName := "Наряд-заказ"
+	lang := options.Lang.orDefault(LangRU)
+	sheetName := lang.T("order.sheet")
 	_, err := f.NewSheet(sheetName)
 	if err != nil {
 		return fmt.Errorf("failed to create order sheet: %w", err)
@@ -31,7 +77,7 @@ func FormatAsOrderExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.
 	f.SetActiveSheet(0)
 	
 	// Create order content
-	if err := createOrderContent(f, sheetName, data, deal, user, customerName, client, colors); err != nil {
+	if err := createOrderContent(f, sheetName, data, deal, user, customerName, client, options, lang, colors); err != nil {
 		return fmt.Errorf("failed to create order content: %w", err)
 	}
 	
@@ -44,7 +90,8 @@ func FormatAsOrderExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.
 }
 
 // FormatAsAssignmentExcel creates the assignment report Excel file
-func FormatAsAssignmentExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.User, customerName string, client *bitrix.Client, outputPath string) error {
+// Uses options.Lang (empty means Russian) and options.PrinterAssignments
+func FormatAsAssignmentExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.User, customerName string, client *bitrix.Client, outputPath string, options OrderReportOptions) error {
 	// Create new Excel file
 	f := excelize.NewFile()
 	colors := DefaultExcelColors()
@@ -53,7 +100,8 @@ func FormatAsAssignmentExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bi
 	f.DeleteSheet("Sheet1")
 	
 	// Create the assignment sheet
-	sheetName := "Сменное задание"
+	lang := options.Lang.orDefault(LangRU)
+	sheetName := lang.T("assignment.sheet")
 	_, err := f.NewSheet(sheetName)
 	if err != nil {
 		return fmt.Errorf("failed to create assignment sheet: %w", err)
@@ -63,7 +111,7 @@ func FormatAsAssignmentExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bi
 	f.SetActiveSheet(0)
 	
 	// Create assignment content
-	if err := createAssignmentContent(f, sheetName, data, deal, user, customerName, colors); err != nil {
+	if err := createAssignmentContent(f, sheetName, data, deal, user, customerName, options, lang, colors); err != nil {
 		return fmt.Errorf("failed to create assignment content: %w", err)
 	}
 	
@@ -76,11 +124,11 @@ func FormatAsAssignmentExcel(data *parser.Parser3MF, deal *bitrix.Deal, user *bi
 }
 
 // createOrderContent creates the detailed order report content
-func createOrderContent(f *excelize.File, sheetName string, data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.User, customerName string, client *bitrix.Client, colors ExcelColors) error {
+func createOrderContent(f *excelize.File, sheetName string, data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.User, customerName string, client *bitrix.Client, options OrderReportOptions, lang Lang, colors ExcelColors) error {
 	row := 1
 	
 	// Title
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "НАРЯД-ЗАКАЗ")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("order.title"))
 	
 	// Title style
 	titleStyle, _ := f.NewStyle(&excelize.Style{
@@ -97,39 +145,58 @@ func createOrderContent(f *excelize.File, sheetName string, data *parser.Parser3
 	
 	row += 2
 	
-	// Deal information block
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Ответственный:")
-	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), user.FullName)
+	// Deal information block, deal and responsible user are clickable links to Bitrix24
+	dealURL := client.GetDealURL(deal.ID)
+	linkStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Color: colors.LinkText, Underline: "single"},
+	})
+	
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.responsible"))
+	if userURL, ok := client.GetUserURL(user.ID); ok {
+		setHyperlinkCell(f, sheetName, "B"+strconv.Itoa(row), user.FullName, userURL, linkStyle)
+	} else {
+		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), user.FullName)
+	}
 	row++
 	
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Заказчик:")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.customer"))
 	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), customerName)
 	row++
 	
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Сделка:")
-	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), deal.ID)
+	if options.Project != "" {
+		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.project"))
+		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), options.Project)
+		row++
+	}
+	
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.deal"))
+	setHyperlinkCell(f, sheetName, "B"+strconv.Itoa(row), deal.ID, dealURL, linkStyle)
 	row++
 	
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Ссылка:")
-	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), client.GetDealURL(deal.ID))
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.link"))
+	setHyperlinkCell(f, sheetName, "B"+strconv.Itoa(row), lang.T("deal.open"), dealURL, linkStyle)
 	row++
 	
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Дата:")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.date"))
 	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), time.Now().Format("02.01.2006"))
 	row += 2
 	
 	// Process each plate
 	for _, plate := range data.Plates {
-		row = createPlateSection(f, sheetName, plate, row, colors)
+		row = createPlateSection(f, sheetName, plate, row, lang, colors)
 		row += 2 // Add space between plates
 	}
 	
+	// Bill of materials across all plates
+	row = createBOMSection(f, sheetName, data, row, lang, colors)
+	row += 2
+	
 	// Materials summary
-	row = createMaterialsSection(f, sheetName, data, row, colors)
+	row = createMaterialsSection(f, sheetName, data, options.MaterialPrices, row, lang, colors)
 	row += 2
 	
 	// Hours section
-	row = createHoursSection(f, sheetName, row, colors)
+	row = createHoursSection(f, sheetName, row, lang, colors)
 	
 	// Set column widths
 	f.SetColWidth(sheetName, "A", "A", 20)
@@ -144,12 +211,20 @@ func createOrderContent(f *excelize.File, sheetName string, data *parser.Parser3
 	return nil
 }
 
+// setHyperlinkCell writes display text into cell and makes it an external link to url
+// The URL itself is shown as the tooltip
+func setHyperlinkCell(f *excelize.File, sheetName, cell, display, url string, style int) {
+	f.SetCellValue(sheetName, cell, display)
+	f.SetCellHyperLink(sheetName, cell, url, "External", excelize.HyperlinkOpts{Display: &display, Tooltip: &url})
+	f.SetCellStyle(sheetName, cell, cell, style)
+}
+
 // createAssignmentContent creates the assignment report content (simplified version)
-func createAssignmentContent(f *excelize.File, sheetName string, data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.User, customerName string, colors ExcelColors) error {
+func createAssignmentContent(f *excelize.File, sheetName string, data *parser.Parser3MF, deal *bitrix.Deal, user *bitrix.User, customerName string, options OrderReportOptions, lang Lang, colors ExcelColors) error {
 	row := 1
 	
 	// Title
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "СМЕННОЕ ЗАДАНИЕ")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("assignment.title"))
 	
 	// Title style
 	titleStyle, _ := f.NewStyle(&excelize.Style{
@@ -167,37 +242,48 @@ func createAssignmentContent(f *excelize.File, sheetName string, data *parser.Pa
 	row += 2
 	
 	// Basic info
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Заказчик:")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.customer"))
 	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), customerName)
 	row++
 	
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Сделка:")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.deal"))
 	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), deal.ID+" - "+deal.Title)
 	row++
 	
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Дата:")
+	if options.Project != "" {
+		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.project"))
+		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), options.Project)
+		row++
+	}
+	
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("deal.date"))
 	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), time.Now().Format("02.01.2006"))
 	row += 2
 	
 	// Simplified plate information
 	for _, plate := range data.Plates {
-		groups := parser.GroupObjectsByName(plate.Objects)
+		// Варианты одного материала не дробят деталь на несколько строк
+		groups := parser.GroupObjectsByNameAndMaterial(plate.Objects)
 		if len(groups) == 0 {
 			continue
 		}
 		
-		// Get first material from groups
-		var firstMaterial string
-		for _, group := range groups {
-			firstMaterial = cleanMaterialName(group.Material)
-			break
-		}
+		// All plate materials (multi-material plates list each of them)
+		materials := plateMaterialsLabel(plate)
 		
 		// Plate header
-		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Стол")
+		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("plate.label"))
 		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), plate.PlateID)
-		f.SetCellValue(sheetName, "C"+strconv.Itoa(row), "Материал")
-		f.SetCellValue(sheetName, "D"+strconv.Itoa(row), firstMaterial)
+		f.SetCellValue(sheetName, "C"+strconv.Itoa(row), lang.T("plate.material"))
+		f.SetCellValue(sheetName, "D"+strconv.Itoa(row), materials)
+		f.SetCellValue(sheetName, "E"+strconv.Itoa(row), lang.T("plate.printer"))
+		printer := platePrinter(plate, options.PrinterAssignments)
+		if printer == "" {
+			printer = lang.T("plate.any_printer")
+		}
+		f.SetCellValue(sheetName, "F"+strconv.Itoa(row), printer)
+		f.SetCellValue(sheetName, "G"+strconv.Itoa(row), lang.T("plate.repeats"))
+		f.SetCellValue(sheetName, "H"+strconv.Itoa(row), plate.RepeatCount())
 		row++
 		
 		// Objects table header
@@ -212,8 +298,8 @@ func createAssignmentContent(f *excelize.File, sheetName string, data *parser.Pa
 			},
 		})
 		
-		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Название детали")
-		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), "Количество")
+		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("parts.name"))
+		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), lang.T("parts.count"))
 		f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "B"+strconv.Itoa(row), headerStyle)
 		row++
 		
@@ -227,6 +313,9 @@ func createAssignmentContent(f *excelize.File, sheetName string, data *parser.Pa
 		row++ // Space between plates
 	}
 	
+	// Totals by material for shift planning
+	createAssignmentMaterialsSection(f, sheetName, data, row, lang, colors)
+	
 	// Set column widths
 	f.SetColWidth(sheetName, "A", "A", 40)
 	f.SetColWidth(sheetName, "B", "B", 15)
@@ -234,42 +323,129 @@ func createAssignmentContent(f *excelize.File, sheetName string, data *parser.Pa
 	f.SetColWidth(sheetName, "D", "D", 25)
 	f.SetColWidth(sheetName, "E", "E", 15)
 	f.SetColWidth(sheetName, "F", "F", 15)
+	f.SetColWidth(sheetName, "G", "G", 15)
 	
 	return nil
 }
 
+// createAssignmentMaterialsSection creates the "by material" section of the assignment report
+func createAssignmentMaterialsSection(f *excelize.File, sheetName string, data *parser.Parser3MF, startRow int, lang Lang, colors ExcelColors) int {
+	row := startRow
+	
+	materials := aggregateByMaterial(data)
+	if len(materials) == 0 {
+		return row
+	}
+	
+	// Section title
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+			Size: 14,
+		},
+	})
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("assignment.by_material"))
+	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "A"+strconv.Itoa(row), titleStyle)
+	row++
+	
+	// Table header
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+		},
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Color:   []string{colors.HeaderBg},
+			Pattern: 1,
+		},
+	})
+	
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("plate.material"))
+	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), lang.T("assignment.distinct_parts"))
+	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), lang.T("parts.count"))
+	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "C"+strconv.Itoa(row), headerStyle)
+	row++
+	
+	for _, material := range materials {
+		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), material.Material)
+		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), material.DistinctParts)
+		f.SetCellValue(sheetName, "C"+strconv.Itoa(row), material.Quantity)
+		row++
+	}
+	
+	return row
+}
+
+// aggregateByMaterial sums part groups of all plates by cleaned material name
+// DistinctParts counts unique part names, Quantity counts all printed copies (including plate repetitions)
+// Parts without material are skipped, rows are sorted by material name
+func aggregateByMaterial(data *parser.Parser3MF) []materialAssignmentRow {
+	parts := make(map[string]map[string]bool)
+	quantities := make(map[string]int)
+	
+	for _, plate := range data.Plates {
+		for _, group := range parser.GroupObjectsByNameAndMaterial(plate.Objects) {
+			material := group.Material
+			if material == "" {
+				continue
+			}
+			if parts[material] == nil {
+				parts[material] = make(map[string]bool)
+			}
+			parts[material][group.Name] = true
+			quantities[material] += group.Count * plate.RepeatCount()
+		}
+	}
+	
+	names := make([]string, 0, len(parts))
+	for name := range parts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	rows := make([]materialAssignmentRow, 0, len(names))
+	for _, name := range names {
+		rows = append(rows, materialAssignmentRow{
+			Material:      name,
+			DistinctParts: len(parts[name]),
+			Quantity:      quantities[name],
+		})
+	}
+	
+	return rows
+}
+
 // createPlateSection creates a section for one plate in the order report
-func createPlateSection(f *excelize.File, sheetName string, plate parser.PlateInfo, startRow int, colors ExcelColors) int {
+// Part counts and weights cover all plate repetitions, print time is for one print
+func createPlateSection(f *excelize.File, sheetName string, plate parser.PlateInfo, startRow int, lang Lang, colors ExcelColors) int {
 	row := startRow
-	groups := parser.GroupObjectsByName(plate.Objects)
+	repeats := plate.RepeatCount()
+	// Варианты одного материала не дробят деталь на несколько строк
+	groups := parser.GroupObjectsByNameAndMaterial(plate.Objects)
 	
 	if len(groups) == 0 {
 		return row
 	}
 	
-	// Get material from first group
-	var firstMaterial string
-	for _, group := range groups {
-		firstMaterial = cleanMaterialName(group.Material)
-		break
-	}
+	// All plate materials (multi-material plates list each of them)
+	materials := plateMaterialsLabel(plate)
 	
 	// Plate header row
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Стол")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("plate.label"))
 	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), plate.PlateID)
-	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), "Повторений")
-	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), 1)
-	f.SetCellValue(sheetName, "E"+strconv.Itoa(row), "Материал")
-	f.SetCellValue(sheetName, "F"+strconv.Itoa(row), firstMaterial)
+	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), lang.T("plate.repeats"))
+	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), repeats)
+	f.SetCellValue(sheetName, "E"+strconv.Itoa(row), lang.T("plate.material"))
+	f.SetCellValue(sheetName, "F"+strconv.Itoa(row), materials)
 	row++
 	
-	// Weight and time row (empty values as requested) - убрали "Вес модели"
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Общий вес, г")
-	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), "")
-	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), "Вес поддержек, г")
-	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), "")
-	f.SetCellValue(sheetName, "E"+strconv.Itoa(row), "Время печати, ч")
-	f.SetCellValue(sheetName, "F"+strconv.Itoa(row), "")
+	// Weight and time row - заполняется из slice_info.config, если проект нарезан
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("plate.total_weight"))
+	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), optionalRounded(plate.WeightGrams*float64(repeats)))
+	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), lang.T("plate.support_weight"))
+	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), optionalRounded(plate.SupportWeightGrams*float64(repeats)))
+	f.SetCellValue(sheetName, "E"+strconv.Itoa(row), lang.T("plate.print_time"))
+	f.SetCellValue(sheetName, "F"+strconv.Itoa(row), optionalRounded(plate.PrintTime.Hours()))
 	row++
 	
 	// Parts table header
@@ -291,9 +467,13 @@ func createPlateSection(f *excelize.File, sheetName string, plate parser.PlateIn
 		},
 	})
 	
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Название детали")
-	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), "Количество на столе")
-	f.SetCellValue(sheetName, "E"+strconv.Itoa(row), "Примерный вес")
+	countLabel := lang.T("parts.count_on_plate")
+	if repeats > 1 {
+		countLabel = lang.T("parts.count_all_repeats")
+	}
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("parts.name"))
+	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), countLabel)
+	f.SetCellValue(sheetName, "E"+strconv.Itoa(row), lang.T("parts.approx_weight"))
 	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "E"+strconv.Itoa(row), headerStyle)
 	// Объединяем ячейки A, B, C для названия детали
 	f.MergeCell(sheetName, "A"+strconv.Itoa(row), "C"+strconv.Itoa(row))
@@ -311,7 +491,7 @@ func createPlateSection(f *excelize.File, sheetName string, plate parser.PlateIn
 	
 	for _, group := range groups {
 		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), group.Name)
-		f.SetCellValue(sheetName, "D"+strconv.Itoa(row), group.Count)
+		f.SetCellValue(sheetName, "D"+strconv.Itoa(row), group.Count*repeats)
 		f.SetCellValue(sheetName, "E"+strconv.Itoa(row), "") // Empty as requested
 		f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "E"+strconv.Itoa(row), dataStyle)
 		// Объединяем ячейки A, B, C для названия детали
@@ -319,25 +499,133 @@ func createPlateSection(f *excelize.File, sheetName string, plate parser.PlateIn
 		row++
 	}
 	
+	// Превью стола справа от таблицы, секция растягивается на высоту картинки
+	if thumbnailRows := addPlateThumbnail(f, sheetName, plate, "H"+strconv.Itoa(startRow), lang); startRow+thumbnailRows > row {
+		row = startRow + thumbnailRows
+	}
+	
 	return row
 }
 
-// createMaterialsSection creates the materials summary section
-func createMaterialsSection(f *excelize.File, sheetName string, data *parser.Parser3MF, startRow int, colors ExcelColors) int {
+// plateMaterialsLabel returns sorted cleaned plate materials separated by comma
+// AMS/MMU plates print several materials, so the first group is not enough
+func plateMaterialsLabel(plate parser.PlateInfo) string {
+	return strings.Join(collectMaterials(&parser.Parser3MF{Plates: []parser.PlateInfo{plate}}), ", ")
+}
+
+// addPlateThumbnail embeds plate preview image at cell and returns number of rows it occupies
+// Missing or unreadable thumbnails are skipped
+func addPlateThumbnail(f *excelize.File, sheetName string, plate parser.PlateInfo, cell string, lang Lang) int {
+	if len(plate.Thumbnail) == 0 {
+		return 0
+	}
+	
+	config, _, err := image.DecodeConfig(bytes.NewReader(plate.Thumbnail))
+	if err != nil || config.Height == 0 {
+		return 0
+	}
+	
+	scale := float64(thumbnailHeightPx) / float64(config.Height)
+	err = f.AddPictureFromBytes(sheetName, cell, &excelize.Picture{
+		Extension: filepath.Ext(plate.ThumbnailPath),
+		File:      plate.Thumbnail,
+		Format: &excelize.GraphicOptions{
+			ScaleX:          scale,
+			ScaleY:          scale,
+			LockAspectRatio: true,
+			AltText:         fmt.Sprintf(lang.T("plate.alt"), plate.PlateID),
+		},
+	})
+	if err != nil {
+		return 0
+	}
+	
+	return int(math.Ceil(float64(thumbnailHeightPx) / defaultRowHeightPx))
+}
+
+// optionalRounded returns value rounded to 2 decimals, or empty string if value is not known
+func optionalRounded(value float64) interface{} {
+	if value <= 0 {
+		return ""
+	}
+	return roundMoney(value)
+}
+
+// createBOMSection creates the bill of materials: every distinct part (name and material)
+// with its total quantity across all plates and repetitions, see collectRepeatedObjectStats
+func createBOMSection(f *excelize.File, sheetName string, data *parser.Parser3MF, startRow int, lang Lang, colors ExcelColors) int {
 	row := startRow
 	
-	// Collect unique materials
-	materialsSet := make(map[string]bool)
-	for _, plate := range data.Plates {
-		for _, obj := range plate.Objects {
-			if obj.Material != "" {
-				cleanMaterial := cleanMaterialName(obj.Material)
-				materialsSet[cleanMaterial] = true
-			}
-		}
+	stats := collectRepeatedObjectStats(data)
+	if len(stats) == 0 {
+		return row
+	}
+	
+	// Section title
+	titleStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+			Size: 14,
+		},
+	})
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("bom.title"))
+	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "A"+strconv.Itoa(row), titleStyle)
+	row++
+	
+	// Table header
+	headerStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Color: colors.HeaderText,
+			Bold:  true,
+		},
+		Fill: excelize.Fill{
+			Type:    "pattern",
+			Color:   []string{colors.HeaderBg},
+			Pattern: 1,
+		},
+		Border: []excelize.Border{
+			{Type: "left", Color: colors.BorderColor, Style: 1},
+			{Type: "top", Color: colors.BorderColor, Style: 1},
+			{Type: "bottom", Color: colors.BorderColor, Style: 1},
+			{Type: "right", Color: colors.BorderColor, Style: 1},
+		},
+	})
+	
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("parts.name"))
+	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), lang.T("plate.material"))
+	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), lang.T("bom.total_quantity"))
+	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), lang.T("bom.plates"))
+	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "D"+strconv.Itoa(row), headerStyle)
+	row++
+	
+	dataStyle, _ := f.NewStyle(&excelize.Style{
+		Border: []excelize.Border{
+			{Type: "left", Color: colors.BorderColor, Style: 1},
+			{Type: "top", Color: colors.BorderColor, Style: 1},
+			{Type: "bottom", Color: colors.BorderColor, Style: 1},
+			{Type: "right", Color: colors.BorderColor, Style: 1},
+		},
+	})
+	
+	for _, stat := range stats {
+		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), stat.Name)
+		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), stat.Material)
+		f.SetCellValue(sheetName, "C"+strconv.Itoa(row), stat.Count)
+		f.SetCellValue(sheetName, "D"+strconv.Itoa(row), formatPlateList(stat.Plates))
+		f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "D"+strconv.Itoa(row), dataStyle)
+		row++
 	}
 	
-	if len(materialsSet) == 0 {
+	return row
+}
+
+// createMaterialsSection creates the materials summary section
+// Weight and cost are filled when slicing data and material price are available
+func createMaterialsSection(f *excelize.File, sheetName string, data *parser.Parser3MF, materialPrices map[string]float64, startRow int, lang Lang, colors ExcelColors) int {
+	row := startRow
+	
+	materials, totalCost := computeMaterialCosts(data, materialPrices)
+	if len(materials) == 0 {
 		return row
 	}
 	
@@ -360,10 +648,10 @@ func createMaterialsSection(f *excelize.File, sheetName string, data *parser.Par
 		},
 	})
 	
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Название")
-	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), "Вес")
-	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), "Стоимость за кг")
-	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), "Стоимость")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("materials.name"))
+	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), lang.T("materials.weight"))
+	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), lang.T("materials.price_per_kg"))
+	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), lang.T("materials.cost"))
 	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "D"+strconv.Itoa(row), headerStyle)
 	row++
 	
@@ -377,20 +665,155 @@ func createMaterialsSection(f *excelize.File, sheetName string, data *parser.Par
 		},
 	})
 	
-	for material := range materialsSet {
-		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), material)
+	for _, material := range materials {
+		f.SetCellValue(sheetName, "A"+strconv.Itoa(row), material.Name)
 		f.SetCellValue(sheetName, "B"+strconv.Itoa(row), "")
 		f.SetCellValue(sheetName, "C"+strconv.Itoa(row), "")
 		f.SetCellValue(sheetName, "D"+strconv.Itoa(row), "")
+		if material.HasWeight {
+			f.SetCellValue(sheetName, "B"+strconv.Itoa(row), roundMoney(material.WeightGrams))
+		}
+		if material.HasPrice {
+			f.SetCellValue(sheetName, "C"+strconv.Itoa(row), roundMoney(material.PricePerKg))
+		}
+		if material.HasCost {
+			f.SetCellValue(sheetName, "D"+strconv.Itoa(row), roundMoney(material.Cost))
+		}
 		f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "D"+strconv.Itoa(row), dataStyle)
 		row++
 	}
 	
+	// Grand total row
+	totalStyle, _ := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{
+			Bold: true,
+		},
+		Border: []excelize.Border{
+			{Type: "left", Color: colors.BorderColor, Style: 1},
+			{Type: "top", Color: colors.BorderColor, Style: 1},
+			{Type: "bottom", Color: colors.BorderColor, Style: 1},
+			{Type: "right", Color: colors.BorderColor, Style: 1},
+		},
+	})
+	
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("materials.total"))
+	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), optionalRounded(totalCost))
+	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "D"+strconv.Itoa(row), totalStyle)
+	row++
+	
 	return row
 }
 
+// computeMaterialCosts collects materials used in the project sorted by name,
+// computes cost = weight/1000 * price per kg and returns the grand total
+// Weights include plate repetitions
+func computeMaterialCosts(data *parser.Parser3MF, materialPrices map[string]float64) ([]materialCostRow, float64) {
+	weights := collectRepeatedMaterialWeights(data)
+	materialsSet := make(map[string]bool)
+	for material := range weights {
+		materialsSet[material] = true
+	}
+	for _, plate := range data.Plates {
+		for _, obj := range plate.Objects {
+			for _, material := range obj.MaterialNames() {
+				materialsSet[parser.CleanMaterialName(material)] = true
+			}
+		}
+	}
+	
+	names := make([]string, 0, len(materialsSet))
+	for name := range materialsSet {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	
+	rows := make([]materialCostRow, 0, len(names))
+	totalCost := 0.0
+	for _, name := range names {
+		row := materialCostRow{Name: name}
+		
+		if weight, ok := weights[name]; ok {
+			row.WeightGrams = weight
+			row.HasWeight = true
+		}
+		
+		if price, ok := lookupMaterialPrice(materialPrices, name); ok {
+			row.PricePerKg = price
+			row.HasPrice = true
+		}
+		
+		if row.HasWeight && row.HasPrice {
+			row.Cost = row.WeightGrams / 1000 * row.PricePerKg
+			row.HasCost = true
+			totalCost += row.Cost
+		}
+		
+		rows = append(rows, row)
+	}
+	
+	return rows, totalCost
+}
+
+// collectRepeatedMaterialWeights sums material usage of all plates like collectMaterialWeights,
+// counting every plate as many times as it is printed
+func collectRepeatedMaterialWeights(data *parser.Parser3MF) map[string]float64 {
+	weights := make(map[string]float64)
+	for _, plate := range data.Plates {
+		for material, weight := range plate.MaterialWeights {
+			if weight > 0 {
+				weights[parser.CleanMaterialName(material)] += weight * float64(plate.RepeatCount())
+			}
+		}
+	}
+	return weights
+}
+
+// lookupMaterialPrice finds price per kg for material, ignoring case and trailing "(...)" groups
+func lookupMaterialPrice(materialPrices map[string]float64, material string) (float64, bool) {
+	return lookupByMaterial(materialPrices, material)
+}
+
+// lookupByMaterial finds the value configured for material, ignoring case and trailing "(...)" groups
+func lookupByMaterial[V any](values map[string]V, material string) (V, bool) {
+	if value, ok := values[material]; ok {
+		return value, true
+	}
+	
+	normalized := strings.ToLower(parser.CleanMaterialName(material))
+	for name, value := range values {
+		if strings.ToLower(parser.CleanMaterialName(name)) == normalized {
+			return value, true
+		}
+	}
+	
+	var zero V
+	return zero, false
+}
+
+// platePrinter returns the printers assigned to the plate materials, joined by ", ".
+// Unmapped materials can be printed anywhere, so "" means the plate can go to any printer
+func platePrinter(plate parser.PlateInfo, printerAssignments map[string]string) string {
+	var printers []string
+	seen := make(map[string]bool)
+	for _, material := range collectMaterials(&parser.Parser3MF{Plates: []parser.PlateInfo{plate}}) {
+		printer, ok := lookupByMaterial(printerAssignments, material)
+		printer = strings.TrimSpace(printer)
+		if !ok || printer == "" || seen[printer] {
+			continue
+		}
+		seen[printer] = true
+		printers = append(printers, printer)
+	}
+	return strings.Join(printers, ", ")
+}
+
+// roundMoney rounds value to 2 decimal places
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
 // createHoursSection creates the hours summary section
-func createHoursSection(f *excelize.File, sheetName string, startRow int, colors ExcelColors) int {
+func createHoursSection(f *excelize.File, sheetName string, startRow int, lang Lang, colors ExcelColors) int {
 	row := startRow
 	
 	// Hours table header
@@ -412,10 +835,10 @@ func createHoursSection(f *excelize.File, sheetName string, startRow int, colors
 		},
 	})
 	
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Тип работ")
-	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), "Часы")
-	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), "Ставка")
-	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), "Стоимость")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("hours.work_type"))
+	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), lang.T("hours.hours"))
+	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), lang.T("hours.rate"))
+	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), lang.T("materials.cost"))
 	f.SetCellStyle(sheetName, "A"+strconv.Itoa(row), "D"+strconv.Itoa(row), headerStyle)
 	row++
 	
@@ -430,7 +853,7 @@ func createHoursSection(f *excelize.File, sheetName string, startRow int, colors
 	})
 	
 	// Machine hours row
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Машино-часы")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("hours.machine"))
 	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), "")
 	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), "")
 	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), "")
@@ -438,7 +861,7 @@ func createHoursSection(f *excelize.File, sheetName string, startRow int, colors
 	row++
 	
 	// Operator hours row
-	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), "Работа оператора")
+	f.SetCellValue(sheetName, "A"+strconv.Itoa(row), lang.T("hours.operator"))
 	f.SetCellValue(sheetName, "B"+strconv.Itoa(row), "")
 	f.SetCellValue(sheetName, "C"+strconv.Itoa(row), "")
 	f.SetCellValue(sheetName, "D"+strconv.Itoa(row), "")
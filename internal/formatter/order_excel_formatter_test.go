@@ -0,0 +1,443 @@
+package formatter
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"farmix-cli/internal/bitrix"
+	"farmix-cli/internal/parser"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestLookupMaterialPrice(t *testing.T) {
+	prices := map[string]float64{
+		"bambu pla basic": 1800,
+		"PETG HF (Black)": 2100,
+	}
+
+	tests := []struct {
+		name      string
+		material  string
+		wantPrice float64
+		wantFound bool
+	}{
+		{"exact match", "bambu pla basic", 1800, true},
+		{"case-insensitive match", "Bambu PLA Basic", 1800, true},
+		{"config key with suffix", "PETG HF", 2100, true},
+		{"unknown material", "ABS", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			price, found := lookupMaterialPrice(prices, tt.material)
+			if found != tt.wantFound || price != tt.wantPrice {
+				t.Errorf("lookupMaterialPrice(%q) = (%v, %v), want (%v, %v)", tt.material, price, found, tt.wantPrice, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestComputeMaterialCosts(t *testing.T) {
+	data := &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{
+				PlateID: 1,
+				Objects: []parser.PlateObject{
+					{Name: "Bracket", Material: "Bambu PLA Basic (Black)"},
+					{Name: "Cover", Material: "Bambu PETG HF"},
+				},
+				MaterialWeights: map[string]float64{
+					"Bambu PLA Basic (Black)": 150,
+					"Bambu PETG HF":           40,
+				},
+			},
+			{
+				PlateID: 2,
+				Objects: []parser.PlateObject{
+					{Name: "Bracket", Material: "Bambu PLA Basic (Black)"},
+					{Name: "Gasket", Material: "TPU 95A"},
+				},
+				MaterialWeights: map[string]float64{
+					"Bambu PLA Basic (Black)": 100,
+					"TPU 95A":                 20,
+				},
+			},
+			{
+				PlateID: 3,
+				Objects: []parser.PlateObject{
+					{Name: "Spacer", Material: "ASA"},
+				},
+			},
+		},
+	}
+	prices := map[string]float64{
+		"bambu pla basic": 1800,
+		"bambu petg hf":   2100,
+		"asa":             2500,
+	}
+
+	rows, total := computeMaterialCosts(data, prices)
+
+	expected := []materialCostRow{
+		{Name: "ASA", PricePerKg: 2500, HasPrice: true},
+		{Name: "Bambu PETG HF", WeightGrams: 40, HasWeight: true, PricePerKg: 2100, HasPrice: true, Cost: 84, HasCost: true},
+		{Name: "Bambu PLA Basic", WeightGrams: 250, HasWeight: true, PricePerKg: 1800, HasPrice: true, Cost: 450, HasCost: true},
+		{Name: "TPU 95A", WeightGrams: 20, HasWeight: true},
+	}
+
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %d: %+v", len(expected), len(rows), rows)
+	}
+	for i, want := range expected {
+		got := rows[i]
+		if got.Name != want.Name || got.HasWeight != want.HasWeight || got.HasPrice != want.HasPrice || got.HasCost != want.HasCost {
+			t.Errorf("row %d = %+v, want %+v", i, got, want)
+			continue
+		}
+		if math.Abs(got.WeightGrams-want.WeightGrams) > 1e-9 || math.Abs(got.PricePerKg-want.PricePerKg) > 1e-9 || math.Abs(got.Cost-want.Cost) > 1e-9 {
+			t.Errorf("row %d = %+v, want %+v", i, got, want)
+		}
+	}
+
+	if math.Abs(total-534) > 1e-9 {
+		t.Errorf("expected grand total 534, got %v", total)
+	}
+}
+
+func TestComputeMaterialCostsWithoutPrices(t *testing.T) {
+	data := &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{
+				PlateID:         1,
+				Objects:         []parser.PlateObject{{Name: "Part", Material: "PLA"}},
+				MaterialWeights: map[string]float64{"PLA": 10},
+			},
+		},
+	}
+
+	rows, total := computeMaterialCosts(data, nil)
+	if len(rows) != 1 {
+		t.Fatalf("expected 1 row, got %d", len(rows))
+	}
+	if rows[0].HasPrice || rows[0].HasCost {
+		t.Errorf("expected price and cost to be blank without price list, got %+v", rows[0])
+	}
+	if total != 0 {
+		t.Errorf("expected zero total, got %v", total)
+	}
+}
+
+func TestAggregateByMaterial(t *testing.T) {
+	data := &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{
+				PlateID: 1,
+				Objects: []parser.PlateObject{
+					{ID: 1, Name: "Bracket", Type: "model", Material: "PLA (Black)"},
+					{ID: 2, Name: "Bracket", Type: "model", Material: "PLA (Black)"},
+					{ID: 3, Name: "Cover", Type: "model", Material: "PETG"},
+				},
+			},
+			{
+				PlateID: 2,
+				Objects: []parser.PlateObject{
+					{ID: 4, Name: "Bracket", Type: "model", Material: "PLA"},
+					{ID: 5, Name: "Clip", Type: "model", Material: "PLA"},
+					{ID: 6, Name: "Clip", Type: "model", Material: "PLA"},
+					{ID: 7, Name: "Clip", Type: "model", Material: "PLA"},
+					{ID: 8, Name: "Cover", Type: "model", Material: "PETG (spool.3mf)"},
+				},
+			},
+		},
+	}
+
+	rows := aggregateByMaterial(data)
+
+	expected := []materialAssignmentRow{
+		{Material: "PETG", DistinctParts: 1, Quantity: 2},
+		{Material: "PLA", DistinctParts: 2, Quantity: 6},
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("expected %d rows, got %d: %+v", len(expected), len(rows), rows)
+	}
+	for i, want := range expected {
+		if rows[i] != want {
+			t.Errorf("row %d = %+v, want %+v", i, rows[i], want)
+		}
+	}
+}
+
+func TestPlateSectionListsAllMaterials(t *testing.T) {
+	plate := parser.PlateInfo{
+		PlateID: 1,
+		Objects: []parser.PlateObject{
+			{ID: 1, Name: "Body", Type: "model", Material: "PLA (Black)"},
+			{ID: 2, Name: "Gasket", Type: "model", Material: "TPU 95A"},
+			{ID: 3, Name: "Body", Type: "model", Material: "PLA"},
+		},
+	}
+
+	if got, want := plateMaterialsLabel(plate), "PLA, TPU 95A"; got != want {
+		t.Errorf("plateMaterialsLabel() = %q, want %q", got, want)
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	createPlateSection(f, "Sheet1", plate, 1, LangRU, DefaultExcelColors())
+
+	if got, _ := f.GetCellValue("Sheet1", "F1"); got != "PLA, TPU 95A" {
+		t.Errorf("plate header material = %q, want both materials", got)
+	}
+}
+
+func TestFormatAsOrderExcelHyperlinks(t *testing.T) {
+	deal := &bitrix.Deal{ID: "42", Title: "Brackets"}
+	user := &bitrix.User{ID: "7", FullName: "Ivan Petrov"}
+	client := bitrix.NewClient("https://example.bitrix24.ru/rest/1/token/")
+
+	outputPath := filepath.Join(t.TempDir(), "order.xlsx")
+	if err := FormatAsOrderExcel(twoPlateData(), deal, user, "ACME", client, outputPath, OrderReportOptions{Lang: LangEN}); err != nil {
+		t.Fatalf("FormatAsOrderExcel() error = %v", err)
+	}
+
+	f, err := excelize.OpenFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to open generated file: %v", err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		cell        string
+		wantDisplay string
+		wantLink    string
+	}{
+		{"B3", "Ivan Petrov", "https://example.bitrix24.ru/company/personal/user/7/"},
+		{"B5", "42", "https://example.bitrix24.ru/crm/deal/details/42/"},
+		{"B6", "Open in Bitrix24", "https://example.bitrix24.ru/crm/deal/details/42/"},
+	}
+	for _, tt := range tests {
+		ok, link, err := f.GetCellHyperLink("Work order", tt.cell)
+		if err != nil || !ok || link != tt.wantLink {
+			t.Errorf("cell %s hyperlink = %v, %q, %v; want %q", tt.cell, ok, link, err, tt.wantLink)
+		}
+		if got, _ := f.GetCellValue("Work order", tt.cell); got != tt.wantDisplay {
+			t.Errorf("cell %s = %q, want %q", tt.cell, got, tt.wantDisplay)
+		}
+	}
+
+	// Customer is plain text
+	if ok, _, _ := f.GetCellHyperLink("Work order", "B4"); ok {
+		t.Error("customer cell B4 must not be a hyperlink")
+	}
+}
+
+func TestOrderReportsProjectHeader(t *testing.T) {
+	deal := &bitrix.Deal{ID: "42", Title: "Brackets"}
+	user := &bitrix.User{ID: "7", FullName: "Ivan Petrov"}
+	client := bitrix.NewClient("https://example.bitrix24.ru/rest/1/token/")
+	options := OrderReportOptions{Lang: LangEN, Project: "Drone frame"}
+
+	dir := t.TempDir()
+	orderPath := filepath.Join(dir, "order.xlsx")
+	assignmentPath := filepath.Join(dir, "assignment.xlsx")
+	if err := FormatAsOrderExcel(twoPlateData(), deal, user, "ACME", client, orderPath, options); err != nil {
+		t.Fatalf("FormatAsOrderExcel() error = %v", err)
+	}
+	if err := FormatAsAssignmentExcel(twoPlateData(), deal, user, "ACME", client, assignmentPath, options); err != nil {
+		t.Fatalf("FormatAsAssignmentExcel() error = %v", err)
+	}
+
+	tests := []struct {
+		path, sheet string
+		cells       map[string]string
+	}{
+		// Project follows the customer, the deal link moves one row down
+		{orderPath, "Work order", map[string]string{"A5": "Project:", "B5": "Drone frame", "B6": "42"}},
+		{assignmentPath, "Shift assignment", map[string]string{"A5": "Project:", "B5": "Drone frame", "A6": "Date:"}},
+	}
+	for _, tt := range tests {
+		f, err := excelize.OpenFile(tt.path)
+		if err != nil {
+			t.Fatalf("failed to open generated file: %v", err)
+		}
+		for cell, want := range tt.cells {
+			if got, _ := f.GetCellValue(tt.sheet, cell); got != want {
+				t.Errorf("%s cell %s = %q, want %q", tt.sheet, cell, got, want)
+			}
+		}
+		f.Close()
+	}
+}
+
+func TestPlatePrinter(t *testing.T) {
+	// viper lower-cases config keys
+	assignments := map[string]string{
+		"bambu asa-gf": "X1 Carbon",
+		"petg hf":      "P1S",
+	}
+
+	tests := []struct {
+		name      string
+		materials []string
+		want      string
+	}{
+		{"mapped material", []string{"Bambu ASA-GF (Black)"}, "X1 Carbon"},
+		{"unmapped material goes anywhere", []string{"PLA"}, ""},
+		{"unmapped materials do not restrict a mapped one", []string{"PLA", "Bambu ASA-GF"}, "X1 Carbon"},
+		{"several mapped printers", []string{"Bambu ASA-GF", "PETG HF (Grey)"}, "X1 Carbon, P1S"},
+		{"no materials", nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plate := parser.PlateInfo{PlateID: 1}
+			for i, material := range tt.materials {
+				plate.Objects = append(plate.Objects, parser.PlateObject{ID: i + 1, Name: "Part", Type: "model", Material: material})
+			}
+			if got := platePrinter(plate, assignments); got != tt.want {
+				t.Errorf("platePrinter(%v) = %q, want %q", tt.materials, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssignmentPrinterColumn(t *testing.T) {
+	data := &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{PlateID: 1, Objects: []parser.PlateObject{{ID: 1, Name: "Duct", Type: "model", Material: "Bambu ASA-GF"}}},
+			{PlateID: 2, Objects: []parser.PlateObject{{ID: 2, Name: "Cover", Type: "model", Material: "PLA"}}},
+		},
+	}
+	deal := &bitrix.Deal{ID: "42", Title: "Ducts"}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if err := createAssignmentContent(f, "Sheet1", data, deal, &bitrix.User{}, "ACME", OrderReportOptions{PrinterAssignments: map[string]string{"bambu asa-gf": "X1 Carbon"}}, LangRU, DefaultExcelColors()); err != nil {
+		t.Fatalf("createAssignmentContent() error = %v", err)
+	}
+
+	// Plate headers: row 7 (plate 1 with one part), row 11 (plate 2)
+	for cell, want := range map[string]string{"E7": "Принтер", "F7": "X1 Carbon", "E11": "Принтер", "F11": "любой"} {
+		if got, _ := f.GetCellValue("Sheet1", cell); got != want {
+			t.Errorf("cell %s = %q, want %q", cell, got, want)
+		}
+	}
+}
+
+func TestPlateRepetitionsMultiplyTotals(t *testing.T) {
+	data := &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{
+				PlateID:     1,
+				Repetitions: 3,
+				Objects: []parser.PlateObject{
+					{ID: 1, Name: "Bracket", Type: "model", Material: "PLA"},
+					{ID: 2, Name: "Bracket", Type: "model", Material: "PLA"},
+					{ID: 3, Name: "Cover", Type: "model", Material: "PETG"},
+				},
+				WeightGrams:     30,
+				MaterialWeights: map[string]float64{"PLA": 20, "PETG": 10},
+			},
+			{
+				PlateID:         2,
+				Objects:         []parser.PlateObject{{ID: 4, Name: "Clip", Type: "model", Material: "PLA"}},
+				MaterialWeights: map[string]float64{"PLA": 5},
+			},
+		},
+	}
+
+	expectedRows := []materialAssignmentRow{
+		{Material: "PETG", DistinctParts: 1, Quantity: 3},
+		{Material: "PLA", DistinctParts: 2, Quantity: 7},
+	}
+	rows := aggregateByMaterial(data)
+	if len(rows) != len(expectedRows) || rows[0] != expectedRows[0] || rows[1] != expectedRows[1] {
+		t.Errorf("aggregateByMaterial() = %+v, want %+v", rows, expectedRows)
+	}
+
+	materials, _ := computeMaterialCosts(data, nil)
+	weights := make(map[string]float64)
+	for _, material := range materials {
+		weights[material.Name] = material.WeightGrams
+	}
+	if weights["PLA"] != 65 || weights["PETG"] != 30 {
+		t.Errorf("material weights = %v, want PLA 65, PETG 30", weights)
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	createPlateSection(f, "Sheet1", data.Plates[0], 1, LangRU, DefaultExcelColors())
+
+	// Header repetitions, plate weight and count header
+	for cell, want := range map[string]string{"D1": "3", "B2": "90", "D3": "Количество (все повторения)"} {
+		if got, _ := f.GetCellValue("Sheet1", cell); got != want {
+			t.Errorf("cell %s = %q, want %q", cell, got, want)
+		}
+	}
+
+	// Per-part counts (parts are not sorted within a plate)
+	counts := make(map[string]string)
+	for _, row := range []string{"4", "5"} {
+		name, _ := f.GetCellValue("Sheet1", "A"+row)
+		counts[name], _ = f.GetCellValue("Sheet1", "D"+row)
+	}
+	if counts["Bracket"] != "6" || counts["Cover"] != "3" {
+		t.Errorf("part counts = %v, want Bracket 6, Cover 3", counts)
+	}
+}
+
+func TestBOMSectionSumsPartsAcrossPlates(t *testing.T) {
+	data := &parser.Parser3MF{
+		Plates: []parser.PlateInfo{
+			{
+				PlateID:     1,
+				Repetitions: 3,
+				Objects: []parser.PlateObject{
+					{ID: 1, Name: "Bracket", Type: "model", Material: "PLA (Black)"},
+					{ID: 2, Name: "Bracket", Type: "model", Material: "PLA (Black)"},
+					{ID: 3, Name: "Cover", Type: "model", Material: "PETG"},
+				},
+			},
+			{
+				PlateID: 2,
+				Objects: []parser.PlateObject{
+					{ID: 4, Name: "Bracket", Type: "model", Material: "PLA"},
+					{ID: 5, Name: "Cover", Type: "model", Material: "PETG"},
+				},
+			},
+		},
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+	if end := createBOMSection(f, "Sheet1", data, 1, LangRU, DefaultExcelColors()); end != 5 {
+		t.Errorf("createBOMSection() returned row %d, want 5", end)
+	}
+
+	expected := [][]string{
+		{"Сводная спецификация"},
+		{"Название детали", "Материал", "Всего", "Столы"},
+		{"Bracket", "PLA", "7", "1, 2"},
+		{"Cover", "PETG", "4", "1, 2"},
+	}
+	rows, err := f.GetRows("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(expected) {
+		t.Fatalf("BOM rows = %q, want %q", rows, expected)
+	}
+	for i, want := range expected {
+		if strings.Join(rows[i], "|") != strings.Join(want, "|") {
+			t.Errorf("row %d = %q, want %q", i+1, rows[i], want)
+		}
+	}
+
+	// The list report keeps per-file counts
+	if stats := collectObjectStats(data); stats[0].Count != 3 {
+		t.Errorf("collectObjectStats() Bracket count = %d, want 3 without repetitions", stats[0].Count)
+	}
+}
@@ -1,13 +1,16 @@
 package formatter
 
 import (
+	"bytes"
 	"fmt"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 
+	"farmix-cli/assets"
 	"farmix-cli/internal/parser"
 
 	"github.com/go-pdf/fpdf"
@@ -18,30 +21,41 @@ type PDFFormatter struct {
 	pdf      *fpdf.Fpdf
 	template PDFTemplate
 	widths   TableColumnWidths
+	lang     Lang
 }
 
-// NewPDFFormatter создает новый генератор PDF с настройками по умолчанию
-func NewPDFFormatter() *PDFFormatter {
-	template := DefaultPDFTemplate()
+// NewPDFFormatter создает новый генератор PDF с заданным форматом страницы
+// Ширины колонок таблиц пересчитываются под ширину области печати
+func NewPDFFormatter(options PDFOptions) (*PDFFormatter, error) {
+	template, err := NewPDFTemplate(options)
+	if err != nil {
+		return nil, err
+	}
 	pdf := fpdf.New(template.Orientation, "mm", template.PageSize, "")
 	
 	return &PDFFormatter{
 		pdf:      pdf,
 		template: template,
-		widths:   DefaultTableWidths(),
-	}
+		widths:   TableWidthsForTemplate(template),
+		lang:     options.Lang.orDefault(LangEN),
+	}, nil
 }
 
 // FormatAsPDF создает PDF отчет из данных 3MF файла
-func FormatAsPDF(data *parser.Parser3MF, outputPath string) error {
-	formatter := NewPDFFormatter()
+func FormatAsPDF(data *parser.Parser3MF, outputPath string, options PDFOptions) error {
+	formatter, err := NewPDFFormatter(options)
+	if err != nil {
+		return err
+	}
 	return formatter.Generate(data, outputPath)
 }
 
 // Generate создает PDF документ и сохраняет его в файл
 func (f *PDFFormatter) Generate(data *parser.Parser3MF, outputPath string) error {
 	// Настройка PDF
-	f.setupPDF()
+	if err := f.setupPDF(); err != nil {
+		return err
+	}
 	
 	// Добавляем первую страницу
 	f.pdf.AddPage()
@@ -52,7 +66,7 @@ func (f *PDFFormatter) Generate(data *parser.Parser3MF, outputPath string) error
 	
 	// Анализ по печатным столам
 	if len(data.Plates) == 0 {
-		f.addText("No plates found in the file.", f.template.FontSize)
+		f.addText(f.lang.T("analysis.no_plates"), f.template.FontSize)
 	} else {
 		for i, plate := range data.Plates {
 			if i > 0 {
@@ -69,11 +83,31 @@ func (f *PDFFormatter) Generate(data *parser.Parser3MF, outputPath string) error
 	return f.pdf.OutputFileAndClose(outputPath)
 }
 
+// pdfFonts - встроенные шрифты: стиль -> файл в assets.Fonts
+var pdfFonts = []struct {
+	style string
+	file  string
+}{
+	{"", "fonts/DejaVuSans.ttf"},
+	{"B", "fonts/DejaVuSans-Bold.ttf"},
+}
+
 // setupPDF настраивает основные параметры PDF документа
-func (f *PDFFormatter) setupPDF() {
-	// Добавляем Unicode шрифты DejaVu Sans
-	f.pdf.AddUTF8Font("DejaVuSans", "", "assets/fonts/DejaVuSans.ttf")
-	f.pdf.AddUTF8Font("DejaVuSans", "B", "assets/fonts/DejaVuSans-Bold.ttf")
+func (f *PDFFormatter) setupPDF() error {
+	// Добавляем Unicode шрифты DejaVu Sans из встроенных ресурсов
+	for _, font := range pdfFonts {
+		fontBytes, err := assets.Fonts.ReadFile(font.file)
+		if err != nil {
+			return fmt.Errorf("ошибка чтения шрифта %s: %w", font.file, err)
+		}
+		f.pdf.AddUTF8FontFromBytes(f.template.FontFamily, font.style, fontBytes)
+		if err := f.pdf.Error(); err != nil {
+			return fmt.Errorf("ошибка регистрации шрифта %s: %w", font.file, err)
+		}
+	}
+	
+	// Поля страницы - таблицы рассчитаны на ширину между ними
+	f.pdf.SetMargins(f.template.MarginX, f.template.MarginY, f.template.MarginX)
 	
 	// Установка автоматических разрывов страниц
 	f.pdf.SetAutoPageBreak(true, f.template.MarginY)
@@ -83,6 +117,18 @@ func (f *PDFFormatter) setupPDF() {
 	
 	// Установка цвета текста по умолчанию
 	f.setTextColor(f.template.Colors.Text)
+	
+	return nil
+}
+
+// tableHeaders возвращает заголовки таблицы объектов на языке отчета
+func (f *PDFFormatter) tableHeaders() []string {
+	return []string{
+		f.lang.T("analysis.object_name"),
+		f.lang.T("analysis.count"),
+		f.lang.T("analysis.type"),
+		f.lang.T("analysis.material"),
+	}
 }
 
 // addDocumentHeader добавляет заголовок документа
@@ -90,7 +136,7 @@ func (f *PDFFormatter) addDocumentHeader(filename string) {
 	// Заголовок
 	f.setTextColor(f.template.Colors.Title)
 	f.pdf.SetFont(f.template.FontFamily, "B", f.template.TitleFontSize)
-	f.pdf.CellFormat(0, f.template.HeaderHeight*0.6, "3MF File Analysis", "", 1, "C", false, 0, "")
+	f.pdf.CellFormat(0, f.template.HeaderHeight*0.6, f.lang.T("analysis.title"), "", 1, "C", false, 0, "")
 	
 	// Имя файла
 	f.pdf.SetFont(f.template.FontFamily, "", f.template.HeaderFontSize)
@@ -99,7 +145,7 @@ func (f *PDFFormatter) addDocumentHeader(filename string) {
 	// Дата создания отчета
 	f.setTextColor(f.template.Colors.Text)
 	f.pdf.SetFont(f.template.FontFamily, "", f.template.FontSize)
-	dateStr := fmt.Sprintf("Generated: %s", time.Now().Format("2006-01-02 15:04:05"))
+	dateStr := fmt.Sprintf(f.lang.T("analysis.generated"), time.Now().Format("2006-01-02 15:04:05"))
 	f.pdf.CellFormat(0, f.template.TableRowHeight, dateStr, "", 1, "C", false, 0, "")
 	
 	f.addVerticalSpace(f.template.SectionSpacing)
@@ -108,20 +154,23 @@ func (f *PDFFormatter) addDocumentHeader(filename string) {
 // addPlateSection добавляет секцию с информацией о печатном столе
 func (f *PDFFormatter) addPlateSection(plate parser.PlateInfo) {
 	// Заголовок секции
-	f.addSectionHeader(fmt.Sprintf("Plate %d: %s", plate.PlateID, plate.PlateName))
+	f.addSectionHeader(fmt.Sprintf(f.lang.T("analysis.plate"), plate.PlateID, plate.PlateName))
+	
+	// Превью стола, если оно есть в архиве
+	f.addPlateThumbnail(plate)
 	
 	if len(plate.Objects) == 0 {
-		f.addText("No objects on this plate.", f.template.FontSize)
+		f.addText(f.lang.T("analysis.no_objects"), f.template.FontSize)
 		return
 	}
 	
 	// Подготовка данных таблицы
 	groups := parser.GroupObjectsByName(plate.Objects)
-	headers := []string{"Object Name", "Count", "Type", "Material"}
+	headers := f.tableHeaders()
 	var rows [][]string
 	
 	for _, group := range groups {
-		cleanMaterial := cleanMaterialName(group.Material)
+		cleanMaterial := group.MaterialLabel()
 		objectType := group.Type
 		if objectType == "assembly" {
 			objectType = "assembly"
@@ -153,6 +202,27 @@ func (f *PDFFormatter) addPlateSection(plate parser.PlateInfo) {
 	f.addTable(headers, rows)
 }
 
+// addPlateThumbnail добавляет превью стола по центру страницы, пропуская отсутствующие или поврежденные изображения
+func (f *PDFFormatter) addPlateThumbnail(plate parser.PlateInfo) {
+	if len(plate.Thumbnail) == 0 {
+		return
+	}
+	
+	imageName := fmt.Sprintf("plate_thumbnail_%d", plate.PlateID)
+	options := fpdf.ImageOptions{ImageType: "PNG", ReadDpi: false}
+	f.pdf.RegisterImageOptionsReader(imageName, options, bytes.NewReader(plate.Thumbnail))
+	if !f.pdf.Ok() {
+		// Некорректное изображение не должно ломать весь отчет
+		f.pdf.ClearError()
+		return
+	}
+	
+	pageWidth, _ := f.pdf.GetPageSize()
+	x := (pageWidth - f.template.ThumbnailSize) / 2
+	f.pdf.ImageOptions(imageName, x, f.pdf.GetY(), f.template.ThumbnailSize, 0, true, options, 0, "")
+	f.addVerticalSpace(f.template.SectionSpacing / 2)
+}
+
 // addTable создает и отрисовывает таблицу
 func (f *PDFFormatter) addTable(headers []string, rows [][]string) {
 	widths := []float64{f.widths.ObjectName, f.widths.Count, f.widths.Type, f.widths.Material}
@@ -190,13 +260,18 @@ func (f *PDFFormatter) addTableRowWithWrapping(row []string, widths []float64, r
 	_, _, _, bottomMargin := f.pdf.GetMargins()
 	_, y := f.pdf.GetXY()
 	
-	// Вычисляем необходимую высоту строки для первой колонки (название объекта)
-	rowHeight := f.calculateRowHeight(row[0], widths[0])
+	// Высота строки определяется самой высокой ячейкой (обычно название объекта)
+	rowHeight := f.template.TableRowHeight
+	for j, cell := range row {
+		if cellHeight := f.calculateRowHeight(f.cellText(j, cell), widths[j]); cellHeight > rowHeight {
+			rowHeight = cellHeight
+		}
+	}
 	
 	if y+rowHeight > pageH-bottomMargin {
 		f.pdf.AddPage()
 		// Повторяем заголовок таблицы на новой странице
-		f.addTableHeader([]string{"Object Name", "Count", "Type", "Material"}, widths)
+		f.addTableHeader(f.tableHeaders(), widths)
 		
 		if rowIndex%2 == 0 {
 			f.setFillColor(f.template.Colors.TableRow1)
@@ -223,10 +298,7 @@ func (f *PDFFormatter) addUniformTableRow(row []string, widths []float64, startX
 		f.pdf.SetXY(currentX, startY)
 		
 		// Подготавливаем текст
-		text := cell
-		if j == 0 && len(cell) > 50 {
-			text = f.wrapText(cell)
-		}
+		text := f.cellText(j, cell)
 		
 		// Определяем выравнивание
 		alignment := "L"
@@ -256,50 +328,91 @@ func (f *PDFFormatter) addFixedHeightMultiCell(width, height float64, text, bord
 	f.pdf.Rect(x, y, width, height, "D")
 	
 	// Добавляем текст как MultiCell без границ и фона
-	// Используем компактную высоту строки для многострочного текста
-	lineHeight := f.template.TableRowHeight * 0.7 // Более компактная высота строки
-	f.pdf.MultiCell(width, lineHeight, text, "", align, false)
+	f.pdf.MultiCell(width, f.tableLineHeight(), text, "", align, false)
 	
 	// Возвращаемся на правильную позицию для следующей ячейки
 	f.pdf.SetXY(x+width, y)
 }
 
+// cellText возвращает текст ячейки в том виде, в котором он будет выведен
+func (f *PDFFormatter) cellText(column int, text string) string {
+	if column == 0 && utf8.RuneCountInString(text) > 50 {
+		return f.wrapText(text)
+	}
+	return text
+}
+
+// tableLineHeight возвращает компактную высоту строки текста внутри ячейки таблицы
+func (f *PDFFormatter) tableLineHeight() float64 {
+	return f.template.TableRowHeight * 0.7
+}
+
 // calculateRowHeight вычисляет необходимую высоту строки для текста
+// Первая строка занимает стандартную высоту, каждая следующая - высоту строки MultiCell
 func (f *PDFFormatter) calculateRowHeight(text string, width float64) float64 {
-	if len(text) <= 50 {
-		return f.template.TableRowHeight
-	}
-	
-	// Приблизительно вычисляем количество строк
 	lines := f.estimateLines(text, width)
-	
-	// Используем компактную высоту: базовая высота + дополнительные строки с меньшим интервалом
-	baseHeight := f.template.TableRowHeight
-	additionalHeight := float64(lines-1) * (f.template.TableRowHeight * 0.6) // 60% от стандартной высоты для дополнительных строк
-	
-	return baseHeight + additionalHeight
+	return f.template.TableRowHeight + float64(lines-1)*f.tableLineHeight()
 }
 
-// estimateLines приблизительно оценивает количество строк для переноса текста
+// estimateLines вычисляет количество строк, которые выведет MultiCell для текста в ячейке ширины width.
+// Ширина измеряется текущим шрифтом через GetStringWidth, перенос - по пробелам,
+// слова длиннее строки разбиваются по символам (как в MultiCell)
 func (f *PDFFormatter) estimateLines(text string, width float64) int {
-	// Средняя ширина символа при текущем размере шрифта (приблизительно)
-	charWidth := f.template.FontSize * 0.6
-	charsPerLine := int(width / charWidth)
-	
-	if charsPerLine <= 0 {
+	cellMargin := f.pdf.GetCellMargin()
+	maxWidth := width - 2*cellMargin
+	if maxWidth <= 0 {
 		return 1
 	}
 	
-	lines := (len(text) + charsPerLine - 1) / charsPerLine
+	lines := 0
+	for _, paragraph := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		lines += f.countWrappedLines(paragraph, maxWidth)
+	}
+	
 	if lines < 1 {
 		return 1
 	}
 	return lines
 }
 
+// countWrappedLines считает строки одного абзаца (без явных переносов) при жадном переносе по словам
+func (f *PDFFormatter) countWrappedLines(paragraph string, maxWidth float64) int {
+	lines := 1
+	currentLine := ""
+	
+	for i, word := range strings.Split(paragraph, " ") {
+		candidate := word
+		if i > 0 {
+			candidate = currentLine + " " + word
+		}
+		if f.pdf.GetStringWidth(candidate) <= maxWidth {
+			currentLine = candidate
+			continue
+		}
+		
+		// Слово не помещается: переносим его на новую строку
+		if i > 0 {
+			lines++
+		}
+		currentLine = ""
+		
+		// Слишком длинное слово разбивается по символам
+		for _, r := range word {
+			next := currentLine + string(r)
+			if currentLine != "" && f.pdf.GetStringWidth(next) > maxWidth {
+				lines++
+				next = string(r)
+			}
+			currentLine = next
+		}
+	}
+	
+	return lines
+}
+
 // wrapText добавляет переносы в длинный текст
 func (f *PDFFormatter) wrapText(text string) string {
-	if len(text) <= 50 {
+	if utf8.RuneCountInString(text) <= 50 {
 		return text
 	}
 	
@@ -316,7 +429,7 @@ func (f *PDFFormatter) wrapText(text string) string {
 					testLine += sep
 				}
 				
-				if len(testLine) <= 50 {
+				if utf8.RuneCountInString(testLine) <= 50 {
 					currentLine = testLine
 				} else {
 					if currentLine != "" {
@@ -337,15 +450,16 @@ func (f *PDFFormatter) wrapText(text string) string {
 		}
 	}
 	
-	// Принудительный перенос каждые 50 символов
+	// Принудительный перенос каждые 50 символов (по рунам, чтобы не разрезать UTF-8)
+	runes := []rune(text)
 	var result strings.Builder
-	for i := 0; i < len(text); i += 50 {
+	for i := 0; i < len(runes); i += 50 {
 		end := i + 50
-		if end > len(text) {
-			end = len(text)
+		if end > len(runes) {
+			end = len(runes)
 		}
-		result.WriteString(text[i:end])
-		if end < len(text) {
+		result.WriteString(string(runes[i:end]))
+		if end < len(runes) {
 			result.WriteString("\n")
 		}
 	}
@@ -382,9 +496,8 @@ func (f *PDFFormatter) addMaterialsSummary(data *parser.Parser3MF) {
 	materialsSet := make(map[string]bool)
 	for _, plate := range data.Plates {
 		for _, obj := range plate.Objects {
-			if obj.Material != "" {
-				cleanMaterial := cleanMaterialName(obj.Material)
-				materialsSet[cleanMaterial] = true
+			for _, material := range obj.MaterialNames() {
+				materialsSet[parser.CleanMaterialName(material)] = true
 			}
 		}
 	}
@@ -401,10 +514,11 @@ func (f *PDFFormatter) addMaterialsSummary(data *parser.Parser3MF) {
 	sort.Strings(materials)
 	
 	// Добавляем секцию материалов
-	f.addSectionHeader("Materials Used")
+	f.addSectionHeader(f.lang.T("analysis.materials"))
 	
-	// Конфигурация для списка
+	// Конфигурация для списка, колонки делят ширину области печати
 	config := DefaultMaterialsConfig()
+	config.ColumnWidth = f.template.UsableWidth() / float64(config.ColumnsCount)
 	
 	f.pdf.SetFont(f.template.FontFamily, "", f.template.FontSize)
 	f.setTextColor(f.template.Colors.Text)
@@ -454,7 +568,7 @@ func (f *PDFFormatter) addSectionHeader(title string) {
 	// Горизонтальная линия под заголовком
 	f.setBorderColor(f.template.Colors.Header)
 	x, y := f.pdf.GetXY()
-	f.pdf.Line(x, y-2, x+170, y-2)
+	f.pdf.Line(x, y-2, x+f.template.UsableWidth(), y-2)
 	
 	f.addVerticalSpace(5)
 	f.setTextColor(f.template.Colors.Text)
@@ -0,0 +1,114 @@
+package formatter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"farmix-cli/internal/parser"
+)
+
+func TestFormatAsPDFFromAnyWorkingDirectory(t *testing.T) {
+	samplePath, err := filepath.Abs(filepath.Join("..", "..", "samples", "22d.3mf"))
+	if err != nil {
+		t.Fatalf("failed to resolve sample path: %v", err)
+	}
+	data, err := parser.Parse3MF(samplePath)
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+
+	// Шрифты встроены, поэтому рабочая директория не должна влиять на результат
+	workDir := t.TempDir()
+	originalDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working directory: %v", err)
+	}
+	if err := os.Chdir(workDir); err != nil {
+		t.Fatalf("failed to change directory: %v", err)
+	}
+	defer os.Chdir(originalDir)
+
+	for _, options := range []PDFOptions{{}, {PageSize: "Letter", Orientation: "L"}} {
+		outputPath := filepath.Join(workDir, "report_"+options.Orientation+"_analysis.pdf")
+		if err := FormatAsPDF(data, outputPath, options); err != nil {
+			t.Fatalf("FormatAsPDF(%+v) error = %v", options, err)
+		}
+
+		content, err := os.ReadFile(outputPath)
+		if err != nil {
+			t.Fatalf("failed to read generated PDF: %v", err)
+		}
+		if !bytes.HasPrefix(content, []byte("%PDF-")) {
+			t.Errorf("generated file is not a PDF: %q", content[:min(len(content), 16)])
+		}
+		if !bytes.Contains(content, []byte("/FontFile2")) {
+			t.Errorf("expected embedded TrueType font in %s", outputPath)
+		}
+	}
+}
+
+func TestEstimateLinesMatchesMultiCell(t *testing.T) {
+	formatter, err := NewPDFFormatter(PDFOptions{})
+	if err != nil {
+		t.Fatalf("NewPDFFormatter() error = %v", err)
+	}
+	if err := formatter.setupPDF(); err != nil {
+		t.Fatalf("setupPDF() error = %v", err)
+	}
+
+	longCyrillic := "Кронштейн крепления двигателя левый усиленный с ребрами жесткости и монтажными отверстиями под винты М3"
+	tests := []struct {
+		name  string
+		text  string
+		width float64
+	}{
+		{"short latin", "gear", 85},
+		{"long cyrillic name", longCyrillic, 85},
+		{"long cyrillic in narrow column", longCyrillic, 40},
+		{"cyrillic word longer than column", "Сверхдлинноеназваниедеталибезпробеловкотороенепомещаетсявколонку", 30},
+		{"wrapped name with explicit breaks", formatter.wrapText(longCyrillic), 85},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			formatter.pdf.AddPage()
+			formatter.pdf.SetFont(formatter.template.FontFamily, "", formatter.template.FontSize)
+
+			expected := formatter.estimateLines(tt.text, tt.width)
+
+			lineHeight := formatter.tableLineHeight()
+			startY := formatter.pdf.GetY()
+			formatter.pdf.MultiCell(tt.width, lineHeight, tt.text, "", "L", false)
+			actual := int((formatter.pdf.GetY()-startY)/lineHeight + 0.5)
+
+			if actual != expected {
+				t.Errorf("estimateLines() = %d, MultiCell emitted %d lines", expected, actual)
+			}
+			if tt.width < 85 && expected < 2 {
+				t.Errorf("expected long text to wrap in %vmm column, got %d line(s)", tt.width, expected)
+			}
+		})
+	}
+}
+
+func TestWrapTextKeepsUTF8Intact(t *testing.T) {
+	formatter, err := NewPDFFormatter(PDFOptions{})
+	if err != nil {
+		t.Fatalf("NewPDFFormatter() error = %v", err)
+	}
+
+	text := "Сверхдлинноеназваниедеталибезпробеловкотороенепомещаетсявколонкуиещечутьчуть"
+	wrapped := formatter.wrapText(text)
+	for _, line := range strings.Split(wrapped, "\n") {
+		if !utf8.ValidString(line) {
+			t.Errorf("wrapped line is not valid UTF-8: %q", line)
+		}
+		if utf8.RuneCountInString(line) > 50 {
+			t.Errorf("wrapped line longer than 50 characters: %q", line)
+		}
+	}
+}
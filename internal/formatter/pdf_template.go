@@ -1,5 +1,10 @@
 package formatter
 
+import (
+	"fmt"
+	"strings"
+)
+
 // PDFTemplate определяет конфигурацию макета для PDF документа
 type PDFTemplate struct {
 	PageSize        string
@@ -13,6 +18,7 @@ type PDFTemplate struct {
 	HeaderHeight    float64
 	TableRowHeight  float64
 	SectionSpacing  float64
+	ThumbnailSize   float64 // Ширина превью стола, мм
 	Colors          PDFColors
 }
 
@@ -41,6 +47,7 @@ func DefaultPDFTemplate() PDFTemplate {
 		HeaderHeight:    25,
 		TableRowHeight:  8,
 		SectionSpacing:  10,
+		ThumbnailSize:   50,
 		Colors: PDFColors{
 			Header:    [3]int{52, 73, 94},   // Темно-синий
 			Title:     [3]int{44, 62, 80},   // Еще темнее синий
@@ -53,6 +60,82 @@ func DefaultPDFTemplate() PDFTemplate {
 	}
 }
 
+// PDFOptions задает формат страницы и язык PDF отчета
+type PDFOptions struct {
+	PageSize    string // A4, Letter (пусто - A4)
+	Orientation string // P (portrait), L (landscape) (пусто - P)
+	Lang        Lang   // ru, en (пусто - en)
+}
+
+// pdfPageSizes - размеры поддерживаемых форматов страницы в портретной ориентации, мм
+var pdfPageSizes = map[string][2]float64{
+	"A4":     {210, 297},
+	"Letter": {215.9, 279.4},
+}
+
+// NewPDFTemplate возвращает шаблон по умолчанию с форматом страницы из options
+func NewPDFTemplate(options PDFOptions) (PDFTemplate, error) {
+	template := DefaultPDFTemplate()
+
+	if options.PageSize != "" {
+		pageSize, err := normalizePageSize(options.PageSize)
+		if err != nil {
+			return PDFTemplate{}, err
+		}
+		template.PageSize = pageSize
+	}
+
+	if options.Orientation != "" {
+		orientation, err := normalizeOrientation(options.Orientation)
+		if err != nil {
+			return PDFTemplate{}, err
+		}
+		template.Orientation = orientation
+	}
+
+	return template, nil
+}
+
+// normalizePageSize приводит название формата к виду, принятому в fpdf (a4 -> A4, LETTER -> Letter)
+func normalizePageSize(pageSize string) (string, error) {
+	for name := range pdfPageSizes {
+		if strings.EqualFold(name, strings.TrimSpace(pageSize)) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("неподдерживаемый формат страницы: %s (поддерживаются: A4, Letter)", pageSize)
+}
+
+// normalizeOrientation приводит ориентацию к P/L (принимаются также portrait/landscape)
+func normalizeOrientation(orientation string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(orientation)) {
+	case "p", "portrait":
+		return "P", nil
+	case "l", "landscape":
+		return "L", nil
+	default:
+		return "", fmt.Errorf("неподдерживаемая ориентация: %s (поддерживаются: P, L)", orientation)
+	}
+}
+
+// PageDimensions возвращает ширину и высоту страницы шаблона с учетом ориентации, мм
+func (t PDFTemplate) PageDimensions() (float64, float64) {
+	size, exists := pdfPageSizes[t.PageSize]
+	if !exists {
+		size = pdfPageSizes["A4"]
+	}
+	if t.Orientation == "L" {
+		return size[1], size[0]
+	}
+	return size[0], size[1]
+}
+
+// UsableWidth возвращает ширину области печати между полями, мм
+func (t PDFTemplate) UsableWidth() float64 {
+	width, _ := t.PageDimensions()
+	return width - 2*t.MarginX
+}
+
 // TableColumnWidths определяет ширину колонок для различных таблиц
 type TableColumnWidths struct {
 	ObjectName float64
@@ -61,6 +144,23 @@ type TableColumnWidths struct {
 	Material   float64
 }
 
+// Total возвращает суммарную ширину колонок
+func (w TableColumnWidths) Total() float64 {
+	return w.ObjectName + w.Count + w.Type + w.Material
+}
+
+// TableWidthsForTemplate масштабирует стандартные пропорции колонок на ширину области печати шаблона
+func TableWidthsForTemplate(template PDFTemplate) TableColumnWidths {
+	base := DefaultTableWidths()
+	scale := template.UsableWidth() / base.Total()
+	return TableColumnWidths{
+		ObjectName: base.ObjectName * scale,
+		Count:      base.Count * scale,
+		Type:       base.Type * scale,
+		Material:   base.Material * scale,
+	}
+}
+
 // DefaultTableWidths возвращает стандартные ширины колонок для A4 портрет
 func DefaultTableWidths() TableColumnWidths {
 	return TableColumnWidths{
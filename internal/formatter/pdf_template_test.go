@@ -0,0 +1,57 @@
+package formatter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTableWidthsForTemplate(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       PDFOptions
+		expectedWidth float64
+	}{
+		{"default is A4 portrait", PDFOptions{}, 170},
+		{"A4 portrait", PDFOptions{PageSize: "A4", Orientation: "P"}, 170},
+		{"A4 landscape", PDFOptions{PageSize: "a4", Orientation: "L"}, 257},
+		{"Letter portrait", PDFOptions{PageSize: "Letter", Orientation: "portrait"}, 175.9},
+		{"Letter landscape", PDFOptions{PageSize: "LETTER", Orientation: "landscape"}, 239.4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := NewPDFTemplate(tt.options)
+			if err != nil {
+				t.Fatalf("NewPDFTemplate() error = %v", err)
+			}
+
+			usable := template.UsableWidth()
+			if math.Abs(usable-tt.expectedWidth) > 1e-9 {
+				t.Errorf("UsableWidth() = %v, want %v", usable, tt.expectedWidth)
+			}
+
+			widths := TableWidthsForTemplate(template)
+			if math.Abs(widths.Total()-usable) > 1e-9 {
+				t.Errorf("column widths sum to %v, want %v", widths.Total(), usable)
+			}
+
+			base := DefaultTableWidths()
+			if math.Abs(widths.ObjectName/widths.Total()-base.ObjectName/base.Total()) > 1e-9 {
+				t.Errorf("column proportions changed: %+v", widths)
+			}
+		})
+	}
+}
+
+func TestNewPDFTemplateInvalidOptions(t *testing.T) {
+	tests := []PDFOptions{
+		{PageSize: "A3"},
+		{Orientation: "X"},
+	}
+
+	for _, options := range tests {
+		if _, err := NewPDFTemplate(options); err == nil {
+			t.Errorf("expected error for %+v", options)
+		}
+	}
+}
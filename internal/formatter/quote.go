@@ -0,0 +1,263 @@
+package formatter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"farmix-cli/internal/parser"
+)
+
+// Источники оценки веса детали
+const (
+	WeightSourceSTL   = "stl"   // объем STL файла и плотность материала
+	WeightSourceSlice = "slice" // доля расхода стола из slice_info.config
+)
+
+// PartEstimate - оценка одной детали, полученная вне 3MF (объем STL, слайсер)
+type PartEstimate struct {
+	// WeightGrams - вес одной детали по объему STL (0 - нет данных)
+	WeightGrams float64
+	// PrintTime - время печати одной детали по результату слайсинга (0 - нет данных)
+	PrintTime time.Duration
+}
+
+// PartEstimateKey возвращает ключ оценки детали в QuoteOptions.Estimates: одна и та же деталь
+// из разных материалов весит по-разному, поэтому материал (очищенный, как при группировке) входит в ключ
+func PartEstimateKey(name, material string) string {
+	return name + "|" + parser.CleanMaterialName(material)
+}
+
+// QuoteOptions - настройки расчета сводной оценки (команда quote)
+type QuoteOptions struct {
+	// Estimates - оценки деталей по названию объекта и материалу (ключ PartEstimateKey)
+	Estimates map[string]PartEstimate
+	// MaterialPrices - стоимость материалов за кг (material_prices из конфигурации)
+	MaterialPrices map[string]float64
+}
+
+// QuotePart - строка сводной оценки: деталь одного материала со всех столов
+type QuotePart struct {
+	Name         string        `json:"name"`
+	Material     string        `json:"material"`
+	Count        int           `json:"count"`
+	WeightGrams  float64       `json:"weight_grams"`  // вес всех экземпляров
+	WeightSource string        `json:"weight_source"` // stl, slice или пусто, если вес неизвестен
+	PrintTime    time.Duration `json:"-"`             // время печати всех экземпляров по слайсеру
+	Cost         float64       `json:"cost"`
+	HasCost      bool          `json:"has_cost"`
+}
+
+// Quote - сводная оценка проекта: детали и итоги по весу, времени и стоимости материала
+type Quote struct {
+	Parts            []QuotePart   `json:"parts"`
+	TotalWeightGrams float64       `json:"total_weight_grams"`
+	TotalPrintTime   time.Duration `json:"-"`
+	TotalCost        float64       `json:"total_cost"`
+}
+
+// BuildQuote собирает сводную оценку проекта.
+// Вес детали берется из оценки по STL, а без нее - из доли расхода материала стола
+// (расход материала на столе делится поровну между объектами этого материала).
+// Общее время печати берется из данных нарезки проекта, а если проект не нарезан -
+// суммируется из времени слайсинга деталей.
+func BuildQuote(data *parser.Parser3MF, options QuoteOptions) *Quote {
+	parts := make(map[string]*QuotePart)
+	var keys []string
+	var slicedTime time.Duration
+
+	for _, plate := range data.Plates {
+		shares := plateWeightShares(plate)
+
+		for _, obj := range plate.Objects {
+			material := parser.CleanMaterialName(obj.Material)
+			key := PartEstimateKey(obj.Name, material)
+			part, exists := parts[key]
+			if !exists {
+				part = &QuotePart{Name: obj.Name, Material: material}
+				parts[key] = part
+				keys = append(keys, key)
+			}
+			part.Count++
+
+			estimate := options.Estimates[key]
+			switch {
+			case estimate.WeightGrams > 0:
+				part.WeightGrams += estimate.WeightGrams
+				part.WeightSource = mergeWeightSource(part.WeightSource, WeightSourceSTL)
+			case shares[material] > 0:
+				part.WeightGrams += shares[material]
+				part.WeightSource = mergeWeightSource(part.WeightSource, WeightSourceSlice)
+			}
+
+			part.PrintTime += estimate.PrintTime
+			slicedTime += estimate.PrintTime
+		}
+	}
+
+	sort.Strings(keys)
+
+	quote := &Quote{TotalPrintTime: data.TotalPrintTime()}
+	if quote.TotalPrintTime == 0 {
+		quote.TotalPrintTime = slicedTime
+	}
+
+	for _, key := range keys {
+		part := parts[key]
+		if price, ok := lookupMaterialPrice(options.MaterialPrices, part.Material); ok && part.WeightGrams > 0 {
+			part.Cost = roundMoney(part.WeightGrams / 1000 * price)
+			part.HasCost = true
+			quote.TotalCost += part.Cost
+		}
+		quote.TotalWeightGrams += part.WeightGrams
+		quote.Parts = append(quote.Parts, *part)
+	}
+	quote.TotalCost = roundMoney(quote.TotalCost)
+
+	return quote
+}
+
+// plateWeightShares возвращает вес одного объекта стола по очищенному названию материала.
+// Без расхода по материалам общий вес стола делится между всеми объектами
+func plateWeightShares(plate parser.PlateInfo) map[string]float64 {
+	counts := make(map[string]int)
+	for _, obj := range plate.Objects {
+		counts[parser.CleanMaterialName(obj.Material)]++
+	}
+
+	shares := make(map[string]float64)
+	if len(plate.MaterialWeights) == 0 {
+		if plate.WeightGrams > 0 && len(plate.Objects) > 0 {
+			share := plate.WeightGrams / float64(len(plate.Objects))
+			for material := range counts {
+				shares[material] = share
+			}
+		}
+		return shares
+	}
+
+	weights := make(map[string]float64)
+	for material, weight := range plate.MaterialWeights {
+		weights[parser.CleanMaterialName(material)] += weight
+	}
+	for material, count := range counts {
+		shares[material] = weights[material] / float64(count)
+	}
+	return shares
+}
+
+// mergeWeightSource объединяет источники веса экземпляров одной детали
+func mergeWeightSource(current, source string) string {
+	if current == "" || current == source {
+		return source
+	}
+	return current + "+" + source
+}
+
+// FormatQuoteAsText выводит сводную оценку таблицей деталей с итогами
+func FormatQuoteAsText(quote *Quote, writer io.Writer, lang Lang) error {
+	lang = lang.orDefault(LangEN)
+	title := lang.T("quote.title")
+	fmt.Fprintf(writer, "%s\n", title)
+	fmt.Fprintf(writer, "%s\n\n", underline(title))
+
+	if len(quote.Parts) == 0 {
+		fmt.Fprintf(writer, "%s\n", lang.T("analysis.no_plates"))
+		return nil
+	}
+
+	headers := []string{
+		lang.T("analysis.object_name"),
+		lang.T("analysis.material"),
+		lang.T("analysis.count"),
+		lang.T("quote.weight"),
+		lang.T("quote.source"),
+		lang.T("analysis.print_time"),
+		lang.T("materials.cost"),
+	}
+
+	rows := make([][]string, len(quote.Parts))
+	for i, part := range quote.Parts {
+		weight, source := "—", "—"
+		if part.WeightGrams > 0 {
+			weight = strconv.FormatFloat(part.WeightGrams, 'f', 2, 64)
+			source = part.WeightSource
+		}
+		printTime := "—"
+		if part.PrintTime > 0 {
+			printTime = formatPrintTime(part.PrintTime, lang)
+		}
+		cost := "—"
+		if part.HasCost {
+			cost = strconv.FormatFloat(part.Cost, 'f', 2, 64)
+		}
+		rows[i] = []string{part.Name, part.Material, strconv.Itoa(part.Count), weight, source, printTime, cost}
+	}
+
+	colWidths := make([]int, len(headers))
+	for i, header := range headers {
+		colWidths[i] = utf8.RuneCountInString(header)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if cellWidth := utf8.RuneCountInString(cell); cellWidth > colWidths[i] {
+				colWidths[i] = cellWidth
+			}
+		}
+	}
+
+	// Количество, вес, время и стоимость выравниваются вправо
+	rightAligned := func(i int) bool { return i == 2 || i == 3 || i >= 5 }
+
+	printBorder(writer, colWidths, "┌", "┬", "┐")
+	printAlignedRow(writer, headers, colWidths, rightAligned)
+	printBorder(writer, colWidths, "├", "┼", "┤")
+	for _, row := range rows {
+		printAlignedRow(writer, row, colWidths, rightAligned)
+	}
+	printBorder(writer, colWidths, "└", "┴", "┘")
+
+	fmt.Fprintf(writer, "\n%s: %.2f\n", lang.T("quote.total_weight"), quote.TotalWeightGrams)
+	fmt.Fprintf(writer, "%s: %s\n", lang.T("analysis.total_print_time"), formatPrintTime(quote.TotalPrintTime, lang))
+	fmt.Fprintf(writer, "%s: %.2f\n", lang.T("quote.total_cost"), quote.TotalCost)
+
+	return nil
+}
+
+// jsonQuote - сводная оценка в JSON, время печати в секундах
+type jsonQuote struct {
+	Parts                 []jsonQuotePart `json:"parts"`
+	TotalWeightGrams      float64         `json:"total_weight_grams"`
+	TotalPrintTimeSeconds int             `json:"total_print_time_seconds"`
+	TotalCost             float64         `json:"total_cost"`
+}
+
+type jsonQuotePart struct {
+	QuotePart
+	PrintTimeSeconds int `json:"print_time_seconds"`
+}
+
+// FormatQuoteAsJSON выводит сводную оценку в JSON
+func FormatQuoteAsJSON(quote *Quote, writer io.Writer) error {
+	result := jsonQuote{
+		Parts:                 make([]jsonQuotePart, 0, len(quote.Parts)),
+		TotalWeightGrams:      quote.TotalWeightGrams,
+		TotalPrintTimeSeconds: int(quote.TotalPrintTime.Seconds()),
+		TotalCost:             quote.TotalCost,
+	}
+	for _, part := range quote.Parts {
+		result.Parts = append(result.Parts, jsonQuotePart{QuotePart: part, PrintTimeSeconds: int(part.PrintTime.Seconds())})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	return nil
+}
\ No newline at end of file
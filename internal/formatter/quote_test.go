@@ -0,0 +1,88 @@
+package formatter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"farmix-cli/internal/parser"
+)
+
+func TestBuildQuoteSplitsPlateWeightByMaterial(t *testing.T) {
+	data := &parser.Parser3MF{Plates: []parser.PlateInfo{{
+		PlateID: 1,
+		Objects: []parser.PlateObject{
+			{ID: 1, Name: "Body", Material: "PLA (Red)"},
+			{ID: 2, Name: "Body", Material: "PLA"},
+			{ID: 3, Name: "Gasket", Material: "TPU"},
+		},
+		WeightGrams:     26,
+		MaterialWeights: map[string]float64{"PLA (Red)": 20, "TPU": 6},
+	}}}
+
+	quote := BuildQuote(data, QuoteOptions{
+		Estimates:      map[string]PartEstimate{PartEstimateKey("Gasket", "TPU"): {PrintTime: 30 * time.Minute}},
+		MaterialPrices: map[string]float64{"pla": 1000},
+	})
+
+	if len(quote.Parts) != 2 {
+		t.Fatalf("got %d parts, want 2: %+v", len(quote.Parts), quote.Parts)
+	}
+	body, gasket := quote.Parts[0], quote.Parts[1]
+	if body.Name != "Body" || body.Count != 2 || body.WeightGrams != 20 || !body.HasCost || body.Cost != 20 {
+		t.Errorf("Body = %+v, want 2 pcs, 20 g, cost 20", body)
+	}
+	if gasket.WeightGrams != 6 || gasket.HasCost || gasket.PrintTime != 30*time.Minute {
+		t.Errorf("Gasket = %+v, want 6 g without price, 30m", gasket)
+	}
+
+	// Проект не нарезан по времени - итог берется из слайсинга деталей
+	if quote.TotalWeightGrams != 26 || quote.TotalCost != 20 || quote.TotalPrintTime != 30*time.Minute {
+		t.Errorf("totals = %.2f g, %.2f, %v; want 26 g, 20, 30m", quote.TotalWeightGrams, quote.TotalCost, quote.TotalPrintTime)
+	}
+}
+
+func TestBuildQuoteEstimatesPerMaterial(t *testing.T) {
+	data := &parser.Parser3MF{Plates: []parser.PlateInfo{{
+		PlateID: 1,
+		Objects: []parser.PlateObject{
+			{ID: 1, Name: "Body", Material: "PLA (Red)"},
+			{ID: 2, Name: "Body", Material: "PETG"},
+		},
+	}}}
+
+	quote := BuildQuote(data, QuoteOptions{Estimates: map[string]PartEstimate{
+		PartEstimateKey("Body", "PLA"):  {WeightGrams: 10},
+		PartEstimateKey("Body", "PETG"): {WeightGrams: 12.7},
+	}})
+
+	if len(quote.Parts) != 2 {
+		t.Fatalf("got %d parts, want 2: %+v", len(quote.Parts), quote.Parts)
+	}
+	weights := map[string]float64{}
+	for _, part := range quote.Parts {
+		weights[part.Material] = part.WeightGrams
+	}
+	if weights["PLA"] != 10 || weights["PETG"] != 12.7 {
+		t.Errorf("weights by material = %v, want PLA 10 g and PETG 12.7 g", weights)
+	}
+}
+
+func TestFormatQuoteAsText(t *testing.T) {
+	quote := &Quote{
+		Parts:            []QuotePart{{Name: "Body", Material: "PLA", Count: 2, WeightGrams: 2.48, WeightSource: WeightSourceSTL}},
+		TotalWeightGrams: 2.48,
+		TotalPrintTime:   90 * time.Minute,
+	}
+
+	var buf bytes.Buffer
+	if err := FormatQuoteAsText(quote, &buf, LangEN); err != nil {
+		t.Fatalf("FormatQuoteAsText() error = %v", err)
+	}
+	for _, want := range []string{"│ Body ", "2.48 │ stl", "Total weight, g: 2.48\n", "Total print time: 1h 30m\n", "Total material cost: 0.00\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
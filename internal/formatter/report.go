@@ -2,22 +2,55 @@ package formatter
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"farmix-cli/internal/bitrix"
 )
 
+// DealReportOptions configures optional columns of the deals report
+type DealReportOptions struct {
+	// MachineHours maps deal ID to print time computed from the deal's 3MF files.
+	// When nil, the computed column is not shown; deals without an entry get an empty cell
+	MachineHours map[string]time.Duration
+	// ASCII draws table borders with +, - and | instead of box-drawing characters
+	ASCII bool
+	// MaxTextWidth caps text columns (funnel, title) of the table in runes, longer values are
+	// cut with an ellipsis; 0 means DefaultReportMaxTextWidth, negative disables the cap.
+	// Numeric columns are never truncated
+	MaxTextWidth int
+	// ConvertTo adds a combined total converted to this currency with ExchangeRates
+	ConvertTo string
+	// ExchangeRates maps currency code (upper case) to its rate in a common base currency,
+	// e.g. {"RUB": 1, "EUR": 100}; required for every currency when ConvertTo is set
+	ExchangeRates map[string]float64
+	// BaseCurrency is the portal base currency, used for deals without CURRENCY_ID.
+	// When empty such deals are summed under an empty currency, which cannot be converted
+	BaseCurrency string
+}
+
+// DefaultReportMaxTextWidth keeps a report row with a long deal title within a typical terminal
+const DefaultReportMaxTextWidth = 40
+
 // FormatReportAsTable formats deals report as ASCII table with aligned columns
 // categoryMap maps category ID to category name
-func FormatReportAsTable(deals []bitrix.DealReportRow, categoryMap map[string]string, writer io.Writer) error {
+func FormatReportAsTable(deals []bitrix.DealReportRow, categoryMap map[string]string, writer io.Writer, options DealReportOptions) error {
 	if len(deals) == 0 {
 		fmt.Fprintf(writer, "Нет сделок для отображения\n")
 		return nil
 	}
 
+	totals, err := ComputeDealReportTotals(deals, options)
+	if err != nil {
+		return err
+	}
+
 	// Define column headers
 	headers := []string{
 		"ID",
@@ -25,39 +58,45 @@ func FormatReportAsTable(deals []bitrix.DealReportRow, categoryMap map[string]st
 		"Название",
 		"Дата создания",
 		"М/ч (₽)",
+	}
+	if options.MachineHours != nil {
+		headers = append(headers, "М/ч (расч.)")
+	}
+	headers = append(headers,
 		"Ч/ч (₽)",
 		"Материал (₽)",
 		"Итог. стоимость (₽)",
 		"Итоговая цена (₽)",
 		"Оплата",
-	}
+	)
+	// Numeric columns (М/ч, М/ч расч., Ч/ч, Материал, Итог. стоимость, Итоговая цена) are right-aligned
+	lastNumeric := len(headers) - 2
+	rightAligned := func(i int) bool { return i >= 4 && i <= lastNumeric }
 
 	// Prepare data rows
 	rows := make([][]string, len(deals))
 	for i, deal := range deals {
-		// Format date (take only the date part if it's a datetime)
-		date := deal.DateCreate
-		if len(date) > 10 {
-			date = date[:10]
-		}
+		date := dealReportDate(deal.DateCreate)
+		categoryName := resolveCategoryName(deal.CategoryID, categoryMap)
 
-		// Get category name from map, fallback to ID if not found
-		categoryName := deal.CategoryID
-		if name, ok := categoryMap[deal.CategoryID]; ok {
-			categoryName = name
-		}
+		rows[i] = dealReportRecord(deal, categoryName, date, options)
+	}
 
-		rows[i] = []string{
-			deal.ID,
-			categoryName,
-			deal.Title,
-			date,
-			bitrix.ParseCustomFieldValue(deal.MachineCost),
-			bitrix.ParseCustomFieldValue(deal.HumanCost),
-			bitrix.ParseCustomFieldValue(deal.MaterialCost),
-			bitrix.ParseCustomFieldValue(deal.TotalCost),
-			bitrix.ParseCustomFieldValue(deal.Opportunity),
-			bitrix.ParseCustomFieldValue(deal.PaymentReceived),
+	maxWidth := options.MaxTextWidth
+	if maxWidth == 0 {
+		maxWidth = DefaultReportMaxTextWidth
+	}
+	ellipsis := "…"
+	if options.ASCII {
+		ellipsis = "..."
+	}
+	if maxWidth > 0 {
+		for _, row := range rows {
+			for i := range row {
+				if !rightAligned(i) {
+					row[i] = truncateRunes(row[i], maxWidth, ellipsis)
+				}
+			}
 		}
 	}
 
@@ -76,32 +115,58 @@ func FormatReportAsTable(deals []bitrix.DealReportRow, categoryMap map[string]st
 		}
 	}
 
+	glyphs := boxGlyphs
+	if options.ASCII {
+		glyphs = asciiGlyphs
+	}
+
 	// Print top border
-	printBorder(writer, colWidths, "┌", "┬", "┐")
+	glyphs.printBorder(writer, colWidths, glyphs.top)
 
 	// Print header
-	printRow(writer, headers, colWidths)
+	glyphs.printRow(writer, headers, colWidths, rightAligned)
 
 	// Print header separator
-	printBorder(writer, colWidths, "├", "┼", "┤")
+	glyphs.printBorder(writer, colWidths, glyphs.separator)
 
 	// Print data rows
 	for _, row := range rows {
-		printRow(writer, row, colWidths)
+		glyphs.printRow(writer, row, colWidths, rightAligned)
 	}
 
 	// Print bottom border
-	printBorder(writer, colWidths, "└", "┴", "┘")
+	glyphs.printBorder(writer, colWidths, glyphs.bottom)
 
-	// Print summary
+	// Print summary, a sum per currency
 	fmt.Fprintf(writer, "\nВсего сделок: %d\n", len(deals))
+	fmt.Fprintf(writer, "Сумма сделок: %s\n", joinCurrencyAmounts(totals.ByCurrency, func(t CurrencyTotals) float64 { return t.Opportunity }))
+	fmt.Fprintf(writer, "Оплачено: %s\n", joinCurrencyAmounts(totals.ByCurrency, func(t CurrencyTotals) float64 { return t.PaymentReceived }))
+	fmt.Fprintf(writer, "К оплате: %s\n", joinCurrencyAmounts(totals.ByCurrency, func(t CurrencyTotals) float64 { return t.Outstanding }))
+	if converted := totals.Converted; converted != nil {
+		fmt.Fprintf(writer, "Итого в %s по курсам: сумма сделок %s, оплачено %s, к оплате %s\n", converted.Currency,
+			formatReportAmount(converted.Opportunity), formatReportAmount(converted.PaymentReceived), formatReportAmount(converted.Outstanding))
+	}
 
 	return nil
 }
 
+// joinCurrencyAmounts formats one amount of every currency, e.g. "4200.50 RUB; 300 EUR"
+func joinCurrencyAmounts(totals []CurrencyTotals, amount func(CurrencyTotals) float64) string {
+	parts := make([]string, len(totals))
+	for i, total := range totals {
+		parts[i] = strings.TrimSpace(formatReportAmount(amount(total)) + " " + total.Currency)
+	}
+	return strings.Join(parts, "; ")
+}
+
 // FormatReportAsCSV formats deals report as CSV
 // categoryMap maps category ID to category name
-func FormatReportAsCSV(deals []bitrix.DealReportRow, categoryMap map[string]string, writer io.Writer) error {
+func FormatReportAsCSV(deals []bitrix.DealReportRow, categoryMap map[string]string, writer io.Writer, options DealReportOptions) error {
+	totals, err := ComputeDealReportTotals(deals, options)
+	if err != nil {
+		return err
+	}
+
 	csvWriter := csv.NewWriter(writer)
 	defer csvWriter.Flush()
 
@@ -112,77 +177,340 @@ func FormatReportAsCSV(deals []bitrix.DealReportRow, categoryMap map[string]stri
 		"Название сделки",
 		"Дата создания",
 		"Рассчетная стоимость м/ч",
+	}
+	if options.MachineHours != nil {
+		headers = append(headers, "Машино-часы по 3MF")
+	}
+	headers = append(headers,
 		"Рассчетная стоимость ч/ч",
 		"Рассчетная стоимость материала",
 		"Итоговая стоимость изготовления",
 		"Итоговая цена",
 		"Оплата получена",
-	}
+	)
 	if err := csvWriter.Write(headers); err != nil {
 		return fmt.Errorf("failed to write CSV headers: %w", err)
 	}
 
 	// Write data rows
 	for _, deal := range deals {
-		// Format date (take only the date part if it's a datetime)
-		date := deal.DateCreate
-		if len(date) > 10 {
-			date = date[:10]
+		date := dealReportDate(deal.DateCreate)
+		categoryName := resolveCategoryName(deal.CategoryID, categoryMap)
+
+		record := dealReportRecord(deal, categoryName, date, options)
+		if err := csvWriter.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
 		}
+	}
 
-		// Get category name from map, fallback to ID if not found
-		categoryName := deal.CategoryID
-		if name, ok := categoryMap[deal.CategoryID]; ok {
-			categoryName = name
+	// Footer rows per currency: totals under "Итоговая цена" and "Оплата получена",
+	// outstanding on its own row; the converted total follows with "(по курсам)"
+	footer := append([]CurrencyTotals{}, totals.ByCurrency...)
+	if totals.Converted != nil {
+		converted := *totals.Converted
+		converted.Currency += " (по курсам)"
+		footer = append(footer, converted)
+	}
+	for _, total := range footer {
+		totalRow := make([]string, len(headers))
+		totalRow[0] = strings.TrimSpace("Итого " + total.Currency)
+		totalRow[len(headers)-2] = formatReportAmount(total.Opportunity)
+		totalRow[len(headers)-1] = formatReportAmount(total.PaymentReceived)
+		outstandingRow := make([]string, len(headers))
+		outstandingRow[0] = strings.TrimSpace("К оплате " + total.Currency)
+		outstandingRow[len(headers)-2] = formatReportAmount(total.Outstanding)
+		if err := csvWriter.WriteAll([][]string{totalRow, outstandingRow}); err != nil {
+			return fmt.Errorf("failed to write CSV totals: %w", err)
 		}
+	}
+
+	return nil
+}
+
+// jsonDealReport is the deals report in JSON output
+type jsonDealReport struct {
+	Deals  []jsonDealReportRow `json:"deals"`
+	Totals DealReportTotals    `json:"totals"`
+}
+
+// jsonDealReportRow is a deal in JSON output; custom field values are parsed as in the table
+type jsonDealReportRow struct {
+	ID              string   `json:"id"`
+	Category        string   `json:"category"`
+	Title           string   `json:"title"`
+	DateCreate      string   `json:"date_create"`
+	Currency        string   `json:"currency"`
+	MachineCost     string   `json:"machine_cost"`
+	MachineHours    *float64 `json:"machine_hours,omitempty"`
+	HumanCost       string   `json:"human_cost"`
+	MaterialCost    string   `json:"material_cost"`
+	TotalCost       string   `json:"total_cost"`
+	Opportunity     string   `json:"opportunity"`
+	PaymentReceived string   `json:"payment_received"`
+}
 
-		record := []string{
-			deal.ID,
-			categoryName,
-			deal.Title,
-			date,
-			bitrix.ParseCustomFieldValue(deal.MachineCost),
-			bitrix.ParseCustomFieldValue(deal.HumanCost),
-			bitrix.ParseCustomFieldValue(deal.MaterialCost),
-			bitrix.ParseCustomFieldValue(deal.TotalCost),
-			bitrix.ParseCustomFieldValue(deal.Opportunity),
-			bitrix.ParseCustomFieldValue(deal.PaymentReceived),
+// FormatReportAsJSON formats deals report as JSON object with deals and totals
+// categoryMap maps category ID to category name
+func FormatReportAsJSON(deals []bitrix.DealReportRow, categoryMap map[string]string, writer io.Writer, options DealReportOptions) error {
+	totals, err := ComputeDealReportTotals(deals, options)
+	if err != nil {
+		return err
+	}
+
+	report := jsonDealReport{
+		Deals:  make([]jsonDealReportRow, 0, len(deals)),
+		Totals: totals,
+	}
+	for _, deal := range deals {
+		row := jsonDealReportRow{
+			ID:              deal.ID,
+			Category:        resolveCategoryName(deal.CategoryID, categoryMap),
+			Title:           deal.Title,
+			DateCreate:      dealReportDate(deal.DateCreate),
+			Currency:        deal.CurrencyID,
+			MachineCost:     bitrix.ParseCustomFieldValue(deal.MachineCost),
+			HumanCost:       bitrix.ParseCustomFieldValue(deal.HumanCost),
+			MaterialCost:    bitrix.ParseCustomFieldValue(deal.MaterialCost),
+			TotalCost:       bitrix.ParseCustomFieldValue(deal.TotalCost),
+			Opportunity:     bitrix.ParseCustomFieldValue(deal.Opportunity),
+			PaymentReceived: bitrix.ParseCustomFieldValue(deal.PaymentReceived),
 		}
-		if err := csvWriter.Write(record); err != nil {
-			return fmt.Errorf("failed to write CSV record: %w", err)
+		if hours, ok := options.MachineHours[deal.ID]; ok && hours > 0 {
+			value := roundMoney(hours.Hours())
+			row.MachineHours = &value
 		}
+		report.Deals = append(report.Deals, row)
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(report); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
 	}
 
 	return nil
 }
 
+// CurrencyTotals are the report sums of the deals in one currency
+type CurrencyTotals struct {
+	Currency        string  `json:"currency"`
+	Opportunity     float64 `json:"opportunity"`
+	PaymentReceived float64 `json:"payment_received"`
+	// Outstanding is Opportunity minus PaymentReceived ("К оплате")
+	Outstanding float64 `json:"outstanding"`
+}
+
+// DealReportTotals are the report footer sums over all deals
+type DealReportTotals struct {
+	// ByCurrency holds the sums per deal currency, sorted by currency code
+	ByCurrency []CurrencyTotals `json:"by_currency"`
+	// Converted is the combined total in DealReportOptions.ConvertTo (nil without conversion)
+	Converted *CurrencyTotals `json:"converted,omitempty"`
+}
+
+// ComputeDealReportTotals sums OPPORTUNITY and payment_received of the deals per currency
+// (payment_received is taken in the deal currency, deals without one are in options.BaseCurrency);
+// missing or non-numeric values count as zero.
+// With options.ConvertTo the sums are also converted to one currency, a missing rate is an error
+func ComputeDealReportTotals(deals []bitrix.DealReportRow, options DealReportOptions) (DealReportTotals, error) {
+	sums := make(map[string]*CurrencyTotals)
+	var currencies []string
+	for _, deal := range deals {
+		currency := strings.ToUpper(strings.TrimSpace(deal.CurrencyID))
+		if currency == "" {
+			currency = strings.ToUpper(strings.TrimSpace(options.BaseCurrency))
+		}
+		total, exists := sums[currency]
+		if !exists {
+			total = &CurrencyTotals{Currency: currency}
+			sums[currency] = total
+			currencies = append(currencies, currency)
+		}
+		total.Opportunity += parseReportAmount(deal.Opportunity)
+		total.PaymentReceived += parseReportAmount(deal.PaymentReceived)
+	}
+	sort.Strings(currencies)
+
+	var totals DealReportTotals
+	for _, currency := range currencies {
+		total := sums[currency]
+		total.Opportunity = roundMoney(total.Opportunity)
+		total.PaymentReceived = roundMoney(total.PaymentReceived)
+		total.Outstanding = roundMoney(total.Opportunity - total.PaymentReceived)
+		totals.ByCurrency = append(totals.ByCurrency, *total)
+	}
+
+	if options.ConvertTo == "" {
+		return totals, nil
+	}
+
+	target := strings.ToUpper(strings.TrimSpace(options.ConvertTo))
+	targetRate, ok := options.ExchangeRates[target]
+	if !ok || targetRate <= 0 {
+		return totals, fmt.Errorf("no exchange rate for currency %s", target)
+	}
+	converted := &CurrencyTotals{Currency: target}
+	for _, total := range totals.ByCurrency {
+		rate, ok := options.ExchangeRates[total.Currency]
+		if !ok || rate <= 0 {
+			return totals, fmt.Errorf("no exchange rate for currency %q", total.Currency)
+		}
+		converted.Opportunity += total.Opportunity * rate / targetRate
+		converted.PaymentReceived += total.PaymentReceived * rate / targetRate
+	}
+	converted.Opportunity = roundMoney(converted.Opportunity)
+	converted.PaymentReceived = roundMoney(converted.PaymentReceived)
+	converted.Outstanding = roundMoney(converted.Opportunity - converted.PaymentReceived)
+	totals.Converted = converted
+
+	return totals, nil
+}
+
+// parseReportAmount converts a field value to a number with ParseCustomFieldValue;
+// values of multiple-value fields are summed
+func parseReportAmount(value interface{}) float64 {
+	var sum float64
+	for _, part := range strings.Split(bitrix.ParseCustomFieldValue(value), ", ") {
+		if amount, err := strconv.ParseFloat(part, 64); err == nil {
+			sum += amount
+		}
+	}
+	return sum
+}
+
+// formatReportAmount formats a total the same way as amounts of the deals
+func formatReportAmount(value float64) string {
+	return bitrix.ParseCustomFieldValue(roundMoney(value))
+}
+
+// dealReportDate takes only the date part of a deal datetime
+func dealReportDate(dateCreate string) string {
+	if len(dateCreate) > 10 {
+		return dateCreate[:10]
+	}
+	return dateCreate
+}
+
+// dealReportRecord returns report cells of a deal; the computed machine hours cell
+// follows the stored machine cost when options.MachineHours is set
+func dealReportRecord(deal bitrix.DealReportRow, categoryName, date string, options DealReportOptions) []string {
+	record := []string{
+		deal.ID,
+		categoryName,
+		deal.Title,
+		date,
+		bitrix.ParseCustomFieldValue(deal.MachineCost),
+	}
+	if options.MachineHours != nil {
+		record = append(record, formatMachineHours(options.MachineHours[deal.ID]))
+	}
+	return append(record,
+		bitrix.ParseCustomFieldValue(deal.HumanCost),
+		bitrix.ParseCustomFieldValue(deal.MaterialCost),
+		bitrix.ParseCustomFieldValue(deal.TotalCost),
+		bitrix.ParseCustomFieldValue(deal.Opportunity),
+		bitrix.ParseCustomFieldValue(deal.PaymentReceived),
+	)
+}
+
+// formatMachineHours formats print time in hours rounded to 2 decimal places,
+// zero (no 3MF or no slicing data) is an empty cell
+func formatMachineHours(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(roundMoney(d.Hours()), 'f', -1, 64)
+}
+
+// truncateRunes cuts value to maxWidth runes, ending it with ellipsis when it was longer
+func truncateRunes(value string, maxWidth int, ellipsis string) string {
+	if utf8.RuneCountInString(value) <= maxWidth {
+		return value
+	}
+	keep := maxWidth - utf8.RuneCountInString(ellipsis)
+	if keep <= 0 {
+		return string([]rune(value)[:maxWidth])
+	}
+	return string([]rune(value)[:keep]) + ellipsis
+}
+
+// unknownCategoryName is shown when a deal's category ID is missing from categoryMap
+const unknownCategoryName = "—"
+
+// resolveCategoryName returns funnel name for the category ID or "—" if it is unknown
+func resolveCategoryName(categoryID string, categoryMap map[string]string) string {
+	if name, ok := categoryMap[categoryID]; ok && name != "" {
+		return name
+	}
+	return unknownCategoryName
+}
+
+// tableGlyphs are the characters used to draw table borders;
+// top, separator and bottom hold the left, middle and right junctions of the border lines
+type tableGlyphs struct {
+	horizontal string
+	vertical   string
+	top        [3]string
+	separator  [3]string
+	bottom     [3]string
+}
+
+// boxGlyphs draw tables with UTF-8 box-drawing characters (interactive default)
+var boxGlyphs = tableGlyphs{
+	horizontal: "─",
+	vertical:   "│",
+	top:        [3]string{"┌", "┬", "┐"},
+	separator:  [3]string{"├", "┼", "┤"},
+	bottom:     [3]string{"└", "┴", "┘"},
+}
+
+// asciiGlyphs draw tables with plain ASCII for files, pipes and non-UTF-8 consumers
+var asciiGlyphs = tableGlyphs{
+	horizontal: "-",
+	vertical:   "|",
+	top:        [3]string{"+", "+", "+"},
+	separator:  [3]string{"+", "+", "+"},
+	bottom:     [3]string{"+", "+", "+"},
+}
+
 // printBorder prints a border line for the table
 func printBorder(writer io.Writer, colWidths []int, left, middle, right string) {
-	fmt.Fprint(writer, left)
+	boxGlyphs.printBorder(writer, colWidths, [3]string{left, middle, right})
+}
+
+// printAlignedRow prints a table row, rightAligned reports which columns are right-aligned
+func printAlignedRow(writer io.Writer, cells []string, colWidths []int, rightAligned func(i int) bool) {
+	boxGlyphs.printRow(writer, cells, colWidths, rightAligned)
+}
+
+// printBorder prints a border line with the given left, middle and right junctions
+func (g tableGlyphs) printBorder(writer io.Writer, colWidths []int, junctions [3]string) {
+	fmt.Fprint(writer, junctions[0])
 	for i, width := range colWidths {
-		fmt.Fprint(writer, strings.Repeat("─", width+2)) // +2 for padding
+		fmt.Fprint(writer, strings.Repeat(g.horizontal, width+2)) // +2 for padding
 		if i < len(colWidths)-1 {
-			fmt.Fprint(writer, middle)
+			fmt.Fprint(writer, junctions[1])
 		}
 	}
-	fmt.Fprintln(writer, right)
+	fmt.Fprintln(writer, junctions[2])
 }
 
-// printRow prints a data row with proper alignment and padding
-func printRow(writer io.Writer, cells []string, colWidths []int) {
-	fmt.Fprint(writer, "│")
+// printRow prints a table row, rightAligned reports which columns are right-aligned
+func (g tableGlyphs) printRow(writer io.Writer, cells []string, colWidths []int, rightAligned func(i int) bool) {
+	fmt.Fprint(writer, g.vertical)
 	for i, cell := range cells {
 		// Calculate padding needed (considering UTF-8 characters)
 		cellWidth := utf8.RuneCountInString(cell)
 		padding := colWidths[i] - cellWidth
 
 		// Right-align numbers, left-align text
-		if i >= 2 && i <= 5 { // Numeric columns (М/ч, Ч/ч, Материал, Итого)
+		if rightAligned(i) {
 			fmt.Fprintf(writer, " %s%s ", strings.Repeat(" ", padding), cell)
 		} else {
 			fmt.Fprintf(writer, " %s%s ", cell, strings.Repeat(" ", padding))
 		}
-		fmt.Fprint(writer, "│")
+		fmt.Fprint(writer, g.vertical)
 	}
 	fmt.Fprintln(writer)
 }
@@ -0,0 +1,396 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"farmix-cli/internal/bitrix"
+)
+
+// TestResolveCategoryName tests resolving category IDs to funnel names
+func TestResolveCategoryName(t *testing.T) {
+	categoryMap := map[string]string{
+		"0": "Общая",
+		"3": "Производство",
+		"5": "",
+	}
+
+	tests := []struct {
+		name       string
+		categoryID string
+		expected   string
+	}{
+		{
+			name:       "Known category",
+			categoryID: "3",
+			expected:   "Производство",
+		},
+		{
+			name:       "Default category",
+			categoryID: "0",
+			expected:   "Общая",
+		},
+		{
+			name:       "Unknown category",
+			categoryID: "42",
+			expected:   "—",
+		},
+		{
+			name:       "Empty category ID",
+			categoryID: "",
+			expected:   "—",
+		},
+		{
+			name:       "Category with empty name",
+			categoryID: "5",
+			expected:   "—",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := resolveCategoryName(tt.categoryID, categoryMap)
+			if result != tt.expected {
+				t.Errorf("resolveCategoryName(%q) = %q, want %q", tt.categoryID, result, tt.expected)
+			}
+		})
+	}
+}
+
+// TestResolveCategoryNameNilMap tests that a nil category map is handled
+func TestResolveCategoryNameNilMap(t *testing.T) {
+	if result := resolveCategoryName("1", nil); result != "—" {
+		t.Errorf("resolveCategoryName with nil map = %q, want %q", result, "—")
+	}
+}
+
+// TestFormatReportAsCSVCategoryColumn tests the "Воронка" column in CSV output
+func TestFormatReportAsCSVCategoryColumn(t *testing.T) {
+	deals := []bitrix.DealReportRow{
+		{ID: "1", Title: "Known", DateCreate: "2025-01-15T10:00:00+03:00", CategoryID: "3"},
+		{ID: "2", Title: "Unknown", DateCreate: "2025-01-16T10:00:00+03:00", CategoryID: "99"},
+	}
+	categoryMap := map[string]string{"3": "Производство"}
+
+	var buf bytes.Buffer
+	if err := FormatReportAsCSV(deals, categoryMap, &buf, DealReportOptions{}); err != nil {
+		t.Fatalf("FormatReportAsCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV output: %v", err)
+	}
+	if len(records) != 5 {
+		t.Fatalf("expected 5 records (header + 2 rows + 2 total rows), got %d", len(records))
+	}
+
+	if records[0][1] != "Воронка" {
+		t.Errorf("expected header %q in column 1, got %q", "Воронка", records[0][1])
+	}
+	if records[1][1] != "Производство" {
+		t.Errorf("expected known category %q, got %q", "Производство", records[1][1])
+	}
+	if records[2][1] != "—" {
+		t.Errorf("expected unknown category %q, got %q", "—", records[2][1])
+	}
+	if records[1][3] != "2025-01-15" {
+		t.Errorf("expected date %q, got %q", "2025-01-15", records[1][3])
+	}
+}
+
+// TestFormatReportAsTableCategoryColumn tests the "Воронка" column in table output
+func TestFormatReportAsTableCategoryColumn(t *testing.T) {
+	deals := []bitrix.DealReportRow{
+		{ID: "1", Title: "Known", CategoryID: "3"},
+		{ID: "2", Title: "Unknown", CategoryID: "99"},
+	}
+	categoryMap := map[string]string{"3": "Производство"}
+
+	var buf bytes.Buffer
+	if err := FormatReportAsTable(deals, categoryMap, &buf, DealReportOptions{}); err != nil {
+		t.Fatalf("FormatReportAsTable() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, want := range []string{"Воронка", "Производство", "│ —", "Всего сделок: 2"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "│ 99") {
+		t.Errorf("expected unknown category ID not to be printed, got:\n%s", output)
+	}
+}
+
+// TestFormatReportMachineHoursColumn tests the computed machine hours column and its blank fallback
+func TestFormatReportMachineHoursColumn(t *testing.T) {
+	deals := []bitrix.DealReportRow{
+		{ID: "1", Title: "Sliced", MachineCost: "500", HumanCost: "300"},
+		{ID: "2", Title: "No 3MF", MachineCost: "700", HumanCost: "100"},
+	}
+	options := DealReportOptions{MachineHours: map[string]time.Duration{"1": 90 * time.Minute}}
+
+	var buf bytes.Buffer
+	if err := FormatReportAsCSV(deals, nil, &buf, options); err != nil {
+		t.Fatalf("FormatReportAsCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV output: %v", err)
+	}
+	if records[0][5] != "Машино-часы по 3MF" {
+		t.Errorf("expected computed column after machine cost, got header %q", records[0][5])
+	}
+	if records[1][4] != "500" || records[1][5] != "1.5" || records[1][6] != "300" {
+		t.Errorf("unexpected sliced deal row: %q", records[1])
+	}
+	if records[2][5] != "" {
+		t.Errorf("expected blank computed cell without 3MF, got %q", records[2][5])
+	}
+
+	buf.Reset()
+	if err := FormatReportAsTable(deals, nil, &buf, options); err != nil {
+		t.Fatalf("FormatReportAsTable() error = %v", err)
+	}
+	if output := buf.String(); !strings.Contains(output, "М/ч (расч.)") || !strings.Contains(output, "│         1.5 │") {
+		t.Errorf("expected computed column in table, got:\n%s", output)
+	}
+
+	buf.Reset()
+	if err := FormatReportAsTable(deals, nil, &buf, DealReportOptions{}); err != nil {
+		t.Fatalf("FormatReportAsTable() error = %v", err)
+	}
+	if strings.Contains(buf.String(), "М/ч (расч.)") {
+		t.Errorf("expected no computed column without MachineHours, got:\n%s", buf.String())
+	}
+}
+
+// TestFormatReportAsTableASCII tests that the ASCII option draws borders without box-drawing runes
+func TestFormatReportAsTableASCII(t *testing.T) {
+	deals := []bitrix.DealReportRow{{ID: "1", Title: "Deal", CategoryID: "3", MachineCost: "500"}}
+
+	var buf bytes.Buffer
+	if err := FormatReportAsTable(deals, map[string]string{"3": "Производство"}, &buf, DealReportOptions{ASCII: true}); err != nil {
+		t.Fatalf("FormatReportAsTable() error = %v", err)
+	}
+
+	output := buf.String()
+	for _, r := range output {
+		if r >= 0x2500 && r <= 0x257F {
+			t.Fatalf("expected no box-drawing runes, found %q in:\n%s", r, output)
+		}
+	}
+	for _, want := range []string{"+----", "| 1  |", "|     500 |", "Всего сделок: 1"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+// TestFormatReportAsTableTruncatesLongTitle tests that a long title is cut to the cap with an ellipsis
+func TestFormatReportAsTableTruncatesLongTitle(t *testing.T) {
+	title := strings.Repeat("Очень длинное название сделки ", 5)
+	deals := []bitrix.DealReportRow{{ID: "1", Title: title, TotalCost: "123456789012345678901234567890"}}
+
+	var buf bytes.Buffer
+	if err := FormatReportAsTable(deals, nil, &buf, DealReportOptions{MaxTextWidth: 20}); err != nil {
+		t.Fatalf("FormatReportAsTable() error = %v", err)
+	}
+
+	output := buf.String()
+	want := "│ " + string([]rune(title)[:19]) + "… │"
+	if !strings.Contains(output, want) {
+		t.Errorf("expected title truncated to 20 runes %q, got:\n%s", want, output)
+	}
+	if !strings.Contains(output, "123456789012345678901234567890") {
+		t.Errorf("expected numeric column to be kept full-width, got:\n%s", output)
+	}
+
+	buf.Reset()
+	if err := FormatReportAsTable(deals, nil, &buf, DealReportOptions{MaxTextWidth: -1}); err != nil {
+		t.Fatalf("FormatReportAsTable() error = %v", err)
+	}
+	if !strings.Contains(buf.String(), title) {
+		t.Errorf("expected full title with the cap disabled, got:\n%s", buf.String())
+	}
+}
+
+func TestTruncateRunes(t *testing.T) {
+	tests := []struct {
+		value    string
+		maxWidth int
+		ellipsis string
+		expected string
+	}{
+		{"Кронштейн", 9, "…", "Кронштейн"},
+		{"Кронштейн", 5, "…", "Крон…"},
+		{"Кронштейн", 5, "...", "Кр..."},
+		{"Кронштейн", 2, "...", "Кр"},
+	}
+
+	for _, tt := range tests {
+		if result := truncateRunes(tt.value, tt.maxWidth, tt.ellipsis); result != tt.expected {
+			t.Errorf("truncateRunes(%q, %d, %q) = %q, want %q", tt.value, tt.maxWidth, tt.ellipsis, result, tt.expected)
+		}
+	}
+}
+
+// paymentDeals are a fully paid, a partially paid and an unpaid deal
+func paymentDeals() []bitrix.DealReportRow {
+	return []bitrix.DealReportRow{
+		{ID: "1", Title: "Paid", Opportunity: "1000|RUB", PaymentReceived: float64(1000)},
+		{ID: "2", Title: "Partial", Opportunity: float64(2500.5), PaymentReceived: "1 000,50"},
+		{ID: "3", Title: "Unpaid", Opportunity: "700|RUB"},
+	}
+}
+
+func TestComputeDealReportTotals(t *testing.T) {
+	totals, err := ComputeDealReportTotals(paymentDeals(), DealReportOptions{})
+	if err != nil {
+		t.Fatalf("ComputeDealReportTotals() error = %v", err)
+	}
+
+	expected := DealReportTotals{ByCurrency: []CurrencyTotals{{Opportunity: 4200.5, PaymentReceived: 2000.5, Outstanding: 2200}}}
+	if !reflect.DeepEqual(totals, expected) {
+		t.Errorf("ComputeDealReportTotals() = %+v, want %+v", totals, expected)
+	}
+}
+
+// currencyDeals are RUB and EUR deals
+func currencyDeals() []bitrix.DealReportRow {
+	return []bitrix.DealReportRow{
+		{ID: "1", Opportunity: "1000|RUB", PaymentReceived: "400", CurrencyID: "RUB"},
+		{ID: "2", Opportunity: "300|EUR", PaymentReceived: "100", CurrencyID: "EUR"},
+		{ID: "3", Opportunity: "500|RUB", CurrencyID: "rub"},
+	}
+}
+
+func TestComputeDealReportTotalsByCurrency(t *testing.T) {
+	totals, err := ComputeDealReportTotals(currencyDeals(), DealReportOptions{})
+	if err != nil {
+		t.Fatalf("ComputeDealReportTotals() error = %v", err)
+	}
+
+	expected := DealReportTotals{ByCurrency: []CurrencyTotals{
+		{Currency: "EUR", Opportunity: 300, PaymentReceived: 100, Outstanding: 200},
+		{Currency: "RUB", Opportunity: 1500, PaymentReceived: 400, Outstanding: 1100},
+	}}
+	if !reflect.DeepEqual(totals, expected) {
+		t.Errorf("ComputeDealReportTotals() = %+v, want %+v", totals, expected)
+	}
+
+	var buf bytes.Buffer
+	if err := FormatReportAsTable(currencyDeals(), nil, &buf, DealReportOptions{}); err != nil {
+		t.Fatalf("FormatReportAsTable() error = %v", err)
+	}
+	if want := "Сумма сделок: 300 EUR; 1500 RUB"; !strings.Contains(buf.String(), want) {
+		t.Errorf("expected footer to contain %q, got:\n%s", want, buf.String())
+	}
+}
+
+func TestComputeDealReportTotalsConverted(t *testing.T) {
+	options := DealReportOptions{ConvertTo: "rub", ExchangeRates: map[string]float64{"RUB": 1, "EUR": 100}}
+	totals, err := ComputeDealReportTotals(currencyDeals(), options)
+	if err != nil {
+		t.Fatalf("ComputeDealReportTotals() error = %v", err)
+	}
+
+	expected := &CurrencyTotals{Currency: "RUB", Opportunity: 31500, PaymentReceived: 10400, Outstanding: 21100}
+	if !reflect.DeepEqual(totals.Converted, expected) {
+		t.Errorf("Converted = %+v, want %+v", totals.Converted, expected)
+	}
+
+	var buf bytes.Buffer
+	if err := FormatReportAsCSV(currencyDeals(), nil, &buf, options); err != nil {
+		t.Fatalf("FormatReportAsCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV output: %v", err)
+	}
+	if last := records[len(records)-1]; last[0] != "К оплате RUB (по курсам)" || last[8] != "21100" {
+		t.Errorf("unexpected converted CSV row: %q", last)
+	}
+
+	options.ExchangeRates = map[string]float64{"RUB": 1}
+	if _, err := ComputeDealReportTotals(currencyDeals(), options); err == nil || !strings.Contains(err.Error(), "EUR") {
+		t.Errorf("expected missing EUR rate error, got %v", err)
+	}
+}
+
+func TestComputeDealReportTotalsBaseCurrency(t *testing.T) {
+	deals := append(currencyDeals(), bitrix.DealReportRow{ID: "4", Opportunity: "200"})
+	options := DealReportOptions{ConvertTo: "RUB", ExchangeRates: map[string]float64{"RUB": 1, "EUR": 100}}
+
+	// Without the base currency a deal without CURRENCY_ID cannot be converted
+	if _, err := ComputeDealReportTotals(deals, options); err == nil {
+		t.Error("expected missing rate error for a deal without currency")
+	}
+
+	options.BaseCurrency = "rub"
+	totals, err := ComputeDealReportTotals(deals, options)
+	if err != nil {
+		t.Fatalf("ComputeDealReportTotals() error = %v", err)
+	}
+	if len(totals.ByCurrency) != 2 || totals.ByCurrency[1].Currency != "RUB" || totals.ByCurrency[1].Opportunity != 1700 {
+		t.Errorf("ByCurrency = %+v, want the deal without currency added to RUB", totals.ByCurrency)
+	}
+	if totals.Converted == nil || totals.Converted.Opportunity != 31700 {
+		t.Errorf("Converted = %+v, want opportunity 31700", totals.Converted)
+	}
+}
+
+// TestFormatReportTotals tests the totals footer in text, CSV and JSON output
+func TestFormatReportTotals(t *testing.T) {
+	deals := paymentDeals()
+
+	var buf bytes.Buffer
+	if err := FormatReportAsTable(deals, nil, &buf, DealReportOptions{}); err != nil {
+		t.Fatalf("FormatReportAsTable() error = %v", err)
+	}
+	for _, want := range []string{"Сумма сделок: 4200.50", "Оплачено: 2000.50", "К оплате: 2200\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected table footer to contain %q, got:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := FormatReportAsCSV(deals, nil, &buf, DealReportOptions{}); err != nil {
+		t.Fatalf("FormatReportAsCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read CSV output: %v", err)
+	}
+	total, outstanding := records[len(records)-2], records[len(records)-1]
+	if total[0] != "Итого" || total[8] != "4200.50" || total[9] != "2000.50" {
+		t.Errorf("unexpected CSV total row: %q", total)
+	}
+	if outstanding[0] != "К оплате" || outstanding[8] != "2200" {
+		t.Errorf("unexpected CSV outstanding row: %q", outstanding)
+	}
+
+	buf.Reset()
+	if err := FormatReportAsJSON(deals, nil, &buf, DealReportOptions{}); err != nil {
+		t.Fatalf("FormatReportAsJSON() error = %v", err)
+	}
+	var report struct {
+		Deals  []map[string]interface{} `json:"deals"`
+		Totals DealReportTotals         `json:"totals"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode JSON output: %v\n%s", err, buf.String())
+	}
+	if len(report.Deals) != 3 || report.Deals[0]["opportunity"] != "1000" {
+		t.Errorf("unexpected JSON deals: %v", report.Deals)
+	}
+	if len(report.Totals.ByCurrency) != 1 || report.Totals.ByCurrency[0].Outstanding != 2200 {
+		t.Errorf("JSON totals = %+v, want outstanding 2200", report.Totals)
+	}
+}
@@ -0,0 +1,195 @@
+package formatter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"farmix-cli/internal/bitrix"
+)
+
+// Store status labels; inactive stores are shown in capitals to stand out in the table
+const (
+	storeStatusActive   = "активен"
+	storeStatusInactive = "НЕАКТИВЕН"
+)
+
+// jsonStore is a store in JSON output; null code and address are kept as null
+type jsonStore struct {
+	ID      int     `json:"id"`
+	Title   string  `json:"title"`
+	Active  bool    `json:"active"`
+	Address *string `json:"address"`
+	Code    *string `json:"code"`
+}
+
+// FormatStoresAsTable formats warehouses as ASCII table with aligned columns
+func FormatStoresAsTable(stores []bitrix.Store, writer io.Writer) error {
+	if len(stores) == 0 {
+		fmt.Fprintf(writer, "Нет складов для отображения\n")
+		return nil
+	}
+
+	headers := []string{"ID", "Название", "Статус", "Адрес", "Код"}
+
+	rows := make([][]string, len(stores))
+	for i, store := range stores {
+		rows[i] = []string{
+			strconv.Itoa(int(store.ID)),
+			store.Title,
+			storeStatus(store),
+			stringOrDash(store.Address),
+			stringOrDash(store.Code),
+		}
+	}
+
+	// Calculate column widths (considering UTF-8 characters)
+	colWidths := make([]int, len(headers))
+	for i, header := range headers {
+		colWidths[i] = utf8.RuneCountInString(header)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if cellWidth := utf8.RuneCountInString(cell); cellWidth > colWidths[i] {
+				colWidths[i] = cellWidth
+			}
+		}
+	}
+
+	// ID is the only numeric column
+	rightAligned := func(i int) bool { return i == 0 }
+
+	printBorder(writer, colWidths, "┌", "┬", "┐")
+	printAlignedRow(writer, headers, colWidths, rightAligned)
+	printBorder(writer, colWidths, "├", "┼", "┤")
+	for _, row := range rows {
+		printAlignedRow(writer, row, colWidths, rightAligned)
+	}
+	printBorder(writer, colWidths, "└", "┴", "┘")
+
+	fmt.Fprintf(writer, "\nВсего складов: %d\n", len(stores))
+	fmt.Fprintf(writer, "ID склада для команды crm-add-store задается ключом store_id в ~/.farmix-cli\n")
+
+	return nil
+}
+
+// FormatStoresAsCSV formats warehouses as CSV, null code and address are empty
+func FormatStoresAsCSV(stores []bitrix.Store, writer io.Writer) error {
+	csvWriter := csv.NewWriter(writer)
+	defer csvWriter.Flush()
+
+	headers := []string{"ID", "Название", "Активен", "Адрес", "Код"}
+	if err := csvWriter.Write(headers); err != nil {
+		return fmt.Errorf("failed to write CSV headers: %w", err)
+	}
+
+	for _, store := range stores {
+		record := []string{
+			strconv.Itoa(int(store.ID)),
+			store.Title,
+			store.Active,
+			stringOrEmpty(store.Address),
+			stringOrEmpty(store.Code),
+		}
+		if err := csvWriter.Write(record); err != nil {
+			return fmt.Errorf("failed to write CSV record: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// FormatStoresAsJSON formats warehouses as JSON array
+func FormatStoresAsJSON(stores []bitrix.Store, writer io.Writer) error {
+	result := make([]jsonStore, 0, len(stores))
+	for _, store := range stores {
+		result = append(result, jsonStore{
+			ID:      int(store.ID),
+			Title:   store.Title,
+			Active:  store.Active == "Y",
+			Address: store.Address,
+			Code:    store.Code,
+		})
+	}
+
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(result); err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+
+	return nil
+}
+
+// FormatStoreReconciliationAsTable prints deal and store document quantities per product;
+// mismatched rows are marked in capitals
+func FormatStoreReconciliationAsTable(rows []bitrix.StoreReconciliationRow, writer io.Writer) error {
+	headers := []string{"ID товара", "В сделке", "В документе", "Сверка"}
+
+	cells := make([][]string, len(rows))
+	for i, row := range rows {
+		status := "ok"
+		if !row.Matches() {
+			status = "РАСХОЖДЕНИЕ"
+		}
+		cells[i] = []string{
+			row.ProductID,
+			strconv.FormatFloat(row.DealQuantity, 'f', -1, 64),
+			strconv.FormatFloat(row.DocumentQuantity, 'f', -1, 64),
+			status,
+		}
+	}
+
+	colWidths := make([]int, len(headers))
+	for i, header := range headers {
+		colWidths[i] = utf8.RuneCountInString(header)
+	}
+	for _, row := range cells {
+		for i, cell := range row {
+			if cellWidth := utf8.RuneCountInString(cell); cellWidth > colWidths[i] {
+				colWidths[i] = cellWidth
+			}
+		}
+	}
+
+	// Quantities are right-aligned
+	rightAligned := func(i int) bool { return i == 1 || i == 2 }
+
+	printBorder(writer, colWidths, "┌", "┬", "┐")
+	printAlignedRow(writer, headers, colWidths, rightAligned)
+	printBorder(writer, colWidths, "├", "┼", "┤")
+	for _, row := range cells {
+		printAlignedRow(writer, row, colWidths, rightAligned)
+	}
+	printBorder(writer, colWidths, "└", "┴", "┘")
+
+	return nil
+}
+
+// storeStatus returns the status label of a store
+func storeStatus(store bitrix.Store) string {
+	if store.Active == "Y" {
+		return storeStatusActive
+	}
+	return storeStatusInactive
+}
+
+// stringOrDash returns the value or "—" for null and empty values
+func stringOrDash(value *string) string {
+	if value == nil || *value == "" {
+		return unknownCategoryName
+	}
+	return *value
+}
+
+// stringOrEmpty returns the value or empty string for null
+func stringOrEmpty(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
\ No newline at end of file
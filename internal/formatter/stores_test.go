@@ -0,0 +1,108 @@
+package formatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"farmix-cli/internal/bitrix"
+)
+
+func testStores() []bitrix.Store {
+	address := "ул. Ленина, 1"
+	code := "MAIN"
+	return []bitrix.Store{
+		{ID: 1, Title: "Основной склад", Active: "Y", Address: &address, Code: &code},
+		{ID: 12, Title: "Старый склад", Active: "N"},
+	}
+}
+
+func TestFormatStoresAsTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FormatStoresAsTable(testStores(), &buf); err != nil {
+		t.Fatalf("FormatStoresAsTable() error = %v", err)
+	}
+	output := buf.String()
+
+	lines := strings.Split(output, "\n")
+	var activeRow, inactiveRow string
+	for _, line := range lines {
+		if strings.Contains(line, "Основной склад") {
+			activeRow = line
+		}
+		if strings.Contains(line, "Старый склад") {
+			inactiveRow = line
+		}
+	}
+
+	for _, want := range []string{"│  1 │", storeStatusActive, "ул. Ленина, 1", "MAIN"} {
+		if !strings.Contains(activeRow, want) {
+			t.Errorf("active store row %q missing %q", activeRow, want)
+		}
+	}
+	for _, want := range []string{"│ 12 │", storeStatusInactive, "│ —"} {
+		if !strings.Contains(inactiveRow, want) {
+			t.Errorf("inactive store row %q missing %q", inactiveRow, want)
+		}
+	}
+	if !strings.Contains(output, "Всего складов: 2") {
+		t.Errorf("expected store count in output:\n%s", output)
+	}
+}
+
+func TestFormatStoresAsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FormatStoresAsCSV(testStores(), &buf); err != nil {
+		t.Fatalf("FormatStoresAsCSV() error = %v", err)
+	}
+
+	expected := "ID,Название,Активен,Адрес,Код\n" +
+		"1,Основной склад,Y,\"ул. Ленина, 1\",MAIN\n" +
+		"12,Старый склад,N,,\n"
+	if buf.String() != expected {
+		t.Errorf("FormatStoresAsCSV() =\n%s\nwant\n%s", buf.String(), expected)
+	}
+}
+
+func TestFormatStoresAsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := FormatStoresAsJSON(testStores(), &buf); err != nil {
+		t.Fatalf("FormatStoresAsJSON() error = %v", err)
+	}
+
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("expected 2 stores, got %d", len(decoded))
+	}
+	if decoded[0]["code"] != "MAIN" || decoded[0]["active"] != true {
+		t.Errorf("unexpected first store: %v", decoded[0])
+	}
+	if decoded[1]["code"] != nil || decoded[1]["address"] != nil || decoded[1]["active"] != false {
+		t.Errorf("expected null code/address and inactive second store, got %v", decoded[1])
+	}
+}
+
+func TestFormatStoreReconciliationAsTable(t *testing.T) {
+	rows := []bitrix.StoreReconciliationRow{
+		{ProductID: "10", DealQuantity: 2, DocumentQuantity: 2},
+		{ProductID: "11", DealQuantity: 1.5, DocumentQuantity: 1},
+	}
+
+	var buf bytes.Buffer
+	if err := FormatStoreReconciliationAsTable(rows, &buf); err != nil {
+		t.Fatalf("FormatStoreReconciliationAsTable() error = %v", err)
+	}
+
+	for _, want := range []string{
+		"│ 10        │        2 │           2 │ ok          │",
+		"│ 11        │      1.5 │           1 │ РАСХОЖДЕНИЕ │",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package parser
+
+import (
+	"fmt"
+	"io/fs"
+	"math"
+	"strings"
+)
+
+// maxComponentDepth ограничивает вложенность компонентов при обходе (защита от циклических ссылок)
+const maxComponentDepth = 16
+
+// Dimensions - габариты объекта в миллиметрах по осям X (ширина), Y (глубина) и Z (высота)
+type Dimensions struct {
+	Width  float64 `json:"width"`
+	Depth  float64 `json:"depth"`
+	Height float64 `json:"height"`
+}
+
+// String форматирует габариты как "Ш x Г x В" с одним знаком после запятой
+func (d Dimensions) String() string {
+	return fmt.Sprintf("%.1f x %.1f x %.1f", d.Width, d.Depth, d.Height)
+}
+
+// unitScale - множители перевода единиц модели 3MF в миллиметры
+var unitScale = map[string]float64{
+	"micron":     0.001,
+	"millimeter": 1,
+	"centimeter": 10,
+	"inch":       25.4,
+	"foot":       304.8,
+	"meter":      1000,
+}
+
+// Apply переводит точку модели в координаты родителя.
+// Матрица 3MF хранится по строкам 4x3: x' = x*m00 + y*m10 + z*m20 + m30 и т.д.
+func (t Transform3D) Apply(v Vertex) Vertex {
+	m := t.Matrix
+	return Vertex{
+		X: v.X*m[0] + v.Y*m[3] + v.Z*m[6] + m[9],
+		Y: v.X*m[1] + v.Y*m[4] + v.Z*m[7] + m[10],
+		Z: v.X*m[2] + v.Y*m[5] + v.Z*m[8] + m[11],
+	}
+}
+
+// then возвращает преобразование "сначала t, потом outer"
+func (t Transform3D) then(outer Transform3D) Transform3D {
+	var result Transform3D
+	for row := 0; row < 4; row++ {
+		for col := 0; col < 3; col++ {
+			var value float64
+			for k := 0; k < 3; k++ {
+				value += t.Matrix[row*3+k] * outer.Matrix[k*3+col]
+			}
+			if row == 3 {
+				value += outer.Matrix[9+col]
+			}
+			result.Matrix[row*3+col] = value
+		}
+	}
+	return result
+}
+
+// boundingBox - границы набора точек
+type boundingBox struct {
+	min, max Vertex
+	empty    bool
+}
+
+func newBoundingBox() *boundingBox {
+	return &boundingBox{empty: true}
+}
+
+func (b *boundingBox) add(v Vertex) {
+	if b.empty {
+		b.min, b.max, b.empty = v, v, false
+		return
+	}
+	b.min = Vertex{X: math.Min(b.min.X, v.X), Y: math.Min(b.min.Y, v.Y), Z: math.Min(b.min.Z, v.Z)}
+	b.max = Vertex{X: math.Max(b.max.X, v.X), Y: math.Max(b.max.Y, v.Y), Z: math.Max(b.max.Z, v.Z)}
+}
+
+// dimensions возвращает габариты, умноженные на scale (перевод в мм), или nil для пустых границ
+func (b *boundingBox) dimensions(scale float64) *Dimensions {
+	if b.empty {
+		return nil
+	}
+	return &Dimensions{
+		Width:  (b.max.X - b.min.X) * scale,
+		Depth:  (b.max.Y - b.min.Y) * scale,
+		Height: (b.max.Z - b.min.Z) * scale,
+	}
+}
+
+// meshResolver находит объекты сетки в основной модели и во внешних файлах компонентов
+// (Bambu/Orca хранят сетки в 3D/Objects/*.model). Разобранные файлы кэшируются по пути
+type meshResolver struct {
+	fsys   fs.FS
+	main   *Model3D
+	models map[string]*Model3D
+	// painted - есть ли в файле данные покраски (см. hasPaintData), чтобы не разбирать сетки без покраски
+	painted map[string]bool
+	// states - состояния покраски по объектам (PaintStates), объект может размещаться на столах несколько раз
+	states map[*ModelObject][]int
+}
+
+func newMeshResolver(fsys fs.FS, main *Model3D) *meshResolver {
+	return &meshResolver{fsys: fsys, main: main, models: make(map[string]*Model3D), painted: make(map[string]bool), states: make(map[*ModelObject][]int)}
+}
+
+// model возвращает модель по пути компонента (пустой путь - основная модель) или nil, если файл не читается
+func (r *meshResolver) model(path string) *Model3D {
+	if path == "" {
+		return r.main
+	}
+	if model, exists := r.models[path]; exists {
+		return model
+	}
+	model, err := ParseAssemblyModel(r.fsys, path)
+	if err != nil {
+		model = nil
+	}
+	r.models[path] = model
+	return model
+}
+
+// object ищет объект по ID в модели по пути компонента
+func (r *meshResolver) object(path string, id int) *ModelObject {
+	model := r.model(path)
+	if model == nil {
+		return nil
+	}
+	for i := range model.Resources {
+		if model.Resources[i].ID == id {
+			return &model.Resources[i]
+		}
+	}
+	return nil
+}
+
+// ObjectDimensions считает габариты объекта сборки в миллиметрах: вершины сетки
+// (включая сетки компонентов) переводятся преобразованием размещения на столе.
+// Возвращает nil, если у объекта нет вершин
+func (r *meshResolver) ObjectDimensions(obj *ModelObject, placement Transform3D) *Dimensions {
+	box := newBoundingBox()
+	r.expand(box, obj, "", placement, 0)
+
+	scale, exists := unitScale[strings.ToLower(strings.TrimSpace(r.main.Unit))]
+	if !exists {
+		scale = 1 // по спецификации 3MF единица по умолчанию - миллиметр
+	}
+	return box.dimensions(scale)
+}
+
+// expand добавляет в box вершины объекта и его компонентов; path - файл, в котором объявлен объект
+func (r *meshResolver) expand(box *boundingBox, obj *ModelObject, path string, transform Transform3D, depth int) {
+	r.walk(obj, path, transform, depth, nil, func(mesh *Mesh, transform Transform3D) {
+		for _, vertex := range mesh.Vertices {
+			box.add(transform.Apply(vertex))
+		}
+	})
+}
+
+// walk вызывает visit для сетки объекта и сеток его компонентов с итоговым преобразованием.
+// Если skip не nil, внешние файлы компонентов, для которых он возвращает true, не читаются
+func (r *meshResolver) walk(obj *ModelObject, path string, transform Transform3D, depth int, skip func(path string) bool, visit func(mesh *Mesh, transform Transform3D)) {
+	if obj == nil || depth > maxComponentDepth {
+		return
+	}
+
+	if obj.Mesh != nil {
+		visit(obj.Mesh, transform)
+	}
+
+	if obj.Components == nil {
+		return
+	}
+	for _, comp := range obj.Components.Components {
+		compPath := path
+		if comp.Path != "" {
+			compPath = comp.Path
+		}
+		if skip != nil && compPath != path && skip(compPath) {
+			continue
+		}
+		child := r.object(compPath, comp.ObjectID)
+		r.walk(child, compPath, ParseTransform(comp.Transform).then(transform), depth+1, skip, visit)
+	}
+}
\ No newline at end of file
@@ -0,0 +1,95 @@
+package parser
+
+import (
+	"math"
+	"testing"
+)
+
+// boxMesh - параллелепипед 20x10x5 мм (только вершины, треугольники для габаритов не нужны)
+const boxMesh = `<mesh><vertices>
+ <vertex x="0" y="0" z="0"/><vertex x="20" y="0" z="0"/><vertex x="20" y="10" z="0"/><vertex x="0" y="10" z="0"/>
+ <vertex x="0" y="0" z="5"/><vertex x="20" y="0" z="5"/><vertex x="20" y="10" z="5"/><vertex x="0" y="10" z="5"/>
+</vertices><triangles/></mesh>`
+
+// boxObjectModel - сетка во внешнем файле, как у Bambu/Orca (3D/Objects/*.model)
+const boxObjectModel = `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+ <resources><object id="1" type="model">` + boxMesh + `</object></resources>
+ <build/>
+</model>`
+
+// boxMainModel размещает объект-сборку дважды: без поворота и с поворотом на 90° вокруг Z
+const boxMainModel = `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+ <resources>
+  <object id="2" type="model" name="Box"><components>
+   <component p:path="/3D/Objects/box.model" xmlns:p="http://schemas.microsoft.com/3dmanufacturing/production/1015/06" objectid="1" transform="1 0 0 0 1 0 0 0 1 -10 -5 0"/>
+  </components></object>
+  <object id="3" type="model" name="Cube">` + boxMesh + `</object>
+ </resources>
+ <build>
+  <item objectid="2" transform="1 0 0 0 1 0 0 0 1 100 100 0"/>
+  <item objectid="2" transform="0 1 0 -1 0 0 0 0 1 50 50 0"/>
+  <item objectid="3" transform="2 0 0 0 2 0 0 0 2 0 0 0"/>
+ </build>
+</model>`
+
+func TestParse3MFObjectDimensions(t *testing.T) {
+	path := writeTest3MF(t, map[string]string{
+		"3D/3dmodel.model":     boxMainModel,
+		"3D/Objects/box.model": boxObjectModel,
+	})
+
+	plain, err := Parse3MF(path)
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if dims := plain.Plates[0].Objects[0].Dimensions; dims != nil {
+		t.Errorf("Parse3MF() computed dimensions %v without WithDimensions", dims)
+	}
+
+	data, err := Parse3MF(path, WithDimensions(true))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+
+	objects := data.Plates[0].Objects
+	want := []Dimensions{
+		{Width: 20, Depth: 10, Height: 5},
+		{Width: 10, Depth: 20, Height: 5},  // повернут на 90°
+		{Width: 40, Depth: 20, Height: 10}, // масштаб x2
+	}
+	if len(objects) != len(want) {
+		t.Fatalf("expected %d objects, got %d", len(want), len(objects))
+	}
+	for i, obj := range objects {
+		if obj.Dimensions == nil {
+			t.Fatalf("object %d (%s): no dimensions", i, obj.Name)
+		}
+		if !sameDimensions(*obj.Dimensions, want[i]) {
+			t.Errorf("object %d (%s) dimensions = %v, want %v", i, obj.Name, *obj.Dimensions, want[i])
+		}
+	}
+
+	groups := GroupObjectsByName(objects)
+	if group := groups["Box|assembly|"]; group.Dimensions == nil || !sameDimensions(*group.Dimensions, want[0]) {
+		t.Errorf("group dimensions = %v, want %v", group.Dimensions, want[0])
+	}
+}
+
+func TestObjectDimensionsUnits(t *testing.T) {
+	model := &Model3D{Unit: "centimeter", Resources: []ModelObject{{ID: 1, Mesh: &Mesh{Vertices: []Vertex{{0, 0, 0}, {2, 1, 0.5}}}}}}
+	got := newMeshResolver(nil, model).ObjectDimensions(&model.Resources[0], ParseTransform(""))
+	if got == nil || !sameDimensions(*got, Dimensions{Width: 20, Depth: 10, Height: 5}) {
+		t.Errorf("ObjectDimensions() = %v, want 20 x 10 x 5", got)
+	}
+
+	if got := newMeshResolver(nil, model).ObjectDimensions(&ModelObject{ID: 2}, ParseTransform("")); got != nil {
+		t.Errorf("ObjectDimensions() without mesh = %v, want nil", got)
+	}
+}
+
+func sameDimensions(a, b Dimensions) bool {
+	const eps = 1e-9
+	return math.Abs(a.Width-b.Width) < eps && math.Abs(a.Depth-b.Depth) < eps && math.Abs(a.Height-b.Height) < eps
+}
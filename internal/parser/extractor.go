@@ -4,10 +4,19 @@ import (
 	"archive/zip"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
 )
 
+// maxExtractedSize ограничивает суммарный размер распакованных файлов (защита от ZIP-бомб)
+var maxExtractedSize int64 = 2 << 30 // 2 ГБ
+
+// ExtractArchive распаковывает архив во временную директорию ОС (os.TempDir: TMPDIR на Unix,
+// TMP/TEMP на Windows). Директорию удаляет вызывающий код через CleanupTemp
 func ExtractArchive(archivePath string) (string, error) {
 	reader, err := zip.OpenReader(archivePath)
 	if err != nil {
@@ -15,13 +24,14 @@ func ExtractArchive(archivePath string) (string, error) {
 	}
 	defer reader.Close()
 
-	tempDir, err := os.MkdirTemp("/tmp", "3mf_*")
+	tempDir, err := os.MkdirTemp("", "3mf_*")
 	if err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
 	}
 
+	remaining := maxExtractedSize
 	for _, file := range reader.File {
-		if err := extractFile(file, tempDir); err != nil {
+		if err := extractFile(file, tempDir, &remaining); err != nil {
 			os.RemoveAll(tempDir)
 			return "", fmt.Errorf("failed to extract file %s: %w", file.Name, err)
 		}
@@ -30,15 +40,24 @@ func ExtractArchive(archivePath string) (string, error) {
 	return tempDir, nil
 }
 
-func extractFile(file *zip.File, destDir string) error {
+// extractFile распаковывает файл архива в destDir, уменьшая remaining на размер файла.
+// Пути вне destDir (Zip Slip) и превышение лимита размера отклоняются
+func extractFile(file *zip.File, destDir string, remaining *int64) error {
+	destPath, err := safeExtractPath(destDir, file.Name)
+	if err != nil {
+		return err
+	}
+
+	if file.UncompressedSize64 > uint64(*remaining) {
+		return fmt.Errorf("archive exceeds extracted size limit of %d bytes", maxExtractedSize)
+	}
+
 	rc, err := file.Open()
 	if err != nil {
 		return err
 	}
 	defer rc.Close()
 
-	destPath := filepath.Join(destDir, file.Name)
-
 	if file.FileInfo().IsDir() {
 		return os.MkdirAll(destPath, file.FileInfo().Mode())
 	}
@@ -53,8 +72,99 @@ func extractFile(file *zip.File, destDir string) error {
 	}
 	defer outFile.Close()
 
-	_, err = io.Copy(outFile, rc)
-	return err
+	// Заявленный в архиве размер может быть подделан, поэтому лимит проверяется и при копировании
+	written, err := io.CopyN(outFile, rc, *remaining+1)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if written > *remaining {
+		return fmt.Errorf("archive exceeds extracted size limit of %d bytes", maxExtractedSize)
+	}
+	*remaining -= written
+	return nil
+}
+
+// safeExtractPath возвращает путь для файла архива внутри destDir
+// и отклоняет имена, выходящие за его пределы (например, "../evil.txt")
+func safeExtractPath(destDir, name string) (string, error) {
+	destPath := filepath.Join(destDir, name)
+
+	rel, err := filepath.Rel(destDir, destPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("illegal file path in archive: %s", name)
+	}
+	return destPath, nil
+}
+
+// maxArchiveFileSize ограничивает размер одной записи архива, читаемой в память
+var maxArchiveFileSize int64 = 1 << 30 // 1 ГБ
+
+// readArchiveFile читает запись архива целиком в память (с ограничением размера).
+// name - путь внутри архива, ведущий "/" допускается
+func readArchiveFile(fsys fs.FS, name string) ([]byte, error) {
+	file, err := fsys.Open(archivePath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxArchiveFileSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxArchiveFileSize {
+		return nil, fmt.Errorf("%s exceeds size limit of %d bytes", name, maxArchiveFileSize)
+	}
+	return data, nil
+}
+
+// readBudgetFS ограничивает суммарный объем, прочитанный из записей архива за один разбор,
+// тем же maxExtractedSize, что и распаковку: лимит одной записи не защищает от множества больших записей
+type readBudgetFS struct {
+	fs.FS
+	remaining *atomic.Int64
+}
+
+func newReadBudgetFS(fsys fs.FS, limit int64) readBudgetFS {
+	remaining := &atomic.Int64{}
+	remaining.Store(limit)
+	return readBudgetFS{FS: fsys, remaining: remaining}
+}
+
+func (b readBudgetFS) Open(name string) (fs.File, error) {
+	file, err := b.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &budgetFile{File: file, remaining: b.remaining}, nil
+}
+
+// ReadDir и Stat не читают содержимое записей и передаются исходной файловой системе
+func (b readBudgetFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(b.FS, name)
+}
+
+func (b readBudgetFS) Stat(name string) (fs.FileInfo, error) {
+	return fs.Stat(b.FS, name)
+}
+
+// budgetFile уменьшает общий остаток при каждом чтении
+type budgetFile struct {
+	fs.File
+	remaining *atomic.Int64
+}
+
+func (f *budgetFile) Read(p []byte) (int, error) {
+	n, err := f.File.Read(p)
+	if f.remaining.Add(-int64(n)) < 0 {
+		return n, fmt.Errorf("archive exceeds read size limit of %d bytes", maxExtractedSize)
+	}
+	return n, err
+}
+
+// archivePath приводит путь из 3MF (например, "/3D/Objects/part.model") к виду, принятому в fs.FS
+func archivePath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
 }
 
 func CleanupTemp(tempDir string) error {
@@ -0,0 +1,144 @@
+package parser
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// extractAll распаковывает архив в поддиректорию временного каталога, как ExtractArchive
+func extractAll(t *testing.T, archivePath, destDir string) error {
+	t.Helper()
+
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer reader.Close()
+
+	remaining := maxExtractedSize
+	for _, file := range reader.File {
+		if err := extractFile(file, destDir, &remaining); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestExtractRejectsZipSlip(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "Metadata/../../evil.txt"} {
+		t.Run(name, func(t *testing.T) {
+			archivePath := writeTest3MF(t, map[string]string{name: "evil"})
+
+			root := t.TempDir()
+			destDir := filepath.Join(root, "extract")
+			if err := os.Mkdir(destDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			err := extractAll(t, archivePath, destDir)
+			if err == nil || !strings.Contains(err.Error(), "illegal file path") {
+				t.Fatalf("expected illegal file path error, got %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(root, "evil.txt")); !os.IsNotExist(err) {
+				t.Error("file was written outside the destination directory")
+			}
+		})
+	}
+}
+
+func TestExtractArchiveRejectsZipSlip(t *testing.T) {
+	archivePath := writeTest3MF(t, map[string]string{"../evil.txt": "evil"})
+
+	if _, err := ExtractArchive(archivePath); err == nil || !strings.Contains(err.Error(), "illegal file path") {
+		t.Fatalf("ExtractArchive() error = %v, want illegal file path", err)
+	}
+}
+
+func TestSafeExtractPath(t *testing.T) {
+	destDir := filepath.Join(t.TempDir(), "extract")
+
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{"3D/3dmodel.model", false},
+		{"Metadata/../3D/3dmodel.model", false},
+		{"/3D/3dmodel.model", false},
+		{"..evil.txt", false},
+		{"..", true},
+		{"../evil.txt", true},
+		{"3D/../../evil.txt", true},
+	}
+
+	for _, tt := range tests {
+		path, err := safeExtractPath(destDir, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("safeExtractPath(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !strings.HasPrefix(path, destDir+string(filepath.Separator)) {
+			t.Errorf("safeExtractPath(%q) = %q, outside %q", tt.name, path, destDir)
+		}
+	}
+}
+
+func TestParseArchiveRejectsOversizedTotalRead(t *testing.T) {
+	files := map[string]string{
+		"3D/3dmodel.model":               twoExtruderModel,
+		"Metadata/model_settings.config": twoExtruderSettings,
+	}
+	archivePath := writeTest3MF(t, files)
+
+	// Каждая запись меньше лимита, но вместе они его превышают
+	defer func(limit int64) { maxExtractedSize = limit }(maxExtractedSize)
+	maxExtractedSize = int64(len(twoExtruderModel) + len(twoExtruderSettings)/2)
+
+	_, err := Parse3MF(archivePath)
+	if err == nil || !strings.Contains(err.Error(), "read size limit") {
+		t.Fatalf("expected read size limit error, got %v", err)
+	}
+}
+
+func TestExtractRejectsOversizedArchive(t *testing.T) {
+	defer func(limit int64) { maxExtractedSize = limit }(maxExtractedSize)
+	maxExtractedSize = 10
+
+	archivePath := writeTest3MF(t, map[string]string{
+		"a.txt": "12345",
+		"b.txt": "1234567890",
+	})
+
+	err := extractAll(t, archivePath, t.TempDir())
+	if err == nil || !strings.Contains(err.Error(), "size limit") {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+}
+
+func TestExtractArchiveUsesTempDirFromEnvironment(t *testing.T) {
+	customTemp := filepath.Join(t.TempDir(), "scratch")
+	if err := os.Mkdir(customTemp, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// os.TempDir читает TMPDIR на Unix и TMP/TEMP на Windows
+	for _, key := range []string{"TMPDIR", "TMP", "TEMP"} {
+		t.Setenv(key, customTemp)
+	}
+
+	archivePath := writeTest3MF(t, map[string]string{"3D/3dmodel.model": twoExtruderModel})
+
+	extractDir, err := ExtractArchive(archivePath)
+	if err != nil {
+		t.Fatalf("ExtractArchive() error = %v", err)
+	}
+	defer CleanupTemp(extractDir)
+
+	if filepath.Dir(extractDir) != customTemp {
+		t.Errorf("extracted to %s, want a directory inside %s", extractDir, customTemp)
+	}
+	if _, err := os.Stat(filepath.Join(extractDir, "3D", "3dmodel.model")); err != nil {
+		t.Errorf("model was not extracted: %v", err)
+	}
+}
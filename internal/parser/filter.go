@@ -0,0 +1,60 @@
+package parser
+
+import "strings"
+
+// PlateFilter - условия отбора столов и объектов (пустые поля не ограничивают выборку)
+type PlateFilter struct {
+	// PlateIDs - номера столов, которые нужно оставить
+	PlateIDs []int
+	// Material - подстрока названия материала без учета регистра (сравнивается с CleanMaterialName)
+	Material string
+	// PrintableOnly отбрасывает непечатаемые объекты (printable="0" в сборке: вспомогательная геометрия)
+	PrintableOnly bool
+}
+
+// IsEmpty сообщает, что фильтр ничего не отбрасывает
+func (f PlateFilter) IsEmpty() bool {
+	return len(f.PlateIDs) == 0 && strings.TrimSpace(f.Material) == "" && !f.PrintableOnly
+}
+
+// Filter возвращает копию проекта только с подходящими столами и объектами.
+// При фильтре по материалу остаются объекты этого материала, а столы без них пропускаются;
+// непечатаемые объекты при PrintableOnly убираются, но сам стол остается.
+// Данные нарезки стола (вес, время) не пересчитываются и относятся ко всему столу
+func (p *Parser3MF) Filter(filter PlateFilter) *Parser3MF {
+	if filter.IsEmpty() {
+		return p
+	}
+
+	plateIDs := make(map[int]bool, len(filter.PlateIDs))
+	for _, id := range filter.PlateIDs {
+		plateIDs[id] = true
+	}
+	material := strings.ToLower(strings.TrimSpace(filter.Material))
+
+	result := &Parser3MF{Plates: []PlateInfo{}, ExtractDir: p.ExtractDir}
+	for _, plate := range p.Plates {
+		if len(plateIDs) > 0 && !plateIDs[plate.PlateID] {
+			continue
+		}
+
+		if material != "" || filter.PrintableOnly {
+			var objects []PlateObject
+			for _, obj := range plate.Objects {
+				if filter.PrintableOnly && !obj.Printable {
+					continue
+				}
+				if material == "" || strings.Contains(strings.ToLower(CleanMaterialName(obj.Material)), material) {
+					objects = append(objects, obj)
+				}
+			}
+			if len(objects) == 0 && material != "" {
+				continue
+			}
+			plate.Objects = objects
+		}
+
+		result.Plates = append(result.Plates, plate)
+	}
+	return result
+}
\ No newline at end of file
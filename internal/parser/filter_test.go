@@ -0,0 +1,86 @@
+package parser
+
+import "testing"
+
+func filterTestData() *Parser3MF {
+	return &Parser3MF{Plates: []PlateInfo{
+		{PlateID: 1, Objects: []PlateObject{
+			{ID: 1, Name: "Body", Material: "Bambu PLA Basic(job.3mf)"},
+			{ID: 2, Name: "Clip", Material: "Generic PETG"},
+		}},
+		{PlateID: 2, Objects: []PlateObject{{ID: 3, Name: "Cover", Material: "Generic PLA"}}},
+		{PlateID: 3, Objects: []PlateObject{{ID: 4, Name: "Bracket", Material: "PETG-CF"}}},
+	}}
+}
+
+func TestParser3MFFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter PlateFilter
+		want   map[int][]string // plate ID -> object names
+	}{
+		{"empty filter", PlateFilter{}, map[int][]string{1: {"Body", "Clip"}, 2: {"Cover"}, 3: {"Bracket"}}},
+		{"plates", PlateFilter{PlateIDs: []int{1, 3}}, map[int][]string{1: {"Body", "Clip"}, 3: {"Bracket"}}},
+		{"material substring case-insensitive", PlateFilter{Material: "petg"}, map[int][]string{1: {"Clip"}, 3: {"Bracket"}}},
+		{"material ignores file suffix", PlateFilter{Material: "job"}, map[int][]string{}},
+		{"plate and material", PlateFilter{PlateIDs: []int{1, 2}, Material: "PLA"}, map[int][]string{1: {"Body"}, 2: {"Cover"}}},
+		{"unknown plate", PlateFilter{PlateIDs: []int{9}}, map[int][]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := filterTestData()
+			filtered := data.Filter(tt.filter)
+
+			if len(filtered.Plates) != len(tt.want) {
+				t.Fatalf("got %d plates, want %d: %+v", len(filtered.Plates), len(tt.want), filtered.Plates)
+			}
+			for _, plate := range filtered.Plates {
+				want, ok := tt.want[plate.PlateID]
+				if !ok {
+					t.Errorf("unexpected plate %d", plate.PlateID)
+					continue
+				}
+				if len(plate.Objects) != len(want) {
+					t.Errorf("plate %d has %d objects, want %v", plate.PlateID, len(plate.Objects), want)
+					continue
+				}
+				for i, obj := range plate.Objects {
+					if obj.Name != want[i] {
+						t.Errorf("plate %d object %d = %s, want %s", plate.PlateID, i, obj.Name, want[i])
+					}
+				}
+			}
+
+			if len(data.Plates) != 3 || len(data.Plates[0].Objects) != 2 {
+				t.Error("Filter must not modify the original project")
+			}
+		})
+	}
+}
+
+func TestParser3MFFilterPrintableOnly(t *testing.T) {
+	data := &Parser3MF{Plates: []PlateInfo{
+		{PlateID: 1, Objects: []PlateObject{
+			{ID: 1, Name: "Body", Material: "PLA", Printable: true},
+			{ID: 2, Name: "Brim helper", Material: "PLA", Printable: false},
+			{ID: 3, Name: "Clip", Material: "PETG", Printable: true},
+		}},
+		{PlateID: 2, Objects: []PlateObject{{ID: 4, Name: "Reference", Material: "PLA", Printable: false}}},
+	}}
+
+	filtered := data.Filter(PlateFilter{PrintableOnly: true})
+	if len(filtered.Plates) != 2 {
+		t.Fatalf("got %d plates, want 2 (plates are kept)", len(filtered.Plates))
+	}
+	if objects := filtered.Plates[0].Objects; len(objects) != 2 || objects[0].Name != "Body" || objects[1].Name != "Clip" {
+		t.Errorf("plate 1 objects = %+v, want Body and Clip", objects)
+	}
+	if objects := filtered.Plates[1].Objects; len(objects) != 0 {
+		t.Errorf("plate 2 objects = %+v, want none", objects)
+	}
+
+	if all := data.Filter(PlateFilter{}); len(all.Plates[0].Objects) != 3 {
+		t.Error("non-printable objects must be kept without PrintableOnly")
+	}
+}
@@ -1,10 +1,15 @@
 package parser
 
+import "strings"
+
 func GroupObjectsByName(objects []PlateObject) map[string]GroupedObject {
 	groups := make(map[string]GroupedObject)
 
 	for _, obj := range objects {
 		key := obj.Name + "|" + obj.Type + "|" + obj.Material // Группируем по имени, типу и материалу
+		if len(obj.Materials) > 0 {
+			key += "|" + strings.Join(obj.Materials, ",") // и по набору материалов покраски
+		}
 		
 		if existing, exists := groups[key]; exists {
 			// Увеличиваем счетчик и добавляем ID
@@ -20,9 +25,123 @@ func GroupObjectsByName(objects []PlateObject) map[string]GroupedObject {
 				Count:      1,
 				Components: obj.Components,
 				ObjectIDs:  []int{obj.ID},
+				Dimensions: obj.Dimensions,
+				Materials:  obj.Materials,
 			}
 		}
 	}
 
 	return groups
+}
+
+// GroupObjectsByNameAndMaterial группирует объекты по имени, типу и очищенному
+// названию материала (см. CleanMaterialName). В отличие от GroupObjectsByName
+// варианты одного материала ("PLA" и "PLA (Red)") попадают в одну группу,
+// а одноименные объекты из разных материалов остаются в разных группах.
+// Material и Materials группы содержат очищенные названия.
+func GroupObjectsByNameAndMaterial(objects []PlateObject) map[string]GroupedObject {
+	groups := make(map[string]GroupedObject)
+
+	for _, obj := range objects {
+		material := CleanMaterialName(obj.Material)
+		materials := CleanMaterialNames(obj.Materials)
+		key := obj.Name + "|" + obj.Type + "|" + material
+		if len(materials) > 0 {
+			key += "|" + strings.Join(materials, ",")
+		}
+
+		if existing, exists := groups[key]; exists {
+			existing.Count++
+			existing.ObjectIDs = append(existing.ObjectIDs, obj.ID)
+			groups[key] = existing
+		} else {
+			groups[key] = GroupedObject{
+				Name:       obj.Name,
+				Type:       obj.Type,
+				Material:   material,
+				Count:      1,
+				Components: obj.Components,
+				ObjectIDs:  []int{obj.ID},
+				Dimensions: obj.Dimensions,
+				Materials:  materials,
+			}
+		}
+	}
+
+	return groups
+}
+
+// MaterialNames возвращает все материалы объекта (без очистки): материалы покраски
+// или единственный Material; пустой список, если материал неизвестен
+func (o PlateObject) MaterialNames() []string {
+	if len(o.Materials) > 0 {
+		return o.Materials
+	}
+	if o.Material == "" {
+		return nil
+	}
+	return []string{o.Material}
+}
+
+// MaterialLabel возвращает очищенное название материала группы для вывода,
+// для раскрашенных объектов - все материалы через запятую
+func (g GroupedObject) MaterialLabel() string {
+	if len(g.Materials) > 0 {
+		return strings.Join(CleanMaterialNames(g.Materials), ", ")
+	}
+	return CleanMaterialName(g.Material)
+}
+
+// CleanMaterialNames очищает названия материалов (CleanMaterialName) и убирает повторы,
+// сохраняя порядок
+func CleanMaterialNames(materials []string) []string {
+	var cleaned []string
+	seen := make(map[string]bool)
+	for _, material := range materials {
+		name := CleanMaterialName(material)
+		if !seen[name] {
+			seen[name] = true
+			cleaned = append(cleaned, name)
+		}
+	}
+	return cleaned
+}
+
+// CleanMaterialName удаляет все группы в скобках в конце названия материала.
+// Правило: пока строка заканчивается на ")", отрезается вся завершающая группа
+// вместе с вложенными скобками. Скобки в середине названия сохраняются.
+// Например:
+//
+//	"Eryone ASA-GF(opengrid-9x9.3mf)" -> "Eryone ASA-GF"
+//	"Eryone ASA-GF (opengrid) (v2)"   -> "Eryone ASA-GF"
+//	"PLA (Matte)(spool-3.3mf)"        -> "PLA"
+//	"PLA (Matte) Black (spool)"       -> "PLA (Matte) Black"
+func CleanMaterialName(material string) string {
+	cleaned := strings.TrimSpace(material)
+	for strings.HasSuffix(cleaned, ")") {
+		start := findOpeningParen(cleaned)
+		if start <= 0 {
+			// Непарные скобки или название целиком в скобках - оставляем как есть
+			break
+		}
+		cleaned = strings.TrimSpace(cleaned[:start])
+	}
+	return cleaned
+}
+
+// findOpeningParen возвращает индекс "(", парной к завершающей ")", или -1
+func findOpeningParen(s string) int {
+	depth := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		switch s[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
 }
\ No newline at end of file
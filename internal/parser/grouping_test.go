@@ -0,0 +1,69 @@
+package parser
+
+import "testing"
+
+func TestCleanMaterialName(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"no parentheses", "Bambu PLA Basic", "Bambu PLA Basic"},
+		{"single group without space", "Eryone ASA-GF(opengrid-9x9.3mf)", "Eryone ASA-GF"},
+		{"single group with space", "Bambu PETG HF (Black)", "Bambu PETG HF"},
+		{"two groups with spaces", "Eryone ASA-GF (opengrid) (v2)", "Eryone ASA-GF"},
+		{"two adjacent groups", "PLA (Matte)(spool-3.3mf)", "PLA"},
+		{"nested group", "PLA (Matte (v2))", "PLA"},
+		{"mid-name group preserved", "PLA (Matte) Black", "PLA (Matte) Black"},
+		{"mid-name group with trailing group", "PLA (Matte) Black (spool)", "PLA (Matte) Black"},
+		{"trailing whitespace", "  PETG (Black)  ", "PETG"},
+		{"whole name in parentheses", "(Generic)", "(Generic)"},
+		{"unbalanced parenthesis", "PLA Matte)", "PLA Matte)"},
+		{"empty string", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CleanMaterialName(tt.input); got != tt.expected {
+				t.Errorf("CleanMaterialName(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestGroupObjectsByNameAndMaterial(t *testing.T) {
+	objects := []PlateObject{
+		{ID: 1, Name: "Bracket", Type: "model", Material: "PLA"},
+		{ID: 2, Name: "Bracket", Type: "model", Material: "PLA (Red)"},
+		{ID: 3, Name: "Bracket", Type: "model", Material: "PETG"},
+	}
+
+	groups := GroupObjectsByNameAndMaterial(objects)
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d: %+v", len(groups), groups)
+	}
+
+	pla, ok := groups["Bracket|model|PLA"]
+	if !ok || pla.Count != 2 || pla.Material != "PLA" {
+		t.Errorf("PLA group = %+v, want count 2 with cleaned material", pla)
+	}
+	if len(pla.ObjectIDs) != 2 || pla.ObjectIDs[0] != 1 || pla.ObjectIDs[1] != 2 {
+		t.Errorf("PLA group object IDs = %v, want [1 2]", pla.ObjectIDs)
+	}
+
+	petg, ok := groups["Bracket|model|PETG"]
+	if !ok || petg.Count != 1 {
+		t.Errorf("PETG group = %+v, want count 1", petg)
+	}
+}
+
+func TestGroupObjectsByNameKeepsRawMaterials(t *testing.T) {
+	objects := []PlateObject{
+		{ID: 1, Name: "Bracket", Type: "model", Material: "PLA"},
+		{ID: 2, Name: "Bracket", Type: "model", Material: "PLA (Red)"},
+	}
+
+	if groups := GroupObjectsByName(objects); len(groups) != 2 {
+		t.Errorf("expected 2 groups by raw material, got %d", len(groups))
+	}
+}
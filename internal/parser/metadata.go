@@ -3,9 +3,10 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"path"
 	"strconv"
+	"strings"
 )
 
 func extractMetadataValue(metadata []MetadataEntry, key string) string {
@@ -122,6 +123,12 @@ func parsePlates(plates []Plate) (map[int]*PlateInfo, map[int]int) {
 			Objects:   []PlateObject{},
 		}
 		
+		// Необязательное число повторений стола для наряд-заказа
+		if repetitionsStr := extractMetadataValue(plate.Metadata, "repetitions"); repetitionsStr != "" {
+			if repetitions, err := strconv.Atoi(strings.TrimSpace(repetitionsStr)); err == nil && repetitions > 0 {
+				plateMap[plateID].Repetitions = repetitions
+			}
+		}
 		// Обрабатываем model_instance элементы для этого plate
 		for _, instance := range plate.Instances {
 			objectID := instance.ObjectID
@@ -140,15 +147,11 @@ func parsePlates(plates []Plate) (map[int]*PlateInfo, map[int]int) {
 	return plateMap, objectToPlateMap
 }
 
-func parseFilamentSettings(extractDir string) map[int]string {
+func parseFilamentSettings(fsys fs.FS) map[int]string {
 	materialMap := make(map[int]string)
 	
-	metadataDir := filepath.Join(extractDir, "Metadata")
-	if _, err := os.Stat(metadataDir); os.IsNotExist(err) {
-		return materialMap
-	}
-	
-	entries, err := os.ReadDir(metadataDir)
+	metadataDir := "Metadata"
+	entries, err := fs.ReadDir(fsys, metadataDir)
 	if err != nil {
 		return materialMap
 	}
@@ -159,7 +162,7 @@ func parseFilamentSettings(extractDir string) map[int]string {
 		}
 		
 		name := entry.Name()
-		if filepath.Ext(name) == ".config" && len(name) > 17 && name[:17] == "filament_settings" {
+		if path.Ext(name) == ".config" && len(name) > 17 && name[:17] == "filament_settings" {
 			// Extract extruder number from filename like "filament_settings_1.config"
 			extruderStr := name[18 : len(name)-7] // Remove "filament_settings_" and ".config"
 			extruderNum, err := strconv.Atoi(extruderStr)
@@ -167,19 +170,9 @@ func parseFilamentSettings(extractDir string) map[int]string {
 				continue
 			}
 			
-			filePath := filepath.Join(metadataDir, name)
-			materialName := parseFilamentConfig(filePath)
+			materialName := parseFilamentConfig(fsys, path.Join(metadataDir, name))
 			if materialName != "" {
 				materialMap[extruderNum] = materialName
-				
-				// Если это первый найденный материал, то используем его как fallback для всех экструдеров
-				if len(materialMap) == 1 {
-					for i := 1; i <= 10; i++ { // Предполагаем максимум 10 экструдеров
-						if _, exists := materialMap[i]; !exists {
-							materialMap[i] = materialName
-						}
-					}
-				}
 			}
 		}
 	}
@@ -187,8 +180,23 @@ func parseFilamentSettings(extractDir string) map[int]string {
 	return materialMap
 }
 
-func parseFilamentConfig(filePath string) string {
-	data, err := os.ReadFile(filePath)
+// resolveExtruderMaterial возвращает материал экструдера по карте filament_settings_N.config.
+// Если в проекте настроен единственный материал, он используется для всех экструдеров,
+// иначе для экструдера без настроек возвращается "Extruder N"
+func resolveExtruderMaterial(extruderID int, materialMap map[int]string) string {
+	if materialName, exists := materialMap[extruderID]; exists {
+		return materialName
+	}
+	if len(materialMap) == 1 {
+		for _, materialName := range materialMap {
+			return materialName
+		}
+	}
+	return fmt.Sprintf("Extruder %d", extruderID)
+}
+
+func parseFilamentConfig(fsys fs.FS, filePath string) string {
+	data, err := readArchiveFile(fsys, filePath)
 	if err != nil {
 		return ""
 	}
@@ -201,8 +209,13 @@ func parseFilamentConfig(filePath string) string {
 	return settings.Name
 }
 
+// extractExtruderID возвращает номер экструдера объекта; если у объекта он не задан,
+// используется экструдер первой детали, затем экструдер 1
 func extractExtruderID(obj ObjectMeta) int {
 	extruderStr := extractMetadataValue(obj.Metadata, "extruder")
+	if extruderStr == "" && len(obj.Parts) > 0 {
+		extruderStr = extractMetadataValue(obj.Parts[0].Metadata, "extruder")
+	}
 	if extruderStr == "" {
 		return 1 // Default to extruder 1
 	}
@@ -2,21 +2,37 @@ package parser
 
 import (
 	"encoding/xml"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"net/url"
 	"strconv"
 	"strings"
 )
 
-func ParseModel3D(extractDir string) (*Model3D, error) {
-	modelPath := filepath.Join(extractDir, "3D", "3dmodel.model")
+// defaultModelPath - расположение основной модели, если в _rels/.rels нет StartPart
+const defaultModelPath = "3D/3dmodel.model"
+
+// startPartRelationshipType - тип связи OPC, указывающей на основную модель 3MF
+const startPartRelationshipType = "http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"
+
+// opcRelationships описывает _rels/.rels архива 3MF
+type opcRelationships struct {
+	Relationships []opcRelationship `xml:"Relationship"`
+}
+
+type opcRelationship struct {
+	Type   string `xml:"Type,attr"`
+	Target string `xml:"Target,attr"`
+}
+
+func ParseModel3D(fsys fs.FS) (*Model3D, error) {
+	modelPath := findStartPartPath(fsys)
 	
-	if _, err := os.Stat(modelPath); os.IsNotExist(err) {
+	data, err := readArchiveFile(fsys, modelPath)
+	if errors.Is(err, fs.ErrNotExist) {
 		return nil, fmt.Errorf("main model file not found: %s", modelPath)
 	}
-
-	data, err := os.ReadFile(modelPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read model file: %w", err)
 	}
@@ -29,14 +45,48 @@ func ParseModel3D(extractDir string) (*Model3D, error) {
 	return &model, nil
 }
 
-func ParseModelSettings(extractDir string) (*ModelSettings, error) {
-	settingsPath := filepath.Join(extractDir, "Metadata", "model_settings.config")
-	
-	if _, err := os.Stat(settingsPath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("model settings file not found: %s", settingsPath)
+// findStartPartPath находит основную модель по связи StartPart из _rels/.rels
+// (Fusion, SolidWorks и другие CAD сохраняют модель не в 3D/3dmodel.model).
+// Если связи нет или она указывает на отсутствующий файл, используется путь по умолчанию
+func findStartPartPath(fsys fs.FS) string {
+	data, err := readArchiveFile(fsys, "_rels/.rels")
+	if err != nil {
+		return defaultModelPath
+	}
+
+	var rels opcRelationships
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return defaultModelPath
 	}
 
-	data, err := os.ReadFile(settingsPath)
+	for _, rel := range rels.Relationships {
+		if rel.Type != startPartRelationshipType || rel.Target == "" {
+			continue
+		}
+
+		target, err := url.PathUnescape(rel.Target)
+		if err != nil {
+			continue
+		}
+		// Цели корневых связей задаются относительно корня архива
+		modelPath := archivePath(target)
+		if _, err := fs.Stat(fsys, modelPath); err == nil {
+			return modelPath
+		}
+	}
+
+	return defaultModelPath
+}
+
+// ParseModelSettings читает Metadata/model_settings.config (столы, имена объектов, экструдеры).
+// Файл пишут только слайсеры Bambu/Orca: если его нет (3MF из CAD), возвращаются пустые настройки без ошибки
+func ParseModelSettings(fsys fs.FS) (*ModelSettings, error) {
+	settingsPath := "Metadata/model_settings.config"
+	
+	data, err := readArchiveFile(fsys, settingsPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &ModelSettings{}, nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to read settings file: %w", err)
 	}
@@ -49,14 +99,13 @@ func ParseModelSettings(extractDir string) (*ModelSettings, error) {
 	return &settings, nil
 }
 
-func ParseAssemblyModel(extractDir, assemblyPath string) (*Model3D, error) {
-	fullPath := filepath.Join(extractDir, assemblyPath)
+func ParseAssemblyModel(fsys fs.FS, assemblyPath string) (*Model3D, error) {
+	fullPath := archivePath(assemblyPath)
 	
-	if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+	data, err := readArchiveFile(fsys, fullPath)
+	if errors.Is(err, fs.ErrNotExist) {
 		return nil, fmt.Errorf("assembly file not found: %s", fullPath)
 	}
-
-	data, err := os.ReadFile(fullPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read assembly file: %w", err)
 	}
@@ -0,0 +1,158 @@
+package parser
+
+import (
+	"bytes"
+	"io"
+	"sort"
+)
+
+// paintAttributes - атрибуты треугольников с покраской по экструдерам (Bambu/Orca и PrusaSlicer)
+var paintAttributes = [][]byte{[]byte("paint_color="), []byte("mmu_segmentation=")}
+
+// hasPaintData проверяет по тексту файла модели, есть ли в нем покраска, не разбирая XML сеток.
+// Результат кэшируется по пути
+func (r *meshResolver) hasPaintData(path string) bool {
+	if painted, exists := r.painted[path]; exists {
+		return painted
+	}
+
+	painted := false
+	if file, err := r.fsys.Open(archivePath(path)); err == nil {
+		painted = containsAny(file, paintAttributes)
+		file.Close()
+	}
+	r.painted[path] = painted
+	return painted
+}
+
+// containsAny читает reader блоками и сообщает, встречается ли в нем одна из строк patterns.
+// Файлы сеток бывают очень большими, поэтому они не читаются в память целиком
+func containsAny(reader io.Reader, patterns [][]byte) bool {
+	overlap := 0
+	for _, pattern := range patterns {
+		if len(pattern) > overlap {
+			overlap = len(pattern)
+		}
+	}
+	overlap--
+
+	buffer := make([]byte, 64*1024+overlap)
+	kept := 0
+	for {
+		n, err := reader.Read(buffer[kept:])
+		chunk := buffer[:kept+n]
+		for _, pattern := range patterns {
+			if bytes.Contains(chunk, pattern) {
+				return true
+			}
+		}
+		if err != nil {
+			return false
+		}
+		// Хвост блока переносится в начало, чтобы найти строку на границе блоков
+		kept = copy(buffer, chunk[max(0, len(chunk)-overlap):])
+	}
+}
+
+// PaintStates возвращает состояния покраски треугольников объекта и его компонентов:
+// 0 - треугольник не покрашен (экструдер объекта), n - покрашен экструдером n.
+// Возвращает nil, если в сетках объекта нет данных покраски
+func (r *meshResolver) PaintStates(obj *ModelObject) []int {
+	if states, exists := r.states[obj]; exists {
+		return states
+	}
+	states := r.paintStates(obj)
+	r.states[obj] = states
+	return states
+}
+
+func (r *meshResolver) paintStates(obj *ModelObject) []int {
+	states := make(map[int]bool)
+	painted := false
+	r.walk(obj, "", Transform3D{}, 0, func(path string) bool { return !r.hasPaintData(path) }, func(mesh *Mesh, _ Transform3D) {
+		for _, triangle := range mesh.Triangles {
+			code := triangle.PaintColor
+			if code == "" {
+				code = triangle.MMUSegmentation
+			}
+			if code == "" {
+				states[0] = true
+				continue
+			}
+			painted = true
+			addPaintStates(code, states)
+		}
+	})
+	if !painted {
+		return nil
+	}
+
+	result := make([]int, 0, len(states))
+	for state := range states {
+		result = append(result, state)
+	}
+	sort.Ints(result)
+	return result
+}
+
+// addPaintStates добавляет в states состояния листьев дерева разбиения треугольника.
+// Код - шестнадцатеричная строка, читается с конца по одной цифре (4 бита) на узел:
+// младшие 2 бита - число разделенных сторон (0 - лист), старшие 2 бита листа - состояние;
+// состояние 3 означает, что следующая цифра содержит состояние минус 3 (формат TriangleSelector PrusaSlicer)
+func addPaintStates(code string, states map[int]bool) {
+	for i := len(code) - 1; i >= 0; i-- {
+		nibble, ok := hexDigit(code[i])
+		if !ok {
+			return
+		}
+		if nibble&0b11 != 0 {
+			continue // узел разбит, состояния у его дочерних узлов
+		}
+
+		state := nibble >> 2
+		if state == 0b11 {
+			i--
+			if i < 0 {
+				return
+			}
+			next, ok := hexDigit(code[i])
+			if !ok {
+				return
+			}
+			state = next + 3
+		}
+		states[state] = true
+	}
+}
+
+func hexDigit(c byte) (int, bool) {
+	switch {
+	case c >= '0' && c <= '9':
+		return int(c - '0'), true
+	case c >= 'A' && c <= 'F':
+		return int(c-'A') + 10, true
+	case c >= 'a' && c <= 'f':
+		return int(c-'a') + 10, true
+	default:
+		return 0, false
+	}
+}
+
+// paintedMaterials переводит состояния покраски в материалы по экструдерам без повторов,
+// в порядке состояний (непокрашенная часть с материалом объекта - первой)
+func paintedMaterials(states []int, baseExtruder int, materialMap map[int]string) []string {
+	var materials []string
+	seen := make(map[string]bool)
+	for _, state := range states {
+		extruder := state
+		if state == 0 {
+			extruder = baseExtruder
+		}
+		material := resolveExtruderMaterial(extruder, materialMap)
+		if !seen[material] {
+			seen[material] = true
+			materials = append(materials, material)
+		}
+	}
+	return materials
+}
\ No newline at end of file
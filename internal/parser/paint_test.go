@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestAddPaintStates(t *testing.T) {
+	tests := []struct {
+		code string
+		want []int
+	}{
+		{"4", []int{1}},
+		{"8", []int{2}},
+		{"0C", []int{3}}, // состояние 3 и больше - во второй цифре минус 3
+		{"1C", []int{4}},
+		{"481", []int{1, 2}}, // разбиение на 2 части: экструдеры 1 и 2
+		{"0", []int{0}},
+		{"", nil},
+		{"zz", nil},
+	}
+
+	for _, tt := range tests {
+		states := make(map[int]bool)
+		addPaintStates(tt.code, states)
+
+		var got []int
+		for state := range states {
+			got = append(got, state)
+		}
+		sort.Ints(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("addPaintStates(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+// paintedMainModel - три объекта с сетками во внешних файлах, как у Bambu/Orca
+const paintedMainModel = `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02" xmlns:p="http://schemas.microsoft.com/3dmanufacturing/production/1015/06">
+ <resources>
+  <object id="1" type="model"><components><component p:path="/3D/Objects/vase.model" objectid="10"/></components></object>
+  <object id="2" type="model"><components><component p:path="/3D/Objects/plain.model" objectid="20"/></components></object>
+  <object id="3" type="model"><components><component p:path="/3D/Objects/cap.model" objectid="30"/></components></object>
+ </resources>
+ <build>
+  <item objectid="1"/>
+  <item objectid="2"/>
+  <item objectid="3"/>
+ </build>
+</model>`
+
+// paintedObjectModel возвращает файл сетки с треугольниками, покрашенными кодами paint_color (пустой - без покраски)
+func paintedObjectModel(id string, codes ...string) string {
+	triangles := ""
+	for _, code := range codes {
+		paint := ""
+		if code != "" {
+			paint = ` paint_color="` + code + `"`
+		}
+		triangles += `<triangle v1="0" v2="1" v3="2"` + paint + `/>`
+	}
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+ <resources><object id="` + id + `" type="model"><mesh><vertices>
+  <vertex x="0" y="0" z="0"/><vertex x="1" y="0" z="0"/><vertex x="0" y="1" z="0"/>
+ </vertices><triangles>` + triangles + `</triangles></mesh></object></resources>
+ <build/>
+</model>`
+}
+
+const paintedSettings = `<?xml version="1.0" encoding="UTF-8"?>
+<config>
+ <object id="1"><metadata key="name" value="Vase"/><metadata key="extruder" value="1"/></object>
+ <object id="2"><metadata key="name" value="Plain"/><metadata key="extruder" value="1"/></object>
+ <object id="3"><metadata key="name" value="Cap"/><metadata key="extruder" value="1"/></object>
+ <plate>
+  <metadata key="plater_id" value="1"/>
+  <model_instance><metadata key="object_id" value="1"/></model_instance>
+  <model_instance><metadata key="object_id" value="2"/></model_instance>
+  <model_instance><metadata key="object_id" value="3"/></model_instance>
+ </plate>
+</config>`
+
+func TestParse3MFPaintedObjectMaterials(t *testing.T) {
+	path := writeTest3MF(t, map[string]string{
+		"3D/3dmodel.model":                    paintedMainModel,
+		"3D/Objects/vase.model":               paintedObjectModel("10", "", "8", "0C"),
+		"3D/Objects/plain.model":              paintedObjectModel("20", "", ""),
+		"3D/Objects/cap.model":                paintedObjectModel("30", "8", "8"),
+		"Metadata/model_settings.config":      paintedSettings,
+		"Metadata/filament_settings_1.config": `{"name": "Bambu PLA Basic (Red)"}`,
+		"Metadata/filament_settings_2.config": `{"name": "Bambu TPU 95A"}`,
+		"Metadata/filament_settings_3.config": `{"name": "Bambu PETG HF"}`,
+	})
+
+	data, err := Parse3MF(path, WithPaintedMaterials(true))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+
+	tests := map[string]struct {
+		material  string
+		materials []string
+	}{
+		// Непокрашенная часть - материал экструдера объекта, затем материалы покраски
+		"Vase": {"Bambu PLA Basic (Red)", []string{"Bambu PLA Basic (Red)", "Bambu TPU 95A", "Bambu PETG HF"}},
+		// Без покраски - как раньше, один материал
+		"Plain": {"Bambu PLA Basic (Red)", nil},
+		// Объект покрашен целиком одним экструдером
+		"Cap": {"Bambu TPU 95A", nil},
+	}
+	objects := data.Plates[0].Objects
+	if len(objects) != len(tests) {
+		t.Fatalf("expected %d objects, got %d", len(tests), len(objects))
+	}
+	for _, obj := range objects {
+		want := tests[obj.Name]
+		if obj.Material != want.material || !reflect.DeepEqual(obj.Materials, want.materials) {
+			t.Errorf("object %q materials = %q %q, want %q %q", obj.Name, obj.Material, obj.Materials, want.material, want.materials)
+		}
+	}
+
+	groups := GroupObjectsByName(objects)
+	if got, want := groups["Vase|model|Bambu PLA Basic (Red)|Bambu PLA Basic (Red),Bambu TPU 95A,Bambu PETG HF"].MaterialLabel(), "Bambu PLA Basic, Bambu TPU 95A, Bambu PETG HF"; got != want {
+		t.Errorf("Vase MaterialLabel() = %q, want %q", got, want)
+	}
+	if got, want := groups["Plain|model|Bambu PLA Basic (Red)"].MaterialLabel(), "Bambu PLA Basic"; got != want {
+		t.Errorf("Plain MaterialLabel() = %q, want %q", got, want)
+	}
+}
@@ -1,24 +1,96 @@
 package parser
 
 import (
+	"archive/zip"
 	"fmt"
+	"io/fs"
+	"os"
 	"sort"
 	"strings"
 )
 
-func Parse3MF(filePath string) (*Parser3MF, error) {
+// defaultPlateID - номер единственного стола, если в 3MF нет описания столов (нет model_settings.config)
+const defaultPlateID = 1
+
+// parseOptions - необязательное поведение разбора 3MF, задается опциями Parse3MFOption
+type parseOptions struct {
+	dimensions       bool
+	keepExtracted    bool
+	paintedMaterials bool
+}
+
+// Parse3MFOption настраивает разбор в Parse3MF
+type Parse3MFOption func(*parseOptions)
+
+// WithDimensions включает расчет габаритов объектов по сеткам (PlateObject.Dimensions).
+// Для этого читаются все файлы сеток, что на больших проектах заметно дольше
+func WithDimensions(enabled bool) Parse3MFOption {
+	return func(options *parseOptions) {
+		options.dimensions = enabled
+	}
+}
+
+// WithPaintedMaterials включает поиск покраски по экструдерам в сетках объектов (PlateObject.Materials).
+// Для этого читаются файлы сеток, поэтому без опции у покрашенного объекта только материал его экструдера
+func WithPaintedMaterials(enabled bool) Parse3MFOption {
+	return func(options *parseOptions) {
+		options.paintedMaterials = enabled
+	}
+}
+
+// WithKeepExtracted распаковывает архив во временную директорию, разбирает файлы оттуда и не удаляет ее,
+// чтобы можно было посмотреть XML. Путь возвращается в Parser3MF.ExtractDir (и в тексте ошибки разбора)
+func WithKeepExtracted(enabled bool) Parse3MFOption {
+	return func(options *parseOptions) {
+		options.keepExtracted = enabled
+	}
+}
+
+// Parse3MF разбирает 3MF файл. Без опций нужные записи читаются прямо из ZIP архива без распаковки на диск
+func Parse3MF(filePath string, opts ...Parse3MFOption) (*Parser3MF, error) {
+	var options parseOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	if options.keepExtracted {
+		return parseExtracted(filePath, options)
+	}
+
+	reader, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open 3MF archive: %w", err)
+	}
+	defer reader.Close()
+
+	return parseArchive(reader, options)
+}
+
+// parseExtracted распаковывает архив и разбирает распакованную директорию, оставляя ее на диске
+func parseExtracted(filePath string, options parseOptions) (*Parser3MF, error) {
 	extractDir, err := ExtractArchive(filePath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to extract 3MF archive: %w", err)
+		return nil, err
 	}
-	defer CleanupTemp(extractDir)
 
-	model, err := ParseModel3D(extractDir)
+	result, err := parseArchive(os.DirFS(extractDir), options)
+	if err != nil {
+		return nil, fmt.Errorf("%w (extracted files kept in %s)", err, extractDir)
+	}
+	result.ExtractDir = extractDir
+	return result, nil
+}
+
+// parseArchive разбирает содержимое 3MF архива (zip.Reader или распакованная директория через os.DirFS)
+func parseArchive(fsys fs.FS, options parseOptions) (*Parser3MF, error) {
+	fsys = newReadBudgetFS(fsys, maxExtractedSize)
+
+	model, err := ParseModel3D(fsys)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse main model: %w", err)
 	}
 
-	settings, err := ParseModelSettings(extractDir)
+	settings, err := ParseModelSettings(fsys)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse model settings: %w", err)
 	}
@@ -26,11 +98,16 @@ func Parse3MF(filePath string) (*Parser3MF, error) {
 	result := &Parser3MF{}
 
 	plateMap, instanceToPlateMap := parsePlates(settings.Plates)
-	materialMap := parseFilamentSettings(extractDir)
+	if len(plateMap) == 0 {
+		// Без описания столов все объекты сборки попадают на один стол без имени и материалов
+		plateMap[defaultPlateID] = &PlateInfo{PlateID: defaultPlateID, Objects: []PlateObject{}}
+	}
+	materialMap := parseFilamentSettings(fsys)
 
 	objectNameMap := make(map[int]string)
 	objectTypeMap := make(map[int]string)
 	objectMaterialMap := make(map[int]string)
+	objectExtruderMap := make(map[int]int)
 	objectComponentsMap := make(map[int][]ComponentInfo)
 	
 	for _, obj := range settings.Objects {
@@ -44,12 +121,8 @@ func Parse3MF(filePath string) (*Parser3MF, error) {
 		}
 		
 		// Extract material information
-		extruderID := extractExtruderID(obj)
-		if materialName, exists := materialMap[extruderID]; exists {
-			objectMaterialMap[obj.ID] = materialName
-		} else {
-			objectMaterialMap[obj.ID] = fmt.Sprintf("Extruder %d", extruderID)
-		}
+		objectExtruderMap[obj.ID] = extractExtruderID(obj)
+		objectMaterialMap[obj.ID] = resolveExtruderMaterial(objectExtruderMap[obj.ID], materialMap)
 	}
 
 	partNameMap := make(map[int]string)
@@ -64,6 +137,7 @@ func Parse3MF(filePath string) (*Parser3MF, error) {
 		obj := &model.Resources[i]
 		modelObjectMap[obj.ID] = obj
 	}
+	meshes := newMeshResolver(fsys, model)
 
 	for _, buildItem := range model.Build {
 		modelObj := modelObjectMap[buildItem.ObjectID]
@@ -93,21 +167,38 @@ func Parse3MF(filePath string) (*Parser3MF, error) {
 		}
 		
 		material := objectMaterialMap[buildItem.ObjectID]
+		position := ParseTransform(buildItem.Transform)
 		
 		plateObject := PlateObject{
 			ID:        buildItem.ObjectID,
 			Name:      name,
 			Type:      objType,
 			Material:  material,
-			Position:  ParseTransform(buildItem.Transform),
+			Position:  position,
 			Printable: printable,
 		}
+		if options.dimensions {
+			plateObject.Dimensions = meshes.ObjectDimensions(modelObj, position)
+		}
+		if options.paintedMaterials {
+			if states := meshes.PaintStates(modelObj); states != nil {
+				baseExtruder, exists := objectExtruderMap[buildItem.ObjectID]
+				if !exists {
+					baseExtruder = 1
+				}
+				materials := paintedMaterials(states, baseExtruder, materialMap)
+				plateObject.Material = materials[0]
+				if len(materials) > 1 {
+					plateObject.Materials = materials
+				}
+			}
+		}
 
 		if objType == "assembly" {
 			if components, exists := objectComponentsMap[buildItem.ObjectID]; exists {
 				plateObject.Components = components
 			} else if modelObj.Components != nil {
-				components, err := processAssemblyComponents(extractDir, modelObj.Components, partNameMap, partFileMap)
+				components, err := processAssemblyComponents(fsys, modelObj.Components, partNameMap, partFileMap)
 				if err != nil {
 					return nil, fmt.Errorf("failed to process assembly components: %w", err)
 				}
@@ -121,7 +212,20 @@ func Parse3MF(filePath string) (*Parser3MF, error) {
 		}
 	}
 
+	// Вес и время печати необязательны: испорченный slice_info.config не мешает разбору проекта
+	sliceInfo, err := ParseSliceInfo(fsys)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring slice info: %v\n", err)
+	}
+
 	for _, plate := range plateMap {
+		if info, exists := sliceInfo[plate.PlateID]; exists {
+			plate.WeightGrams = info.WeightGrams
+			plate.SupportWeightGrams = info.SupportWeightGrams
+			plate.PrintTime = info.PrintTime
+			plate.MaterialWeights = mapFilamentWeights(info.FilamentWeights, materialMap)
+		}
+		plate.ThumbnailPath, plate.Thumbnail = findPlateThumbnail(fsys, plate.PlateID, len(plateMap))
 		result.Plates = append(result.Plates, *plate)
 	}
 
@@ -133,6 +237,19 @@ func Parse3MF(filePath string) (*Parser3MF, error) {
 	return result, nil
 }
 
+// mapFilamentWeights переводит расход по номерам экструдеров в расход по названиям материалов
+func mapFilamentWeights(filamentWeights map[int]float64, materialMap map[int]string) map[string]float64 {
+	if len(filamentWeights) == 0 {
+		return nil
+	}
+
+	result := make(map[string]float64)
+	for extruderID, weight := range filamentWeights {
+		result[resolveExtruderMaterial(extruderID, materialMap)] += weight
+	}
+	return result
+}
+
 func getObjectName(objectID int, objectNameMap, partNameMap map[int]string, modelObj *ModelObject) string {
 	if name, exists := objectNameMap[objectID]; exists && name != "" {
 		return name
@@ -159,7 +276,7 @@ func findPlateForObject(objectID int, instanceToPlateMap map[int]int, plateMap m
 	return 0
 }
 
-func processAssemblyComponents(extractDir string, components *ComponentsCollection, partNameMap, partFileMap map[int]string) ([]ComponentInfo, error) {
+func processAssemblyComponents(fsys fs.FS, components *ComponentsCollection, partNameMap, partFileMap map[int]string) ([]ComponentInfo, error) {
 	var result []ComponentInfo
 
 	for _, comp := range components.Components {
@@ -177,7 +294,7 @@ func processAssemblyComponents(extractDir string, components *ComponentsCollecti
 		if comp.Path != "" {
 			compInfo.SourceFile = comp.Path
 			
-			assemblyModel, err := ParseAssemblyModel(extractDir, comp.Path)
+			assemblyModel, err := ParseAssemblyModel(fsys, comp.Path)
 			if err == nil && len(assemblyModel.Resources) > 0 {
 				for _, res := range assemblyModel.Resources {
 					if res.ID == comp.ObjectID && res.Name != "" {
@@ -190,7 +307,7 @@ func processAssemblyComponents(extractDir string, components *ComponentsCollecti
 			compInfo.SourceFile = sourceFile
 		}
 
-		if !isEmptyAssembly(extractDir, compInfo.SourceFile) {
+		if !isEmptyAssembly(fsys, compInfo.SourceFile) {
 			result = append(result, compInfo)
 		}
 	}
@@ -198,7 +315,7 @@ func processAssemblyComponents(extractDir string, components *ComponentsCollecti
 	return result, nil
 }
 
-func isEmptyAssembly(extractDir, assemblyPath string) bool {
+func isEmptyAssembly(fsys fs.FS, assemblyPath string) bool {
 	if assemblyPath == "" {
 		return true
 	}
@@ -207,7 +324,7 @@ func isEmptyAssembly(extractDir, assemblyPath string) bool {
 		return false
 	}
 
-	assemblyModel, err := ParseAssemblyModel(extractDir, assemblyPath)
+	assemblyModel, err := ParseAssemblyModel(fsys, assemblyPath)
 	if err != nil {
 		return true
 	}
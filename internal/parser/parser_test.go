@@ -0,0 +1,493 @@
+package parser
+
+import (
+	"archive/zip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTest3MF packs files (archive path -> content) into a 3MF archive in a temp dir
+func writeTest3MF(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "test.3mf")
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	archive := zip.NewWriter(out)
+	for name, content := range files {
+		w, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const twoExtruderModel = `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter">
+ <resources>
+  <object id="1" type="model"><components><component objectid="10" path="/3D/Objects/a.model"/></components></object>
+  <object id="2" type="model"><components><component objectid="20" path="/3D/Objects/b.model"/></components></object>
+  <object id="3" type="model"><components><component objectid="30" path="/3D/Objects/c.model"/></components></object>
+ </resources>
+ <build>
+  <item objectid="1" transform="1 0 0 0 1 0 0 0 1 0 0 0"/>
+  <item objectid="2" transform="1 0 0 0 1 0 0 0 1 10 0 0"/>
+  <item objectid="3" transform="1 0 0 0 1 0 0 0 1 20 0 0"/>
+ </build>
+</model>`
+
+const twoExtruderSettings = `<?xml version="1.0" encoding="UTF-8"?>
+<config>
+ <object id="1">
+  <metadata key="name" value="Body"/>
+  <metadata key="extruder" value="1"/>
+  <part id="10" subtype="normal_part"><metadata key="name" value="Body"/></part>
+ </object>
+ <object id="2">
+  <metadata key="name" value="Gasket"/>
+  <metadata key="extruder" value="2"/>
+  <part id="20" subtype="normal_part"><metadata key="name" value="Gasket"/></part>
+ </object>
+ <object id="3">
+  <metadata key="name" value="Clip"/>
+  <part id="30" subtype="normal_part"><metadata key="name" value="Clip"/><metadata key="extruder" value="2"/></part>
+ </object>
+ <plate>
+  <metadata key="plater_id" value="1"/>
+  <model_instance><metadata key="object_id" value="1"/></model_instance>
+  <model_instance><metadata key="object_id" value="2"/></model_instance>
+  <model_instance><metadata key="object_id" value="3"/></model_instance>
+ </plate>
+</config>`
+
+func TestParse3MFAssignsMaterialPerExtruder(t *testing.T) {
+	path := writeTest3MF(t, map[string]string{
+		"3D/3dmodel.model":                    twoExtruderModel,
+		"Metadata/model_settings.config":      twoExtruderSettings,
+		"Metadata/filament_settings_1.config": `{"name": "Bambu PLA Basic"}`,
+		"Metadata/filament_settings_2.config": `{"name": "Bambu TPU 95A"}`,
+	})
+
+	data, err := Parse3MF(path)
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if len(data.Plates) != 1 {
+		t.Fatalf("expected 1 plate, got %d", len(data.Plates))
+	}
+
+	want := map[string]string{
+		"Body":   "Bambu PLA Basic",
+		"Gasket": "Bambu TPU 95A",
+		"Clip":   "Bambu TPU 95A", // extruder taken from the part
+	}
+	objects := data.Plates[0].Objects
+	if len(objects) != len(want) {
+		t.Fatalf("expected %d objects, got %d", len(want), len(objects))
+	}
+	for _, obj := range objects {
+		if obj.Material != want[obj.Name] {
+			t.Errorf("object %q material = %q, want %q", obj.Name, obj.Material, want[obj.Name])
+		}
+	}
+}
+
+func TestResolveExtruderMaterial(t *testing.T) {
+	tests := []struct {
+		name        string
+		extruderID  int
+		materialMap map[int]string
+		want        string
+	}{
+		{"configured extruder", 2, map[int]string{1: "PLA", 2: "PETG"}, "PETG"},
+		{"unconfigured extruder in multi-material project", 3, map[int]string{1: "PLA", 2: "PETG"}, "Extruder 3"},
+		{"single material project", 2, map[int]string{1: "PLA"}, "PLA"},
+		{"no filament settings", 1, map[int]string{}, "Extruder 1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveExtruderMaterial(tt.extruderID, tt.materialMap); got != tt.want {
+				t.Errorf("resolveExtruderMaterial(%d) = %q, want %q", tt.extruderID, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse3MFPrintTime(t *testing.T) {
+	sliceInfo, err := os.ReadFile(filepath.Join("testdata", "slice_info.config"))
+	if err != nil {
+		t.Fatalf("failed to read testdata: %v", err)
+	}
+
+	files := map[string]string{
+		"3D/3dmodel.model":               twoExtruderModel,
+		"Metadata/model_settings.config": twoExtruderSettings,
+	}
+
+	data, err := Parse3MF(writeTest3MF(t, files))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if got := data.TotalPrintTime(); got != 0 {
+		t.Errorf("TotalPrintTime() without slice info = %v, want 0", got)
+	}
+
+	files["Metadata/slice_info.config"] = string(sliceInfo)
+	data, err = Parse3MF(writeTest3MF(t, files))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if got := data.Plates[0].PrintTime; got != 90*time.Minute {
+		t.Errorf("plate 1 PrintTime = %v, want 1h30m", got)
+	}
+	if got := data.TotalPrintTime(); got != 90*time.Minute {
+		t.Errorf("TotalPrintTime() = %v, want 1h30m (plates missing from the project are ignored)", got)
+	}
+
+	// Испорченный slice_info.config пропускается, столы разбираются без веса и времени
+	files["Metadata/slice_info.config"] = "<config><plate>"
+	data, err = Parse3MF(writeTest3MF(t, files))
+	if err != nil {
+		t.Fatalf("Parse3MF() with malformed slice info error = %v", err)
+	}
+	if len(data.Plates) == 0 || data.TotalPrintTime() != 0 {
+		t.Errorf("malformed slice info: plates = %d, TotalPrintTime() = %v; want plates without print time", len(data.Plates), data.TotalPrintTime())
+	}
+}
+
+func TestTotalPrintTime(t *testing.T) {
+	data := &Parser3MF{Plates: []PlateInfo{
+		{PlateID: 1, PrintTime: 90 * time.Minute},
+		{PlateID: 2},
+		{PlateID: 3, PrintTime: 30 * time.Minute},
+	}}
+	if got := data.TotalPrintTime(); got != 2*time.Hour {
+		t.Errorf("TotalPrintTime() = %v, want 2h", got)
+	}
+}
+
+const startPartRels = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+ <Relationship Target="/Thumbnails/thumbnail.png" Id="rel1" Type="http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail"/>
+ <Relationship Target="/3D/Export%20Model.model" Id="rel0" Type="http://schemas.microsoft.com/3dmanufacturing/2013/01/3dmodel"/>
+</Relationships>`
+
+func TestParse3MFFindsModelFromStartPart(t *testing.T) {
+	path := writeTest3MF(t, map[string]string{
+		"[Content_Types].xml":            `<?xml version="1.0" encoding="UTF-8"?><Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"/>`,
+		"_rels/.rels":                    startPartRels,
+		"3D/Export Model.model":          twoExtruderModel,
+		"Metadata/model_settings.config": twoExtruderSettings,
+	})
+
+	data, err := Parse3MF(path)
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if len(data.Plates) != 1 || len(data.Plates[0].Objects) != 3 {
+		t.Fatalf("expected 3 objects on 1 plate from non-default model path, got %+v", data.Plates)
+	}
+}
+
+func TestFindStartPartPath(t *testing.T) {
+	writeFile := func(t *testing.T, dir, name, content string) {
+		t.Helper()
+		fullPath := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	relsTo := func(target string) string {
+		return `<Relationships><Relationship Target="` + target + `" Id="rel0" Type="` + startPartRelationshipType + `"/></Relationships>`
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{"no relationships", map[string]string{}, "3D/3dmodel.model"},
+		{"start part", map[string]string{"_rels/.rels": startPartRels, "3D/Export Model.model": ""}, "3D/Export Model.model"},
+		{"relative target", map[string]string{"_rels/.rels": relsTo("Models/main.model"), "Models/main.model": ""}, "Models/main.model"},
+		{"missing target", map[string]string{"_rels/.rels": relsTo("/3D/missing.model")}, "3D/3dmodel.model"},
+		{"target outside archive", map[string]string{"_rels/.rels": relsTo("/../../etc/passwd")}, "3D/3dmodel.model"},
+		{"malformed relationships", map[string]string{"_rels/.rels": "<Relationships"}, "3D/3dmodel.model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				writeFile(t, dir, name, content)
+			}
+			if got := findStartPartPath(os.DirFS(dir)); got != tt.want {
+				t.Errorf("findStartPartPath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingFS запоминает все записи архива, которые открывал парсер
+type recordingFS struct {
+	fs.FS
+	opened map[string]bool
+}
+
+func (r *recordingFS) Open(name string) (fs.File, error) {
+	r.opened[name] = true
+	return r.FS.Open(name)
+}
+
+func TestParseArchiveReadsOnlyRequiredEntries(t *testing.T) {
+	path := writeTest3MF(t, map[string]string{
+		"3D/3dmodel.model":                    twoExtruderModel,
+		"Metadata/model_settings.config":      twoExtruderSettings,
+		"Metadata/filament_settings_1.config": `{"name": "Bambu PLA Basic"}`,
+		"Metadata/plate_1.gcode":              "G28",
+		"Metadata/top_1.png":                  "png",
+		"Auxiliaries/Model Pictures/big.jpg":  "jpg",
+		"3D/Objects/a.model":                  paintedObjectModel("10", "8"),
+	})
+
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+
+	recorder := &recordingFS{FS: reader, opened: make(map[string]bool)}
+	if _, err := parseArchive(recorder, parseOptions{}); err != nil {
+		t.Fatalf("parseArchive() error = %v", err)
+	}
+
+	for _, name := range []string{"3D/3dmodel.model", "Metadata/model_settings.config", "Metadata/filament_settings_1.config"} {
+		if !recorder.opened[name] {
+			t.Errorf("required entry %s was not read", name)
+		}
+	}
+	for _, name := range []string{"Metadata/plate_1.gcode", "Metadata/top_1.png", "Auxiliaries/Model Pictures/big.jpg", "3D/Objects/a.model"} {
+		if recorder.opened[name] {
+			t.Errorf("entry %s should not be read", name)
+		}
+	}
+
+	// Файлы сеток объектов читаются только для поиска покраски
+	recorder = &recordingFS{FS: reader, opened: make(map[string]bool)}
+	if _, err := parseArchive(recorder, parseOptions{paintedMaterials: true}); err != nil {
+		t.Fatalf("parseArchive() error = %v", err)
+	}
+	if !recorder.opened["3D/Objects/a.model"] {
+		t.Error("object mesh 3D/Objects/a.model was not read with paintedMaterials")
+	}
+}
+
+func TestParse3MFDoesNotCreateTempDir(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("TMPDIR", tempDir)
+
+	path := writeTest3MF(t, map[string]string{
+		"3D/3dmodel.model":               twoExtruderModel,
+		"Metadata/model_settings.config": twoExtruderSettings,
+	})
+	if _, err := Parse3MF(path); err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+
+	brokenPath := writeTest3MF(t, map[string]string{"3D/3dmodel.model": "<model"})
+	if _, err := Parse3MF(brokenPath); err == nil {
+		t.Fatal("expected error for broken model")
+	}
+
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Parse3MF left %d entries in temp dir", len(entries))
+	}
+}
+
+func TestParse3MFPlateRepetitions(t *testing.T) {
+	files := map[string]string{
+		"3D/3dmodel.model":               twoExtruderModel,
+		"Metadata/model_settings.config": twoExtruderSettings,
+	}
+
+	data, err := Parse3MF(writeTest3MF(t, files))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if plate := data.Plates[0]; plate.Repetitions != 0 || plate.RepeatCount() != 1 {
+		t.Errorf("plate without metadata: Repetitions = %d, RepeatCount() = %d, want 0 and 1", plate.Repetitions, plate.RepeatCount())
+	}
+
+	files["Metadata/model_settings.config"] = strings.Replace(twoExtruderSettings,
+		`<metadata key="plater_id" value="1"/>`,
+		`<metadata key="plater_id" value="1"/><metadata key="repetitions" value="3"/>`, 1)
+	data, err = Parse3MF(writeTest3MF(t, files))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if got := data.Plates[0].RepeatCount(); got != 3 {
+		t.Errorf("RepeatCount() = %d, want 3", got)
+	}
+}
+
+// cadModel - 3MF из CAD без Metadata/model_settings.config: только сетки и build items
+const cadModel = `<?xml version="1.0" encoding="UTF-8"?>
+<model unit="millimeter" xmlns="http://schemas.microsoft.com/3dmanufacturing/core/2015/02">
+ <resources>
+  <object id="1" type="model" name="Bracket"><mesh><vertices><vertex x="0" y="0" z="0"/></vertices><triangles/></mesh></object>
+  <object id="2" type="model" name="Cover"><mesh><vertices><vertex x="0" y="0" z="0"/></vertices><triangles/></mesh></object>
+ </resources>
+ <build>
+  <item objectid="1"/>
+  <item objectid="1" transform="1 0 0 0 1 0 0 0 1 40 0 0"/>
+  <item objectid="2"/>
+ </build>
+</model>`
+
+func TestParse3MFWithoutModelSettings(t *testing.T) {
+	data, err := Parse3MF(writeTest3MF(t, map[string]string{"3D/3dmodel.model": cadModel}))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+
+	if len(data.Plates) != 1 {
+		t.Fatalf("expected 1 default plate, got %d", len(data.Plates))
+	}
+	plate := data.Plates[0]
+	if plate.PlateID != defaultPlateID || plate.PlateName != "" {
+		t.Errorf("default plate = %d %q, want %d without name", plate.PlateID, plate.PlateName, defaultPlateID)
+	}
+
+	counts := make(map[string]int)
+	for _, obj := range plate.Objects {
+		counts[obj.Name]++
+		if obj.Material != "" || !obj.Printable {
+			t.Errorf("object %q: material %q, printable %v; want no material, printable", obj.Name, obj.Material, obj.Printable)
+		}
+	}
+	if counts["Bracket"] != 2 || counts["Cover"] != 1 || len(plate.Objects) != 3 {
+		t.Errorf("objects = %v, want Bracket x2 and Cover x1", counts)
+	}
+}
+
+func TestParse3MFKeepExtracted(t *testing.T) {
+	tempDir := t.TempDir()
+	for _, key := range []string{"TMPDIR", "TMP", "TEMP"} {
+		t.Setenv(key, tempDir)
+	}
+	path := writeTest3MF(t, map[string]string{
+		"3D/3dmodel.model":               twoExtruderModel,
+		"Metadata/model_settings.config": twoExtruderSettings,
+	})
+
+	// Без опции архив читается напрямую и ничего не остается на диске
+	data, err := Parse3MF(path, WithKeepExtracted(false))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if data.ExtractDir != "" {
+		t.Errorf("ExtractDir = %q, want empty without KeepExtracted", data.ExtractDir)
+	}
+	if entries, _ := os.ReadDir(tempDir); len(entries) != 0 {
+		t.Fatalf("parse without KeepExtracted left %d entries in temp dir", len(entries))
+	}
+
+	data, err = Parse3MF(path, WithKeepExtracted(true))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if filepath.Dir(data.ExtractDir) != tempDir {
+		t.Fatalf("ExtractDir = %q, want a directory inside %s", data.ExtractDir, tempDir)
+	}
+	if _, err := os.Stat(filepath.Join(data.ExtractDir, "Metadata", "model_settings.config")); err != nil {
+		t.Errorf("extracted settings are missing: %v", err)
+	}
+	if len(data.Plates) != 1 || len(data.Plates[0].Objects) != 3 {
+		t.Errorf("parsed %+v, want 1 plate with 3 objects", data.Plates)
+	}
+	if filtered := data.Filter(PlateFilter{PlateIDs: []int{1}}); filtered.ExtractDir != data.ExtractDir {
+		t.Errorf("Filter() dropped ExtractDir")
+	}
+
+	// Если разбор не удался, директория тоже остается, а путь есть в ошибке
+	brokenPath := writeTest3MF(t, map[string]string{"3D/3dmodel.model": "<model"})
+	_, parseErr := Parse3MF(brokenPath, WithKeepExtracted(true))
+	if parseErr == nil {
+		t.Fatal("expected error for broken model")
+	}
+	entries, err := os.ReadDir(tempDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("temp dir has %d entries, want 2 kept extract directories", len(entries))
+	}
+	for _, entry := range entries {
+		if dir := filepath.Join(tempDir, entry.Name()); dir != data.ExtractDir && !strings.Contains(parseErr.Error(), dir) {
+			t.Errorf("error %q does not mention kept directory %s", parseErr, dir)
+		}
+	}
+}
+
+func TestParse3MFCombinedOptions(t *testing.T) {
+	t.Setenv("TMPDIR", t.TempDir())
+	path := writeTest3MF(t, map[string]string{
+		"3D/3dmodel.model":     boxMainModel,
+		"3D/Objects/box.model": boxObjectModel,
+	})
+
+	// Габариты считаются и при разборе распакованной директории
+	data, err := Parse3MF(path, WithKeepExtracted(true), WithDimensions(true))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if data.ExtractDir == "" {
+		t.Fatal("ExtractDir is empty with WithKeepExtracted(true)")
+	}
+	if _, err := os.Stat(filepath.Join(data.ExtractDir, "3D", "Objects", "box.model")); err != nil {
+		t.Errorf("extracted mesh is missing: %v", err)
+	}
+	dims := data.Plates[0].Objects[0].Dimensions
+	if dims == nil || !sameDimensions(*dims, Dimensions{Width: 20, Depth: 10, Height: 5}) {
+		t.Errorf("dimensions = %v, want 20 x 10 x 5", dims)
+	}
+
+	// Последняя опция побеждает, без опций результат совпадает с выключенными опциями
+	data, err = Parse3MF(path, WithKeepExtracted(true), WithDimensions(true), WithKeepExtracted(false), WithDimensions(false))
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	plain, err := Parse3MF(path)
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if data.ExtractDir != "" || data.Plates[0].Objects[0].Dimensions != nil {
+		t.Errorf("disabled options still applied: ExtractDir = %q, dimensions = %v", data.ExtractDir, data.Plates[0].Objects[0].Dimensions)
+	}
+	if !reflect.DeepEqual(data, plain) {
+		t.Errorf("Parse3MF() with disabled options = %+v, want %+v", data, plain)
+	}
+}
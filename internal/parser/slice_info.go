@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"time"
+)
+
+// SliceInfoConfig описывает Metadata/slice_info.config, который Bambu Studio / OrcaSlicer
+// сохраняют в 3MF после нарезки проекта
+type SliceInfoConfig struct {
+	Plates []SliceInfoPlate `xml:"plate"`
+}
+
+type SliceInfoPlate struct {
+	Metadata  []MetadataEntry     `xml:"metadata"`
+	Filaments []SliceInfoFilament `xml:"filament"`
+}
+
+type SliceInfoFilament struct {
+	ID    int     `xml:"id,attr"`
+	Type  string  `xml:"type,attr"`
+	Color string  `xml:"color,attr"`
+	UsedM float64 `xml:"used_m,attr"`
+	UsedG float64 `xml:"used_g,attr"`
+}
+
+// PlateSliceInfo содержит данные нарезки для одного стола
+type PlateSliceInfo struct {
+	WeightGrams        float64
+	SupportWeightGrams float64
+	PrintTime          time.Duration
+	// FilamentWeights - расход филамента в граммах по номеру экструдера
+	FilamentWeights map[int]float64
+}
+
+// ParseSliceInfo читает Metadata/slice_info.config и возвращает данные нарезки по номеру стола.
+// Если файл отсутствует (проект не нарезан), возвращается пустая карта без ошибки
+func ParseSliceInfo(fsys fs.FS) (map[int]PlateSliceInfo, error) {
+	result := make(map[int]PlateSliceInfo)
+
+	data, err := readArchiveFile(fsys, "Metadata/slice_info.config")
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return result, nil
+		}
+		return nil, fmt.Errorf("failed to read slice info: %w", err)
+	}
+
+	var config SliceInfoConfig
+	if err := xml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse slice info XML: %w", err)
+	}
+
+	for _, plate := range config.Plates {
+		plateID, err := strconv.Atoi(extractMetadataValue(plate.Metadata, "index"))
+		if err != nil {
+			continue
+		}
+
+		info := PlateSliceInfo{
+			WeightGrams:        parseMetadataFloat(plate.Metadata, "weight"),
+			SupportWeightGrams: parseMetadataFloat(plate.Metadata, "support_weight"),
+			PrintTime:          time.Duration(parseMetadataFloat(plate.Metadata, "prediction")) * time.Second,
+			FilamentWeights:    make(map[int]float64),
+		}
+
+		for _, filament := range plate.Filaments {
+			info.FilamentWeights[filament.ID] += filament.UsedG
+		}
+
+		// Если общий вес не указан, суммируем вес по филаментам
+		if info.WeightGrams == 0 {
+			for _, filament := range plate.Filaments {
+				info.WeightGrams += filament.UsedG
+			}
+		}
+
+		result[plateID] = info
+	}
+
+	return result, nil
+}
+
+// TotalPrintTime возвращает суммарное время печати всех столов.
+// Столы без данных нарезки не учитываются; для ненарезанного проекта результат нулевой
+func (p *Parser3MF) TotalPrintTime() time.Duration {
+	var total time.Duration
+	for _, plate := range p.Plates {
+		total += plate.PrintTime
+	}
+	return total
+}
+
+func parseMetadataFloat(metadata []MetadataEntry, key string) float64 {
+	value, err := strconv.ParseFloat(extractMetadataValue(metadata, key), 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
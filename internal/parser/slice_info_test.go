@@ -0,0 +1,111 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// prepareSliceInfoDir копирует testdata/slice_info.config в структуру распакованного 3MF
+func prepareSliceInfoDir(t *testing.T) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join("testdata", "slice_info.config"))
+	if err != nil {
+		t.Fatalf("failed to read testdata: %v", err)
+	}
+
+	extractDir := t.TempDir()
+	metadataDir := filepath.Join(extractDir, "Metadata")
+	if err := os.MkdirAll(metadataDir, 0755); err != nil {
+		t.Fatalf("failed to create metadata dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(metadataDir, "slice_info.config"), data, 0644); err != nil {
+		t.Fatalf("failed to write slice info: %v", err)
+	}
+
+	return extractDir
+}
+
+func TestParseSliceInfo(t *testing.T) {
+	result, err := ParseSliceInfo(os.DirFS(prepareSliceInfoDir(t)))
+	if err != nil {
+		t.Fatalf("ParseSliceInfo() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		plateID  int
+		expected PlateSliceInfo
+	}{
+		{
+			name:    "plate with weight and support weight",
+			plateID: 1,
+			expected: PlateSliceInfo{
+				WeightGrams:        45.67,
+				SupportWeightGrams: 3.21,
+				PrintTime:          90 * time.Minute,
+				FilamentWeights:    map[int]float64{1: 45.67},
+			},
+		},
+		{
+			name:    "weight summed from filaments",
+			plateID: 2,
+			expected: PlateSliceInfo{
+				WeightGrams:     12.75,
+				PrintTime:       30 * time.Minute,
+				FilamentWeights: map[int]float64{1: 10.50, 2: 2.25},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, exists := result[tt.plateID]
+			if !exists {
+				t.Fatalf("plate %d not found in slice info", tt.plateID)
+			}
+			if !reflect.DeepEqual(info, tt.expected) {
+				t.Errorf("plate %d = %+v, want %+v", tt.plateID, info, tt.expected)
+			}
+		})
+	}
+
+	if len(result) != 2 {
+		t.Errorf("expected 2 plates (plate without index skipped), got %d", len(result))
+	}
+}
+
+func TestParseSliceInfoMissingFile(t *testing.T) {
+	result, err := ParseSliceInfo(os.DirFS(t.TempDir()))
+	if err != nil {
+		t.Fatalf("expected no error for missing slice info, got %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("expected empty result, got %v", result)
+	}
+}
+
+func TestParseSliceInfoUnslicedProject(t *testing.T) {
+	// Файл из ненарезанного проекта содержит только заголовок
+	extractDir := t.TempDir()
+	metadataDir := filepath.Join(extractDir, "Metadata")
+	os.MkdirAll(metadataDir, 0755)
+	header := `<?xml version="1.0" encoding="UTF-8"?>
+<config>
+  <header>
+    <header_item key="X-BBL-Client-Type" value="slicer"/>
+  </header>
+</config>`
+	os.WriteFile(filepath.Join(metadataDir, "slice_info.config"), []byte(header), 0644)
+
+	result, err := ParseSliceInfo(os.DirFS(extractDir))
+	if err != nil {
+		t.Fatalf("ParseSliceInfo() error = %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("expected no plates for unsliced project, got %v", result)
+	}
+}
@@ -0,0 +1,29 @@
+package parser
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// findPlateThumbnail ищет превью стола в архиве.
+// Сначала проверяется Metadata/plate_N.png, для единственного стола также
+// Metadata/thumbnail.png. Возвращает путь внутри архива и содержимое файла,
+// либо пустые значения, если превью нет
+func findPlateThumbnail(fsys fs.FS, plateID int, plateCount int) (string, []byte) {
+	candidates := []string{
+		fmt.Sprintf("Metadata/plate_%d.png", plateID),
+	}
+	if plateCount == 1 {
+		candidates = append(candidates, "Metadata/thumbnail.png")
+	}
+
+	for _, candidate := range candidates {
+		data, err := readArchiveFile(fsys, candidate)
+		if err != nil || len(data) == 0 {
+			continue
+		}
+		return candidate, data
+	}
+
+	return "", nil
+}
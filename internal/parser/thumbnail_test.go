@@ -0,0 +1,69 @@
+package parser
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+func TestParse3MFFindsPlateThumbnails(t *testing.T) {
+	samplePath := filepath.Join("..", "..", "samples", "22d.3mf")
+	if _, err := os.Stat(samplePath); err != nil {
+		t.Skipf("sample file not available: %v", err)
+	}
+
+	data, err := Parse3MF(samplePath)
+	if err != nil {
+		t.Fatalf("Parse3MF() error = %v", err)
+	}
+	if len(data.Plates) == 0 {
+		t.Fatal("expected plates in sample file")
+	}
+
+	for _, plate := range data.Plates {
+		expectedPath := fmt.Sprintf("Metadata/plate_%d.png", plate.PlateID)
+		if plate.ThumbnailPath != expectedPath {
+			t.Errorf("plate %d thumbnail path = %q, want %q", plate.PlateID, plate.ThumbnailPath, expectedPath)
+		}
+		if !bytes.HasPrefix(plate.Thumbnail, pngSignature) {
+			t.Errorf("plate %d thumbnail is not a PNG image", plate.PlateID)
+		}
+	}
+}
+
+func TestFindPlateThumbnail(t *testing.T) {
+	extractDir := t.TempDir()
+	metadataDir := filepath.Join(extractDir, "Metadata")
+	if err := os.MkdirAll(metadataDir, 0755); err != nil {
+		t.Fatalf("failed to create metadata dir: %v", err)
+	}
+	os.WriteFile(filepath.Join(metadataDir, "plate_2.png"), pngSignature, 0644)
+	os.WriteFile(filepath.Join(metadataDir, "thumbnail.png"), pngSignature, 0644)
+
+	tests := []struct {
+		name         string
+		plateID      int
+		plateCount   int
+		expectedPath string
+	}{
+		{"plate specific thumbnail", 2, 3, "Metadata/plate_2.png"},
+		{"single plate falls back to thumbnail.png", 1, 1, "Metadata/thumbnail.png"},
+		{"missing thumbnail on multi-plate project", 1, 3, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, data := findPlateThumbnail(os.DirFS(extractDir), tt.plateID, tt.plateCount)
+			if path != tt.expectedPath {
+				t.Errorf("findPlateThumbnail() path = %q, want %q", path, tt.expectedPath)
+			}
+			if (len(data) > 0) != (tt.expectedPath != "") {
+				t.Errorf("findPlateThumbnail() returned %d bytes for path %q", len(data), path)
+			}
+		})
+	}
+}
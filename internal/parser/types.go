@@ -1,5 +1,7 @@
 package parser
 
+import "time"
+
 type Transform3D struct {
 	Matrix [12]float64 `json:"matrix"`
 }
@@ -19,12 +21,40 @@ type PlateObject struct {
 	Position   Transform3D     `json:"position"`
 	Printable  bool            `json:"printable"`
 	Components []ComponentInfo `json:"components,omitempty"`
+	// Dimensions - габариты объекта на столе в мм (nil, если в модели нет вершин)
+	Dimensions *Dimensions `json:"dimensions,omitempty"`
+	// Materials - все материалы раскрашенного объекта (покраска по экструдерам), если их больше одного;
+	// Material в этом случае - первый из них
+	Materials []string `json:"materials,omitempty"`
 }
 
 type PlateInfo struct {
 	PlateID   int           `json:"plate_id"`
 	PlateName string        `json:"plate_name"`
 	Objects   []PlateObject `json:"objects"`
+
+	// Данные нарезки из Metadata/slice_info.config (нулевые, если проект не нарезан)
+	WeightGrams        float64       `json:"weight_grams,omitempty"`
+	SupportWeightGrams float64       `json:"support_weight_grams,omitempty"`
+	PrintTime          time.Duration `json:"-"`
+	// MaterialWeights - расход материала в граммах по названию материала
+	MaterialWeights map[string]float64 `json:"material_weights,omitempty"`
+
+	// Превью стола: путь внутри архива (например, Metadata/plate_1.png) и содержимое PNG
+	ThumbnailPath string `json:"thumbnail_path,omitempty"`
+	Thumbnail     []byte `json:"-"`
+
+	// Repetitions - сколько раз печатается стол (metadata "repetitions" стола или order --repeat),
+	// 0 - один раз
+	Repetitions int `json:"repetitions,omitempty"`
+}
+
+// RepeatCount возвращает число печатей стола (не меньше 1)
+func (p PlateInfo) RepeatCount() int {
+	if p.Repetitions < 1 {
+		return 1
+	}
+	return p.Repetitions
 }
 
 type GroupedObject struct {
@@ -34,10 +64,16 @@ type GroupedObject struct {
 	Count      int             `json:"count"`
 	Components []ComponentInfo `json:"components,omitempty"`
 	ObjectIDs  []int           `json:"object_ids"`
+	// Dimensions - габариты первого объекта группы в мм
+	Dimensions *Dimensions `json:"dimensions,omitempty"`
+	// Materials - все материалы раскрашенных объектов группы (см. PlateObject.Materials)
+	Materials []string `json:"materials,omitempty"`
 }
 
 type Parser3MF struct {
 	Plates []PlateInfo `json:"plates"`
+	// ExtractDir - директория с распакованным архивом, если разбор шел с WithKeepExtracted
+	ExtractDir string `json:"-"`
 }
 
 type ModelObject struct {
@@ -63,6 +99,9 @@ type Triangle struct {
 	V1 int `xml:"v1,attr"`
 	V2 int `xml:"v2,attr"`
 	V3 int `xml:"v3,attr"`
+	// Покраска треугольника по экструдерам: paint_color у Bambu/Orca, slic3rpe:mmu_segmentation у PrusaSlicer
+	PaintColor      string `xml:"paint_color,attr"`
+	MMUSegmentation string `xml:"mmu_segmentation,attr"`
 }
 
 type ComponentsCollection struct {
@@ -0,0 +1,46 @@
+package slicer
+
+import (
+	"fmt"
+	"math"
+
+	"farmix-cli/internal/stl"
+)
+
+// Допущения оценки без слайсинга: модель печатается сплошной из PLA прутком 1.75 мм слоями 0.2 мм
+const (
+	estimateMaterial      = "PLA"
+	estimateLayerHeightMM = 0.2
+)
+
+// EstimateFromModel оценивает расход филамента по объему STL модели без слайсинга.
+// Вес считается по плотности PLA для сплошной модели (без заполнения и поддержек), длина - по сечению
+// прутка 1.75 мм, количество слоев - по высоте модели. Время печати не оценивается.
+// Результат помечен Estimated и SlicingSuccess=false: это не данные слайсера
+func EstimateFromModel(stlFile string) (*SliceResult, error) {
+	volume, err := stl.CalculateVolume(stlFile, stl.VolumeConfig{Units: "mm3", Material: estimateMaterial})
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate model volume: %w", err)
+	}
+
+	bbox, err := stl.GetBoundingBox(stlFile, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate model height: %w", err)
+	}
+
+	filamentArea := math.Pi * math.Pow(DefaultFilamentDiameterMM/2, 2)
+	height := bbox.Max.Z - bbox.Min.Z
+
+	return &SliceResult{
+		FilamentUsed: FilamentUsage{
+			LengthMM:     volume.Volume / filamentArea,
+			WeightGrams:  volume.Weight,
+			VolumeMM3:    volume.Volume,
+			MaterialType: estimateMaterial,
+		},
+		LayerCount:     int(math.Ceil(height / estimateLayerHeightMM)),
+		LayerHeight:    estimateLayerHeightMM,
+		SlicingSuccess: false,
+		Estimated:      true,
+	}, nil
+}
\ No newline at end of file
@@ -24,6 +24,9 @@ func ParseGCodeFile(filePath string) (*GCodeStats, error) {
 		MaterialTypes:    make([]string, 0),
 	}
 
+	// Приоритет варианта, из которого взято stats.PrintTime (см. printTimePatterns)
+	printTimePriority := 0
+
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
@@ -39,7 +42,9 @@ func ParseGCodeFile(filePath string) (*GCodeStats, error) {
 		// Парсим различные поля
 		parseFilamentLength(comment, stats)
 		parseFilamentWeight(comment, stats)
-		parsePrintTime(comment, stats)
+		if printTime, priority, ok := parsePrintTime(comment); ok && priority > printTimePriority {
+			stats.PrintTime, printTimePriority = printTime, priority
+		}
 		parseLayerInfo(comment, stats)
 		parseMaterialType(comment, stats)
 	}
@@ -145,43 +150,89 @@ func parseMultiExtruderWeight(comment string, stats *GCodeStats) bool {
 	return len(values) > 1
 }
 
-// parsePrintTime ищет информацию о времени печати
-func parsePrintTime(comment string, stats *GCodeStats) {
-	patterns := []string{
-		`estimated\s+printing\s+time\s*=\s*(\d+)h\s*(\d+)m\s*(\d+)s`,
-		`print\s+time\s*:\s*(\d+)h\s*(\d+)m\s*(\d+)s`,
-		`total\s+print\s+time\s*:\s*(\d+):(\d+):(\d+)`,
-		`TIME:(\d+)`,
-	}
+// printTimePattern - вариант записи времени печати в G-code; из нескольких найденных в файле
+// берется вариант с наибольшим priority (общее время важнее времени печати самой модели)
+type printTimePattern struct {
+	re       *regexp.Regexp
+	priority int
+	parse    func(matches []string) (time.Duration, bool)
+}
 
-	// Формат: часы, минуты, секунды
-	for i, pattern := range patterns[:3] {
-		re := regexp.MustCompile(`(?i)` + pattern)
-		if matches := re.FindStringSubmatch(comment); len(matches) > 3 {
-			hours, _ := strconv.Atoi(matches[1])
-			minutes, _ := strconv.Atoi(matches[2])
-			seconds, _ := strconv.Atoi(matches[3])
-			
-			if i == 2 { // формат HH:MM:SS
-				stats.PrintTime = time.Duration(hours)*time.Hour + 
-								 time.Duration(minutes)*time.Minute + 
-								 time.Duration(seconds)*time.Second
-			} else {
-				stats.PrintTime = time.Duration(hours)*time.Hour + 
-								 time.Duration(minutes)*time.Minute + 
-								 time.Duration(seconds)*time.Second
-			}
-			return
+// durationTextPattern - длительность вида "1d 2h 3m 4s", любые компоненты могут отсутствовать
+const durationTextPattern = `((?:\d+\s*[dhms]\s*)+)`
+
+var printTimePatterns = []printTimePattern{
+	// Bambu Studio: "model printing time: 1h 23m 45s; total estimated time: 1h 30m 0s"
+	{regexp.MustCompile(`(?i)total\s+estimated\s+time\s*[:=]\s*` + durationTextPattern), 3, parseDurationMatch},
+	// Orca/Prusa: "estimated printing time (normal mode) = 1h 2m 3s" (silent mode не учитывается)
+	{regexp.MustCompile(`(?i)estimated\s+printing\s+time(?:\s*\(normal\s+mode\))?\s*[:=]\s*` + durationTextPattern), 2, parseDurationMatch},
+	{regexp.MustCompile(`(?i)total\s+print\s+time\s*:\s*(\d+):(\d+):(\d+)`), 2, parseClockMatch},
+	// Cura: ";TIME:5400" в секундах (";TIME_ELAPSED:" - время на конец слоя, не итог)
+	{regexp.MustCompile(`(?i)^TIME:(\d+)$`), 2, parseSecondsMatch},
+	{regexp.MustCompile(`(?i)model\s+printing\s+time\s*[:=]\s*` + durationTextPattern), 1, parseDurationMatch},
+	{regexp.MustCompile(`(?i)print\s+time\s*:\s*` + durationTextPattern), 1, parseDurationMatch},
+}
+
+// durationComponentRegex выделяет компоненты длительности: "23m" -> 23, "m"
+var durationComponentRegex = regexp.MustCompile(`(\d+)\s*([dhms])`)
+
+// durationUnits - единицы компонентов длительности
+var durationUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"h": time.Hour,
+	"m": time.Minute,
+	"s": time.Second,
+}
+
+// parsePrintTime ищет время печати в комментарии и возвращает его с приоритетом варианта записи
+// (при нескольких вариантах в одной строке - с наибольшим)
+func parsePrintTime(comment string) (time.Duration, int, bool) {
+	var best time.Duration
+	bestPriority := 0
+	for _, pattern := range printTimePatterns {
+		if pattern.priority <= bestPriority {
+			continue
+		}
+		matches := pattern.re.FindStringSubmatch(comment)
+		if matches == nil {
+			continue
+		}
+		if duration, ok := pattern.parse(matches); ok {
+			best, bestPriority = duration, pattern.priority
 		}
 	}
+	return best, bestPriority, bestPriority > 0
+}
 
-	// Формат: только секунды
-	re := regexp.MustCompile(`(?i)` + patterns[3])
-	if matches := re.FindStringSubmatch(comment); len(matches) > 1 {
-		if seconds, err := strconv.Atoi(matches[1]); err == nil {
-			stats.PrintTime = time.Duration(seconds) * time.Second
+// parseDurationMatch разбирает длительность "1d 2h 3m 4s" из первой группы
+func parseDurationMatch(matches []string) (time.Duration, bool) {
+	var duration time.Duration
+	components := durationComponentRegex.FindAllStringSubmatch(strings.ToLower(matches[1]), -1)
+	for _, component := range components {
+		value, err := strconv.Atoi(component[1])
+		if err != nil {
+			return 0, false
 		}
+		duration += time.Duration(value) * durationUnits[component[2]]
+	}
+	return duration, len(components) > 0
+}
+
+// parseClockMatch разбирает время HH:MM:SS из трех групп
+func parseClockMatch(matches []string) (time.Duration, bool) {
+	hours, _ := strconv.Atoi(matches[1])
+	minutes, _ := strconv.Atoi(matches[2])
+	seconds, _ := strconv.Atoi(matches[3])
+	return time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second, true
+}
+
+// parseSecondsMatch разбирает время в секундах из первой группы
+func parseSecondsMatch(matches []string) (time.Duration, bool) {
+	seconds, err := strconv.Atoi(matches[1])
+	if err != nil {
+		return 0, false
 	}
+	return time.Duration(seconds) * time.Second, true
 }
 
 // parseLayerInfo ищет информацию о слоях
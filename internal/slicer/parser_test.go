@@ -0,0 +1,130 @@
+package slicer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePrintTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		comment string
+		want    time.Duration
+		found   bool
+	}{
+		{"bambu total over model", "model printing time: 1h 23m 45s; total estimated time: 1h 30m 0s", 90 * time.Minute, true},
+		{"bambu with days", "model printing time: 1d 2h 3m 4s; total estimated time: 1d 2h 10m 4s", 26*time.Hour + 10*time.Minute + 4*time.Second, true},
+		{"bambu minutes only", "model printing time: 12m 5s; total estimated time: 18m 40s", 18*time.Minute + 40*time.Second, true},
+		{"orca normal mode", "estimated printing time (normal mode) = 2h 5m 17s", 2*time.Hour + 5*time.Minute + 17*time.Second, true},
+		{"prusa without hours", "estimated printing time (normal mode) = 45m 12s", 45*time.Minute + 12*time.Second, true},
+		{"orca silent mode ignored", "estimated printing time (silent mode) = 2h 20m 1s", 0, false},
+		{"legacy estimated", "estimated printing time = 0h 15m 30s", 15*time.Minute + 30*time.Second, true},
+		{"cura total", "TIME:5400", 90 * time.Minute, true},
+		{"cura elapsed ignored", "TIME_ELAPSED:5399.873046", 0, false},
+		{"clock format", "total print time: 01:02:03", time.Hour + 2*time.Minute + 3*time.Second, true},
+		{"unrelated", "layer_height = 0.2", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, found := parsePrintTime(tt.comment)
+			if found != tt.found || got != tt.want {
+				t.Errorf("parsePrintTime(%q) = (%v, %v), want (%v, %v)", tt.comment, got, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+// Длинные печати пишутся с днями: "2d 3h 15m"; формы без дней не должны сломаться
+func TestParsePrintTimeDays(t *testing.T) {
+	const day = 24 * time.Hour
+	tests := []struct {
+		name    string
+		comment string
+		want    time.Duration
+	}{
+		{"days full", "estimated printing time = 1d 2h 3m 4s", day + 2*time.Hour + 3*time.Minute + 4*time.Second},
+		{"days zero hours", "estimated printing time (normal mode) = 2d 0h 30m", 2*day + 30*time.Minute},
+		{"days without seconds", "total estimated time: 2d 3h 15m", 2*day + 3*time.Hour + 15*time.Minute},
+		{"days uppercase", "print time: 1D 1H", day + time.Hour},
+		{"hours minutes seconds", "estimated printing time = 5h 4m 3s", 5*time.Hour + 4*time.Minute + 3*time.Second},
+		{"hour only", "estimated printing time = 3h", 3 * time.Hour},
+		{"seconds only", "estimated printing time (normal mode) = 42s", 42 * time.Second},
+		{"clock HH:MM:SS", "total print time: 49:15:00", 49*time.Hour + 15*time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, found := parsePrintTime(tt.comment)
+			if !found || got != tt.want {
+				t.Errorf("parsePrintTime(%q) = (%v, %v), want %v", tt.comment, got, found, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGCodeFilePrintTime(t *testing.T) {
+	tests := []struct {
+		name  string
+		gcode []string
+		want  time.Duration
+	}{
+		{
+			name: "bambu total wins over later model time",
+			gcode: []string{
+				"; HEADER_BLOCK_START",
+				"; BambuStudio 01.09.00.70",
+				"; model printing time: 1h 23m 45s; total estimated time: 1h 30m 0s",
+				"; total layer number: 120",
+				"; HEADER_BLOCK_END",
+				"; filament used [mm] = 1500.25",
+				"; filament used [g] = 4.47",
+				"; model printing time: 1h 23m 45s",
+			},
+			want: 90 * time.Minute,
+		},
+		{
+			name: "cura time and elapsed",
+			gcode: []string{
+				";FLAVOR:Marlin",
+				";TIME:5400",
+				";Filament used: 1.5m",
+				";LAYER_COUNT:120",
+				"; filament used [mm] = 1500",
+				";TIME_ELAPSED:12.5",
+				";TIME_ELAPSED:5399.87",
+			},
+			want: 90 * time.Minute,
+		},
+		{
+			name: "orca normal and silent mode",
+			gcode: []string{
+				"; filament used [mm] = 1500.25",
+				"; filament used [g] = 4.47",
+				"; estimated printing time (normal mode) = 2h 5m 17s",
+				"; estimated printing time (silent mode) = 2h 20m 1s",
+			},
+			want: 2*time.Hour + 5*time.Minute + 17*time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "part.gcode")
+			if err := os.WriteFile(path, []byte(strings.Join(tt.gcode, "\n")+"\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			stats, err := ParseGCodeFile(path)
+			if err != nil {
+				t.Fatalf("ParseGCodeFile() error = %v", err)
+			}
+			if stats.PrintTime != tt.want {
+				t.Errorf("PrintTime = %v, want %v", stats.PrintTime, tt.want)
+			}
+		})
+	}
+}
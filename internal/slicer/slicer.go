@@ -55,6 +55,8 @@ func SliceSTL(config SliceConfig) (*SliceResult, error) {
 			OutputFile:     actualOutputFile,
 		}, err
 	}
+	// Вес по длине и плотности, если слайсер записал только длину
+	DeriveFilamentWeight(stats, config.FilamentDiameterMM)
 
 	// Формируем результат
 	result := &SliceResult{
@@ -137,7 +139,7 @@ func executeOrcaSlicer(config SliceConfig, outputFile string) error {
 	
 	// Если есть вывод, показываем его (для отладки)
 	if len(output) > 0 {
-		fmt.Printf("OrcaSlicer output (exit code %d):\n%s\n", exitCode, string(output))
+		fmt.Fprintf(os.Stderr, "OrcaSlicer output (exit code %d):\n%s\n", exitCode, string(output))
 	}
 	
 	// Не считаем ошибкой, если OrcaSlicer завершился с предупреждениями
@@ -11,6 +11,8 @@ type SliceResult struct {
 	OutputFile       string        `json:"output_file"`
 	SlicingSuccess   bool          `json:"slicing_success"`
 	ErrorMessage     string        `json:"error_message,omitempty"`
+	// Estimated - значения оценены по объему модели (EstimateFromModel), а не получены слайсингом
+	Estimated bool `json:"estimated,omitempty"`
 }
 
 // FilamentUsage содержит информацию о расходе филамента
@@ -23,13 +25,14 @@ type FilamentUsage struct {
 
 // SliceConfig содержит конфигурацию для слайсинга
 type SliceConfig struct {
-	OrcaPath       string            `json:"orca_path"`       // Путь к исполняемому файлу OrcaSlicer
-	STLFile        string            `json:"stl_file"`        // Путь к STL файлу
-	OutputDir      string            `json:"output_dir"`      // Директория для сохранения результата
-	PrinterProfile string            `json:"printer_profile"` // Профиль принтера
-	MaterialProfile string           `json:"material_profile"` // Профиль материала
-	PrintProfile   string            `json:"print_profile"`   // Профиль печати
-	ExtraParams    map[string]string `json:"extra_params"`    // Дополнительные параметры
+	OrcaPath           string            `json:"orca_path"`            // Путь к исполняемому файлу OrcaSlicer
+	STLFile            string            `json:"stl_file"`             // Путь к STL файлу
+	OutputDir          string            `json:"output_dir"`           // Директория для сохранения результата
+	PrinterProfile     string            `json:"printer_profile"`      // Профиль принтера
+	MaterialProfile    string            `json:"material_profile"`     // Профиль материала
+	PrintProfile       string            `json:"print_profile"`        // Профиль печати
+	FilamentDiameterMM float64           `json:"filament_diameter_mm"` // Диаметр прутка для расчета веса по длине (0 - 1.75 мм)
+	ExtraParams        map[string]string `json:"extra_params"`         // Дополнительные параметры
 }
 
 // GCodeStats содержит статистику, извлеченную из G-code
@@ -0,0 +1,58 @@
+package slicer
+
+import (
+	"math"
+	"strings"
+
+	"farmix-cli/internal/stl"
+)
+
+// DefaultFilamentDiameterMM - диаметр прутка, если в SliceConfig не задан FilamentDiameterMM
+const DefaultFilamentDiameterMM = 1.75
+
+// filamentWeightGrams переводит длину прутка в вес: объем π r² L (мм³) -> см³ * плотность (г/см³)
+func filamentWeightGrams(lengthMM, diameterMM, density float64) float64 {
+	radius := diameterMM / 2
+	volumeMM3 := math.Pi * radius * radius * lengthMM
+	return volumeMM3 / 1000 * density
+}
+
+// DeriveFilamentWeight заполняет FilamentWeightG по FilamentLengthMM, если G-code содержит только длину
+// (некоторые слайсеры не пишут "filament used [g]"). Плотность берется из stl.MaterialDensity по типу
+// материала экструдера: при одном типе он используется для всех экструдеров. Если вес уже есть,
+// длины нет или плотность материала неизвестна, stats не меняется.
+// diameterMM <= 0 означает DefaultFilamentDiameterMM
+func DeriveFilamentWeight(stats *GCodeStats, diameterMM float64) {
+	if len(stats.FilamentWeightG) > 0 || len(stats.FilamentLengthMM) == 0 {
+		return
+	}
+	if diameterMM <= 0 {
+		diameterMM = DefaultFilamentDiameterMM
+	}
+
+	weights := make([]float64, len(stats.FilamentLengthMM))
+	for i, length := range stats.FilamentLengthMM {
+		density, ok := extruderDensity(stats.MaterialTypes, i)
+		if !ok {
+			return
+		}
+		weights[i] = filamentWeightGrams(length, diameterMM, density)
+	}
+	stats.FilamentWeightG = weights
+}
+
+// extruderDensity возвращает плотность материала экструдера index (единственный тип - для всех экструдеров)
+func extruderDensity(materialTypes []string, index int) (float64, bool) {
+	var material string
+	switch {
+	case len(materialTypes) == 1:
+		material = materialTypes[0]
+	case index < len(materialTypes):
+		material = materialTypes[index]
+	default:
+		return 0, false
+	}
+
+	density, ok := stl.MaterialDensity[strings.ToUpper(strings.TrimSpace(material))]
+	return density, ok
+}
\ No newline at end of file
@@ -0,0 +1,77 @@
+package slicer
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeriveFilamentWeightFromLength(t *testing.T) {
+	stats := &GCodeStats{FilamentLengthMM: []float64{1000}, MaterialTypes: []string{"PLA"}}
+	DeriveFilamentWeight(stats, 0)
+
+	// 1 м прутка 1.75 мм: π * 0.875² * 1000 = 2405.28 мм³ = 2.405 см³, PLA 1.24 г/см³ -> 2.98 г
+	if len(stats.FilamentWeightG) != 1 || math.Abs(stats.FilamentWeightG[0]-2.9826) > 0.001 {
+		t.Errorf("FilamentWeightG = %v, want [2.98]", stats.FilamentWeightG)
+	}
+
+	// Пруток 2.85 мм при той же длине весит в (2.85/1.75)² раз больше
+	thick := &GCodeStats{FilamentLengthMM: []float64{1000}, MaterialTypes: []string{"pla"}}
+	DeriveFilamentWeight(thick, 2.85)
+	if want := stats.FilamentWeightG[0] * math.Pow(2.85/1.75, 2); len(thick.FilamentWeightG) != 1 || math.Abs(thick.FilamentWeightG[0]-want) > 1e-9 {
+		t.Errorf("FilamentWeightG for 2.85 mm = %v, want [%.4f]", thick.FilamentWeightG, want)
+	}
+}
+
+func TestDeriveFilamentWeightPerExtruder(t *testing.T) {
+	stats := &GCodeStats{FilamentLengthMM: []float64{1000, 1000}, MaterialTypes: []string{"PLA", "PETG"}}
+	DeriveFilamentWeight(stats, DefaultFilamentDiameterMM)
+
+	if len(stats.FilamentWeightG) != 2 || stats.FilamentWeightG[1] <= stats.FilamentWeightG[0] {
+		t.Errorf("FilamentWeightG = %v, want PETG (1.27) heavier than PLA (1.24)", stats.FilamentWeightG)
+	}
+}
+
+func TestDeriveFilamentWeightKeepsExistingWeight(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats GCodeStats
+		want  []float64
+	}{
+		{"both present", GCodeStats{FilamentLengthMM: []float64{1000}, FilamentWeightG: []float64{5.5}, MaterialTypes: []string{"PLA"}}, []float64{5.5}},
+		{"unknown material", GCodeStats{FilamentLengthMM: []float64{1000}, MaterialTypes: []string{"UNOBTAINIUM"}}, nil},
+		{"no material", GCodeStats{FilamentLengthMM: []float64{1000}}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := tt.stats
+			DeriveFilamentWeight(&stats, 0)
+			if len(stats.FilamentWeightG) != len(tt.want) || (len(tt.want) > 0 && stats.FilamentWeightG[0] != tt.want[0]) {
+				t.Errorf("FilamentWeightG = %v, want %v", stats.FilamentWeightG, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGCodeFileLengthOnly(t *testing.T) {
+	gcode := filepath.Join(t.TempDir(), "part.gcode")
+	content := "; filament used [mm] = 1000\n; filament_type = PETG\nG1 X10\n"
+	if err := os.WriteFile(gcode, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := ParseGCodeFile(gcode)
+	if err != nil {
+		t.Fatalf("ParseGCodeFile() error = %v", err)
+	}
+	if len(stats.FilamentWeightG) != 0 {
+		t.Fatalf("G-code has no weight, got %v", stats.FilamentWeightG)
+	}
+
+	DeriveFilamentWeight(stats, 0)
+	if len(stats.FilamentWeightG) != 1 || math.Abs(stats.FilamentWeightG[0]-3.0547) > 0.001 {
+		t.Errorf("FilamentWeightG = %v, want [3.05] (PETG 1.27 g/cm³)", stats.FilamentWeightG)
+	}
+}
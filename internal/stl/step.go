@@ -0,0 +1,133 @@
+package stl
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// ErrNoTessellator возвращается для STEP файла, если внешний тесселятор не настроен
+var ErrNoTessellator = errors.New("STEP volume requires a configured tessellator (step_tessellator)")
+
+// IsSTEPFile проверяет расширение STEP файла (.step, .stp)
+func IsSTEPFile(filePath string) bool {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	return ext == ".step" || ext == ".stp"
+}
+
+// PrepareMeshFile возвращает путь к STL файлу с геометрией модели.
+// STL используется как есть; STEP тесселируется внешней командой tessellator
+// во временный STL, который удаляет cleanup. Без tessellator для STEP возвращается ErrNoTessellator
+func PrepareMeshFile(filePath, tessellator string) (string, func(), error) {
+	noCleanup := func() {}
+
+	switch {
+	case strings.HasSuffix(strings.ToLower(filePath), ".stl"):
+		return filePath, noCleanup, nil
+	case IsSTEPFile(filePath):
+		if strings.TrimSpace(tessellator) == "" {
+			return "", noCleanup, ErrNoTessellator
+		}
+		return tessellate(filePath, tessellator)
+	default:
+		return "", noCleanup, fmt.Errorf("file must have .stl, .step or .stp extension: %s", filePath)
+	}
+}
+
+// tessellate запускает команду tessellator для STEP файла и возвращает путь к полученному STL.
+// В команде подставляются {input} и {output}; без них пути добавляются в конец аргументов
+func tessellate(stepFile, tessellator string) (string, func(), error) {
+	if _, err := os.Stat(stepFile); err != nil {
+		return "", func() {}, fmt.Errorf("STEP file not found: %s", stepFile)
+	}
+
+	tempDir, err := os.MkdirTemp("", "farmix-cli_step_*")
+	if err != nil {
+		return "", func() {}, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	cleanup := func() { os.RemoveAll(tempDir) }
+
+	baseName := strings.TrimSuffix(filepath.Base(stepFile), filepath.Ext(stepFile))
+	outputFile := filepath.Join(tempDir, baseName+".stl")
+
+	args, err := tessellatorArgs(tessellator, stepFile, outputFile)
+	if err != nil {
+		cleanup()
+		return "", func() {}, err
+	}
+	output, err := exec.Command(args[0], args[1:]...).CombinedOutput()
+	if err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("STEP tessellation failed: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	if _, err := os.Stat(outputFile); err != nil {
+		cleanup()
+		return "", func() {}, fmt.Errorf("STEP tessellator did not create %s", filepath.Base(outputFile))
+	}
+
+	return outputFile, cleanup, nil
+}
+
+// tessellatorArgs разбивает команду тесселятора на аргументы (см. splitCommandLine) и подставляет пути файлов
+func tessellatorArgs(tessellator, input, output string) ([]string, error) {
+	fields, err := splitCommandLine(tessellator)
+	if err != nil {
+		return nil, fmt.Errorf("invalid step_tessellator command: %w", err)
+	}
+	if !strings.Contains(tessellator, "{input}") && !strings.Contains(tessellator, "{output}") {
+		return append(fields, input, output), nil
+	}
+
+	args := make([]string, len(fields))
+	for i, field := range fields {
+		field = strings.ReplaceAll(field, "{input}", input)
+		args[i] = strings.ReplaceAll(field, "{output}", output)
+	}
+	return args, nil
+}
+
+// splitCommandLine разбивает команду на аргументы по пробелам, как командная оболочка:
+// аргумент с пробелами берется в двойные или одинарные кавычки
+// ("/Applications/Free CAD.app/Contents/MacOS/FreeCADCmd" step2stl.py).
+// Обратная косая черта не экранирует, чтобы пути Windows (C:\Program Files\...) не менялись
+func splitCommandLine(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, command)
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
\ No newline at end of file
@@ -0,0 +1,149 @@
+package stl
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestPrepareMeshFileDispatch(t *testing.T) {
+	dir := t.TempDir()
+	stepFile := filepath.Join(dir, "part.STEP")
+	if err := os.WriteFile(stepFile, []byte("ISO-10303-21;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		wantErr error
+		passes  bool
+	}{
+		{"stl used as is", "model.STL", nil, true},
+		{"step without tessellator", stepFile, ErrNoTessellator, false},
+		{"stp without tessellator", "part.stp", ErrNoTessellator, false},
+		{"unsupported extension", "model.obj", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meshFile, cleanup, err := PrepareMeshFile(tt.file, "")
+			defer cleanup()
+
+			if tt.passes {
+				if err != nil || meshFile != tt.file {
+					t.Errorf("PrepareMeshFile(%q) = (%q, %v), want file unchanged", tt.file, meshFile, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("PrepareMeshFile(%q) expected error", tt.file)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("PrepareMeshFile(%q) error = %v, want %v", tt.file, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCalculateVolumeSTEPWithoutTessellator(t *testing.T) {
+	stepFile := filepath.Join(t.TempDir(), "part.step")
+	if err := os.WriteFile(stepFile, []byte("ISO-10303-21;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CalculateVolume(stepFile, VolumeConfig{Units: "mm3"}); !errors.Is(err, ErrNoTessellator) {
+		t.Errorf("CalculateVolume() error = %v, want %v", err, ErrNoTessellator)
+	}
+}
+
+func TestCalculateVolumeSTEPTessellator(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("tessellator stub uses cp")
+	}
+
+	cube, err := filepath.Abs("../../samples/test_cube.stl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stepFile := filepath.Join(t.TempDir(), "cube.stp")
+	if err := os.WriteFile(stepFile, []byte("ISO-10303-21;"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// "Тесселятор" копирует готовый STL в {output}
+	config := VolumeConfig{Units: "mm3", Tessellator: "cp " + cube + " {output}"}
+	fromSTEP, err := CalculateVolume(stepFile, config)
+	if err != nil {
+		t.Fatalf("CalculateVolume() error = %v", err)
+	}
+	fromSTL, err := CalculateVolume(cube, VolumeConfig{Units: "mm3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fromSTEP.Volume != fromSTL.Volume {
+		t.Errorf("STEP volume = %v, want %v", fromSTEP.Volume, fromSTL.Volume)
+	}
+	if fromSTEP.FilePath != stepFile {
+		t.Errorf("FilePath = %q, want %q", fromSTEP.FilePath, stepFile)
+	}
+
+	if _, err := GetBoundingBox(stepFile, config.Tessellator); err != nil {
+		t.Errorf("GetBoundingBox() error = %v", err)
+	}
+}
+
+func TestTessellatorArgs(t *testing.T) {
+	got, err := tessellatorArgs("conv --in {input} --out={output}", "a.step", "b.stl")
+	if err != nil {
+		t.Fatalf("tessellatorArgs() error = %v", err)
+	}
+	want := []string{"conv", "--in", "a.step", "--out=b.stl"}
+	if len(got) != len(want) {
+		t.Fatalf("tessellatorArgs() = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("tessellatorArgs()[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	appended, _ := tessellatorArgs("step2stl", "a.step", "b.stl")
+	if len(appended) != 3 || appended[1] != "a.step" || appended[2] != "b.stl" {
+		t.Errorf("tessellatorArgs() without placeholders = %q", appended)
+	}
+}
+
+func TestSplitCommandLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    []string
+		wantErr bool
+	}{
+		{"plain", "freecadcmd step2stl.py {input} {output}", []string{"freecadcmd", "step2stl.py", "{input}", "{output}"}, false},
+		{"double quoted path with spaces", `"/Applications/Free CAD.app/Contents/MacOS/FreeCADCmd" "my scripts/step2stl.py" {input}`,
+			[]string{"/Applications/Free CAD.app/Contents/MacOS/FreeCADCmd", "my scripts/step2stl.py", "{input}"}, false},
+		{"single quotes inside argument", `conv --out='{output}'  -v`, []string{"conv", "--out={output}", "-v"}, false},
+		{"windows path keeps backslashes", `"C:\Program Files\FreeCAD\bin\FreeCADCmd.exe" step2stl.py`,
+			[]string{`C:\Program Files\FreeCAD\bin\FreeCADCmd.exe`, "step2stl.py"}, false},
+		{"empty quoted argument", `conv ""`, []string{"conv", ""}, false},
+		{"unterminated quote", `"/Applications/Free CAD.app step2stl.py`, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCommandLine(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommandLine(%q) error = %v, wantErr %v", tt.command, err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCommandLine(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
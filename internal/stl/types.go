@@ -10,6 +10,12 @@ type VolumeResult struct {
 	Density       float64 `json:"density"`        // Плотность материала г/см³
 	FilePath      string  `json:"file_path"`      // Путь к исходному файлу
 	IsValid       bool    `json:"is_valid"`       // Валидность модели (замкнутая поверхность)
+
+	SurfaceArea    float64 `json:"surface_area"`         // Площадь поверхности, мм²
+	AdjustedWeight float64 `json:"adjusted_weight"`      // Вес с учетом заполнения, г (равен Weight без настроек заполнения)
+	InfillPercent  float64 `json:"infill_percent"`       // Процент заполнения, использованный в расчете
+	ShellThickness float64 `json:"shell_thickness"`      // Толщина оболочки, мм
+	Diagnostic     string  `json:"diagnostic,omitempty"` // Причина IsValid=false (например, вывернутая поверхность)
 }
 
 // MaterialDensity содержит плотности популярных 3D материалов (г/см³)
@@ -38,6 +44,7 @@ type Vector3D struct {
 // Triangle представляет треугольник с тремя вершинами
 type Triangle struct {
 	V0, V1, V2 Vector3D
+	Normal     Vector3D // нормаль из STL файла (нулевая, если не сохранена)
 }
 
 // BoundingBox представляет ограничивающий параллелепипед
@@ -47,7 +54,30 @@ type BoundingBox struct {
 
 // VolumeConfig содержит конфигурацию для расчета объема
 type VolumeConfig struct {
-	Units    string  // mm3, cm3, in3, m3
-	Material string  // название материала
-	Density  float64 // плотность в г/см³ (переопределяет материал)
+	Units       string             // mm3, cm3, in3, m3
+	SourceUnits string             // единицы координат в STL файле: mm, cm, in (пусто - mm)
+	Material    string             // название материала
+	Density     float64            // плотность в г/см³ (переопределяет материал)
+	Densities   map[string]float64 // таблица плотностей (nil - встроенная MaterialDensity)
+	Infill      *InfillConfig      // параметры заполнения (nil - модель считается сплошной)
+
+	// AssumePositive считает отрицательный signed volume допустимым (без диагностики вывернутой поверхности)
+	AssumePositive bool
+
+	// Tessellator - команда, превращающая STEP в STL (см. PrepareMeshFile); пусто - STEP не поддерживается
+	Tessellator string
+}
+
+// SourceUnitScales - множители перевода единиц координат STL в миллиметры
+var SourceUnitScales = map[string]float64{
+	"mm": 1,
+	"cm": 10,
+	"in": 25.4,
+}
+
+// InfillConfig описывает упрощенную модель печати: оболочка заданной толщины
+// печатается сплошной, внутренний объем - с заданным процентом заполнения
+type InfillConfig struct {
+	Percent        float64 // процент заполнения 0-100
+	ShellThickness float64 // толщина оболочки в мм
 }
\ No newline at end of file
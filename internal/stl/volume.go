@@ -9,85 +9,166 @@ import (
 	"github.com/hschendel/stl"
 )
 
-// CalculateVolume вычисляет объем STL файла
+// MergeDensities объединяет встроенную таблицу плотностей с пользовательской.
+// Пользовательские значения переопределяют встроенные, ключи приводятся к верхнему регистру
+func MergeDensities(overrides map[string]float64) map[string]float64 {
+	merged := make(map[string]float64, len(MaterialDensity)+len(overrides))
+	for material, density := range MaterialDensity {
+		merged[strings.ToUpper(material)] = density
+	}
+	for material, density := range overrides {
+		if density <= 0 {
+			continue
+		}
+		merged[strings.ToUpper(strings.TrimSpace(material))] = density
+	}
+	return merged
+}
+
+// resolveDensity определяет плотность и название материала для расчета веса
+func resolveDensity(config VolumeConfig) (float64, string) {
+	density := config.Density
+	material := config.Material
+	if density != 0 || material == "" {
+		return density, material
+	}
+
+	densities := config.Densities
+	if densities == nil {
+		densities = MaterialDensity
+	}
+
+	if d, exists := densities[strings.ToUpper(strings.TrimSpace(material))]; exists {
+		return d, material
+	}
+	return 0, "Unknown"
+}
+
+// CalculateVolume вычисляет объем STL файла.
+// STEP файл (.step, .stp) предварительно тесселируется командой config.Tessellator (см. PrepareMeshFile)
 func CalculateVolume(filePath string, config VolumeConfig) (*VolumeResult, error) {
 	// Проверка существования файла
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return nil, fmt.Errorf("STL file not found: %s", filePath)
+		return nil, fmt.Errorf("model file not found: %s", filePath)
 	}
 
-	// Проверка расширения файла
-	if !strings.HasSuffix(strings.ToLower(filePath), ".stl") {
-		return nil, fmt.Errorf("file must have .stl extension: %s", filePath)
+	// STEP превращается во временный STL, STL читается как есть
+	meshFile, cleanup, err := PrepareMeshFile(filePath, config.Tessellator)
+	if err != nil {
+		return nil, err
 	}
+	defer cleanup()
 
 	// Чтение STL файла
-	solid, err := stl.ReadFile(filePath)
+	solid, err := stl.ReadFile(meshFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read STL file: %w", err)
 	}
 
+	// STL не хранит единицы измерения, координаты приводятся к миллиметрам
+	scale, err := SourceUnitScale(config.SourceUnits)
+	if err != nil {
+		return nil, err
+	}
+
 	// Конвертация в наш формат треугольников
-	triangles := convertToTriangles(solid)
+	triangles := scaleTriangles(convertToTriangles(solid), scale)
 	if len(triangles) == 0 {
 		return nil, fmt.Errorf("no triangles found in STL file")
 	}
 
 	// Вычисление объема с помощью алгоритма signed tetrahedron volumes
-	volume := calculateMeshVolume(triangles)
+	signedVolume := calculateMeshVolume(triangles)
 
-	// Валидация - объем должен быть положительным
-	isValid := volume > 0
-	if !isValid {
-		// Если объем отрицательный, вероятно нормали повернуты неправильно
-		volume = math.Abs(volume)
-	}
+	// Валидация - объем должен быть положительным, в отчет идет модуль объема
+	isValid, diagnostic := checkWinding(signedVolume, triangles, config.AssumePositive)
+	volume := math.Abs(signedVolume)
 
 	// Конвертация единиц измерения
 	convertedVolume, volumeUnit := convertVolumeUnits(volume, config.Units)
 
 	// Определение плотности материала
-	density := config.Density
-	material := config.Material
-	if density == 0 && material != "" {
-		if d, exists := MaterialDensity[strings.ToUpper(material)]; exists {
-			density = d
-		} else {
-			material = "Unknown"
-		}
+	density, material := resolveDensity(config)
+
+	// Площадь поверхности нужна для оценки объема оболочки
+	surfaceArea := calculateSurfaceArea(triangles)
+
+	// Объем с учетом заполнения (без настроек заполнения равен сплошному)
+	infillPercent := 100.0
+	var shellThickness float64
+	printedVolume := volume
+	if config.Infill != nil {
+		infillPercent = config.Infill.Percent
+		shellThickness = config.Infill.ShellThickness
+		printedVolume = calculateInfillVolume(volume, surfaceArea, infillPercent, shellThickness)
 	}
 
 	// Вычисление веса (если известна плотность)
-	var weight float64
+	var weight, adjustedWeight float64
 	if density > 0 {
 		// Конвертируем объем в см³ для расчета веса
-		volumeCm3 := convertToCm3(volume)
-		weight = volumeCm3 * density
+		weight = convertToCm3(volume) * density
+		adjustedWeight = convertToCm3(printedVolume) * density
 	}
 
 	result := &VolumeResult{
-		Volume:        convertedVolume,
-		VolumeUnit:    volumeUnit,
-		Triangles:     len(triangles),
-		Weight:        weight,
-		Material:      material,
-		Density:       density,
-		FilePath:      filePath,
-		IsValid:       isValid,
+		Volume:         convertedVolume,
+		VolumeUnit:     volumeUnit,
+		Triangles:      len(triangles),
+		Weight:         weight,
+		Material:       material,
+		Density:        density,
+		FilePath:       filePath,
+		IsValid:        isValid,
+		Diagnostic:     diagnostic,
+		SurfaceArea:    surfaceArea,
+		AdjustedWeight: adjustedWeight,
+		InfillPercent:  infillPercent,
+		ShellThickness: shellThickness,
 	}
 
 	return result, nil
 }
 
+// SourceUnitScale возвращает множитель перевода координат STL в миллиметры (пустые единицы - mm)
+func SourceUnitScale(units string) (float64, error) {
+	if units == "" {
+		return 1, nil
+	}
+	scale, exists := SourceUnitScales[strings.ToLower(strings.TrimSpace(units))]
+	if !exists {
+		return 0, fmt.Errorf("unsupported source units: %s (supported: mm, cm, in)", units)
+	}
+	return scale, nil
+}
+
+// scaleTriangles умножает координаты вершин на scale (на месте)
+func scaleTriangles(triangles []Triangle, scale float64) []Triangle {
+	if scale == 1 {
+		return triangles
+	}
+	for i := range triangles {
+		triangles[i].V0 = scaleVector(triangles[i].V0, scale)
+		triangles[i].V1 = scaleVector(triangles[i].V1, scale)
+		triangles[i].V2 = scaleVector(triangles[i].V2, scale)
+	}
+	return triangles
+}
+
+func scaleVector(v Vector3D, scale float64) Vector3D {
+	return Vector3D{X: v.X * scale, Y: v.Y * scale, Z: v.Z * scale}
+}
+
 // convertToTriangles конвертирует STL solid в наш формат треугольников
 func convertToTriangles(solid *stl.Solid) []Triangle {
 	triangles := make([]Triangle, len(solid.Triangles))
 	
 	for i, t := range solid.Triangles {
 		triangles[i] = Triangle{
-			V0: Vector3D{X: float64(t.Vertices[0][0]), Y: float64(t.Vertices[0][1]), Z: float64(t.Vertices[0][2])},
-			V1: Vector3D{X: float64(t.Vertices[1][0]), Y: float64(t.Vertices[1][1]), Z: float64(t.Vertices[1][2])},
-			V2: Vector3D{X: float64(t.Vertices[2][0]), Y: float64(t.Vertices[2][1]), Z: float64(t.Vertices[2][2])},
+			V0:     Vector3D{X: float64(t.Vertices[0][0]), Y: float64(t.Vertices[0][1]), Z: float64(t.Vertices[0][2])},
+			V1:     Vector3D{X: float64(t.Vertices[1][0]), Y: float64(t.Vertices[1][1]), Z: float64(t.Vertices[1][2])},
+			V2:     Vector3D{X: float64(t.Vertices[2][0]), Y: float64(t.Vertices[2][1]), Z: float64(t.Vertices[2][2])},
+			Normal: Vector3D{X: float64(t.Normal[0]), Y: float64(t.Normal[1]), Z: float64(t.Normal[2])},
 		}
 	}
 	
@@ -114,7 +195,73 @@ func calculateMeshVolume(triangles []Triangle) float64 {
 		volume += signedVolume
 	}
 	
-	return math.Abs(volume)
+	// Знак сохраняется: отрицательный объем означает вывернутую поверхность (см. checkWinding)
+	return volume
+}
+
+// checkWinding проверяет знак объема и сверяет порядок вершин с сохраненными нормалями.
+// Отрицательный объем делает модель невалидной с диагностикой, которая различает
+// вывернутую модель (нормали тоже смотрят внутрь) и обратный порядок вершин при
+// правильных нормалях. С assumePositive отрицательный объем считается допустимым
+func checkWinding(signedVolume float64, triangles []Triangle, assumePositive bool) (bool, string) {
+	if signedVolume == 0 {
+		return false, "zero volume: mesh is flat or not closed"
+	}
+	if signedVolume > 0 || assumePositive {
+		return true, ""
+	}
+
+	withNormals, mismatched := countNormalMismatches(triangles)
+	if withNormals > 0 && mismatched*2 > withNormals {
+		return false, fmt.Sprintf("inverted winding: vertex order of %d of %d triangles is opposite to their stored normals (volume taken as absolute value)", mismatched, withNormals)
+	}
+	return false, "inverted winding: mesh is inside out, faces and normals point inward (volume taken as absolute value)"
+}
+
+// countNormalMismatches возвращает число треугольников с сохраненной нормалью
+// и число тех из них, у которых нормаль противоположна порядку вершин
+func countNormalMismatches(triangles []Triangle) (withNormals, mismatched int) {
+	for _, triangle := range triangles {
+		if triangle.Normal == (Vector3D{}) {
+			continue
+		}
+		withNormals++
+
+		windingNormal := crossProduct(
+			Vector3D{X: triangle.V1.X - triangle.V0.X, Y: triangle.V1.Y - triangle.V0.Y, Z: triangle.V1.Z - triangle.V0.Z},
+			Vector3D{X: triangle.V2.X - triangle.V0.X, Y: triangle.V2.Y - triangle.V0.Y, Z: triangle.V2.Z - triangle.V0.Z},
+		)
+		if dotProduct(windingNormal, triangle.Normal) < 0 {
+			mismatched++
+		}
+	}
+	return withNormals, mismatched
+}
+
+// calculateSurfaceArea вычисляет площадь поверхности mesh (в единицах файла, обычно мм²)
+func calculateSurfaceArea(triangles []Triangle) float64 {
+	area := 0.0
+	for _, triangle := range triangles {
+		edge1 := Vector3D{X: triangle.V1.X - triangle.V0.X, Y: triangle.V1.Y - triangle.V0.Y, Z: triangle.V1.Z - triangle.V0.Z}
+		edge2 := Vector3D{X: triangle.V2.X - triangle.V0.X, Y: triangle.V2.Y - triangle.V0.Y, Z: triangle.V2.Z - triangle.V0.Z}
+		cross := crossProduct(edge1, edge2)
+		area += math.Sqrt(dotProduct(cross, cross)) / 2.0
+	}
+	return area
+}
+
+// calculateInfillVolume оценивает фактически напечатанный объем:
+// оболочка (площадь поверхности * толщина) считается сплошной,
+// внутренний объем - с процентом заполнения infillPercent
+func calculateInfillVolume(solidVolume, surfaceArea, infillPercent, shellThickness float64) float64 {
+	shellVolume := surfaceArea * shellThickness
+	if shellVolume >= solidVolume {
+		// Тонкостенная модель - печатается целиком сплошной
+		return solidVolume
+	}
+
+	interiorVolume := solidVolume - shellVolume
+	return shellVolume + interiorVolume*infillPercent/100.0
 }
 
 // crossProduct вычисляет векторное произведение двух векторов
@@ -154,8 +301,15 @@ func convertToCm3(volumeMM3 float64) float64 {
 }
 
 // GetBoundingBox возвращает ограничивающий параллелепипед для STL файла
-func GetBoundingBox(filePath string) (*BoundingBox, error) {
-	solid, err := stl.ReadFile(filePath)
+// STEP файл тесселируется командой tessellator, как в CalculateVolume
+func GetBoundingBox(filePath, tessellator string) (*BoundingBox, error) {
+	meshFile, cleanup, err := PrepareMeshFile(filePath, tessellator)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	solid, err := stl.ReadFile(meshFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read STL file: %w", err)
 	}
@@ -189,6 +343,12 @@ func GetBoundingBox(filePath string) (*BoundingBox, error) {
 	return bbox, nil
 }
 
+// Scaled возвращает параллелепипед с координатами, умноженными на scale
+// (например, SourceUnitScale для перевода в миллиметры)
+func (bbox *BoundingBox) Scaled(scale float64) *BoundingBox {
+	return &BoundingBox{Min: scaleVector(bbox.Min, scale), Max: scaleVector(bbox.Max, scale)}
+}
+
 // GetDimensions возвращает размеры модели в миллиметрах
 func (bbox *BoundingBox) GetDimensions() (width, depth, height float64) {
 	return bbox.Max.X - bbox.Min.X,
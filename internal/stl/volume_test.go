@@ -0,0 +1,304 @@
+package stl
+
+import (
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hschendel/stl"
+)
+
+func TestMergeDensities(t *testing.T) {
+	merged := MergeDensities(map[string]float64{
+		"pla":      1.30,
+		"PA-CF":    1.17,
+		"Glow PLA": 1.32,
+		"broken":   0,
+	})
+
+	tests := []struct {
+		name     string
+		material string
+		expected float64
+		exists   bool
+	}{
+		{"config overrides built-in", "PLA", 1.30, true},
+		{"new specialty material", "PA-CF", 1.17, true},
+		{"key upper-cased", "GLOW PLA", 1.32, true},
+		{"built-in kept", "PETG", 1.27, true},
+		{"non-positive density skipped", "BROKEN", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			density, exists := merged[tt.material]
+			if exists != tt.exists || density != tt.expected {
+				t.Errorf("merged[%q] = (%v, %v), want (%v, %v)", tt.material, density, exists, tt.expected, tt.exists)
+			}
+		})
+	}
+
+	if MaterialDensity["PLA"] != 1.24 {
+		t.Errorf("built-in table must not be modified, PLA = %v", MaterialDensity["PLA"])
+	}
+}
+
+func TestResolveDensity(t *testing.T) {
+	densities := MergeDensities(map[string]float64{"PLA": 1.30, "pa-cf": 1.17})
+
+	tests := []struct {
+		name             string
+		config           VolumeConfig
+		expectedDensity  float64
+		expectedMaterial string
+	}{
+		{
+			name:             "config entry overrides built-in",
+			config:           VolumeConfig{Material: "PLA", Densities: densities},
+			expectedDensity:  1.30,
+			expectedMaterial: "PLA",
+		},
+		{
+			name:             "case-insensitive lookup",
+			config:           VolumeConfig{Material: "Pa-Cf", Densities: densities},
+			expectedDensity:  1.17,
+			expectedMaterial: "Pa-Cf",
+		},
+		{
+			name:             "built-in table when no overrides",
+			config:           VolumeConfig{Material: "petg"},
+			expectedDensity:  1.27,
+			expectedMaterial: "petg",
+		},
+		{
+			name:             "unknown material",
+			config:           VolumeConfig{Material: "Unobtainium", Densities: densities},
+			expectedDensity:  0,
+			expectedMaterial: "Unknown",
+		},
+		{
+			name:             "explicit density wins",
+			config:           VolumeConfig{Material: "PLA", Density: 2.0, Densities: densities},
+			expectedDensity:  2.0,
+			expectedMaterial: "PLA",
+		},
+		{
+			name:             "no material",
+			config:           VolumeConfig{Densities: densities},
+			expectedDensity:  0,
+			expectedMaterial: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			density, material := resolveDensity(tt.config)
+			if density != tt.expectedDensity || material != tt.expectedMaterial {
+				t.Errorf("resolveDensity() = (%v, %q), want (%v, %q)", density, material, tt.expectedDensity, tt.expectedMaterial)
+			}
+		})
+	}
+}
+
+func TestCalculateSurfaceArea(t *testing.T) {
+	triangles := []Triangle{
+		{V0: Vector3D{0, 0, 0}, V1: Vector3D{10, 0, 0}, V2: Vector3D{0, 10, 0}},
+		{V0: Vector3D{0, 0, 0}, V1: Vector3D{0, 4, 0}, V2: Vector3D{0, 0, 5}},
+	}
+
+	if area := calculateSurfaceArea(triangles); math.Abs(area-60) > 1e-9 {
+		t.Errorf("calculateSurfaceArea() = %v, want 60", area)
+	}
+}
+
+func TestCalculateInfillVolume(t *testing.T) {
+	// Куб 10 мм: объем 1000 мм³, площадь 600 мм²
+	tests := []struct {
+		name           string
+		infillPercent  float64
+		shellThickness float64
+		expected       float64
+	}{
+		{"100% infill equals solid", 100, 0.8, 1000},
+		{"0% infill is shell only", 0, 0.8, 480},
+		{"partial infill", 20, 0.8, 480 + 520*0.2},
+		{"no shell scales interior", 50, 0, 500},
+		{"shell thicker than model stays solid", 0, 2, 1000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			volume := calculateInfillVolume(1000, 600, tt.infillPercent, tt.shellThickness)
+			if math.Abs(volume-tt.expected) > 1e-9 {
+				t.Errorf("calculateInfillVolume() = %v, want %v", volume, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCalculateVolumeInfill(t *testing.T) {
+	solid, err := CalculateVolume("../../samples/test_cube.stl", VolumeConfig{Units: "mm3", Material: "PLA"})
+	if err != nil {
+		t.Fatalf("CalculateVolume() error = %v", err)
+	}
+	if solid.AdjustedWeight != solid.Weight || solid.InfillPercent != 100 {
+		t.Errorf("without infill config adjusted weight = %v (infill %v), want solid %v", solid.AdjustedWeight, solid.InfillPercent, solid.Weight)
+	}
+
+	shellOnly, err := CalculateVolume("../../samples/test_cube.stl", VolumeConfig{
+		Units:    "mm3",
+		Material: "PLA",
+		Infill:   &InfillConfig{Percent: 0, ShellThickness: 0.8},
+	})
+	if err != nil {
+		t.Fatalf("CalculateVolume() error = %v", err)
+	}
+	expected := 480 / 1000.0 * 1.24
+	if math.Abs(shellOnly.AdjustedWeight-expected) > 1e-9 {
+		t.Errorf("shell-only adjusted weight = %v, want %v", shellOnly.AdjustedWeight, expected)
+	}
+	if shellOnly.Weight != solid.Weight {
+		t.Errorf("solid weight changed with infill config: %v != %v", shellOnly.Weight, solid.Weight)
+	}
+}
+
+// unitCube возвращает треугольники куба со стороной 1 (в единицах файла):
+// вершины обходятся против часовой стрелки снаружи, нормали смотрят наружу
+func unitCube() []stl.Triangle {
+	v := func(x, y, z float32) stl.Vec3 { return stl.Vec3{x, y, z} }
+	faces := []struct {
+		normal  stl.Vec3
+		corners [4]stl.Vec3
+	}{
+		{v(0, 0, -1), [4]stl.Vec3{v(0, 0, 0), v(0, 1, 0), v(1, 1, 0), v(1, 0, 0)}}, // низ
+		{v(0, 0, 1), [4]stl.Vec3{v(0, 0, 1), v(1, 0, 1), v(1, 1, 1), v(0, 1, 1)}},  // верх
+		{v(0, -1, 0), [4]stl.Vec3{v(0, 0, 0), v(1, 0, 0), v(1, 0, 1), v(0, 0, 1)}}, // перед
+		{v(0, 1, 0), [4]stl.Vec3{v(0, 1, 0), v(0, 1, 1), v(1, 1, 1), v(1, 1, 0)}},  // зад
+		{v(-1, 0, 0), [4]stl.Vec3{v(0, 0, 0), v(0, 0, 1), v(0, 1, 1), v(0, 1, 0)}}, // лево
+		{v(1, 0, 0), [4]stl.Vec3{v(1, 0, 0), v(1, 1, 0), v(1, 1, 1), v(1, 0, 1)}},  // право
+	}
+
+	var triangles []stl.Triangle
+	for _, f := range faces {
+		c := f.corners
+		triangles = append(triangles,
+			stl.Triangle{Normal: f.normal, Vertices: [3]stl.Vec3{c[0], c[1], c[2]}},
+			stl.Triangle{Normal: f.normal, Vertices: [3]stl.Vec3{c[0], c[2], c[3]}},
+		)
+	}
+	return triangles
+}
+
+// reverseWinding меняет порядок обхода вершин; с flipNormals нормали тоже разворачиваются
+func reverseWinding(triangles []stl.Triangle, flipNormals bool) []stl.Triangle {
+	result := make([]stl.Triangle, len(triangles))
+	for i, triangle := range triangles {
+		triangle.Vertices[1], triangle.Vertices[2] = triangle.Vertices[2], triangle.Vertices[1]
+		if flipNormals {
+			triangle.Normal = stl.Vec3{-triangle.Normal[0], -triangle.Normal[1], -triangle.Normal[2]}
+		}
+		result[i] = triangle
+	}
+	return result
+}
+
+// writeSTL сохраняет треугольники в бинарный STL во временной директории
+func writeSTL(t *testing.T, triangles []stl.Triangle) string {
+	t.Helper()
+
+	solid := &stl.Solid{Name: "cube", Triangles: triangles, IsAscii: false}
+	path := filepath.Join(t.TempDir(), "cube.stl")
+	if err := solid.WriteFile(path); err != nil {
+		t.Fatalf("failed to write STL: %v", err)
+	}
+	return path
+}
+
+// writeUnitCube сохраняет правильно ориентированный куб со стороной 1
+func writeUnitCube(t *testing.T) string {
+	return writeSTL(t, unitCube())
+}
+
+func TestCalculateVolumeSourceUnits(t *testing.T) {
+	path := writeUnitCube(t)
+
+	tests := []struct {
+		sourceUnits string
+		expected    float64 // мм³
+	}{
+		{"", 1},
+		{"mm", 1},
+		{"cm", 1000},
+		{"in", 25.4 * 25.4 * 25.4},
+		{"IN", 25.4 * 25.4 * 25.4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sourceUnits, func(t *testing.T) {
+			result, err := CalculateVolume(path, VolumeConfig{Units: "mm3", SourceUnits: tt.sourceUnits, Material: "PLA"})
+			if err != nil {
+				t.Fatalf("CalculateVolume() error = %v", err)
+			}
+			if !result.IsValid {
+				t.Error("unit cube should be a valid mesh")
+			}
+			if math.Abs(result.Volume-tt.expected) > tt.expected*1e-6 {
+				t.Errorf("volume = %v mm³, want %v", result.Volume, tt.expected)
+			}
+			if expectedWeight := tt.expected / 1000 * 1.24; math.Abs(result.Weight-expectedWeight) > expectedWeight*1e-6 {
+				t.Errorf("weight = %v g, want %v", result.Weight, expectedWeight)
+			}
+		})
+	}
+
+	if _, err := CalculateVolume(path, VolumeConfig{SourceUnits: "ft"}); err == nil {
+		t.Error("expected error for unsupported source units")
+	}
+}
+
+func TestBoundingBoxScaled(t *testing.T) {
+	bbox := &BoundingBox{Min: Vector3D{0, 0, 0}, Max: Vector3D{1, 2, 3}}
+	width, depth, height := bbox.Scaled(25.4).GetDimensions()
+	for i, pair := range [][2]float64{{width, 25.4}, {depth, 50.8}, {height, 76.2}} {
+		if math.Abs(pair[0]-pair[1]) > 1e-9 {
+			t.Errorf("dimension %d = %v, want %v", i, pair[0], pair[1])
+		}
+	}
+}
+
+func TestCalculateVolumeWindingDiagnostics(t *testing.T) {
+	tests := []struct {
+		name           string
+		triangles      []stl.Triangle
+		assumePositive bool
+		wantValid      bool
+		wantDiagnostic string
+	}{
+		{"correctly wound cube", unitCube(), false, true, ""},
+		{"inside-out cube", reverseWinding(unitCube(), true), false, false, "mesh is inside out"},
+		{"winding opposite to normals", reverseWinding(unitCube(), false), false, false, "opposite to their stored normals"},
+		{"inside-out cube with assume positive", reverseWinding(unitCube(), true), true, true, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := CalculateVolume(writeSTL(t, tt.triangles), VolumeConfig{AssumePositive: tt.assumePositive})
+			if err != nil {
+				t.Fatalf("CalculateVolume() error = %v", err)
+			}
+			if math.Abs(result.Volume-1) > 1e-6 {
+				t.Errorf("volume = %v, want absolute value 1", result.Volume)
+			}
+			if result.IsValid != tt.wantValid {
+				t.Errorf("IsValid = %v, want %v", result.IsValid, tt.wantValid)
+			}
+			if tt.wantDiagnostic == "" && result.Diagnostic != "" {
+				t.Errorf("unexpected diagnostic %q", result.Diagnostic)
+			}
+			if !strings.Contains(result.Diagnostic, tt.wantDiagnostic) {
+				t.Errorf("diagnostic = %q, want it to contain %q", result.Diagnostic, tt.wantDiagnostic)
+			}
+		})
+	}
+}